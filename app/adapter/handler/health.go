@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfajri11/xyz-backend-monolith/util/health"
+)
+
+// HealthHandler answers the probes of the orchestrator and the load balancer, its responses are not
+// wrapped in the general response as they are read by machines.
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Liveness only tells the process is able to answer, dependencies are left to readiness so a database
+// outage does not restart every instance.
+func (handler *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusUp})
+}
+
+func (handler *HealthHandler) Readiness(c *gin.Context) {
+	report := handler.checker.Ready(c)
+	c.JSON(statusCode(report), health.Report{Status: report.Status})
+}
+
+// Status is the report for operators: build version, status and latency of every dependency. it is not
+// authenticated so it leaves out their errors and details, the status admin task prints them.
+func (handler *HealthHandler) Status(c *gin.Context) {
+	report := handler.checker.Ready(c)
+	c.JSON(statusCode(report), report.Summary())
+}
+
+func statusCode(report health.Report) int {
+	if report.Status == health.StatusDown {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
@@ -1,42 +1,41 @@
 package handler
 
 import (
-	"fmt"
-	"net/http"
+	"errors"
 
-	"github.com/gin-contrib/requestid"
 	"github.com/gin-gonic/gin"
 	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
 	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
 	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
-	"github.com/rs/zerolog/log"
 )
 
 type LoanHandler struct {
-	loanService port.LoanService
-	userService port.UserService
+	loanService            port.LoanService
+	userService            port.UserService
+	loanApplicationService port.LoanApplicationService
 }
 
-func New(loanService port.LoanService, userService port.UserService) *LoanHandler {
+func New(loanService port.LoanService, userService port.UserService, loanApplicationService port.LoanApplicationService) *LoanHandler {
+	registerValidation()
 	return &LoanHandler{
-		loanService: loanService,
-		userService: userService,
+		loanService:            loanService,
+		userService:            userService,
+		loanApplicationService: loanApplicationService,
 	}
 }
 
 func (handler *LoanHandler) CreateLoan(c *gin.Context) {
 	var req domain.CreateLoanReq
-	rid := requestid.Get(c)
-	logger := log.With().Str("requestID", rid).Logger()
 	uid := c.GetInt64("uid")
 	if uid == 0 {
-		logger.Error().Err(fmt.Errorf("CreateLoan: invalid user id")).Msg("")
-		writeError(c, apperror.ErrBadRequest)
+		writeError(c, apperror.WrapError(errors.New("CreateLoan: invalid user id"), apperror.ErrBadRequest))
 		return
 	}
 
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
+		writeBindError(c, err)
+		return
 	}
 
 	isValid, err := handler.userService.ValidateData(c, domain.ValidateUserReq{
@@ -49,37 +48,89 @@ func (handler *LoanHandler) CreateLoan(c *gin.Context) {
 	})
 
 	if err != nil {
-		logger.Error().Err(err).Msg("error while validate data")
 		writeError(c, err)
 		return
 	}
 
 	if !isValid {
-		logger.Err(fmt.Errorf("CreateLoan: invalid user data")).Msg("")
-		writeError(c, apperror.ErrBadRequest)
+		writeError(c, apperror.WrapError(errors.New("CreateLoan: invalid user data"), apperror.ErrBadRequest))
 		return
 	}
 
-	otrAmoutn := handler.loanService.CalculateOTRAmount(req.Amount)
+	// a loan is no longer inserted directly, the request is filed as a loan application
+	// and converted into a contract once it is approved.
+	app, err := handler.loanApplicationService.CreateDraft(c, uid)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
 
-	handler.loanService.CreateLoan(c, domain.Loan{
-		UserID:          uid,
-		ContractNumber:  req.ContractNumber,
-		OTRAmount:       otrAmoutn,
-		PrincipalAmount: otrAmoutn - req.DownPayment,
-	})
+	steps := []func() (*domain.LoanApplication, error){
+		func() (*domain.LoanApplication, error) {
+			return handler.loanApplicationService.UpdateAsset(c, uid, app.ID, domain.UpdateLoanApplicationAssetReq{
+				AssetName:   req.AssetName,
+				Amount:      req.Amount,
+				DownPayment: req.DownPayment,
+				LoanTypeID:  req.LoanTypeID,
+				OTRAmount:   handler.loanService.CalculateOTRAmount(req.Amount),
+			})
+		},
+		func() (*domain.LoanApplication, error) {
+			return handler.loanApplicationService.UpdateTenor(c, uid, app.ID, domain.UpdateLoanApplicationTenorReq{
+				LimitTypeID: req.LimitTypeID,
+				Tenor:       req.Tenor,
+			})
+		},
+		func() (*domain.LoanApplication, error) {
+			return handler.loanApplicationService.UpdateDocuments(c, uid, app.ID, domain.UpdateLoanApplicationDocumentsReq{
+				NationalIDPhoto: req.NationalIDPhoto,
+				UserPhoto:       req.UserPhoto,
+			})
+		},
+		func() (*domain.LoanApplication, error) {
+			return handler.loanApplicationService.Submit(c, uid, app.ID)
+		},
+	}
+
+	for _, step := range steps {
+		app, err = step()
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+	}
+
+	writeSuccess(c, presentLoanApplication(*app, language(c)))
+}
+
+func (handler *LoanHandler) ListLoans(c *gin.Context) {
+	var req domain.ListLoansReq
+	uid := c.GetInt64("uid")
+	if uid == 0 {
+		writeError(c, apperror.WrapError(errors.New("ListLoans: invalid user id"), apperror.ErrBadRequest))
+		return
+	}
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	page, err := handler.loanService.ListLoans(c, uid, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
 
-	c.JSON(http.StatusOK, nil)
+	writeSuccess(c, presentLoanPage(*page))
 }
 
 func (handler *LoanHandler) GetLoanByContractNumber(c *gin.Context) {
 	contractNumber := c.Param("contractNumber")
-	rid := requestid.Get(c)
-	logger := log.With().Str("requestID", rid).Logger()
 	uid := c.GetInt64("uid")
 	if uid == 0 {
-		logger.Error().Err(fmt.Errorf("CreateLoan: invalid user id")).Msg("")
-		writeError(c, apperror.ErrBadRequest)
+		writeError(c, apperror.WrapError(errors.New("GetLoanByContractNumber: invalid user id"), apperror.ErrBadRequest))
+		return
 	}
 	loan, err := handler.loanService.GetLoanByUserIDAndContractNumber(c, uid, contractNumber)
 	if err != nil {
@@ -87,18 +138,16 @@ func (handler *LoanHandler) GetLoanByContractNumber(c *gin.Context) {
 		return
 	}
 
-	writeSuccess(c, loan)
+	setETag(c, loan.Version)
+	writeSuccess(c, presentLoan(*loan))
 }
 
 func (handler *LoanHandler) GetLoanPaymentsByContractNumber(c *gin.Context) {
 	contractNumber := c.Param("contractNumber")
-	rid := requestid.Get(c)
-	logger := log.With().Str("requestID", rid).Logger()
-
 	uid := c.GetInt64("uid")
 	if uid == 0 {
-		logger.Error().Err(fmt.Errorf("CreateLoan: invalid user id")).Msg("")
-		writeError(c, apperror.ErrBadRequest)
+		writeError(c, apperror.WrapError(errors.New("GetLoanPaymentsByContractNumber: invalid user id"), apperror.ErrBadRequest))
+		return
 	}
 	loanPayments, err := handler.loanService.GetLoanPaymentsByUserIDAndContractNumber(c, uid, contractNumber)
 	if err != nil {
@@ -106,5 +155,38 @@ func (handler *LoanHandler) GetLoanPaymentsByContractNumber(c *gin.Context) {
 		return
 	}
 
-	writeSuccess(c, loanPayments)
+	writeSuccess(c, presentLoanPayments(loanPayments))
+}
+
+// PostLoanPayment posts a payment to the loan, with If-Match it is only posted while the loan is still at
+// the version of the entity tag.
+func (handler *LoanHandler) PostLoanPayment(c *gin.Context) {
+	var req domain.PostLoanPaymentReq
+	contractNumber := c.Param("contractNumber")
+	uid := c.GetInt64("uid")
+	if uid == 0 {
+		writeError(c, apperror.WrapError(errors.New("PostLoanPayment: invalid user id"), apperror.ErrBadRequest))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	version, err := ifMatch(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	req.Version = version
+
+	loan, err := handler.loanService.PostLoanPayment(c, uid, contractNumber, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	setETag(c, loan.Version)
+	writeSuccess(c, presentLoan(*loan))
 }
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+func (handler *LoanHandler) CreateLoanApplication(c *gin.Context) {
+	uid := c.GetInt64("uid")
+	if uid == 0 {
+		writeError(c, apperror.WrapError(errors.New("CreateLoanApplication: invalid user id"), apperror.ErrBadRequest))
+		return
+	}
+
+	app, err := handler.loanApplicationService.CreateDraft(c, uid)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	writeSuccess(c, presentLoanApplication(*app, language(c)))
+}
+
+func (handler *LoanHandler) GetLoanApplication(c *gin.Context) {
+	uid, id, ok := applicationParams(c)
+	if !ok {
+		writeError(c, apperror.WrapError(errors.New("GetLoanApplication: invalid user id or application id"), apperror.ErrBadRequest))
+		return
+	}
+
+	app, err := handler.loanApplicationService.GetLoanApplication(c, uid, id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	writeSuccess(c, presentLoanApplication(*app, language(c)))
+}
+
+func (handler *LoanHandler) UpdateLoanApplicationAsset(c *gin.Context) {
+	var req domain.UpdateLoanApplicationAssetReq
+	uid, id, ok := applicationParams(c)
+	if !ok {
+		writeError(c, apperror.WrapError(errors.New("UpdateLoanApplicationAsset: invalid user id or application id"), apperror.ErrBadRequest))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	req.OTRAmount = handler.loanService.CalculateOTRAmount(req.Amount)
+	app, err := handler.loanApplicationService.UpdateAsset(c, uid, id, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	writeSuccess(c, presentLoanApplication(*app, language(c)))
+}
+
+func (handler *LoanHandler) UpdateLoanApplicationTenor(c *gin.Context) {
+	var req domain.UpdateLoanApplicationTenorReq
+	uid, id, ok := applicationParams(c)
+	if !ok {
+		writeError(c, apperror.WrapError(errors.New("UpdateLoanApplicationTenor: invalid user id or application id"), apperror.ErrBadRequest))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	app, err := handler.loanApplicationService.UpdateTenor(c, uid, id, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	writeSuccess(c, presentLoanApplication(*app, language(c)))
+}
+
+func (handler *LoanHandler) UpdateLoanApplicationDocuments(c *gin.Context) {
+	var req domain.UpdateLoanApplicationDocumentsReq
+	uid, id, ok := applicationParams(c)
+	if !ok {
+		writeError(c, apperror.WrapError(errors.New("UpdateLoanApplicationDocuments: invalid user id or application id"), apperror.ErrBadRequest))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBindError(c, err)
+		return
+	}
+
+	app, err := handler.loanApplicationService.UpdateDocuments(c, uid, id, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	writeSuccess(c, presentLoanApplication(*app, language(c)))
+}
+
+func (handler *LoanHandler) SubmitLoanApplication(c *gin.Context) {
+	uid, id, ok := applicationParams(c)
+	if !ok {
+		writeError(c, apperror.WrapError(errors.New("SubmitLoanApplication: invalid user id or application id"), apperror.ErrBadRequest))
+		return
+	}
+
+	app, err := handler.loanApplicationService.Submit(c, uid, id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	writeSuccess(c, presentLoanApplication(*app, language(c)))
+}
+
+func (handler *LoanHandler) AbandonLoanApplication(c *gin.Context) {
+	uid, id, ok := applicationParams(c)
+	if !ok {
+		writeError(c, apperror.WrapError(errors.New("AbandonLoanApplication: invalid user id or application id"), apperror.ErrBadRequest))
+		return
+	}
+
+	app, err := handler.loanApplicationService.Abandon(c, uid, id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	writeSuccess(c, presentLoanApplication(*app, language(c)))
+}
+
+func applicationParams(c *gin.Context) (uid int64, id int64, ok bool) {
+	uid = c.GetInt64("uid")
+	if uid == 0 {
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, 0, false
+	}
+
+	return uid, id, true
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/util/i18n"
+)
+
+// the types below are the v1 json contract of the api. domain types must never be written to the client directly,
+// a breaking change to any of these types needs a new version instead of an edit.
+//
+// conventions: money is a decimal string with two fraction digits, calendar dates are yyyy-mm-dd,
+// instants are RFC 3339 in UTC and enums are plain strings. absent values are null.
+
+type LoanV1 struct {
+	ID              int64       `json:"id"`
+	ContractNumber  string      `json:"contract_number"`
+	AssetName       string      `json:"asset_name"`
+	LoanType        string      `json:"loan_type"`
+	Status          *string     `json:"status"`
+	OTRAmount       string      `json:"otr_amount"`
+	PrincipalAmount string      `json:"principal_amount"`
+	InterestRate    *string     `json:"interest_rate"`
+	StartDate       *string     `json:"start_date"`
+	Limit           LoanLimitV1 `json:"limit"`
+}
+
+type LoanLimitV1 struct {
+	Amount *string `json:"amount"`
+	Term   int8    `json:"term"`
+}
+
+type LoanSummaryV1 struct {
+	LoanV1
+	NextDueDate       *string `json:"next_due_date"`
+	OutstandingAmount string  `json:"outstanding_amount"`
+	PaidAmount        string  `json:"paid_amount"`
+	InstallmentsPaid  int     `json:"installments_paid"`
+	InstallmentsTotal int     `json:"installments_total"`
+}
+
+type LoanPageV1 struct {
+	Loans      []LoanSummaryV1 `json:"loans"`
+	NextCursor *string         `json:"next_cursor"`
+}
+
+type LoanPaymentV1 struct {
+	ID      int64  `json:"id"`
+	LoanID  int64  `json:"loan_id"`
+	Amount  string `json:"amount"`
+	Date    string `json:"date"`
+	Channel string `json:"channel"`
+}
+
+type LoanApplicationV1 struct {
+	ID                 int64   `json:"id"`
+	Status             string  `json:"status"`
+	AssetName          *string `json:"asset_name"`
+	OTRAmount          *string `json:"otr_amount"`
+	DownPayment        *string `json:"down_payment"`
+	LoanTypeID         *int16  `json:"loan_type_id"`
+	LimitTypeID        *int16  `json:"limit_type_id"`
+	Tenor              *int16  `json:"tenor"`
+	HasNationalIDPhoto bool    `json:"has_national_id_photo"`
+	HasUserPhoto       bool    `json:"has_user_photo"`
+	ContractNumber     *string `json:"contract_number"`
+	DecisionReason     *string `json:"decision_reason"`
+	SubmittedAt        *string `json:"submitted_at"`
+	DecidedAt          *string `json:"decided_at"`
+	// Notification tells the customer the decision in their language, it is null until the decision.
+	Notification *string `json:"notification"`
+	CreatedAt    string  `json:"created_at"`
+	UpdatedAt    string  `json:"updated_at"`
+}
+
+func presentLoan(loan domain.LoanAll) LoanV1 {
+	return LoanV1{
+		ID:              loan.ID,
+		ContractNumber:  loan.ContractNumber,
+		AssetName:       loan.AssetName,
+		LoanType:        string(loan.LoanType.Name),
+		Status:          nullString(loan.Status),
+		OTRAmount:       money(loan.OTRAmount),
+		PrincipalAmount: money(loan.PrincipalAmount),
+		InterestRate:    nullRate(loan.InterestRate),
+		StartDate:       nullDate(loan.StartDate),
+		Limit: LoanLimitV1{
+			Amount: nullMoney(loan.LimitType.Amount),
+			Term:   loan.LimitType.Term,
+		},
+	}
+}
+
+func presentLoanPage(page domain.LoanPage) LoanPageV1 {
+	resp := LoanPageV1{Loans: make([]LoanSummaryV1, 0, len(page.Loans))}
+	for _, loan := range page.Loans {
+		resp.Loans = append(resp.Loans, LoanSummaryV1{
+			LoanV1:            presentLoan(loan.LoanAll),
+			NextDueDate:       nullDate(loan.NextDueDate),
+			OutstandingAmount: money(loan.OutstandingAmount),
+			PaidAmount:        money(loan.PaidAmount),
+			InstallmentsPaid:  loan.InstallmentsPaid,
+			InstallmentsTotal: loan.InstallmentsTotal,
+		})
+	}
+	if page.NextCursor != "" {
+		resp.NextCursor = &page.NextCursor
+	}
+
+	return resp
+}
+
+func presentLoanPayments(payments []domain.LoanPayment) []LoanPaymentV1 {
+	resp := make([]LoanPaymentV1, 0, len(payments))
+	for _, payment := range payments {
+		resp = append(resp, LoanPaymentV1{
+			ID:      payment.ID,
+			LoanID:  payment.LoanID,
+			Amount:  money(payment.Amount),
+			Date:    instant(payment.Date),
+			Channel: payment.Channel,
+		})
+	}
+
+	return resp
+}
+
+func presentLoanApplication(app domain.LoanApplication, lang i18n.Lang) LoanApplicationV1 {
+	resp := LoanApplicationV1{
+		ID:                 app.ID,
+		Status:             string(app.Status),
+		AssetName:          nullString(app.AssetName),
+		OTRAmount:          nullMoney(app.OTRAmount),
+		DownPayment:        nullMoney(app.DownPayment),
+		HasNationalIDPhoto: len(app.NationalIDPhoto) > 0,
+		HasUserPhoto:       len(app.UserPhoto) > 0,
+		ContractNumber:     nullString(app.ContractNumber),
+		DecisionReason:     nullString(app.DecisionReason),
+		SubmittedAt:        nullInstant(app.SubmittedAt),
+		DecidedAt:          nullInstant(app.DecidedAt),
+		CreatedAt:          instant(app.CreatedAt),
+		UpdatedAt:          instant(app.UpdatedAt),
+	}
+	if app.LoanTypeID.Valid {
+		resp.LoanTypeID = &app.LoanTypeID.Int16
+	}
+	if app.LimitTypeID.Valid {
+		resp.LimitTypeID = &app.LimitTypeID.Int16
+	}
+	if app.Tenor.Valid {
+		resp.Tenor = &app.Tenor.Int16
+	}
+	if app.Status == domain.ApplicationApproved || app.Status == domain.ApplicationRejected {
+		notification := i18n.Message(lang, i18n.NotificationPrefix+"LOAN_APPLICATION_"+string(app.Status), i18n.Params{
+			"amount":          i18n.IDR(app.PrincipalAmount()),
+			"contract_number": app.ContractNumber.String,
+		})
+		resp.Notification = &notification
+	}
+
+	return resp
+}
+
+func money(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func nullMoney(v sql.NullFloat64) *string {
+	if !v.Valid {
+		return nil
+	}
+	s := money(v.Float64)
+	return &s
+}
+
+func nullRate(v sql.NullFloat64) *string {
+	if !v.Valid {
+		return nil
+	}
+	s := strconv.FormatFloat(v.Float64, 'f', -1, 64)
+	return &s
+}
+
+func nullString(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullDate(v sql.NullTime) *string {
+	if !v.Valid {
+		return nil
+	}
+	s := v.Time.Format(time.DateOnly)
+	return &s
+}
+
+func instant(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func nullInstant(v sql.NullTime) *string {
+	if !v.Valid {
+		return nil
+	}
+	s := instant(v.Time)
+	return &s
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/util/i18n"
+	"github.com/mfajri11/xyz-backend-monolith/util/mapper"
+	"github.com/stretchr/testify/assert"
+)
+
+// run `go test ./app/adapter/handler -update` after an intended change of the api contract
+var update = flag.Bool("update", false, "update golden files")
+
+func assertGolden(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	assert.NoError(t, err)
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		assert.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+
+	want, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+}
+
+func fixtureLoan() domain.LoanAll {
+	return domain.LoanAll{
+		ID:              2,
+		UserID:          1,
+		ContractNumber:  "JKT-01-20210101-0000012",
+		OTRAmount:       13000000,
+		PrincipalAmount: 12000000.5,
+		AssetName:       "Avanza",
+		LoanType:        domain.LoanType{ID: 1, Name: domain.CAR},
+		LimitType:       domain.LimitType{ID: 1, Amount: mapper.NewSQLNullableFloat64(50000000), Term: 12},
+		Status:          mapper.NewSQLNUllableString("ACTIVE"),
+		StartDate:       mapper.NewSQLNullableTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+		InterestRate:    mapper.NewSQLNullableFloat64(0.015),
+	}
+}
+
+func TestPresentLoan(t *testing.T) {
+	tests := []struct {
+		name   string
+		golden string
+		loan   domain.LoanAll
+	}{
+		{name: "Given a complete loan, it should present every field", golden: "loan_v1", loan: fixtureLoan()},
+		{name: "Given a loan with null columns, it should present nulls", golden: "loan_v1_nulls", loan: domain.LoanAll{
+			ID:              3,
+			UserID:          1,
+			ContractNumber:  "JKT-03-20210101-0000020",
+			OTRAmount:       1300,
+			PrincipalAmount: 1000,
+			AssetName:       "Fridge",
+			LoanType:        domain.LoanType{Name: domain.WHITEGOODS},
+			LimitType:       domain.LimitType{Term: 6},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertGolden(t, tt.golden, presentLoan(tt.loan))
+		})
+	}
+}
+
+func TestPresentLoanPage(t *testing.T) {
+	assertGolden(t, "loan_page_v1", presentLoanPage(domain.LoanPage{
+		Loans: []domain.LoanSummary{{
+			LoanAll:           fixtureLoan(),
+			InstallmentsPaid:  2,
+			InstallmentsTotal: 12,
+			PaidAmount:        2000000,
+			OutstandingAmount: 10000000.5,
+			NextDueDate:       mapper.NewSQLNullableTime(time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)),
+		}},
+		NextCursor: "eyJpZCI6Mn0",
+	}))
+	assertGolden(t, "loan_page_v1_empty", presentLoanPage(domain.LoanPage{}))
+}
+
+func TestPresentLoanPayments(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	assertGolden(t, "loan_payments_v1", presentLoanPayments([]domain.LoanPayment{
+		{ID: 1, LoanID: 2, Amount: 1000000, Date: time.Date(2021, 2, 1, 9, 30, 0, 0, jakarta), Channel: "VIRTUAL_ACCOUNT"},
+		{ID: 2, LoanID: 2, Amount: 1000000.25, Date: time.Date(2021, 3, 1, 9, 30, 0, 0, time.UTC), Channel: "RETAIL"},
+	}))
+}
+
+func TestPresentLoanApplication(t *testing.T) {
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	assertGolden(t, "loan_application_v1", presentLoanApplication(domain.LoanApplication{
+		ID:              7,
+		UserID:          1,
+		Status:          domain.ApplicationApproved,
+		AssetName:       mapper.NewSQLNUllableString("Avanza"),
+		OTRAmount:       mapper.NewSQLNullableFloat64(13000000),
+		DownPayment:     mapper.NewSQLNullableFloat64(1000000),
+		LoanTypeID:      mapper.NewSQLNUllableInt16(1),
+		LimitTypeID:     mapper.NewSQLNUllableInt16(1),
+		Tenor:           mapper.NewSQLNUllableInt16(12),
+		NationalIDPhoto: []byte("photo"),
+		UserPhoto:       []byte("photo"),
+		LoanID:          mapper.NewSQLNullableInt64(2),
+		ContractNumber:  mapper.NewSQLNUllableString("JKT-01-20210101-0000012"),
+		SubmittedAt:     mapper.NewSQLNullableTime(createdAt.Add(time.Hour)),
+		DecidedAt:       mapper.NewSQLNullableTime(createdAt.Add(2 * time.Hour)),
+		CreatedAt:       createdAt,
+		UpdatedAt:       createdAt.Add(2 * time.Hour),
+	}, i18n.EN))
+	assertGolden(t, "loan_application_v1_draft", presentLoanApplication(domain.LoanApplication{
+		ID:        8,
+		UserID:    1,
+		Status:    domain.ApplicationDraft,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}, i18n.EN))
+}
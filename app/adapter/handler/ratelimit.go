@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfajri11/xyz-backend-monolith/infra/ratelimit"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+)
+
+// what the requests of a rule are counted by.
+const (
+	ByIP   = "ip"
+	ByUser = "user"
+	ByNIK  = "nik"
+)
+
+// nationalIDKey keeps the national id of the body in the gin context, so the body is decoded once.
+const nationalIDKey = "ratelimit.nationalID"
+
+// RateLimitRule counts the requests of Route, as it is registered e.g. GET /loans/:contractNumber or * for
+// every route, by By under Rule.
+type RateLimitRule struct {
+	Route string
+	By    string
+	ratelimit.Rule
+}
+
+// RateLimit answers 429 to a request over one of the rules matching it, and tells the client how much of
+// the tightest one is left in the X-RateLimit headers. the request is checked against every rule before it
+// is counted, a request refused by one rule does not use up the others. rules is called for every request so
+// a reload applies at once. the requests go through when the limiter fails, it must not take the api down
+// with it.
+func RateLimit(limiter *ratelimit.Limiter, rules func() []RateLimitRule) gin.HandlerFunc {
+	type match struct {
+		rule RateLimitRule
+		key  string
+	}
+
+	refuse := func(c *gin.Context, rule RateLimitRule, decision ratelimit.Decision) {
+		metrics.IncRateLimited(rule.Route, rule.By)
+		setRateLimitHeaders(c, decision)
+		err := fmt.Errorf("RateLimit: %s by %s: %w", rule.Route, rule.By, &ratelimit.Error{Decision: decision})
+		writeError(c, apperror.WrapError(err, apperror.ErrTooManyRequests))
+	}
+
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+
+		var matches []match
+		for _, rule := range rules() {
+			if rule.Route != "*" && rule.Route != route {
+				continue
+			}
+			value, ok := rateLimitKey(c, rule.By)
+			if !ok {
+				continue
+			}
+
+			key := RateLimitKey(rule, value)
+			decision, err := limiter.Check(c, key, rule.Rule)
+			if err != nil {
+				log.Ctx(c).Warn().Err(err).Str("route", rule.Route).Str("by", rule.By).Msg("rate limit skipped")
+				continue
+			}
+			if !decision.Allowed {
+				refuse(c, rule, decision)
+				return
+			}
+			matches = append(matches, match{rule: rule, key: key})
+		}
+
+		var tightest *ratelimit.Decision
+		for _, m := range matches {
+			decision, err := limiter.Allow(c, m.key, m.rule.Rule)
+			if err != nil {
+				log.Ctx(c).Warn().Err(err).Str("route", m.rule.Route).Str("by", m.rule.By).Msg("rate limit skipped")
+				continue
+			}
+			// a concurrent request may have taken what was left since the check
+			if !decision.Allowed {
+				refuse(c, m.rule, decision)
+				return
+			}
+			if tightest == nil || decision.Remaining < tightest.Remaining {
+				tightest = &decision
+			}
+		}
+
+		if tightest != nil {
+			setRateLimitHeaders(c, *tightest)
+		}
+		c.Next()
+	}
+}
+
+// RateLimitKey returns the key of the requests counted under rule for value, e.g. the id of a user for a
+// rule by user or the hash of a national id for a rule by nik.
+func RateLimitKey(rule RateLimitRule, value string) string {
+	return fmt.Sprintf("ratelimit:%s:%s:%s:%s:%s", rule.Route, rule.By, rule.Algorithm, rule.Period, value)
+}
+
+// rateLimitKey returns what a request is counted by, false when the request has none e.g. an anonymous one
+// by user.
+func rateLimitKey(c *gin.Context, by string) (string, bool) {
+	switch by {
+	case ByIP:
+		return c.ClientIP(), true
+	case ByUser:
+		if uid, ok := c.Get("uid"); ok {
+			return fmt.Sprint(uid), true
+		}
+	case ByNIK:
+		// the national id is hashed, the store must not hold personal data
+		nid := nationalID(c)
+		return ratelimit.Hash(nid), nid != ""
+	}
+	return "", false
+}
+
+// nationalID returns the national_id of a json body, the body is put back for the handler. only the first
+// maxNationalIDRead bytes are read: the photos of a loan request are megabytes long, a national id sent after
+// them is not found and the rule does not count the request.
+func nationalID(c *gin.Context) string {
+	if nid, ok := c.Get(nationalIDKey); ok {
+		return nid.(string)
+	}
+
+	var nid string
+	if body := c.Request.Body; body != nil {
+		var read bytes.Buffer
+		nid = findNationalID(io.TeeReader(io.LimitReader(body, maxNationalIDRead), &read))
+		c.Request.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(&read, body), body}
+	}
+	c.Set(nationalIDKey, nid)
+	return nid
+}
+
+// maxNationalIDRead bounds what nationalID reads of a body.
+const maxNationalIDRead = 64 << 10
+
+// findNationalID decodes the members of the json object of r until the national_id one. a body that is not
+// json has no national id, the handler reports it.
+func findNationalID(r io.Reader) string {
+	dec := json.NewDecoder(r)
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return ""
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if key == "national_id" {
+			var nid string
+			if err := dec.Decode(&nid); err != nil {
+				return ""
+			}
+			return nid
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return ""
+		}
+	}
+	return ""
+}
+
+func setRateLimitHeaders(c *gin.Context, decision ratelimit.Decision) {
+	c.Header("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	c.Header("X-RateLimit-Reset", seconds(decision.ResetAfter))
+}
+
+// seconds rounds d up to whole seconds, a client waiting the rounded down time would come back too early.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
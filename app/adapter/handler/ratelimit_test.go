@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfajri11/xyz-backend-monolith/infra/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingStore is a rate limit store that is down.
+type failingStore struct{}
+
+func (failingStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error {
+	return errors.New("connection refused")
+}
+
+func (failingStore) Delete(ctx context.Context, keys ...string) error {
+	return errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	once := ratelimit.Rule{Algorithm: ratelimit.SlidingWindow, Limit: 1, Period: time.Minute}
+	loanBody := `{"national_id":"3171011501950003"}`
+	photo := strings.Repeat("A", 2*maxNationalIDRead)
+
+	type request struct {
+		uid        int64
+		remoteAddr string
+		body       string
+		wantStatus int
+	}
+	tests := []struct {
+		name     string
+		store    ratelimit.Store
+		rules    []RateLimitRule
+		requests []request
+	}{
+		{
+			name:  "Given a rule by nik, it should count the national id of the body and leave the body to the handler",
+			rules: []RateLimitRule{{Route: "POST /loan", By: ByNIK, Rule: once}},
+			requests: []request{
+				{body: loanBody, wantStatus: http.StatusOK},
+				{body: `{"national_id":"3171011501950004"}`, wantStatus: http.StatusOK},
+				{body: loanBody, wantStatus: http.StatusTooManyRequests},
+				{body: `not json`, wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name:  "Given a rule by nik and a large body, it should read the national id before the photos only",
+			rules: []RateLimitRule{{Route: "POST /loan", By: ByNIK, Rule: once}},
+			requests: []request{
+				{body: `{"national_id":"3171011501950003","user_photo":"` + photo + `"}`, wantStatus: http.StatusOK},
+				{body: `{"national_id":"3171011501950003","user_photo":"` + photo + `"}`, wantStatus: http.StatusTooManyRequests},
+				{body: `{"user_photo":"` + photo + `","national_id":"3171011501950003"}`, wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name:  "Given a rule by user, it should not count the anonymous requests",
+			rules: []RateLimitRule{{Route: "*", By: ByUser, Rule: once}},
+			requests: []request{
+				{wantStatus: http.StatusOK},
+				{wantStatus: http.StatusOK},
+				{uid: 1, wantStatus: http.StatusOK},
+				{uid: 1, wantStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:  "Given a rule by ip of another route, it should not count the requests",
+			rules: []RateLimitRule{{Route: "GET /loans", By: ByIP, Rule: once}},
+			requests: []request{
+				{wantStatus: http.StatusOK},
+				{wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name:  "Given a rule by ip, it should count the addresses apart",
+			rules: []RateLimitRule{{Route: "POST /loan", By: ByIP, Rule: once}},
+			requests: []request{
+				{remoteAddr: "10.0.0.1:5000", wantStatus: http.StatusOK},
+				{remoteAddr: "10.0.0.2:5000", wantStatus: http.StatusOK},
+				{remoteAddr: "10.0.0.1:5001", wantStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "Given a request refused by a later rule, it should not use up the earlier ones",
+			rules: []RateLimitRule{
+				{Route: "*", By: ByIP, Rule: ratelimit.Rule{Algorithm: ratelimit.FixedWindow, Limit: 2, Period: time.Minute}},
+				{Route: "POST /loan", By: ByUser, Rule: once},
+			},
+			requests: []request{
+				{uid: 1, wantStatus: http.StatusOK},
+				{uid: 1, wantStatus: http.StatusTooManyRequests},
+				{uid: 2, wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name:  "Given a store that is down, it should let the requests through",
+			store: failingStore{},
+			rules: []RateLimitRule{{Route: "*", By: ByIP, Rule: once}},
+			requests: []request{
+				{wantStatus: http.StatusOK},
+				{wantStatus: http.StatusOK},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store
+			if store == nil {
+				store = ratelimit.NewMemory()
+			}
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if uid, err := strconv.ParseInt(c.GetHeader("X-User-ID"), 10, 64); err == nil {
+					c.Set("uid", uid)
+				}
+			})
+			router.Use(RateLimit(ratelimit.New(store), func() []RateLimitRule { return tt.rules }))
+			router.POST("/loan", func(c *gin.Context) {
+				body, _ := io.ReadAll(c.Request.Body)
+				c.String(http.StatusOK, string(body))
+			})
+
+			for i, r := range tt.requests {
+				req := httptest.NewRequest(http.MethodPost, "/loan", strings.NewReader(r.body))
+				if r.uid != 0 {
+					req.Header.Set("X-User-ID", strconv.FormatInt(r.uid, 10))
+				}
+				if r.remoteAddr != "" {
+					req.RemoteAddr = r.remoteAddr
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, r.wantStatus, w.Code, "request %d", i+1)
+				if w.Code == http.StatusOK {
+					assert.Equal(t, r.body, w.Body.String(), "request %d should reach the handler with its body", i+1)
+				} else {
+					assert.NotEmpty(t, w.Header().Get("Retry-After"), "request %d", i+1)
+					assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"), "request %d", i+1)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+// RequireSchema answers 503 to the requests while check fails, e.g. while the database is not reached yet
+// or not migrated to the version the build expects, so the api never writes to a schema it does not know.
+func RequireSchema(check func(ctx context.Context) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := check(c); err != nil {
+			writeError(c, apperror.WrapError(fmt.Errorf("RequireSchema: %w", err), apperror.ErrServiceUnavailable))
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,25 +1,91 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-contrib/requestid"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/infra/ratelimit"
 	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/i18n"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/tracing"
+	"github.com/mfajri11/xyz-backend-monolith/util/validation"
 )
 
+var registerValidationOnce sync.Once
+
+// registerValidation plugs the custom validation tags into the validator used by gin binding.
+func registerValidation() {
+	registerValidationOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			panic("gin binding validator is not a go-playground validator")
+		}
+		if err := validation.Register(v); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// writeError answers with the problem details of err. only the public message of the error code is sent,
+// in the language asked by the client, the internal cause is logged together with the request and trace id
+// so it can be found from the response. it is the one place a failed request is logged, the handlers and
+// services only return the error. an error over a rate limit tells the client when to retry.
 func writeError(c *gin.Context, err error) {
-	serr := apperror.SentinelError(err)
-	if serr != nil {
-		sc, msg := serr.APIError()
-		c.JSON(sc, domain.GeneralResponse{
-			Success: false,
-			Message: msg,
-		})
+	problem := newProblem(c, err)
+	var limited *ratelimit.Error
+	if errors.As(err, &limited) {
+		c.Header("Retry-After", seconds(limited.Decision.RetryAfter))
 	}
 
-	c.JSON(500, domain.GeneralResponse{
-		Success: false,
-		Message: "opps! something went wrong",
-	})
+	logger := log.Ctx(c)
+	le := logger.Warn()
+	if problem.Status >= http.StatusInternalServerError {
+		le = logger.Error()
+	}
+	le.Err(err).Str("code", problem.Code).Int("status", problem.Status).Msg("request failed")
+
+	writeProblem(c, problem)
+}
+
+func writeProblem(c *gin.Context, problem apperror.Problem) {
+	c.Header("Content-Type", apperror.ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// setETag tags the response with the version of the resource, e.g. "3". the tag is strong, a version is one
+// exact state of the resource.
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatch returns the version of the entity tag in If-Match, zero without the header or with * so the write
+// applies whatever the version. a weak, malformed or list of tags is a bad request.
+func ifMatch(c *gin.Context) (int64, error) {
+	tag := strings.TrimSpace(c.GetHeader("If-Match"))
+	if tag == "" || tag == "*" {
+		return 0, nil
+	}
+
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		err := fmt.Errorf("ifMatch: invalid entity tag %s", tag)
+		return 0, apperror.WrapError(err, apperror.ErrBadRequest)
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version < 1 {
+		err = fmt.Errorf("ifMatch: invalid entity tag %s", tag)
+		return 0, apperror.WrapError(err, apperror.ErrBadRequest)
+	}
+	return version, nil
 }
 
 func writeSuccess(c *gin.Context, data interface{}) {
@@ -29,3 +95,43 @@ func writeSuccess(c *gin.Context, data interface{}) {
 		Data:    data,
 	})
 }
+
+// writeBindError answers 422 with every failing field when the payload does not pass validation
+// and 400 when it can not be decoded at all.
+func writeBindError(c *gin.Context, err error) {
+	errs, ok := validation.Translate(err, language(c))
+	if !ok {
+		writeError(c, apperror.WrapError(err, apperror.ErrBadRequest))
+		return
+	}
+
+	problem := newProblem(c, apperror.ErrUnprocessableEntity)
+	problem.Errors = errs
+	log.Ctx(c).Warn().Err(err).Str("code", problem.Code).Int("status", problem.Status).Msg("request failed")
+	writeProblem(c, problem)
+}
+
+func newProblem(c *gin.Context, err error) apperror.Problem {
+	lang := language(c)
+	problem := apperror.NewProblem(err, c.Request.URL.Path)
+	problem.RequestID = requestid.Get(c)
+	problem.TraceID = traceID(c)
+	if detail := i18n.Message(lang, i18n.ErrorPrefix+problem.Code, nil); detail != "" {
+		problem.Detail = detail
+	}
+
+	return problem
+}
+
+// language negotiates the language of the response from the Accept-Language header.
+func language(c *gin.Context) i18n.Lang {
+	lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", string(lang))
+	return lang
+}
+
+// traceID returns the id of the trace the request belongs to, it is the one propagated by the caller
+// in the traceparent header when there is any.
+func traceID(c *gin.Context) string {
+	return tracing.TraceID(c.Request.Context())
+}
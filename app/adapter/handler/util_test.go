@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWriteError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		err        error
+		lang       string
+		wantStatus int
+		wantCode   string
+		wantDetail string
+		wantLang   string
+	}{
+		{
+			name:       "Given a wrapped sentinel, it should answer with the sentinel status",
+			err:        apperror.WrapError(errors.New("loan with contract number 1 not found"), apperror.ErrNotFound),
+			wantStatus: http.StatusNotFound,
+			wantCode:   "NOT_FOUND",
+			wantDetail: "Data tidak ditemukan",
+			wantLang:   "id",
+		},
+		{
+			name:       "Given an english speaking client, it should answer in english",
+			err:        apperror.WrapError(errors.New("loan with contract number 1 not found"), apperror.ErrNotFound),
+			lang:       "en-US,en;q=0.9,id;q=0.8",
+			wantStatus: http.StatusNotFound,
+			wantCode:   "NOT_FOUND",
+			wantDetail: "Resource not found",
+			wantLang:   "en",
+		},
+		{
+			name:       "Given an unknown error, it should answer internal server error without the cause",
+			err:        errors.New("Error 1045: Access denied for user 'root'"),
+			lang:       "fr-FR",
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "INTERNAL_ERROR",
+			wantDetail: "Maaf, terjadi kesalahan pada sistem kami",
+			wantLang:   "id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/loans/1", nil).WithContext(tracedContext())
+			c.Request.Header.Set("Accept-Language", tt.lang)
+
+			writeError(c, tt.err)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, apperror.ProblemContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantLang, w.Header().Get("Content-Language"))
+			assert.NotContains(t, w.Body.String(), tt.err.Error())
+
+			// a single json document must be written
+			var problem apperror.Problem
+			dec := json.NewDecoder(w.Body)
+			assert.NoError(t, dec.Decode(&problem))
+			assert.False(t, dec.More())
+			assert.Equal(t, tt.wantCode, problem.Code)
+			assert.Equal(t, tt.wantDetail, problem.Detail)
+			assert.Equal(t, "/loans/1", problem.Instance)
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", problem.TraceID)
+		})
+	}
+}
+
+func tracedContext() context.Context {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+}
+
+func TestIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name        string
+		header      string
+		wantVersion int64
+		wantErr     error
+	}{
+		{name: "Given no If-Match, it should match any version"},
+		{name: "Given If-Match *, it should match any version", header: "*"},
+		{name: "Given the tag of a version, it should return the version", header: `"3"`, wantVersion: 3},
+		{name: "Given a weak tag, it should return bad request", header: `W/"3"`, wantErr: apperror.ErrBadRequest},
+		{name: "Given a list of tags, it should return bad request", header: `"3", "4"`, wantErr: apperror.ErrBadRequest},
+		{name: "Given an unquoted tag, it should return bad request", header: `3`, wantErr: apperror.ErrBadRequest},
+		{name: "Given a tag of no version, it should return bad request", header: `"0"`, wantErr: apperror.ErrBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/loan/1/payments", nil)
+			c.Request.Header.Set("If-Match", tt.header)
+
+			version, err := ifMatch(c)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantVersion, version)
+		})
+	}
+}
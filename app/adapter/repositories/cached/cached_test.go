@@ -0,0 +1,90 @@
+package cached
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	"github.com/mfajri11/xyz-backend-monolith/infra/cache"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/event"
+	"github.com/stretchr/testify/assert"
+)
+
+// references counts the reads of the reference data.
+type references struct {
+	reads atomic.Int32
+}
+
+func (r *references) GetReferenceData(ctx context.Context) (*domain.ReferenceData, error) {
+	r.reads.Add(1)
+	return &domain.ReferenceData{LoanTypes: map[int16]domain.LoanType{1: {ID: 1, Name: domain.CAR}}}, nil
+}
+
+// loans counts the reads of the loan detail, the contract number NOT-FOUND is missing.
+type loans struct {
+	port.LoanRepository
+	reads atomic.Int32
+}
+
+func (l *loans) GetLoanByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (*domain.LoanAll, error) {
+	l.reads.Add(1)
+	if contractNumber == "NOT-FOUND" {
+		return nil, apperror.WrapError(errors.New("loan not found"), apperror.ErrNotFound)
+	}
+	return &domain.LoanAll{UserID: uid, ContractNumber: contractNumber}, nil
+}
+
+func TestReferenceRepository(t *testing.T) {
+	ctx := context.Background()
+	next := &references{}
+	repo := NewReferenceRepository(next, cache.NewLRU(10), time.Hour)
+	bus := event.NewBus()
+	bus.Subscribe(domain.EventReferenceDataChanged, repo.Invalidate)
+
+	for range 3 {
+		got, err := repo.GetReferenceData(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.CAR, got.LoanTypes[1].Name)
+	}
+	assert.Equal(t, int32(1), next.reads.Load(), "the reads after the first should hit the cache")
+
+	bus.Publish(ctx, domain.ReferenceDataChanged{})
+	_, err := repo.GetReferenceData(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), next.reads.Load(), "the read after a change should load again")
+}
+
+func TestLoanRepository(t *testing.T) {
+	ctx := context.Background()
+	next := &loans{}
+	repo := NewLoanRepository(next, cache.NewLRU(10), time.Minute)
+	bus := event.NewBus()
+	bus.Subscribe(domain.EventLoanChanged, repo.Invalidate)
+
+	for range 2 {
+		got, err := repo.GetLoanByUserIDAndContractNumber(ctx, 1, "XYZ-01")
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.LoanAll{UserID: 1, ContractNumber: "XYZ-01"}, got)
+	}
+	_, err := repo.GetLoanByUserIDAndContractNumber(ctx, 2, "XYZ-01")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), next.reads.Load(), "the loans should be cached by user and contract number")
+
+	bus.Publish(ctx, domain.LoanChanged{UserID: 2, ContractNumber: "XYZ-01"})
+	_, err = repo.GetLoanByUserIDAndContractNumber(ctx, 1, "XYZ-01")
+	assert.NoError(t, err)
+	_, err = repo.GetLoanByUserIDAndContractNumber(ctx, 2, "XYZ-01")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), next.reads.Load(), "only the changed loan should load again")
+
+	for range 2 {
+		_, err = repo.GetLoanByUserIDAndContractNumber(ctx, 1, "NOT-FOUND")
+		assert.ErrorIs(t, err, apperror.ErrNotFound)
+	}
+	assert.Equal(t, int32(5), next.reads.Load(), "a not found should not be cached")
+}
@@ -0,0 +1,44 @@
+package cached
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	"github.com/mfajri11/xyz-backend-monolith/infra/cache"
+	"github.com/mfajri11/xyz-backend-monolith/util/event"
+)
+
+// LoanRepository caches the loan detail, the other reads and the writes go straight to the wrapped
+// repository.
+type LoanRepository struct {
+	port.LoanRepository
+	loader *cache.Loader[*domain.LoanAll]
+}
+
+// NewLoanRepository keeps the loans read from next in c for ttl.
+func NewLoanRepository(next port.LoanRepository, c cache.Cache, ttl time.Duration) *LoanRepository {
+	return &LoanRepository{
+		LoanRepository: next,
+		loader:         cache.NewLoader[*domain.LoanAll](c, "loan", ttl),
+	}
+}
+
+func (repo *LoanRepository) GetLoanByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (*domain.LoanAll, error) {
+	return repo.loader.Get(ctx, repo.key(uid, contractNumber), func(ctx context.Context) (*domain.LoanAll, error) {
+		return repo.LoanRepository.GetLoanByUserIDAndContractNumber(ctx, uid, contractNumber)
+	})
+}
+
+// Invalidate handles domain.LoanChanged.
+func (repo *LoanRepository) Invalidate(ctx context.Context, e event.Event) {
+	if changed, ok := e.(domain.LoanChanged); ok {
+		repo.loader.Invalidate(ctx, repo.key(changed.UserID, changed.ContractNumber))
+	}
+}
+
+func (repo *LoanRepository) key(uid int64, contractNumber string) string {
+	return repo.loader.Key(strconv.FormatInt(uid, 10), contractNumber)
+}
@@ -0,0 +1,35 @@
+// Package cached wraps the repositories whose reads are hot or rarely change with a cache, the domain
+// events of the changes remove what they made stale.
+package cached
+
+import (
+	"context"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	"github.com/mfajri11/xyz-backend-monolith/infra/cache"
+	"github.com/mfajri11/xyz-backend-monolith/util/event"
+)
+
+type ReferenceRepository struct {
+	next   port.ReferenceRepository
+	loader *cache.Loader[*domain.ReferenceData]
+}
+
+// NewReferenceRepository keeps the reference data of next in c for ttl.
+func NewReferenceRepository(next port.ReferenceRepository, c cache.Cache, ttl time.Duration) *ReferenceRepository {
+	return &ReferenceRepository{
+		next:   next,
+		loader: cache.NewLoader[*domain.ReferenceData](c, "reference", ttl),
+	}
+}
+
+func (repo *ReferenceRepository) GetReferenceData(ctx context.Context) (*domain.ReferenceData, error) {
+	return repo.loader.Get(ctx, repo.loader.Key(), repo.next.GetReferenceData)
+}
+
+// Invalidate handles domain.ReferenceDataChanged.
+func (repo *ReferenceRepository) Invalidate(ctx context.Context, e event.Event) {
+	repo.loader.Invalidate(ctx, repo.loader.Key())
+}
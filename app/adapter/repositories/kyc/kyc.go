@@ -0,0 +1,131 @@
+package kyc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	uhttp "github.com/mfajri11/xyz-backend-monolith/util/http"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+)
+
+// Provider calls the kyc vendor, it is shared by the user repositories of every storage.
+type Provider struct {
+	kycClient *uhttp.HTTPClient
+}
+
+func New(kycClient *uhttp.HTTPClient) *Provider {
+	return &Provider{
+		kycClient: kycClient,
+	}
+}
+
+func (p *Provider) ValidateSalary(ctx context.Context, req domain.KYCValidateSalaryReq) (*domain.KYCValidateSalaryResp, error) {
+	start := time.Now()
+	resp, err := p.kycClient.Post(ctx, p.kycClient.BaseURL+"/veryfi/national-id", req)
+	metrics.ObserveKYCRequest("salary", start, statusCode(resp))
+	if err != nil {
+		err = fmt.Errorf("ValidateSalary: error kyc request: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrKYCUnavailable)
+	}
+
+	if resp.StatusCode != 200 {
+		err = fmt.Errorf("ValidateSalary: unexpected status code: %d", resp.StatusCode)
+		return nil, wrapKYCStatusError(err, resp.StatusCode)
+	}
+
+	defer resp.Body.Close()
+	var kycData domain.KYCValidateSalaryResp
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		err = fmt.Errorf("ValidateSalary: error read kyc body response: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	if err = json.Unmarshal(b, &kycData); err != nil {
+		err = fmt.Errorf("ValidateSalary: error unmarshalling kyc data: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return &kycData, nil
+}
+
+func (p *Provider) ValidateNationalID(ctx context.Context, req domain.KYCValidateNationalIDReq) (*domain.KYCValidateNationalIDResp, error) {
+	start := time.Now()
+	resp, err := p.kycClient.Post(ctx, p.kycClient.BaseURL+"/veryfi/national-id", req)
+	metrics.ObserveKYCRequest("national_id", start, statusCode(resp))
+	if err != nil {
+		err = fmt.Errorf("VerifyNationalID: error kyc request: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrKYCUnavailable)
+	}
+
+	if resp.StatusCode != 200 {
+		err = fmt.Errorf("VerifyNationalID: unexpected status code: %d", resp.StatusCode)
+		return nil, wrapKYCStatusError(err, resp.StatusCode)
+	}
+
+	defer resp.Body.Close()
+	var kycData domain.KYCValidateNationalIDResp
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		err = fmt.Errorf("VerifyNationalID: error read kyc body response: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	if err = json.Unmarshal(b, &kycData); err != nil {
+		err = fmt.Errorf("VerifyNationalID: error unmarshalling kyc data: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return &kycData, nil
+}
+
+func (p *Provider) ValidatePhoto(ctx context.Context, req domain.KYCValidatePhotoReq) (*domain.KYCValidatePhotoResp, error) {
+	start := time.Now()
+	resp, err := p.kycClient.Post(ctx, p.kycClient.BaseURL+"/veryfi/national-id", req)
+	metrics.ObserveKYCRequest("photo", start, statusCode(resp))
+	if err != nil {
+		err = fmt.Errorf("VerifyPhoto: error kyc request: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrKYCUnavailable)
+	}
+
+	if resp.StatusCode != 200 {
+		err = fmt.Errorf("VerifyPhoto: unexpected status code: %d", resp.StatusCode)
+		return nil, wrapKYCStatusError(err, resp.StatusCode)
+	}
+
+	defer resp.Body.Close()
+	var kycData domain.KYCValidatePhotoResp
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		err = fmt.Errorf("VerifyPhoto: error read kyc body response: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	if err = json.Unmarshal(b, &kycData); err != nil {
+		err = fmt.Errorf("VerifyPhoto: error unmarshalling kyc data: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return &kycData, nil
+}
+
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+// wrapKYCStatusError treats a server side failure of the vendor as unavailability, anything else means our request was rejected.
+func wrapKYCStatusError(err error, statusCode int) error {
+	if statusCode >= http.StatusInternalServerError {
+		return apperror.WrapError(err, apperror.ErrKYCUnavailable)
+	}
+	return apperror.WrapError(err, apperror.ErrBadRequest)
+}
@@ -0,0 +1,241 @@
+package kyc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	uhttp "github.com/mfajri11/xyz-backend-monolith/util/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIOReaderCloser(b interface{}) io.ReadCloser {
+	bb, _ := json.Marshal(b)
+	return io.NopCloser(bytes.NewReader(bb))
+}
+
+func newByte(v interface{}) []byte {
+	bb, _ := json.Marshal(v)
+	return bb
+}
+
+func TestProvider_ValidateNationalID(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		req domain.KYCValidateNationalIDReq
+	}
+	type mock func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc
+	tests := []struct {
+		name    string
+		p       *Provider
+		args    args
+		doMock  mock
+		want    *domain.KYCValidateNationalIDResp
+		wantErr bool
+	}{
+		{
+			name: "Given a valid national id, it should return true",
+			p:    &Provider{},
+			args: args{
+				ctx: context.Background(),
+				req: domain.KYCValidateNationalIDReq{
+					NationalID:  "1122334455667788",
+					LegalName:   "John Doe",
+					DateOfBirth: "2000-01-01",
+					ReferenceID: "12345678912345678",
+				},
+			},
+			doMock: func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc {
+				return func(r *http.Request) (*http.Response, error) {
+					w.WriteHeader(http.StatusOK)
+					w.Write(newByte(domain.KYCValidateNationalIDResp{
+						Message: "Success",
+						Data: domain.KYCData{
+							NationalID:  true,
+							LegalName:   true,
+							DateOfBirth: true,
+							ReferenceID: "12345678912345678",
+						},
+					}))
+					return w.Result(), nil
+
+				}
+			},
+			want: &domain.KYCValidateNationalIDResp{
+				Message: "Success",
+				Data: domain.KYCData{
+					NationalID:  true,
+					LegalName:   true,
+					DateOfBirth: true,
+					ReferenceID: "12345678912345678",
+				},
+			},
+		},
+		{
+			name: "Given a invalid national id, it should return false",
+			p:    &Provider{},
+			args: args{
+				ctx: context.Background(),
+				req: domain.KYCValidateNationalIDReq{
+					NationalID:  "1122334455667788",
+					LegalName:   "John Doe",
+					DateOfBirth: "2000-01-01",
+					ReferenceID: "12345678912345678",
+				},
+			},
+			doMock: func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc {
+				return func(r *http.Request) (*http.Response, error) {
+					w.WriteHeader(http.StatusOK)
+					w.Write(newByte(domain.KYCValidateNationalIDResp{
+						Message: "Fail validate national id",
+						Data: domain.KYCData{
+							NationalID:  false,
+							LegalName:   false,
+							DateOfBirth: false,
+							ReferenceID: "12345678912345678",
+						},
+					}))
+					return w.Result(), nil
+
+				}
+			},
+			want: &domain.KYCValidateNationalIDResp{
+				Message: "Fail validate national id",
+				Data: domain.KYCData{
+					NationalID:  false,
+					LegalName:   false,
+					DateOfBirth: false,
+					ReferenceID: "12345678912345678",
+				},
+			},
+		},
+		{
+			name: "Given a valid national id, but it should return error",
+			p:    &Provider{},
+			args: args{
+				ctx: context.Background(),
+				req: domain.KYCValidateNationalIDReq{
+					NationalID:  "1122334455667788",
+					LegalName:   "John Doe",
+					DateOfBirth: "2000-01-01",
+					ReferenceID: "12345678912345678",
+				},
+			},
+			doMock: func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc {
+				return func(r *http.Request) (*http.Response, error) {
+					w.WriteHeader(http.StatusInternalServerError)
+					return w.Result(), nil
+
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/verify/national-id", newIOReaderCloser(tt.args.req))
+			w := httptest.NewRecorder()
+			tt.p.kycClient = uhttp.NewMock(tt.doMock(r, w))
+
+			got, err := tt.p.ValidateNationalID(tt.args.ctx, tt.args.req)
+
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got, got)
+		})
+	}
+}
+
+func TestProvider_ValidateSalary(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		req domain.KYCValidateSalaryReq
+	}
+	type mock func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc
+	tests := []struct {
+		name    string
+		p       *Provider
+		args    args
+		doMock  mock
+		want    *domain.KYCValidateSalaryResp
+		wantErr bool
+	}{
+		{
+			name: "Given a valid salary, it should return true",
+			p:    &Provider{},
+			args: args{
+				ctx: context.Background(),
+				req: domain.KYCValidateSalaryReq{
+					NationalID:  "1122334455667788",
+					LegalName:   "John Doe",
+					Salary:      "1000",
+					ReferenceID: "12345678912345678",
+				},
+			},
+			doMock: func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc {
+				return func(r *http.Request) (*http.Response, error) {
+					w.WriteHeader(http.StatusOK)
+					w.Write(newByte(domain.KYCValidateSalaryResp{
+						Message: "Success",
+						Data: domain.KYCData{
+							NationalID:  true,
+							LegalName:   true,
+							ReferenceID: "12345678912345678",
+							SalaryUper:  "2000",
+							SalaryLower: "500",
+						},
+					}))
+					return w.Result(), nil
+
+				}
+			},
+			want: &domain.KYCValidateSalaryResp{
+				Message: "Success",
+				Data: domain.KYCData{
+					NationalID:  true,
+					LegalName:   true,
+					ReferenceID: "12345678912345678",
+					SalaryUper:  "2000",
+					SalaryLower: "500",
+				},
+			},
+		},
+		{
+			name: "Given a valid salary, but it should return error",
+			p:    &Provider{},
+			args: args{
+				ctx: context.Background(),
+				req: domain.KYCValidateSalaryReq{
+					NationalID:  "1122334455667788",
+					LegalName:   "John Doe",
+					Salary:      "100000",
+					ReferenceID: "12345678912345678",
+				},
+			},
+			doMock: func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc {
+				return func(r *http.Request) (*http.Response, error) {
+					w.WriteHeader(http.StatusInternalServerError)
+					return w.Result(), nil
+
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/verify/income", newIOReaderCloser(tt.args.req))
+			w := httptest.NewRecorder()
+			tt.p.kycClient = uhttp.NewMock(tt.doMock(r, w))
+
+			got, err := tt.p.ValidateSalary(tt.args.ctx, tt.args.req)
+
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got, got)
+		})
+	}
+}
@@ -4,24 +4,36 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/mysql"
 	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
 )
 
 type LoanRepositories struct {
-	dbConn *sql.DB
+	cluster    *infradb.Cluster
+	references port.ReferenceRepository
 }
 
-func New(db *sql.DB) *LoanRepositories {
+// New returns the repository of the loans in cluster, their loan and limit types are read from references.
+func New(cluster *infradb.Cluster, references port.ReferenceRepository) *LoanRepositories {
 	return &LoanRepositories{
-		dbConn: db,
+		cluster:    cluster,
+		references: references,
 	}
 }
 
 func (repo *LoanRepositories) CreateLoan(ctx context.Context, loan domain.Loan) error {
-	_, err := repo.dbConn.ExecContext(ctx, createLoan, loan.UserID, loan.ContractNumber, loan.OTRAmount, loan.PrincipalAmount, loan.AssetName,
+	_, err := repo.cluster.Writer(ctx).ExecContext(ctx, createLoan, loan.UserID, loan.ContractNumber, loan.OTRAmount, loan.PrincipalAmount, loan.AssetName,
 		loan.LoanTypeID, loan.LimitTypeID, loan.Status, loan.StartDate, loan.InterestRate)
+	if mysql.IsDuplicateEntry(err) {
+		err = fmt.Errorf("CreateLoan: loan with contract number %s already exists: %w", loan.ContractNumber, err)
+		return apperror.WrapError(err, apperror.ErrContractNumberConflict)
+	}
+
 	if err != nil {
 		err = fmt.Errorf("CreateLoan: error insert loan: %w", err)
 		return apperror.WrapError(err, apperror.ErrInternalServerError)
@@ -31,8 +43,11 @@ func (repo *LoanRepositories) CreateLoan(ctx context.Context, loan domain.Loan)
 }
 
 func (repo *LoanRepositories) GetLoanByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (*domain.LoanAll, error) {
-	var loan domain.LoanAll
-	err := repo.dbConn.QueryRowContext(context.Background(), getLoanByContractNumber, uid, contractNumber).
+	var (
+		loan                    domain.LoanAll
+		loanTypeID, limitTypeID sql.NullInt16
+	)
+	err := repo.cluster.Reader(ctx).QueryRowContext(ctx, getLoanByContractNumber, uid, contractNumber).
 		Scan(
 			&loan.ID,
 			&loan.UserID,
@@ -40,12 +55,12 @@ func (repo *LoanRepositories) GetLoanByUserIDAndContractNumber(ctx context.Conte
 			&loan.OTRAmount,
 			&loan.PrincipalAmount,
 			&loan.AssetName,
-			&loan.LoanType.Name,
-			&loan.LimitType.Amount,
-			&loan.LimitType.Term,
+			&loanTypeID,
+			&limitTypeID,
 			&loan.Status,
 			&loan.StartDate,
 			&loan.InterestRate,
+			&loan.Version,
 		)
 
 	if err == sql.ErrNoRows {
@@ -58,14 +73,147 @@ func (repo *LoanRepositories) GetLoanByUserIDAndContractNumber(ctx context.Conte
 		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
 	}
 
+	references, err := repo.references.GetReferenceData(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetLoanByContractNumber: error get reference data: %w", err)
+	}
+	loanType, loanTypeOK := references.LoanTypes[loanTypeID.Int16]
+	limitType, limitTypeOK := references.LimitTypes[limitTypeID.Int16]
+	// like the inner joins the reference data replaces, a loan without its types is not found
+	if !loanTypeID.Valid || !limitTypeID.Valid || !loanTypeOK || !limitTypeOK {
+		err = fmt.Errorf("GetLoanByContractNumber: loan with contract number %s has no loan or limit type", contractNumber)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
+	}
+	loan.LoanType = domain.LoanType{Name: loanType.Name}
+	loan.LimitType = domain.LimitType{Amount: limitType.Amount, Term: limitType.Term}
+
 	return &loan, nil
 
 }
 
+func (repo *LoanRepositories) ListLoansByUserID(ctx context.Context, filter domain.LoanFilter) ([]domain.LoanSummary, error) {
+	sortColumn, ok := sortColumns[string(filter.SortBy)]
+	if !ok {
+		err := fmt.Errorf("ListLoansByUserID: unknown sort field %s", filter.SortBy)
+		return nil, apperror.WrapError(err, apperror.ErrBadRequest)
+	}
+
+	query := strings.Builder{}
+	query.WriteString(listLoansByUserID)
+	args := []interface{}{filter.UserID, filter.UserID}
+	if filter.Status != "" {
+		query.WriteString(" AND l.status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.LoanType != "" {
+		query.WriteString(" AND lot.name = ?")
+		args = append(args, filter.LoanType)
+	}
+	if filter.StartDateFrom.Valid {
+		query.WriteString(" AND l.start_date >= ?")
+		args = append(args, filter.StartDateFrom.Time)
+	}
+	if filter.StartDateTo.Valid {
+		query.WriteString(" AND l.start_date < ?")
+		args = append(args, filter.StartDateTo.Time)
+	}
+
+	op, direction := ">", "ASC"
+	if filter.SortDesc {
+		op, direction = "<", "DESC"
+	}
+	if filter.Cursor != nil {
+		fmt.Fprintf(&query, " AND (%[1]s %[2]s ? OR (%[1]s = ? AND l.id %[2]s ?))", sortColumn, op)
+		args = append(args, filter.Cursor.SortValue, filter.Cursor.SortValue, filter.Cursor.ID)
+	}
+	fmt.Fprintf(&query, " ORDER BY %[1]s %[2]s, l.id %[2]s LIMIT ?", sortColumn, direction)
+	args = append(args, filter.Limit)
+
+	rows, err := repo.cluster.Reader(ctx).QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		err = fmt.Errorf("ListLoansByUserID: error select query: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	defer rows.Close()
+
+	loans := []domain.LoanSummary{}
+	for rows.Next() {
+		var loan domain.LoanSummary
+		err := rows.Scan(
+			&loan.ID,
+			&loan.UserID,
+			&loan.ContractNumber,
+			&loan.OTRAmount,
+			&loan.PrincipalAmount,
+			&loan.AssetName,
+			&loan.LoanType.Name,
+			&loan.LimitType.Amount,
+			&loan.LimitType.Term,
+			&loan.Status,
+			&loan.StartDate,
+			&loan.InterestRate,
+			&loan.InstallmentsPaid,
+			&loan.PaidAmount,
+		)
+		if err != nil {
+			err = fmt.Errorf("ListLoansByUserID: error scan query: %w", err)
+			return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+		}
+		loans = append(loans, loan)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("ListLoansByUserID: error iterate rows: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return loans, nil
+}
+
+// CreateLoanPayment inserts the payment and increments the version of its loan in one transaction, given a
+// version the loan has moved past the payment is refused with a version conflict.
 func (repo *LoanRepositories) CreateLoanPayment(ctx context.Context, loanPayment domain.LoanPayment) error {
-	_, err := repo.dbConn.ExecContext(ctx, createLoanPayment, loanPayment.LoanID, loanPayment.Amount, loanPayment.Date, loanPayment.Channel)
+	tx, err := repo.cluster.BeginTx(ctx, nil)
+	if err != nil {
+		err = fmt.Errorf("CreateLoanPayment: error begin transaction: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	defer tx.Rollback()
+
+	var version interface{}
+	if loanPayment.LoanVersion != 0 {
+		version = loanPayment.LoanVersion
+	}
+	res, err := tx.ExecContext(ctx, incrementLoanVersion, loanPayment.LoanID, version)
+	if err != nil {
+		err = fmt.Errorf("CreateLoanPayment: error update loan version: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	affected, err := res.RowsAffected()
 	if err != nil {
-		err = fmt.Errorf("createLoanPayment: error insert loan payment: %w", err)
+		err = fmt.Errorf("CreateLoanPayment: error get affected rows: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	if affected == 0 && loanPayment.LoanVersion != 0 {
+		err = fmt.Errorf("CreateLoanPayment: loan %d is no longer at version %d", loanPayment.LoanID, loanPayment.LoanVersion)
+		return apperror.WrapError(err, apperror.ErrVersionConflict)
+	}
+
+	if affected == 0 {
+		err = fmt.Errorf("CreateLoanPayment: error insert loan payment: loan %d does not exist", loanPayment.LoanID)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	_, err = tx.ExecContext(ctx, createLoanPayment, loanPayment.LoanID, loanPayment.Amount, loanPayment.Date, loanPayment.Channel)
+	if err != nil {
+		err = fmt.Errorf("CreateLoanPayment: error insert loan payment: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	if err = tx.Commit(); err != nil {
+		err = fmt.Errorf("CreateLoanPayment: error commit transaction: %w", err)
 		return apperror.WrapError(err, apperror.ErrInternalServerError)
 	}
 
@@ -74,40 +222,52 @@ func (repo *LoanRepositories) CreateLoanPayment(ctx context.Context, loanPayment
 
 func (repo *LoanRepositories) GetLoanPaymentsByLoanID(ctx context.Context, loanID int64) ([]domain.LoanPayment, error) {
 	var loanPayments []domain.LoanPayment
-	rows, err := repo.dbConn.QueryContext(ctx, getLoanPaymentsByLoanID, loanID)
+	rows, err := repo.cluster.Reader(ctx).QueryContext(ctx, getLoanPaymentsByLoanID, loanID)
 	if err != nil {
 		err = fmt.Errorf("GetLoanPaymentsByLoanID: error select query: %w", err)
 		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var loanPayment domain.LoanPayment
-		if err := rows.Scan(&loanPayment.Amount, &loanPayment.Date, &loanPayment.Channel); err != nil {
+		if err := rows.Scan(&loanPayment.ID, &loanPayment.LoanID, &loanPayment.Amount, &loanPayment.Date, &loanPayment.Channel); err != nil {
 			err = fmt.Errorf("GetLoanPaymentsByLoanID: error scan query: %w", err)
 			return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
 		}
 		loanPayments = append(loanPayments, loanPayment)
 	}
 
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("GetLoanPaymentsByLoanID: error iterate rows: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
 	return loanPayments, nil
 }
 
 func (repo *LoanRepositories) GetLoanPaymentsByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) ([]domain.LoanPayment, error) {
 	var loanPayments []domain.LoanPayment
-	rows, err := repo.dbConn.QueryContext(ctx, getLoanPaymentsByContractNumber, uid, contractNumber)
+	rows, err := repo.cluster.Reader(ctx).QueryContext(ctx, getLoanPaymentsByContractNumber, uid, contractNumber)
 	if err != nil {
 		err = fmt.Errorf("getLoanPaymentsByContractNumber: error select query: %w", err)
 		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var loanPayment domain.LoanPayment
-		if err := rows.Scan(&loanPayment.Amount, &loanPayment.Date, &loanPayment.Channel); err != nil {
+		if err := rows.Scan(&loanPayment.ID, &loanPayment.LoanID, &loanPayment.Amount, &loanPayment.Date, &loanPayment.Channel); err != nil {
 			err = fmt.Errorf("getLoanPaymentsByContractNumber: error scan query: %w", err)
 			return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
 		}
 		loanPayments = append(loanPayments, loanPayment)
 	}
 
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("getLoanPaymentsByContractNumber: error iterate rows: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
 	return loanPayments, nil
 }
@@ -9,11 +9,20 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	referenceRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/reference"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/repotest"
+	userRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/user"
 	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
 	"github.com/mfajri11/xyz-backend-monolith/util/mapper"
 	"github.com/stretchr/testify/assert"
 )
 
+func TestMain(m *testing.M) {
+	repotest.Main(m)
+}
+
 func TestLoanRepositories_CreateLoan(t *testing.T) {
 	type args struct {
 		ctx  context.Context
@@ -80,7 +89,7 @@ func TestLoanRepositories_CreateLoan(t *testing.T) {
 			conn, sqlMock, err := sqlmock.New()
 			assert.NoError(t, err)
 			defer conn.Close()
-			tt.repo.dbConn = conn
+			tt.repo.cluster = infradb.NewCluster(conn)
 			tt.prepareMock(&mock{
 				Sqlmock: sqlMock,
 			})
@@ -104,7 +113,7 @@ func TestLoanRepositories_CreateLoanPayment(t *testing.T) {
 		repo        *LoanRepositories
 		args        args
 		prepareMock func(mock *mock)
-		wantErr     bool
+		wantErr     error
 	}{
 		{
 			name: "Given a valid loan payment, it should return no error",
@@ -119,8 +128,51 @@ func TestLoanRepositories_CreateLoanPayment(t *testing.T) {
 				},
 			},
 			prepareMock: func(mock *mock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(incrementLoanVersion)).WithArgs(1, nil).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(createLoanPayment)).WithArgs(1, float64(1000), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), "test").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Given a loan payment against the current loan version, it should return no error",
+			repo: &LoanRepositories{},
+			args: args{
+				ctx: context.Background(),
+				loanPayment: domain.LoanPayment{
+					Amount:      float64(1000),
+					LoanID:      1,
+					Date:        time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+					Channel:     "test",
+					LoanVersion: 2,
+				},
+			},
+			prepareMock: func(mock *mock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(incrementLoanVersion)).WithArgs(1, int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(regexp.QuoteMeta(createLoanPayment)).WithArgs(1, float64(1000), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), "test").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Given a loan payment against a stale loan version, it should return a version conflict",
+			repo: &LoanRepositories{},
+			args: args{
+				ctx: context.Background(),
+				loanPayment: domain.LoanPayment{
+					Amount:      float64(1000),
+					LoanID:      1,
+					Date:        time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+					Channel:     "test",
+					LoanVersion: 1,
+				},
 			},
+			prepareMock: func(mock *mock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(incrementLoanVersion)).WithArgs(1, int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: apperror.ErrVersionConflict,
 		},
 		{
 			name: "Given a valid loan payment, but insert it return error",
@@ -135,9 +187,12 @@ func TestLoanRepositories_CreateLoanPayment(t *testing.T) {
 				},
 			},
 			prepareMock: func(mock *mock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(incrementLoanVersion)).WithArgs(1, nil).WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectExec(regexp.QuoteMeta(createLoanPayment)).WithArgs(1, float64(1000), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), "test").WillReturnResult(nil).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
 			},
-			wantErr: true,
+			wantErr: apperror.ErrInternalServerError,
 		},
 	}
 	for _, tt := range tests {
@@ -145,13 +200,17 @@ func TestLoanRepositories_CreateLoanPayment(t *testing.T) {
 			conn, sqlMock, err := sqlmock.New()
 			assert.NoError(t, err)
 			defer conn.Close()
-			tt.repo.dbConn = conn
+			tt.repo.cluster = infradb.NewCluster(conn)
 			tt.prepareMock(&mock{
 				Sqlmock: sqlMock,
 			})
 
 			err = tt.repo.CreateLoanPayment(tt.args.ctx, tt.args.loanPayment)
-			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantErr != nil, err != nil, err)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
 		})
 	}
 }
@@ -180,15 +239,20 @@ func TestLoanRepositories_GetLoanPaymentsByLoanID(t *testing.T) {
 				loanID: 1,
 			},
 			prepareMock: func(m *mock) {
-				m.ExpectQuery(regexp.QuoteMeta(getLoanPaymentsByLoanID)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"amount", "date", "channel"}).AddRow(float64(1000), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), "test").AddRow(float64(4000), time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), "test-2"))
+				m.ExpectQuery(regexp.QuoteMeta(getLoanPaymentsByLoanID)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "loan_id", "amount", "date", "channel"}).AddRow(1, 1, float64(1000), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), "test").AddRow(2, 1, float64(4000), time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), "test-2")).
+					RowsWillBeClosed()
 			},
 			want: []domain.LoanPayment{
 				{
+					ID:      1,
+					LoanID:  1,
 					Amount:  float64(1000),
 					Date:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
 					Channel: "test",
 				},
 				{
+					ID:      2,
+					LoanID:  1,
 					Amount:  float64(4000),
 					Date:    time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
 					Channel: "test-2",
@@ -203,7 +267,7 @@ func TestLoanRepositories_GetLoanPaymentsByLoanID(t *testing.T) {
 				loanID: -1,
 			},
 			prepareMock: func(m *mock) {
-				m.ExpectQuery(regexp.QuoteMeta(getLoanPaymentsByLoanID)).WithArgs(-1).WillReturnRows(sqlmock.NewRows([]string{"amount", "date", "channel"}))
+				m.ExpectQuery(regexp.QuoteMeta(getLoanPaymentsByLoanID)).WithArgs(-1).WillReturnRows(sqlmock.NewRows([]string{"id", "loan_id", "amount", "date", "channel"}))
 			},
 		},
 		{
@@ -214,7 +278,21 @@ func TestLoanRepositories_GetLoanPaymentsByLoanID(t *testing.T) {
 				loanID: 0,
 			},
 			prepareMock: func(m *mock) {
-				m.ExpectQuery(regexp.QuoteMeta(getLoanPaymentsByLoanID)).WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"amount", "date", "channel"})).WillReturnError(errors.New("oops!"))
+				m.ExpectQuery(regexp.QuoteMeta(getLoanPaymentsByLoanID)).WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"id", "loan_id", "amount", "date", "channel"})).WillReturnError(errors.New("oops!"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Given a row that can not be scanned, it should return error and close the rows",
+			repo: &LoanRepositories{},
+			args: args{
+				ctx:    context.Background(),
+				loanID: 1,
+			},
+			prepareMock: func(m *mock) {
+				m.ExpectQuery(regexp.QuoteMeta(getLoanPaymentsByLoanID)).WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "loan_id", "amount", "date", "channel"}).AddRow(1, 1, "not a number", time.Now(), "test").AddRow(2, 1, float64(1), time.Now(), "test")).
+					RowsWillBeClosed()
 			},
 			wantErr: true,
 		},
@@ -224,7 +302,7 @@ func TestLoanRepositories_GetLoanPaymentsByLoanID(t *testing.T) {
 			conn, sqlMock, err := sqlmock.New()
 			assert.NoError(t, err)
 			defer conn.Close()
-			tt.repo.dbConn = conn
+			tt.repo.cluster = infradb.NewCluster(conn)
 			tt.prepareMock(&mock{
 				Sqlmock: sqlMock,
 			})
@@ -233,6 +311,7 @@ func TestLoanRepositories_GetLoanPaymentsByLoanID(t *testing.T) {
 
 			assert.Equal(t, tt.wantErr, err != nil, err)
 			assert.Equal(t, tt.want, got)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
 		})
 	}
 }
@@ -259,18 +338,24 @@ func TestLoanRepositories_GetLoanPaymentsByUserIDAndContractNumber(t *testing.T)
 			repo: &LoanRepositories{},
 			args: args{
 				ctx:            context.Background(),
+				userID:         1,
 				contractNumber: "XYZ-LAI-01",
 			},
 			prepareMock: func(m *mock) {
-				m.ExpectQuery(regexp.QuoteMeta(getLoanByContractNumber)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"amount", "date", "channel"}).AddRow(float64(1000), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), "test").AddRow(float64(4000), time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), "test-2"))
+				m.ExpectQuery(regexp.QuoteMeta(getLoanPaymentsByContractNumber)).WithArgs(1, "XYZ-LAI-01").WillReturnRows(sqlmock.NewRows([]string{"id", "loan_id", "amount", "date", "channel"}).AddRow(1, 1, float64(1000), time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), "test").AddRow(2, 1, float64(4000), time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), "test-2")).
+					RowsWillBeClosed()
 			},
 			want: []domain.LoanPayment{
 				{
+					ID:      1,
+					LoanID:  1,
 					Amount:  float64(1000),
 					Date:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
 					Channel: "test",
 				},
 				{
+					ID:      2,
+					LoanID:  1,
 					Amount:  float64(4000),
 					Date:    time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
 					Channel: "test-2",
@@ -282,10 +367,11 @@ func TestLoanRepositories_GetLoanPaymentsByUserIDAndContractNumber(t *testing.T)
 			repo: &LoanRepositories{},
 			args: args{
 				ctx:            context.Background(),
+				userID:         1,
 				contractNumber: "XYZ-LAI--1",
 			},
 			prepareMock: func(m *mock) {
-				m.ExpectQuery(regexp.QuoteMeta(getLoanByContractNumber)).WithArgs(-1).WillReturnRows(sqlmock.NewRows([]string{"amount", "date", "channel"}))
+				m.ExpectQuery(regexp.QuoteMeta(getLoanPaymentsByContractNumber)).WithArgs(1, "XYZ-LAI--1").WillReturnRows(sqlmock.NewRows([]string{"id", "loan_id", "amount", "date", "channel"}))
 			},
 		},
 		{
@@ -293,10 +379,11 @@ func TestLoanRepositories_GetLoanPaymentsByUserIDAndContractNumber(t *testing.T)
 			repo: &LoanRepositories{},
 			args: args{
 				ctx:            context.Background(),
+				userID:         1,
 				contractNumber: "XYZ-LAI-0",
 			},
 			prepareMock: func(m *mock) {
-				m.ExpectQuery(regexp.QuoteMeta(getLoanByContractNumber)).WithArgs(0).WillReturnRows(sqlmock.NewRows([]string{"amount", "date", "channel"})).WillReturnError(errors.New("oops!"))
+				m.ExpectQuery(regexp.QuoteMeta(getLoanPaymentsByContractNumber)).WithArgs(1, "XYZ-LAI-0").WillReturnRows(sqlmock.NewRows([]string{"id", "loan_id", "amount", "date", "channel"})).WillReturnError(errors.New("oops!"))
 			},
 			wantErr: true,
 		},
@@ -306,15 +393,240 @@ func TestLoanRepositories_GetLoanPaymentsByUserIDAndContractNumber(t *testing.T)
 			conn, sqlMock, err := sqlmock.New()
 			assert.NoError(t, err)
 			defer conn.Close()
-			tt.repo.dbConn = conn
+			tt.repo.cluster = infradb.NewCluster(conn)
 			tt.prepareMock(&mock{
 				Sqlmock: sqlMock,
 			})
 
-			got, err := tt.repo.GetLoanByUserIDAndContractNumber(tt.args.ctx, tt.args.userID, tt.args.contractNumber)
+			got, err := tt.repo.GetLoanPaymentsByUserIDAndContractNumber(tt.args.ctx, tt.args.userID, tt.args.contractNumber)
+
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLoanRepositories_ListLoansByUserID(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		filter domain.LoanFilter
+	}
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	columns := []string{"id", "user_id", "contract_number", "otr_amount", "principal_amount", "asset_name", "name", "amount", "term", "status", "start_date",
+		"interest_rate", "paid_count", "paid_amount"}
+	startDate := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		repo        *LoanRepositories
+		args        args
+		prepareMock func(m *mock)
+		want        []domain.LoanSummary
+		wantErr     bool
+	}{
+		{
+			name: "Given a user without filter, it should return the first page",
+			repo: &LoanRepositories{},
+			args: args{
+				ctx:    context.Background(),
+				filter: domain.LoanFilter{UserID: 1, SortBy: domain.SortByStartDate, SortDesc: true, Limit: 3},
+			},
+			prepareMock: func(m *mock) {
+				query := listLoansByUserID + " ORDER BY COALESCE(l.start_date, '1970-01-01 00:00:00') DESC, l.id DESC LIMIT ?"
+				m.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(1, 1, 3).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(2, 1, "JKT-01-20210101-0000012", float64(1300), float64(1200), "car", "CAR", float64(5000), 12, "ACTIVE", startDate, 0.1, 2, float64(200)))
+			},
+			want: []domain.LoanSummary{
+				{
+					LoanAll: domain.LoanAll{
+						ID:              2,
+						UserID:          1,
+						ContractNumber:  "JKT-01-20210101-0000012",
+						OTRAmount:       1300,
+						PrincipalAmount: 1200,
+						AssetName:       "car",
+						LoanType:        domain.LoanType{Name: domain.CAR},
+						LimitType:       domain.LimitType{Amount: mapper.NewSQLNullableFloat64(5000), Term: 12},
+						Status:          mapper.NewSQLNUllableString("ACTIVE"),
+						StartDate:       mapper.NewSQLNullableTime(startDate),
+						InterestRate:    mapper.NewSQLNullableFloat64(0.1),
+					},
+					InstallmentsPaid: 2,
+					PaidAmount:       200,
+				},
+			},
+		},
+		{
+			name: "Given filters and a cursor, it should continue after the cursor",
+			repo: &LoanRepositories{},
+			args: args{
+				ctx: context.Background(),
+				filter: domain.LoanFilter{
+					UserID:        1,
+					Status:        domain.ACIIVE,
+					LoanType:      domain.CAR,
+					StartDateFrom: mapper.NewSQLNullableTime(startDate),
+					SortBy:        domain.SortByPrincipalAmount,
+					Cursor:        &domain.LoanCursor{SortValue: "1200", ID: 2},
+					Limit:         3,
+				},
+			},
+			prepareMock: func(m *mock) {
+				query := listLoansByUserID + " AND l.status = ? AND lot.name = ? AND l.start_date >= ?" +
+					" AND (l.principal_amount > ? OR (l.principal_amount = ? AND l.id > ?)) ORDER BY l.principal_amount ASC, l.id ASC LIMIT ?"
+				m.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(1, 1, "ACTIVE", "CAR", startDate, "1200", "1200", 2, 3).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want: []domain.LoanSummary{},
+		},
+		{
+			name: "Given an unknown sort field, it should return error",
+			repo: &LoanRepositories{},
+			args: args{
+				ctx:    context.Background(),
+				filter: domain.LoanFilter{UserID: 1, SortBy: "id; DROP TABLE loan", Limit: 3},
+			},
+			prepareMock: func(m *mock) {},
+			wantErr:     true,
+		},
+		{
+			name: "Given a user, but select it return error",
+			repo: &LoanRepositories{},
+			args: args{
+				ctx:    context.Background(),
+				filter: domain.LoanFilter{UserID: 1, SortBy: domain.SortByStartDate, Limit: 3},
+			},
+			prepareMock: func(m *mock) {
+				m.ExpectQuery(regexp.QuoteMeta(listLoansByUserID)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.repo.cluster = infradb.NewCluster(conn)
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			got, err := tt.repo.ListLoansByUserID(tt.args.ctx, tt.args.filter)
 
 			assert.Equal(t, tt.wantErr, err != nil, err)
 			assert.Equal(t, tt.want, got)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLoanRepositories_Behaviour(t *testing.T) {
+	repotest.LoanRepository(t, func(t *testing.T) repotest.Store {
+		db := repotest.MySQL(t)
+		return repotest.Store{Loans: New(infradb.NewCluster(db), referenceRepository.New(infradb.NewCluster(db))), Users: userRepository.New(infradb.NewCluster(db), nil)}
+	})
+}
+
+func TestLoanRepositories_GetLoanByUserIDAndContractNumber(t *testing.T) {
+	type args struct {
+		ctx            context.Context
+		userID         int64
+		contractNumber string
+	}
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	columns := []string{"id", "user_id", "contract_number", "otr_amount", "principal_amount", "asset_name", "loan_type_id", "limit_type_id", "status", "start_date", "interest_rate", "version"}
+	references := referenceData{
+		LoanTypes:  map[int16]domain.LoanType{1: {ID: 1, Name: domain.CAR}},
+		LimitTypes: map[int16]domain.LimitType{1: {ID: 1, Amount: mapper.NewSQLNullableFloat64(100000), Term: 1}},
+	}
+	tests := []struct {
+		name        string
+		repo        *LoanRepositories
+		args        args
+		prepareMock func(m *mock)
+		want        *domain.LoanAll
+		wantErr     error
+	}{
+		{
+			name: "Given a contract number of the user, it should return the loan",
+			repo: &LoanRepositories{},
+			args: args{ctx: context.Background(), userID: 1, contractNumber: "XYZ-LAI-01"},
+			prepareMock: func(m *mock) {
+				m.ExpectQuery(regexp.QuoteMeta(getLoanByContractNumber)).WithArgs(1, "XYZ-LAI-01").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "XYZ-LAI-01", float64(1100), float64(1000), "car", 1, 1, []byte("ACTIVE"), nil, nil, 4))
+			},
+			want: &domain.LoanAll{
+				ID:              1,
+				UserID:          1,
+				ContractNumber:  "XYZ-LAI-01",
+				OTRAmount:       1100,
+				PrincipalAmount: 1000,
+				AssetName:       "car",
+				LoanType:        domain.LoanType{Name: domain.CAR},
+				LimitType:       domain.LimitType{Amount: mapper.NewSQLNullableFloat64(100000), Term: 1},
+				Status:          mapper.NewSQLNUllableString("ACTIVE"),
+				Version:         4,
+			},
+		},
+		{
+			name: "Given a loan of a limit type missing from the reference data, it should return not found",
+			repo: &LoanRepositories{},
+			args: args{ctx: context.Background(), userID: 1, contractNumber: "XYZ-LAI-01"},
+			prepareMock: func(m *mock) {
+				m.ExpectQuery(regexp.QuoteMeta(getLoanByContractNumber)).WithArgs(1, "XYZ-LAI-01").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "XYZ-LAI-01", float64(1100), float64(1000), "car", 1, 9, []byte("ACTIVE"), nil, nil, 4))
+			},
+			wantErr: apperror.ErrNotFound,
+		},
+		{
+			name: "Given an unknown contract number, it should return not found",
+			repo: &LoanRepositories{},
+			args: args{ctx: context.Background(), userID: 1, contractNumber: "XYZ-LAI-02"},
+			prepareMock: func(m *mock) {
+				m.ExpectQuery(regexp.QuoteMeta(getLoanByContractNumber)).WithArgs(1, "XYZ-LAI-02").WillReturnRows(sqlmock.NewRows(columns))
+			},
+			wantErr: apperror.ErrNotFound,
+		},
+		{
+			name:        "Given a cancelled context, it should not query and return the context error",
+			repo:        &LoanRepositories{},
+			args:        args{ctx: cancelled, userID: 1, contractNumber: "XYZ-LAI-01"},
+			prepareMock: func(m *mock) {},
+			wantErr:     context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.repo.cluster = infradb.NewCluster(conn)
+			tt.repo.references = references
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			got, err := tt.repo.GetLoanByUserIDAndContractNumber(tt.args.ctx, tt.args.userID, tt.args.contractNumber)
+			assert.Equal(t, tt.wantErr != nil, err != nil, err)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
 		})
 	}
 }
+
+// referenceData serves fixed reference data to the loan reads.
+type referenceData domain.ReferenceData
+
+func (r referenceData) GetReferenceData(ctx context.Context) (*domain.ReferenceData, error) {
+	data := domain.ReferenceData(r)
+	return &data, nil
+}
@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	pgdb "github.com/mfajri11/xyz-backend-monolith/infra/db/postgres"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+type LoanRepository struct {
+	cluster    *infradb.Cluster
+	references port.ReferenceRepository
+}
+
+// New returns the repository of the loans in cluster, their loan and limit types are read from references.
+func New(cluster *infradb.Cluster, references port.ReferenceRepository) *LoanRepository {
+	return &LoanRepository{
+		cluster:    cluster,
+		references: references,
+	}
+}
+
+func (repo *LoanRepository) CreateLoan(ctx context.Context, loan domain.Loan) error {
+	_, err := repo.cluster.Writer(ctx).ExecContext(ctx, createLoan, loan.UserID, loan.ContractNumber, loan.OTRAmount, loan.PrincipalAmount, loan.AssetName,
+		loan.LoanTypeID, loan.LimitTypeID, loan.Status, loan.StartDate, loan.InterestRate)
+	if pgdb.IsUniqueViolation(err) {
+		err = fmt.Errorf("CreateLoan: loan with contract number %s already exists: %w", loan.ContractNumber, err)
+		return apperror.WrapError(err, apperror.ErrContractNumberConflict)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("CreateLoan: error insert loan: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return nil
+}
+
+func (repo *LoanRepository) GetLoanByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (*domain.LoanAll, error) {
+	var (
+		loan                    domain.LoanAll
+		loanTypeID, limitTypeID sql.NullInt16
+	)
+	err := repo.cluster.Reader(ctx).QueryRowContext(ctx, getLoanByContractNumber, uid, contractNumber).
+		Scan(
+			&loan.ID,
+			&loan.UserID,
+			&loan.ContractNumber,
+			&loan.OTRAmount,
+			&loan.PrincipalAmount,
+			&loan.AssetName,
+			&loanTypeID,
+			&limitTypeID,
+			&loan.Status,
+			&loan.StartDate,
+			&loan.InterestRate,
+			&loan.Version,
+		)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("GetLoanByUserIDAndContractNumber: loan with contract number %s not found", contractNumber)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("GetLoanByUserIDAndContractNumber: error select query: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	references, err := repo.references.GetReferenceData(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetLoanByUserIDAndContractNumber: error get reference data: %w", err)
+	}
+	loanType, loanTypeOK := references.LoanTypes[loanTypeID.Int16]
+	limitType, limitTypeOK := references.LimitTypes[limitTypeID.Int16]
+	// like the inner joins the reference data replaces, a loan without its types is not found
+	if !loanTypeID.Valid || !limitTypeID.Valid || !loanTypeOK || !limitTypeOK {
+		err = fmt.Errorf("GetLoanByUserIDAndContractNumber: loan with contract number %s has no loan or limit type", contractNumber)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
+	}
+	loan.LoanType = domain.LoanType{Name: loanType.Name}
+	loan.LimitType = domain.LimitType{Amount: limitType.Amount, Term: limitType.Term}
+
+	return &loan, nil
+}
+
+func (repo *LoanRepository) ListLoansByUserID(ctx context.Context, filter domain.LoanFilter) ([]domain.LoanSummary, error) {
+	sortColumn, ok := sortColumns[string(filter.SortBy)]
+	if !ok {
+		err := fmt.Errorf("ListLoansByUserID: unknown sort field %s", filter.SortBy)
+		return nil, apperror.WrapError(err, apperror.ErrBadRequest)
+	}
+
+	query := strings.Builder{}
+	query.WriteString(listLoansByUserID)
+	args := []interface{}{filter.UserID, filter.UserID}
+	if filter.Status != "" {
+		query.WriteString(" AND l.status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.LoanType != "" {
+		query.WriteString(" AND lot.name = ?")
+		args = append(args, filter.LoanType)
+	}
+	if filter.StartDateFrom.Valid {
+		query.WriteString(" AND l.start_date >= ?")
+		args = append(args, filter.StartDateFrom.Time)
+	}
+	if filter.StartDateTo.Valid {
+		query.WriteString(" AND l.start_date < ?")
+		args = append(args, filter.StartDateTo.Time)
+	}
+
+	op, direction := ">", "ASC"
+	if filter.SortDesc {
+		op, direction = "<", "DESC"
+	}
+	if filter.Cursor != nil {
+		fmt.Fprintf(&query, " AND (%[1]s %[2]s ? OR (%[1]s = ? AND l.id %[2]s ?))", sortColumn, op)
+		args = append(args, filter.Cursor.SortValue, filter.Cursor.SortValue, filter.Cursor.ID)
+	}
+	fmt.Fprintf(&query, " ORDER BY %[1]s %[2]s, l.id %[2]s LIMIT ?", sortColumn, direction)
+	args = append(args, filter.Limit)
+
+	rows, err := repo.cluster.Reader(ctx).QueryContext(ctx, pgdb.Rebind(query.String()), args...)
+	if err != nil {
+		err = fmt.Errorf("ListLoansByUserID: error select query: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	defer rows.Close()
+
+	loans := []domain.LoanSummary{}
+	for rows.Next() {
+		var loan domain.LoanSummary
+		err := rows.Scan(
+			&loan.ID,
+			&loan.UserID,
+			&loan.ContractNumber,
+			&loan.OTRAmount,
+			&loan.PrincipalAmount,
+			&loan.AssetName,
+			&loan.LoanType.Name,
+			&loan.LimitType.Amount,
+			&loan.LimitType.Term,
+			&loan.Status,
+			&loan.StartDate,
+			&loan.InterestRate,
+			&loan.InstallmentsPaid,
+			&loan.PaidAmount,
+		)
+		if err != nil {
+			err = fmt.Errorf("ListLoansByUserID: error scan query: %w", err)
+			return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+		}
+		loans = append(loans, loan)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("ListLoansByUserID: error iterate rows: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return loans, nil
+}
+
+// CreateLoanPayment inserts the payment and increments the version of its loan in one transaction, given a
+// version the loan has moved past the payment is refused with a version conflict.
+func (repo *LoanRepository) CreateLoanPayment(ctx context.Context, loanPayment domain.LoanPayment) error {
+	tx, err := repo.cluster.BeginTx(ctx, nil)
+	if err != nil {
+		err = fmt.Errorf("CreateLoanPayment: error begin transaction: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	defer tx.Rollback()
+
+	var version interface{}
+	if loanPayment.LoanVersion != 0 {
+		version = loanPayment.LoanVersion
+	}
+	res, err := tx.ExecContext(ctx, incrementLoanVersion, loanPayment.LoanID, version)
+	if err != nil {
+		err = fmt.Errorf("CreateLoanPayment: error update loan version: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		err = fmt.Errorf("CreateLoanPayment: error get affected rows: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	if affected == 0 && loanPayment.LoanVersion != 0 {
+		err = fmt.Errorf("CreateLoanPayment: loan %d is no longer at version %d", loanPayment.LoanID, loanPayment.LoanVersion)
+		return apperror.WrapError(err, apperror.ErrVersionConflict)
+	}
+
+	if affected == 0 {
+		err = fmt.Errorf("CreateLoanPayment: error insert loan payment: loan %d does not exist", loanPayment.LoanID)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	_, err = tx.ExecContext(ctx, createLoanPayment, loanPayment.LoanID, loanPayment.Amount, loanPayment.Date, loanPayment.Channel)
+	if err != nil {
+		err = fmt.Errorf("CreateLoanPayment: error insert loan payment: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	if err = tx.Commit(); err != nil {
+		err = fmt.Errorf("CreateLoanPayment: error commit transaction: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return nil
+}
+
+func (repo *LoanRepository) GetLoanPaymentsByLoanID(ctx context.Context, loanID int64) ([]domain.LoanPayment, error) {
+	payments, err := repo.queryLoanPayments(ctx, getLoanPaymentsByLoanID, loanID)
+	if err != nil {
+		err = fmt.Errorf("GetLoanPaymentsByLoanID: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return payments, nil
+}
+
+func (repo *LoanRepository) GetLoanPaymentsByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) ([]domain.LoanPayment, error) {
+	payments, err := repo.queryLoanPayments(ctx, getLoanPaymentsByContractNumber, uid, contractNumber)
+	if err != nil {
+		err = fmt.Errorf("GetLoanPaymentsByUserIDAndContractNumber: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return payments, nil
+}
+
+func (repo *LoanRepository) queryLoanPayments(ctx context.Context, query string, args ...interface{}) ([]domain.LoanPayment, error) {
+	rows, err := repo.cluster.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error select query: %w", err)
+	}
+	defer rows.Close()
+
+	var loanPayments []domain.LoanPayment
+	for rows.Next() {
+		var loanPayment domain.LoanPayment
+		if err := rows.Scan(&loanPayment.ID, &loanPayment.LoanID, &loanPayment.Amount, &loanPayment.Date, &loanPayment.Channel); err != nil {
+			return nil, fmt.Errorf("error scan query: %w", err)
+		}
+		loanPayments = append(loanPayments, loanPayment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterate rows: %w", err)
+	}
+
+	return loanPayments, nil
+}
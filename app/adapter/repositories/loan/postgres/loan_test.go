@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"testing"
+
+	referenceRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/reference/postgres"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/repotest"
+	userRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/user/postgres"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+)
+
+func TestMain(m *testing.M) {
+	repotest.Main(m)
+}
+
+func TestLoanRepository_Behaviour(t *testing.T) {
+	repotest.LoanRepository(t, func(t *testing.T) repotest.Store {
+		db := repotest.Postgres(t)
+		return repotest.Store{Loans: New(infradb.NewCluster(db), referenceRepository.New(infradb.NewCluster(db))), Users: userRepository.New(infradb.NewCluster(db), nil)}
+	})
+}
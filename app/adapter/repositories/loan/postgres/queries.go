@@ -0,0 +1,30 @@
+package postgres
+
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
+var (
+	createLoan = infradb.Named("loan.create_loan", `INSERT INTO loan (user_id, contract_number, otr_amount, principal_amount, asset_name, loan_type_id, limit_type_id, status, start_date, interest_rate) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`)
+
+	// a version of NULL increments any version, see LoanPayment.LoanVersion.
+	incrementLoanVersion = infradb.Named("loan.increment_loan_version", `UPDATE loan SET version = version + 1 WHERE id = $1 AND version = COALESCE($2, version)`)
+
+	createLoanPayment = infradb.Named("loan.create_loan_payment", `INSERT INTO loan_payment (loan_id, amount, date, channel) VALUES ($1, $2, $3, $4)`)
+
+	// the loan and limit types are resolved from the reference data rather than joined, see GetReferenceData.
+	getLoanByContractNumber = infradb.Named("loan.get_loan_by_contract_number", `SELECT id, user_id, contract_number, otr_amount, principal_amount, asset_name, loan_type_id, limit_type_id, status, start_date, interest_rate, version FROM loan WHERE user_id = $1 AND contract_number = $2`)
+
+	getLoanPaymentsByLoanID = infradb.Named("loan.get_loan_payments_by_loan_id", `SELECT id, loan_id, amount, date, channel FROM loan_payment WHERE loan_id = $1 ORDER BY date, id`)
+
+	getLoanPaymentsByContractNumber = infradb.Named("loan.get_loan_payments_by_contract_number", `SELECT p.id, p.loan_id, p.amount, p.date, p.channel FROM loan_payment p JOIN loan l ON p.loan_id = l.id WHERE l.user_id = $1 AND l.contract_number = $2 ORDER BY p.date, p.id`)
+
+	// the filters, the keyset condition, the order and the limit are appended with ? placeholders, see Rebind.
+	// the payments are summed for the loans of the user only, both placeholders take the user id.
+	listLoansByUserID = infradb.Named("loan.list_loans_by_user_id", `SELECT l.id, l.user_id, l.contract_number, l.otr_amount, l.principal_amount, l.asset_name, lot.name, lit.amount, lit.term, l.status, l.start_date, l.interest_rate, COALESCE(p.paid_count, 0), COALESCE(p.paid_amount, 0) FROM loan l JOIN limit_type lit ON l.limit_type_id = lit.id JOIN loan_type lot ON l.loan_type_id = lot.id LEFT JOIN (SELECT lp.loan_id, COUNT(*) AS paid_count, SUM(lp.amount) AS paid_amount FROM loan_payment lp JOIN loan pl ON lp.loan_id = pl.id WHERE pl.user_id = ? GROUP BY lp.loan_id) p ON p.loan_id = l.id WHERE l.user_id = ?`)
+)
+
+// sortColumns whitelists the expressions a loan list can be ordered by, null start dates sort as the epoch
+// so they can still take part in the keyset comparison.
+var sortColumns = map[string]string{
+	"start_date":       "COALESCE(l.start_date, TIMESTAMP '1970-01-01 00:00:00')",
+	"principal_amount": "l.principal_amount",
+}
@@ -1,16 +1,32 @@
 package loan
 
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
 var (
-	createLoan = `INSERT INTO loan (user_id, contract_number, otr_amount, principal_amount, asset_name, loan_type_id, limit_type_id, status, start_date, interest_rate) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	createLoan = infradb.Named("loan.create_loan", `INSERT INTO loan (user_id, contract_number, otr_amount, principal_amount, asset_name, loan_type_id, limit_type_id, status, start_date, interest_rate) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+
+	// a version of NULL increments any version, see LoanPayment.LoanVersion.
+	incrementLoanVersion = infradb.Named("loan.increment_loan_version", `UPDATE loan SET version = version + 1 WHERE id = ? AND version = COALESCE(?, version)`)
 
-	createLoanPayment = `INSERT INTO loan_payment (loan_id, amount, date, channel) VALUES(?, ?, ?)`
+	createLoanPayment = infradb.Named("loan.create_loan_payment", `INSERT INTO loan_payment (loan_id, amount, date, channel) VALUES (?, ?, ?, ?)`)
 
-	getLoanByContractNumber = `SELECT l.id, l.user_id, l.contract_number, l.otr_amount, l.principal_amount, l.asset_name, lot.name , lit.amount, lit.term , l.status, l.start_date, l.interest_rate FROM loan l JOIN limit_type lit ON l.limit_type_id = lit.id JOIN loan_type lot ON  l.loan_type_id  = lot.id WHERE l.user_id = ? AND l.contract_number = ?`
+	// the loan and limit types are resolved from the reference data rather than joined, see GetReferenceData.
+	getLoanByContractNumber = infradb.Named("loan.get_loan_by_contract_number", `SELECT id, user_id, contract_number, otr_amount, principal_amount, asset_name, loan_type_id, limit_type_id, status, start_date, interest_rate, version FROM loan WHERE user_id = ? AND contract_number = ?`)
 
-	getLoanPaymentsByLoanID = `SELECT amount, date, channel FROM loan_payment WHERE loan_id = ?`
+	getLoanPaymentsByLoanID = infradb.Named("loan.get_loan_payments_by_loan_id", `SELECT id, loan_id, amount, date, channel FROM loan_payment WHERE loan_id = ? ORDER BY date, id`)
 
-	getLoanPaymentsByContractNumber = `WITH lpymnt_id AS (
+	getLoanPaymentsByContractNumber = infradb.Named("loan.get_loan_payments_by_contract_number", `WITH lpymnt_id AS (
 		SELECT id FROM loan WHERE user_id = ? AND contract_number = ?
 	)
-	SELECT amount, date, channel FROM loan_payment WHERE loan_id IN (SELECT id FROM lpymnt_id)`
+	SELECT id, loan_id, amount, date, channel FROM loan_payment WHERE loan_id IN (SELECT id FROM lpymnt_id) ORDER BY date, id`)
+
+	// the payments are summed for the loans of the user only, both placeholders take the user id.
+	listLoansByUserID = infradb.Named("loan.list_loans_by_user_id", `SELECT l.id, l.user_id, l.contract_number, l.otr_amount, l.principal_amount, l.asset_name, lot.name, lit.amount, lit.term, l.status, l.start_date, l.interest_rate, COALESCE(p.paid_count, 0), COALESCE(p.paid_amount, 0) FROM loan l JOIN limit_type lit ON l.limit_type_id = lit.id JOIN loan_type lot ON l.loan_type_id = lot.id LEFT JOIN (SELECT lp.loan_id, COUNT(*) AS paid_count, SUM(lp.amount) AS paid_amount FROM loan_payment lp JOIN loan pl ON lp.loan_id = pl.id WHERE pl.user_id = ? GROUP BY lp.loan_id) p ON p.loan_id = l.id WHERE l.user_id = ?`)
 )
+
+// sortColumns whitelists the expressions a loan list can be ordered by, null start dates sort as the epoch
+// so they can still take part in the keyset comparison.
+var sortColumns = map[string]string{
+	"start_date":       "COALESCE(l.start_date, '1970-01-01 00:00:00')",
+	"principal_amount": "l.principal_amount",
+}
@@ -0,0 +1,310 @@
+package loanapplication
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/mapper"
+	"github.com/stretchr/testify/assert"
+)
+
+var loanApplicationColumns = []string{"id", "user_id", "status", "asset_name", "otr_amount", "down_payment", "loan_type_id", "limit_type_id", "tenor",
+	"national_id_photo", "user_photo", "loan_id", "contract_number", "decision_reason", "submitted_at", "decided_at", "created_at", "updated_at"}
+
+func TestLoanApplicationRepository_CreateLoanApplication(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		app domain.LoanApplication
+	}
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	tests := []struct {
+		name        string
+		repo        *LoanApplicationRepository
+		args        args
+		prepareMock func(mock *mock)
+		want        int64
+		wantErr     bool
+	}{
+		{
+			name: "Given a draft application, it should return the inserted id",
+			repo: &LoanApplicationRepository{},
+			args: args{
+				ctx: context.Background(),
+				app: domain.LoanApplication{UserID: 1, Status: domain.ApplicationDraft},
+			},
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(createLoanApplication)).WithArgs(1, "DRAFT").WillReturnResult(sqlmock.NewResult(7, 1))
+			},
+			want: 7,
+		},
+		{
+			name: "Given a draft application, but insert it return error",
+			repo: &LoanApplicationRepository{},
+			args: args{
+				ctx: context.Background(),
+				app: domain.LoanApplication{UserID: 1, Status: domain.ApplicationDraft},
+			},
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(createLoanApplication)).WithArgs(1, "DRAFT").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.repo.cluster = infradb.NewCluster(conn)
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			got, err := tt.repo.CreateLoanApplication(tt.args.ctx, tt.args.app)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoanApplicationRepository_GetLoanApplicationByUserIDAndID(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		uid int64
+		id  int64
+	}
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	createdAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		repo         *LoanApplicationRepository
+		args         args
+		prepareMock  func(mock *mock)
+		want         *domain.LoanApplication
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{
+			name: "Given an existing application, it should return the application",
+			repo: &LoanApplicationRepository{},
+			args: args{
+				ctx: context.Background(),
+				uid: 1,
+				id:  7,
+			},
+			prepareMock: func(mock *mock) {
+				mock.ExpectQuery(regexp.QuoteMeta(getLoanApplicationByUserIDAndID)).WithArgs(1, 7).WillReturnRows(sqlmock.NewRows(loanApplicationColumns).
+					AddRow(7, 1, "DRAFT", "car", float64(1300), float64(100), 1, nil, nil, nil, nil, nil, nil, nil, nil, nil, createdAt, createdAt))
+			},
+			want: &domain.LoanApplication{
+				ID:          7,
+				UserID:      1,
+				Status:      domain.ApplicationDraft,
+				AssetName:   mapper.NewSQLNUllableString("car"),
+				OTRAmount:   mapper.NewSQLNullableFloat64(1300),
+				DownPayment: mapper.NewSQLNullableFloat64(100),
+				LoanTypeID:  mapper.NewSQLNUllableInt16(1),
+				CreatedAt:   createdAt,
+				UpdatedAt:   createdAt,
+			},
+		},
+		{
+			name: "Given an unknown application, it should return not found",
+			repo: &LoanApplicationRepository{},
+			args: args{
+				ctx: context.Background(),
+				uid: 1,
+				id:  8,
+			},
+			prepareMock: func(mock *mock) {
+				mock.ExpectQuery(regexp.QuoteMeta(getLoanApplicationByUserIDAndID)).WithArgs(1, 8).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr:      true,
+			wantNotFound: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.repo.cluster = infradb.NewCluster(conn)
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			got, err := tt.repo.GetLoanApplicationByUserIDAndID(tt.args.ctx, tt.args.uid, tt.args.id)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantNotFound, errors.Is(err, apperror.ErrNotFound), err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoanApplicationRepository_UpdateLoanApplication(t *testing.T) {
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	submittedAt := mapper.NewSQLNullableTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	app := domain.LoanApplication{ID: 7, UserID: 1, Status: domain.ApplicationSubmitted, SubmittedAt: submittedAt}
+	tests := []struct {
+		name        string
+		prepareMock func(mock *mock)
+		wantErr     error
+	}{
+		{
+			name: "Given an application still in the status it was read with, it should update it",
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateLoanApplication)).
+					WithArgs("SUBMITTED", nil, nil, nil, nil, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, submittedAt.Time, nil, 7, "DRAFT").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Given a write of the values already stored, it should not return a conflict",
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateLoanApplication)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(getLoanApplicationStatus)).WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("DRAFT"))
+			},
+		},
+		{
+			name: "Given an application changed since it was read, it should return a version conflict",
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateLoanApplication)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(getLoanApplicationStatus)).WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("ABANDONED"))
+			},
+			wantErr: apperror.ErrVersionConflict,
+		},
+		{
+			name: "Given an application that is gone, it should return a version conflict",
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateLoanApplication)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(getLoanApplicationStatus)).WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"status"}))
+			},
+			wantErr: apperror.ErrVersionConflict,
+		},
+		{
+			name: "Given an application, but update it return error",
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateLoanApplication)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: apperror.ErrInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			repo := &LoanApplicationRepository{cluster: infradb.NewCluster(conn)}
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			err = repo.UpdateLoanApplication(context.Background(), app, domain.ApplicationDraft)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLoanApplicationRepository_ConvertToLoan(t *testing.T) {
+	type args struct {
+		ctx  context.Context
+		app  domain.LoanApplication
+		loan domain.Loan
+	}
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	decidedAt := mapper.NewSQLNullableTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	app := domain.LoanApplication{ID: 7, UserID: 1, Status: domain.ApplicationSubmitted, DecidedAt: decidedAt}
+	loan := domain.Loan{
+		UserID:          1,
+		ContractNumber:  "XYZ-1",
+		OTRAmount:       1300,
+		PrincipalAmount: 1200,
+		AssetName:       "car",
+		LoanTypeID:      mapper.NewSQLNUllableInt16(1),
+		LimitTypeID:     mapper.NewSQLNUllableInt16(1),
+		Status:          mapper.NewSQLNUllableString("ACTIVE"),
+		StartDate:       decidedAt,
+	}
+	tests := []struct {
+		name        string
+		repo        *LoanApplicationRepository
+		args        args
+		prepareMock func(mock *mock)
+		want        int64
+		wantErr     bool
+	}{
+		{
+			name: "Given a submitted application, it should insert the loan and approve the application",
+			repo: &LoanApplicationRepository{},
+			args: args{ctx: context.Background(), app: app, loan: loan},
+			prepareMock: func(mock *mock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(createLoanFromApplication)).WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectExec(regexp.QuoteMeta(approveLoanApplication)).WithArgs("APPROVED", 3, "XYZ-1", nil, decidedAt.Time, 7, "SUBMITTED").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			want: 3,
+		},
+		{
+			name: "Given an application decided concurrently, it should rollback",
+			repo: &LoanApplicationRepository{},
+			args: args{ctx: context.Background(), app: app, loan: loan},
+			prepareMock: func(mock *mock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(createLoanFromApplication)).WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectExec(regexp.QuoteMeta(approveLoanApplication)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "Given a submitted application, but insert loan return error",
+			repo: &LoanApplicationRepository{},
+			args: args{ctx: context.Background(), app: app, loan: loan},
+			prepareMock: func(mock *mock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(createLoanFromApplication)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.repo.cluster = infradb.NewCluster(conn)
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			got, err := tt.repo.ConvertToLoan(tt.args.ctx, tt.args.app, tt.args.loan)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	pgdb "github.com/mfajri11/xyz-backend-monolith/infra/db/postgres"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+type LoanApplicationRepository struct {
+	cluster *infradb.Cluster
+}
+
+func New(cluster *infradb.Cluster) *LoanApplicationRepository {
+	return &LoanApplicationRepository{
+		cluster: cluster,
+	}
+}
+
+func (repo *LoanApplicationRepository) CreateLoanApplication(ctx context.Context, app domain.LoanApplication) (int64, error) {
+	var id int64
+	err := repo.cluster.Writer(ctx).QueryRowContext(ctx, createLoanApplication, app.UserID, app.Status).Scan(&id)
+	if err != nil {
+		err = fmt.Errorf("CreateLoanApplication: error insert loan application: %w", err)
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return id, nil
+}
+
+func (repo *LoanApplicationRepository) GetLoanApplicationByID(ctx context.Context, id int64) (*domain.LoanApplication, error) {
+	app, err := scanLoanApplication(repo.cluster.Primary().QueryRowContext(ctx, getLoanApplicationByID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("GetLoanApplicationByID: loan application with id %d not found", id)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("GetLoanApplicationByID: error select query: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return app, nil
+}
+
+func (repo *LoanApplicationRepository) GetLoanApplicationByUserIDAndID(ctx context.Context, uid int64, id int64) (*domain.LoanApplication, error) {
+	app, err := scanLoanApplication(repo.cluster.Primary().QueryRowContext(ctx, getLoanApplicationByUserIDAndID, uid, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("GetLoanApplicationByUserIDAndID: loan application with id %d not found", id)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("GetLoanApplicationByUserIDAndID: error select query: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return app, nil
+}
+
+func (repo *LoanApplicationRepository) UpdateLoanApplication(ctx context.Context, app domain.LoanApplication, from domain.LoanApplicationStatus) error {
+	res, err := repo.cluster.Writer(ctx).ExecContext(ctx, updateLoanApplication, app.Status, app.AssetName, app.OTRAmount, app.DownPayment,
+		app.LoanTypeID, app.LimitTypeID, app.Tenor, app.NationalIDPhoto, app.UserPhoto, app.DecisionReason, app.SubmittedAt, app.DecidedAt, app.ID, from)
+	if err != nil {
+		err = fmt.Errorf("UpdateLoanApplication: error update loan application: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		err = fmt.Errorf("UpdateLoanApplication: error get affected rows: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	if affected == 0 {
+		err = fmt.Errorf("UpdateLoanApplication: loan application with id %d is no longer %s", app.ID, from)
+		return apperror.WrapError(err, apperror.ErrVersionConflict)
+	}
+
+	return nil
+}
+
+func (repo *LoanApplicationRepository) ConvertToLoan(ctx context.Context, app domain.LoanApplication, loan domain.Loan) (int64, error) {
+	tx, err := repo.cluster.BeginTx(ctx, nil)
+	if err != nil {
+		err = fmt.Errorf("ConvertToLoan: error begin transaction: %w", err)
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	defer tx.Rollback()
+
+	var loanID int64
+	err = tx.QueryRowContext(ctx, createLoanFromApplication, loan.UserID, loan.ContractNumber, loan.OTRAmount, loan.PrincipalAmount, loan.AssetName,
+		loan.LoanTypeID, loan.LimitTypeID, loan.Status, loan.StartDate, loan.InterestRate).Scan(&loanID)
+	if pgdb.IsUniqueViolation(err) {
+		err = fmt.Errorf("ConvertToLoan: loan with contract number %s already exists: %w", loan.ContractNumber, err)
+		return 0, apperror.WrapError(err, apperror.ErrContractNumberConflict)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("ConvertToLoan: error insert loan: %w", err)
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	// the status condition makes sure a concurrent decision can not convert the same application twice
+	res, err := tx.ExecContext(ctx, approveLoanApplication, domain.ApplicationApproved, loanID, loan.ContractNumber, app.DecisionReason, app.DecidedAt,
+		app.ID, domain.ApplicationSubmitted)
+	if err != nil {
+		err = fmt.Errorf("ConvertToLoan: error update loan application: %w", err)
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		err = fmt.Errorf("ConvertToLoan: error get affected rows: %w", err)
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	if affected == 0 {
+		err = fmt.Errorf("ConvertToLoan: loan application with id %d is no longer submitted", app.ID)
+		return 0, apperror.WrapError(err, apperror.ErrLoanApplicationAlreadyDecided)
+	}
+
+	if err = tx.Commit(); err != nil {
+		err = fmt.Errorf("ConvertToLoan: error commit transaction: %w", err)
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return loanID, nil
+}
+
+func scanLoanApplication(row *infradb.Row) (*domain.LoanApplication, error) {
+	var app domain.LoanApplication
+	err := row.Scan(
+		&app.ID,
+		&app.UserID,
+		&app.Status,
+		&app.AssetName,
+		&app.OTRAmount,
+		&app.DownPayment,
+		&app.LoanTypeID,
+		&app.LimitTypeID,
+		&app.Tenor,
+		&app.NationalIDPhoto,
+		&app.UserPhoto,
+		&app.LoanID,
+		&app.ContractNumber,
+		&app.DecisionReason,
+		&app.SubmittedAt,
+		&app.DecidedAt,
+		&app.CreatedAt,
+		&app.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &app, nil
+}
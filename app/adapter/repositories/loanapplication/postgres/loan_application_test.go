@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/mapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoanApplicationRepository_CreateLoanApplication(t *testing.T) {
+	type args struct {
+		ctx context.Context
+		app domain.LoanApplication
+	}
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	tests := []struct {
+		name        string
+		repo        *LoanApplicationRepository
+		args        args
+		prepareMock func(mock *mock)
+		want        int64
+		wantErr     bool
+	}{
+		{
+			name: "Given a draft application, it should return the returned id",
+			repo: &LoanApplicationRepository{},
+			args: args{
+				ctx: context.Background(),
+				app: domain.LoanApplication{UserID: 1, Status: domain.ApplicationDraft},
+			},
+			prepareMock: func(mock *mock) {
+				mock.ExpectQuery(regexp.QuoteMeta(createLoanApplication)).WithArgs(1, "DRAFT").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			},
+			want: 7,
+		},
+		{
+			name: "Given a draft application, but insert it return error",
+			repo: &LoanApplicationRepository{},
+			args: args{
+				ctx: context.Background(),
+				app: domain.LoanApplication{UserID: 1, Status: domain.ApplicationDraft},
+			},
+			prepareMock: func(mock *mock) {
+				mock.ExpectQuery(regexp.QuoteMeta(createLoanApplication)).WithArgs(1, "DRAFT").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.repo.cluster = infradb.NewCluster(conn)
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			got, err := tt.repo.CreateLoanApplication(tt.args.ctx, tt.args.app)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoanApplicationRepository_UpdateLoanApplication(t *testing.T) {
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	submittedAt := mapper.NewSQLNullableTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	app := domain.LoanApplication{ID: 7, UserID: 1, Status: domain.ApplicationSubmitted, SubmittedAt: submittedAt}
+	tests := []struct {
+		name        string
+		prepareMock func(mock *mock)
+		wantErr     error
+	}{
+		{
+			name: "Given an application still in the status it was read with, it should update it",
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateLoanApplication)).
+					WithArgs("SUBMITTED", nil, nil, nil, nil, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, submittedAt.Time, nil, 7, "DRAFT").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Given an application changed since it was read, it should return a version conflict",
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(updateLoanApplication)).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: apperror.ErrVersionConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			repo := &LoanApplicationRepository{cluster: infradb.NewCluster(conn)}
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			err = repo.UpdateLoanApplication(context.Background(), app, domain.ApplicationDraft)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLoanApplicationRepository_ConvertToLoan(t *testing.T) {
+	type args struct {
+		ctx  context.Context
+		app  domain.LoanApplication
+		loan domain.Loan
+	}
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	decidedAt := mapper.NewSQLNullableTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	app := domain.LoanApplication{ID: 7, UserID: 1, Status: domain.ApplicationSubmitted, DecidedAt: decidedAt}
+	loan := domain.Loan{
+		UserID:          1,
+		ContractNumber:  "XYZ-1",
+		OTRAmount:       1300,
+		PrincipalAmount: 1200,
+		AssetName:       "car",
+		LoanTypeID:      mapper.NewSQLNUllableInt16(1),
+		LimitTypeID:     mapper.NewSQLNUllableInt16(1),
+		Status:          mapper.NewSQLNUllableString("ACTIVE"),
+		StartDate:       decidedAt,
+	}
+	tests := []struct {
+		name        string
+		repo        *LoanApplicationRepository
+		args        args
+		prepareMock func(mock *mock)
+		want        int64
+		wantErr     bool
+	}{
+		{
+			name: "Given a submitted application, it should insert the loan and approve the application",
+			repo: &LoanApplicationRepository{},
+			args: args{ctx: context.Background(), app: app, loan: loan},
+			prepareMock: func(mock *mock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(createLoanFromApplication)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(approveLoanApplication)).WithArgs("APPROVED", 3, "XYZ-1", nil, decidedAt.Time, 7, "SUBMITTED").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			want: 3,
+		},
+		{
+			name: "Given an application decided concurrently, it should rollback",
+			repo: &LoanApplicationRepository{},
+			args: args{ctx: context.Background(), app: app, loan: loan},
+			prepareMock: func(mock *mock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(createLoanFromApplication)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectExec(regexp.QuoteMeta(approveLoanApplication)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "Given a submitted application, but insert loan return error",
+			repo: &LoanApplicationRepository{},
+			args: args{ctx: context.Background(), app: app, loan: loan},
+			prepareMock: func(mock *mock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(createLoanFromApplication)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.repo.cluster = infradb.NewCluster(conn)
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			got, err := tt.repo.ConvertToLoan(tt.args.ctx, tt.args.app, tt.args.loan)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
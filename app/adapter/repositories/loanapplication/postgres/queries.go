@@ -0,0 +1,17 @@
+package postgres
+
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
+var (
+	createLoanApplication = infradb.Named("loan_application.create_loan_application", `INSERT INTO loan_application (user_id, status) VALUES ($1, $2) RETURNING id`)
+
+	getLoanApplicationByID = infradb.Named("loan_application.get_loan_application_by_id", `SELECT id, user_id, status, asset_name, otr_amount, down_payment, loan_type_id, limit_type_id, tenor, national_id_photo, user_photo, loan_id, contract_number, decision_reason, submitted_at, decided_at, created_at, updated_at FROM loan_application WHERE id = $1`)
+
+	getLoanApplicationByUserIDAndID = infradb.Named("loan_application.get_loan_application_by_user_id_and_id", `SELECT id, user_id, status, asset_name, otr_amount, down_payment, loan_type_id, limit_type_id, tenor, national_id_photo, user_photo, loan_id, contract_number, decision_reason, submitted_at, decided_at, created_at, updated_at FROM loan_application WHERE user_id = $1 AND id = $2`)
+
+	updateLoanApplication = infradb.Named("loan_application.update_loan_application", `UPDATE loan_application SET status = $1, asset_name = $2, otr_amount = $3, down_payment = $4, loan_type_id = $5, limit_type_id = $6, tenor = $7, national_id_photo = $8, user_photo = $9, decision_reason = $10, submitted_at = $11, decided_at = $12, updated_at = CURRENT_TIMESTAMP WHERE id = $13 AND status = $14`)
+
+	createLoanFromApplication = infradb.Named("loan_application.create_loan_from_application", `INSERT INTO loan (user_id, contract_number, otr_amount, principal_amount, asset_name, loan_type_id, limit_type_id, status, start_date, interest_rate) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`)
+
+	approveLoanApplication = infradb.Named("loan_application.approve_loan_application", `UPDATE loan_application SET status = $1, loan_id = $2, contract_number = $3, decision_reason = $4, decided_at = $5, updated_at = CURRENT_TIMESTAMP WHERE id = $6 AND status = $7`)
+)
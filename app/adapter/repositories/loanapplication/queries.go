@@ -0,0 +1,19 @@
+package loanapplication
+
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
+var (
+	createLoanApplication = infradb.Named("loan_application.create_loan_application", `INSERT INTO loan_application (user_id, status) VALUES (?, ?)`)
+
+	getLoanApplicationByID = infradb.Named("loan_application.get_loan_application_by_id", `SELECT id, user_id, status, asset_name, otr_amount, down_payment, loan_type_id, limit_type_id, tenor, national_id_photo, user_photo, loan_id, contract_number, decision_reason, submitted_at, decided_at, created_at, updated_at FROM loan_application WHERE id = ?`)
+
+	getLoanApplicationByUserIDAndID = infradb.Named("loan_application.get_loan_application_by_user_id_and_id", `SELECT id, user_id, status, asset_name, otr_amount, down_payment, loan_type_id, limit_type_id, tenor, national_id_photo, user_photo, loan_id, contract_number, decision_reason, submitted_at, decided_at, created_at, updated_at FROM loan_application WHERE user_id = ? AND id = ?`)
+
+	updateLoanApplication = infradb.Named("loan_application.update_loan_application", `UPDATE loan_application SET status = ?, asset_name = ?, otr_amount = ?, down_payment = ?, loan_type_id = ?, limit_type_id = ?, tenor = ?, national_id_photo = ?, user_photo = ?, decision_reason = ?, submitted_at = ?, decided_at = ? WHERE id = ? AND status = ?`)
+
+	getLoanApplicationStatus = infradb.Named("loan_application.get_loan_application_status", `SELECT status FROM loan_application WHERE id = ?`)
+
+	createLoanFromApplication = infradb.Named("loan_application.create_loan_from_application", `INSERT INTO loan (user_id, contract_number, otr_amount, principal_amount, asset_name, loan_type_id, limit_type_id, status, start_date, interest_rate) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+
+	approveLoanApplication = infradb.Named("loan_application.approve_loan_application", `UPDATE loan_application SET status = ?, loan_id = ?, contract_number = ?, decision_reason = ?, decided_at = ? WHERE id = ? AND status = ?`)
+)
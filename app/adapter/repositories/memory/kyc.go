@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/seed"
+)
+
+// salaryTolerance is the share of the registered salary the verified range spans on each side.
+const salaryTolerance = 0.2
+
+// KYCProvider answers like the kyc vendor for the identities it knows, the demo users of the seed package.
+// an unknown national id is reported as not registered, so every kyc failure can be tried locally.
+type KYCProvider struct {
+	identities map[string]seed.DemoUser
+}
+
+func NewKYCProvider() *KYCProvider {
+	p := &KYCProvider{identities: map[string]seed.DemoUser{}}
+	for _, u := range seed.DemoUsers {
+		p.identities[u.NationalID] = u
+	}
+	return p
+}
+
+func (p *KYCProvider) ValidateSalary(ctx context.Context, req domain.KYCValidateSalaryReq) (*domain.KYCValidateSalaryResp, error) {
+	resp := &domain.KYCValidateSalaryResp{Message: "success", Data: domain.KYCData{ReferenceID: req.ReferenceID}}
+	identity, ok := p.identities[req.NationalID]
+	if !ok {
+		// an empty range fails every salary
+		resp.Data.SalaryLower, resp.Data.SalaryUper = "0", "0"
+		return resp, nil
+	}
+
+	resp.Data.NationalID = true
+	resp.Data.LegalName = identity.LegalName == req.LegalName
+	resp.Data.SalaryLower = strconv.FormatFloat(identity.Salary*(1-salaryTolerance), 'f', 2, 64)
+	resp.Data.SalaryUper = strconv.FormatFloat(identity.Salary*(1+salaryTolerance), 'f', 2, 64)
+	return resp, nil
+}
+
+func (p *KYCProvider) ValidateNationalID(ctx context.Context, req domain.KYCValidateNationalIDReq) (*domain.KYCValidateNationalIDResp, error) {
+	resp := &domain.KYCValidateNationalIDResp{Message: "success", Data: domain.KYCData{ReferenceID: req.ReferenceID}}
+	identity, ok := p.identities[req.NationalID]
+	if !ok {
+		return resp, nil
+	}
+
+	resp.Data.NationalID = true
+	resp.Data.LegalName = identity.LegalName == req.LegalName
+	resp.Data.DateOfBirth = identity.BirthOfDate == req.DateOfBirth
+	return resp, nil
+}
+
+func (p *KYCProvider) ValidatePhoto(ctx context.Context, req domain.KYCValidatePhotoReq) (*domain.KYCValidatePhotoResp, error) {
+	resp := &domain.KYCValidatePhotoResp{Message: "success"}
+	resp.Data.Status = "invalid"
+	if _, ok := p.identities[req.NationalID]; ok {
+		resp.Data.Status = "valid"
+	}
+	return resp, nil
+}
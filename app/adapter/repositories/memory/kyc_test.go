@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/seed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKYCProvider_ValidateNationalID(t *testing.T) {
+	demo := seed.DemoUsers[0]
+	tests := []struct {
+		name string
+		req  domain.KYCValidateNationalIDReq
+		want domain.KYCData
+	}{
+		{
+			name: "Given the data of a known identity, it should validate every field",
+			req:  domain.KYCValidateNationalIDReq{NationalID: demo.NationalID, LegalName: demo.LegalName, DateOfBirth: demo.BirthOfDate, ReferenceID: "ref"},
+			want: domain.KYCData{NationalID: true, LegalName: true, DateOfBirth: true, ReferenceID: "ref"},
+		},
+		{
+			name: "Given a known national id with another name, it should not validate the name",
+			req:  domain.KYCValidateNationalIDReq{NationalID: demo.NationalID, LegalName: "Someone Else", DateOfBirth: demo.BirthOfDate},
+			want: domain.KYCData{NationalID: true, DateOfBirth: true},
+		},
+		{
+			name: "Given an unknown national id, it should not validate anything",
+			req:  domain.KYCValidateNationalIDReq{NationalID: "9999999999999999", LegalName: demo.LegalName, DateOfBirth: demo.BirthOfDate},
+			want: domain.KYCData{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewKYCProvider().ValidateNationalID(context.Background(), tt.req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Data)
+		})
+	}
+}
+
+func TestKYCProvider_ValidateSalary(t *testing.T) {
+	demo := seed.DemoUsers[0]
+	tests := []struct {
+		name       string
+		nid        string
+		salary     float64
+		wantInside bool
+	}{
+		{
+			name:       "Given the registered salary, it should be inside the verified range",
+			nid:        demo.NationalID,
+			salary:     demo.Salary,
+			wantInside: true,
+		},
+		{
+			name:   "Given twice the registered salary, it should be outside the verified range",
+			nid:    demo.NationalID,
+			salary: demo.Salary * 2,
+		},
+		{
+			name:   "Given an unknown national id, it should be outside the verified range",
+			nid:    "9999999999999999",
+			salary: demo.Salary,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewKYCProvider().ValidateSalary(context.Background(), domain.KYCValidateSalaryReq{NationalID: tt.nid, LegalName: demo.LegalName})
+			require.NoError(t, err)
+			lower, err := strconv.ParseFloat(got.Data.SalaryLower, 64)
+			require.NoError(t, err)
+			upper, err := strconv.ParseFloat(got.Data.SalaryUper, 64)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantInside, tt.salary > lower && tt.salary < upper)
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+// cursorTimeLayout is the layout of a start date in a loan cursor, see the loan service.
+const cursorTimeLayout = time.DateTime
+
+type LoanRepository struct {
+	store *Store
+}
+
+func NewLoanRepository(store *Store) *LoanRepository {
+	return &LoanRepository{
+		store: store,
+	}
+}
+
+func (repo *LoanRepository) CreateLoan(ctx context.Context, loan domain.Loan) error {
+	if err := contextError(ctx, "CreateLoan"); err != nil {
+		return err
+	}
+
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, err := repo.store.insertLoan(loan); err != nil {
+		return fmt.Errorf("CreateLoan: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *LoanRepository) GetLoanByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (*domain.LoanAll, error) {
+	if err := contextError(ctx, "GetLoanByUserIDAndContractNumber"); err != nil {
+		return nil, err
+	}
+
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	for _, loan := range repo.store.loans {
+		if loan.UserID != uid || loan.ContractNumber != contractNumber {
+			continue
+		}
+		// like the inner joins of the sql query, a loan without its types is not found
+		if all, ok := repo.store.joinLoan(loan); ok {
+			// like the sql repositories only the detail carries the version, it is what a write is made against
+			all.Version = loan.Version
+			return &all, nil
+		}
+	}
+
+	err := fmt.Errorf("GetLoanByUserIDAndContractNumber: loan with contract number %s not found", contractNumber)
+	return nil, apperror.WrapError(err, apperror.ErrNotFound)
+}
+
+func (repo *LoanRepository) ListLoansByUserID(ctx context.Context, filter domain.LoanFilter) ([]domain.LoanSummary, error) {
+	if err := contextError(ctx, "ListLoansByUserID"); err != nil {
+		return nil, err
+	}
+
+	sortValue, ok := sortValues[filter.SortBy]
+	if !ok {
+		err := fmt.Errorf("ListLoansByUserID: unknown sort field %s", filter.SortBy)
+		return nil, apperror.WrapError(err, apperror.ErrBadRequest)
+	}
+
+	var cursor float64
+	if filter.Cursor != nil {
+		var err error
+		if cursor, err = parseSortValue(filter.SortBy, filter.Cursor.SortValue); err != nil {
+			err = fmt.Errorf("ListLoansByUserID: invalid cursor value %q: %w", filter.Cursor.SortValue, err)
+			return nil, apperror.WrapError(err, apperror.ErrBadRequest)
+		}
+	}
+
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	loans := []domain.LoanSummary{}
+	for _, loan := range repo.store.loans {
+		all, ok := repo.store.joinLoan(loan)
+		if !ok || loan.UserID != filter.UserID || !matchLoanFilter(all, filter) {
+			continue
+		}
+		if filter.Cursor != nil {
+			v := sortValue(all)
+			after := v > cursor || (v == cursor && all.ID > filter.Cursor.ID)
+			if filter.SortDesc {
+				after = v < cursor || (v == cursor && all.ID < filter.Cursor.ID)
+			}
+			if !after {
+				continue
+			}
+		}
+
+		summary := domain.LoanSummary{LoanAll: all}
+		for _, payment := range repo.store.payments {
+			if payment.LoanID == loan.ID {
+				summary.InstallmentsPaid++
+				summary.PaidAmount += payment.Amount
+			}
+		}
+		loans = append(loans, summary)
+	}
+
+	sort.Slice(loans, func(i, j int) bool {
+		vi, vj := sortValue(loans[i].LoanAll), sortValue(loans[j].LoanAll)
+		if vi == vj {
+			vi, vj = float64(loans[i].ID), float64(loans[j].ID)
+		}
+		if filter.SortDesc {
+			return vi > vj
+		}
+		return vi < vj
+	})
+	if len(loans) > filter.Limit {
+		loans = loans[:filter.Limit]
+	}
+
+	return loans, nil
+}
+
+func (repo *LoanRepository) CreateLoanPayment(ctx context.Context, loanPayment domain.LoanPayment) error {
+	if err := contextError(ctx, "CreateLoanPayment"); err != nil {
+		return err
+	}
+
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	loan, ok := repo.store.loans[loanPayment.LoanID]
+	if loanPayment.LoanVersion != 0 && (!ok || loan.Version != loanPayment.LoanVersion) {
+		err := fmt.Errorf("CreateLoanPayment: loan %d is no longer at version %d", loanPayment.LoanID, loanPayment.LoanVersion)
+		return apperror.WrapError(err, apperror.ErrVersionConflict)
+	}
+	if !ok {
+		err := fmt.Errorf("CreateLoanPayment: error insert loan payment: loan %d does not exist", loanPayment.LoanID)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	// a payment changes the loan, e.g. its outstanding amount
+	loan.Version++
+	repo.store.loans[loan.ID] = loan
+
+	loanPayment.Date = loanPayment.Date.UTC()
+	loanPayment.LoanVersion = 0
+	repo.store.lastPaymentID++
+	loanPayment.ID = repo.store.lastPaymentID
+	repo.store.payments[loanPayment.ID] = loanPayment
+
+	return nil
+}
+
+func (repo *LoanRepository) GetLoanPaymentsByLoanID(ctx context.Context, loanID int64) ([]domain.LoanPayment, error) {
+	if err := contextError(ctx, "GetLoanPaymentsByLoanID"); err != nil {
+		return nil, err
+	}
+
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	return repo.store.loanPayments(func(loan domain.Loan) bool { return loan.ID == loanID }), nil
+}
+
+func (repo *LoanRepository) GetLoanPaymentsByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) ([]domain.LoanPayment, error) {
+	if err := contextError(ctx, "GetLoanPaymentsByUserIDAndContractNumber"); err != nil {
+		return nil, err
+	}
+
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	return repo.store.loanPayments(func(loan domain.Loan) bool { return loan.UserID == uid && loan.ContractNumber == contractNumber }), nil
+}
+
+// insertLoan checks the constraints of the loan table and inserts the loan, the caller holds the write lock.
+func (s *Store) insertLoan(loan domain.Loan) (int64, error) {
+	for _, other := range s.loans {
+		if other.ContractNumber == loan.ContractNumber {
+			err := fmt.Errorf("loan with contract number %s already exists", loan.ContractNumber)
+			return 0, apperror.WrapError(err, apperror.ErrContractNumberConflict)
+		}
+	}
+
+	_, userOK := s.users[int(loan.UserID)]
+	_, loanTypeOK := s.loanTypes[loan.LoanTypeID.Int16]
+	_, limitTypeOK := s.limitTypes[loan.LimitTypeID.Int16]
+	if !userOK || (loan.LoanTypeID.Valid && !loanTypeOK) || (loan.LimitTypeID.Valid && !limitTypeOK) {
+		err := fmt.Errorf("error insert loan: unknown user, loan type or limit type")
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	// dates are kept in utc, as the sql repositories read them back
+	loan.StartDate.Time = loan.StartDate.Time.UTC()
+	s.lastLoanID++
+	loan.ID = s.lastLoanID
+	loan.Version = 1
+	s.loans[loan.ID] = loan
+
+	return loan.ID, nil
+}
+
+// joinLoan returns the loan with its loan and limit types, false when one of them is missing.
+func (s *Store) joinLoan(loan domain.Loan) (domain.LoanAll, bool) {
+	loanType, loanTypeOK := s.loanTypes[loan.LoanTypeID.Int16]
+	limitType, limitTypeOK := s.limitTypes[loan.LimitTypeID.Int16]
+	if !loan.LoanTypeID.Valid || !loan.LimitTypeID.Valid || !loanTypeOK || !limitTypeOK {
+		return domain.LoanAll{}, false
+	}
+
+	return domain.LoanAll{
+		ID:              loan.ID,
+		UserID:          loan.UserID,
+		ContractNumber:  loan.ContractNumber,
+		OTRAmount:       loan.OTRAmount,
+		PrincipalAmount: loan.PrincipalAmount,
+		AssetName:       loan.AssetName,
+		LoanType:        domain.LoanType{Name: loanType.Name},
+		LimitType:       domain.LimitType{Amount: limitType.Amount, Term: limitType.Term},
+		Status:          loan.Status,
+		StartDate:       loan.StartDate,
+		InterestRate:    loan.InterestRate,
+	}, true
+}
+
+// loanPayments returns the payments of the loans matching fn ordered by date, the caller holds the read lock.
+func (s *Store) loanPayments(fn func(loan domain.Loan) bool) []domain.LoanPayment {
+	var payments []domain.LoanPayment
+	for _, payment := range s.payments {
+		if fn(s.loans[payment.LoanID]) {
+			payments = append(payments, payment)
+		}
+	}
+
+	sort.Slice(payments, func(i, j int) bool {
+		if !payments[i].Date.Equal(payments[j].Date) {
+			return payments[i].Date.Before(payments[j].Date)
+		}
+		return payments[i].ID < payments[j].ID
+	})
+
+	return payments
+}
+
+// sortValues are the loan list orders, as numbers so a cursor compares the same way for every field.
+// like the sql repositories a null start date sorts as the epoch.
+var sortValues = map[domain.LoanSortField]func(loan domain.LoanAll) float64{
+	domain.SortByStartDate: func(loan domain.LoanAll) float64 {
+		if !loan.StartDate.Valid {
+			return 0
+		}
+		return float64(loan.StartDate.Time.Unix())
+	},
+	domain.SortByPrincipalAmount: func(loan domain.LoanAll) float64 {
+		return loan.PrincipalAmount
+	},
+}
+
+func parseSortValue(sortBy domain.LoanSortField, value string) (float64, error) {
+	if sortBy == domain.SortByStartDate {
+		t, err := time.Parse(cursorTimeLayout, value)
+		if err != nil {
+			return 0, err
+		}
+		return float64(t.Unix()), nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func matchLoanFilter(loan domain.LoanAll, filter domain.LoanFilter) bool {
+	switch {
+	case filter.Status != "" && loan.Status.String != string(filter.Status):
+		return false
+	case filter.LoanType != "" && loan.LoanType.Name != filter.LoanType:
+		return false
+	case filter.StartDateFrom.Valid && (!loan.StartDate.Valid || loan.StartDate.Time.Before(filter.StartDateFrom.Time)):
+		return false
+	case filter.StartDateTo.Valid && (!loan.StartDate.Valid || !loan.StartDate.Time.Before(filter.StartDateTo.Time)):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+type LoanApplicationRepository struct {
+	store *Store
+}
+
+func NewLoanApplicationRepository(store *Store) *LoanApplicationRepository {
+	return &LoanApplicationRepository{
+		store: store,
+	}
+}
+
+func (repo *LoanApplicationRepository) CreateLoanApplication(ctx context.Context, app domain.LoanApplication) (int64, error) {
+	if err := contextError(ctx, "CreateLoanApplication"); err != nil {
+		return 0, err
+	}
+
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.users[int(app.UserID)]; !ok {
+		err := fmt.Errorf("CreateLoanApplication: error insert loan application: user %d does not exist", app.UserID)
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	now := time.Now().UTC()
+	repo.store.lastApplicationID++
+	app.ID = repo.store.lastApplicationID
+	app.CreatedAt, app.UpdatedAt = now, now
+	repo.store.applications[app.ID] = cloneLoanApplication(app)
+
+	return app.ID, nil
+}
+
+func (repo *LoanApplicationRepository) GetLoanApplicationByID(ctx context.Context, id int64) (*domain.LoanApplication, error) {
+	if err := contextError(ctx, "GetLoanApplicationByID"); err != nil {
+		return nil, err
+	}
+
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	app, ok := repo.store.applications[id]
+	if !ok {
+		err := fmt.Errorf("GetLoanApplicationByID: loan application with id %d not found", id)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
+	}
+
+	app = cloneLoanApplication(app)
+	return &app, nil
+}
+
+func (repo *LoanApplicationRepository) GetLoanApplicationByUserIDAndID(ctx context.Context, uid int64, id int64) (*domain.LoanApplication, error) {
+	if err := contextError(ctx, "GetLoanApplicationByUserIDAndID"); err != nil {
+		return nil, err
+	}
+
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	app, ok := repo.store.applications[id]
+	if !ok || app.UserID != uid {
+		err := fmt.Errorf("GetLoanApplicationByUserIDAndID: loan application with id %d not found", id)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
+	}
+
+	app = cloneLoanApplication(app)
+	return &app, nil
+}
+
+// UpdateLoanApplication saves the fields set by the customer steps as long as the application is still from.
+func (repo *LoanApplicationRepository) UpdateLoanApplication(ctx context.Context, app domain.LoanApplication, from domain.LoanApplicationStatus) error {
+	if err := contextError(ctx, "UpdateLoanApplication"); err != nil {
+		return err
+	}
+
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	saved, ok := repo.store.applications[app.ID]
+	if !ok || saved.Status != from {
+		err := fmt.Errorf("UpdateLoanApplication: loan application with id %d is no longer %s", app.ID, from)
+		return apperror.WrapError(err, apperror.ErrVersionConflict)
+	}
+
+	saved.Status = app.Status
+	saved.AssetName = app.AssetName
+	saved.OTRAmount = app.OTRAmount
+	saved.DownPayment = app.DownPayment
+	saved.LoanTypeID = app.LoanTypeID
+	saved.LimitTypeID = app.LimitTypeID
+	saved.Tenor = app.Tenor
+	saved.NationalIDPhoto = app.NationalIDPhoto
+	saved.UserPhoto = app.UserPhoto
+	saved.DecisionReason = app.DecisionReason
+	saved.SubmittedAt = app.SubmittedAt
+	saved.DecidedAt = app.DecidedAt
+	saved.UpdatedAt = time.Now().UTC()
+	repo.store.applications[app.ID] = cloneLoanApplication(saved)
+
+	return nil
+}
+
+func (repo *LoanApplicationRepository) ConvertToLoan(ctx context.Context, app domain.LoanApplication, loan domain.Loan) (int64, error) {
+	if err := contextError(ctx, "ConvertToLoan"); err != nil {
+		return 0, err
+	}
+
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	// the checks come before any write, so a failed conversion leaves the store unchanged like a rolled back transaction
+	saved, ok := repo.store.applications[app.ID]
+	if !ok || saved.Status != domain.ApplicationSubmitted {
+		err := fmt.Errorf("ConvertToLoan: loan application with id %d is no longer submitted", app.ID)
+		return 0, apperror.WrapError(err, apperror.ErrLoanApplicationAlreadyDecided)
+	}
+
+	loanID, err := repo.store.insertLoan(loan)
+	if err != nil {
+		return 0, fmt.Errorf("ConvertToLoan: %w", err)
+	}
+
+	saved.Status = domain.ApplicationApproved
+	saved.LoanID.Int64, saved.LoanID.Valid = loanID, true
+	saved.ContractNumber.String, saved.ContractNumber.Valid = loan.ContractNumber, true
+	saved.DecisionReason = app.DecisionReason
+	saved.DecidedAt = app.DecidedAt
+	saved.UpdatedAt = time.Now().UTC()
+	repo.store.applications[app.ID] = saved
+
+	return loanID, nil
+}
+
+// cloneLoanApplication copies the photos so a caller can not change the stored application through them.
+func cloneLoanApplication(app domain.LoanApplication) domain.LoanApplication {
+	app.NationalIDPhoto = bytes.Clone(app.NationalIDPhoto)
+	app.UserPhoto = bytes.Clone(app.UserPhoto)
+	return app
+}
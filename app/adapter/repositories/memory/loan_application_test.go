@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoanApplicationRepository_ConvertToLoan(t *testing.T) {
+	decidedAt := sql.NullTime{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	newLoan := func(contractNumber string) domain.Loan {
+		return domain.Loan{
+			UserID:          1,
+			ContractNumber:  contractNumber,
+			OTRAmount:       1300,
+			PrincipalAmount: 1200,
+			AssetName:       "car",
+			LoanTypeID:      sql.NullInt16{Int16: 1, Valid: true},
+			LimitTypeID:     sql.NullInt16{Int16: 1, Valid: true},
+			Status:          sql.NullString{String: "ACTIVE", Valid: true},
+			StartDate:       decidedAt,
+		}
+	}
+	tests := []struct {
+		name    string
+		status  domain.LoanApplicationStatus
+		taken   bool
+		wantErr error
+	}{
+		{
+			name:   "Given a submitted application, it should create the loan and approve the application",
+			status: domain.ApplicationSubmitted,
+		},
+		{
+			name:    "Given an application already decided, it should return already decided",
+			status:  domain.ApplicationRejected,
+			wantErr: apperror.ErrLoanApplicationAlreadyDecided,
+		},
+		{
+			name:    "Given a contract number already taken, it should return a conflict and keep the application submitted",
+			status:  domain.ApplicationSubmitted,
+			taken:   true,
+			wantErr: apperror.ErrContractNumberConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewStore()
+			apps, loans := NewLoanApplicationRepository(store), NewLoanRepository(store)
+			if tt.taken {
+				require.NoError(t, loans.CreateLoan(ctx, newLoan("JKT-1")))
+			}
+			id, err := apps.CreateLoanApplication(ctx, domain.LoanApplication{UserID: 1, Status: domain.ApplicationDraft})
+			require.NoError(t, err)
+			require.NoError(t, apps.UpdateLoanApplication(ctx, domain.LoanApplication{ID: id, Status: tt.status}, domain.ApplicationDraft))
+
+			loanID, err := apps.ConvertToLoan(ctx, domain.LoanApplication{ID: id, DecidedAt: decidedAt}, newLoan("JKT-1"))
+			app, getErr := apps.GetLoanApplicationByUserIDAndID(ctx, 1, id)
+			require.NoError(t, getErr)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				assert.Equal(t, tt.status, app.Status)
+				assert.False(t, app.LoanID.Valid)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, domain.ApplicationApproved, app.Status)
+			assert.Equal(t, sql.NullInt64{Int64: loanID, Valid: true}, app.LoanID)
+			assert.Equal(t, sql.NullString{String: "JKT-1", Valid: true}, app.ContractNumber)
+			_, err = loans.GetLoanByUserIDAndContractNumber(ctx, 1, "JKT-1")
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLoanApplicationRepository_GetLoanApplicationByUserIDAndID(t *testing.T) {
+	ctx := context.Background()
+	repo := NewLoanApplicationRepository(NewStore())
+	id, err := repo.CreateLoanApplication(ctx, domain.LoanApplication{UserID: 1, Status: domain.ApplicationDraft})
+	require.NoError(t, err)
+
+	_, err = repo.GetLoanApplicationByUserIDAndID(ctx, 2, id)
+	assert.True(t, errors.Is(err, apperror.ErrNotFound), err)
+
+	_, err = repo.CreateLoanApplication(ctx, domain.LoanApplication{UserID: 99, Status: domain.ApplicationDraft})
+	assert.True(t, errors.Is(err, apperror.ErrInternalServerError), err)
+}
+
+func TestLoanApplicationRepository_UpdateLoanApplication(t *testing.T) {
+	ctx := context.Background()
+	apps := NewLoanApplicationRepository(NewStore())
+	id, err := apps.CreateLoanApplication(ctx, domain.LoanApplication{UserID: 1, Status: domain.ApplicationDraft})
+	require.NoError(t, err)
+
+	require.NoError(t, apps.UpdateLoanApplication(ctx, domain.LoanApplication{ID: id, Status: domain.ApplicationAbandoned}, domain.ApplicationDraft))
+
+	// a second writer read the application while it was still a draft
+	err = apps.UpdateLoanApplication(ctx, domain.LoanApplication{ID: id, Status: domain.ApplicationSubmitted}, domain.ApplicationDraft)
+	assert.True(t, errors.Is(err, apperror.ErrVersionConflict), err)
+	app, err := apps.GetLoanApplicationByUserIDAndID(ctx, 1, id)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ApplicationAbandoned, app.Status)
+}
@@ -0,0 +1,16 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/repotest"
+)
+
+func newTestStore(t *testing.T) repotest.Store {
+	store := NewStore()
+	return repotest.Store{Loans: NewLoanRepository(store), Users: NewUserRepository(store, NewKYCProvider())}
+}
+
+func TestLoanRepository_Behaviour(t *testing.T) {
+	repotest.LoanRepository(t, newTestStore)
+}
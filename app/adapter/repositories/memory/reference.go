@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"context"
+	"maps"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+)
+
+type ReferenceRepository struct {
+	store *Store
+}
+
+func NewReferenceRepository(store *Store) *ReferenceRepository {
+	return &ReferenceRepository{
+		store: store,
+	}
+}
+
+func (repo *ReferenceRepository) GetReferenceData(ctx context.Context) (*domain.ReferenceData, error) {
+	if err := contextError(ctx, "GetReferenceData"); err != nil {
+		return nil, err
+	}
+
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	return &domain.ReferenceData{
+		LoanTypes:  maps.Clone(repo.store.loanTypes),
+		LimitTypes: maps.Clone(repo.store.limitTypes),
+	}, nil
+}
@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferenceRepository_GetReferenceData(t *testing.T) {
+	repo := NewReferenceRepository(NewStore())
+
+	data, err := repo.GetReferenceData(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, domain.CAR, data.LoanTypes[1].Name)
+	assert.NotEmpty(t, data.LimitTypes)
+
+	delete(data.LoanTypes, 1)
+	data, err = repo.GetReferenceData(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, data.LoanTypes, int16(1), "the store should not be changed through the data returned")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = repo.GetReferenceData(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
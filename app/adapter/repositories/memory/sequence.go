@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"context"
+	"time"
+)
+
+type SequenceRepository struct {
+	store *Store
+}
+
+func NewSequenceRepository(store *Store) *SequenceRepository {
+	return &SequenceRepository{
+		store: store,
+	}
+}
+
+func (repo *SequenceRepository) NextContractSequence(ctx context.Context, prefix string, date time.Time) (int64, error) {
+	if err := contextError(ctx, "NextContractSequence"); err != nil {
+		return 0, err
+	}
+
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	key := prefix + "/" + date.Format(time.DateOnly)
+	repo.store.sequences[key]++
+
+	return repo.store.sequences[key], nil
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceRepository_NextContractSequence(t *testing.T) {
+	repo := NewSequenceRepository(NewStore())
+	date := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = map[int64]bool{}
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seq, err := repo.NextContractSequence(context.Background(), "JKT-01", date)
+			assert.NoError(t, err)
+			mu.Lock()
+			seen[seq] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, seen, 50)
+
+	seq, err := repo.NextContractSequence(context.Background(), "JKT-01", date.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), seq)
+}
@@ -0,0 +1,104 @@
+// Package memory implements the repositories and the kyc provider in memory, for local development
+// and fast tests. It keeps the semantics of the sql repositories, e.g. the not found and conflict errors,
+// and is safe for concurrent use. Nothing survives a restart.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/seed"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+// Store holds the tables shared by the repositories, e.g. converting a loan application creates a loan.
+// a single lock guards every table so a write spanning several of them is atomic like a transaction.
+type Store struct {
+	mu sync.RWMutex
+
+	users        map[int]domain.UserEntity
+	loanTypes    map[int16]domain.LoanType
+	limitTypes   map[int16]domain.LimitType
+	loans        map[int64]domain.Loan
+	payments     map[int64]domain.LoanPayment
+	applications map[int64]domain.LoanApplication
+	sequences    map[string]int64
+
+	lastUserID        int
+	lastLoanID        int64
+	lastPaymentID     int64
+	lastApplicationID int64
+}
+
+// NewStore returns a store holding the reference data and the demo users of the seed package.
+func NewStore() *Store {
+	s := &Store{
+		users:        map[int]domain.UserEntity{},
+		loanTypes:    map[int16]domain.LoanType{},
+		limitTypes:   map[int16]domain.LimitType{},
+		loans:        map[int64]domain.Loan{},
+		payments:     map[int64]domain.LoanPayment{},
+		applications: map[int64]domain.LoanApplication{},
+		sequences:    map[string]int64{},
+	}
+
+	for _, t := range seed.LoanTypes {
+		s.loanTypes[int16(t.ID)] = domain.LoanType{ID: int8(t.ID), Name: domain.LoanTypeName(t.Name)}
+	}
+	for _, t := range seed.LimitTypes {
+		s.limitTypes[int16(t.ID)] = domain.LimitType{ID: int8(t.ID), Amount: sql.NullFloat64{Float64: t.Amount, Valid: true}, Term: int8(t.Term)}
+	}
+
+	now := time.Now()
+	for _, u := range seed.DemoUsers {
+		s.lastUserID++
+		birthOfDate, err := time.Parse(time.DateOnly, u.BirthOfDate)
+		s.users[s.lastUserID] = domain.UserEntity{
+			ID:                    s.lastUserID,
+			NationalID:            u.NationalID,
+			FullName:              u.FullName,
+			LegalName:             u.LegalName,
+			BirthOfPlace:          sql.NullString{String: u.BirthOfPlace, Valid: true},
+			BirthOfDate:           sql.NullTime{Time: birthOfDate, Valid: err == nil},
+			Salary:                sql.NullFloat64{Float64: u.Salary, Valid: true},
+			IsNationalIDValidated: true,
+			IsPhotoValidated:      true,
+			ISSalaryValidated:     true,
+			CreatedAt:             now,
+			UpdatedAt:             now,
+			CreatedBy:             "seed",
+			Version:               1,
+		}
+	}
+
+	return s
+}
+
+// AddUser registers user as a new customer and returns its id, the api has no sign up so it stands in
+// for the one of the customer service.
+func (s *Store) AddUser(user domain.UserEntity) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastUserID++
+	now := time.Now()
+	user.ID = s.lastUserID
+	user.CreatedAt, user.UpdatedAt = now, now
+	// like the default of the version column
+	user.Version = 1
+	s.users[user.ID] = user
+	return user.ID
+}
+
+// contextError returns the error of a done context wrapped like the sql repositories do, every method
+// checks it before touching the store so a cancelled write is never applied.
+func contextError(ctx context.Context, op string) error {
+	if err := ctx.Err(); err != nil {
+		return apperror.WrapError(fmt.Errorf("%s: %w", op, err), apperror.ErrInternalServerError)
+	}
+	return nil
+}
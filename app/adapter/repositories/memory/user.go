@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+type UserRepository struct {
+	port.KYCProvider
+	store *Store
+}
+
+func NewUserRepository(store *Store, kycProvider port.KYCProvider) *UserRepository {
+	return &UserRepository{
+		KYCProvider: kycProvider,
+		store:       store,
+	}
+}
+
+func (repo *UserRepository) FindOneByNationalID(ctx context.Context, nid string) (*domain.UserEntity, error) {
+	if err := contextError(ctx, "FindOneByNationalID"); err != nil {
+		return nil, err
+	}
+
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	for _, user := range repo.store.users {
+		if user.NationalID == nid {
+			return &user, nil
+		}
+	}
+
+	err := fmt.Errorf("FindOneByNationalID: user with national id %s not found", nid)
+	return nil, apperror.WrapError(err, apperror.ErrNotFound)
+}
+
+func (repo *UserRepository) FindOneByID(ctx context.Context, id int) (*domain.UserEntity, error) {
+	if err := contextError(ctx, "FindOneByID"); err != nil {
+		return nil, err
+	}
+
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	user, ok := repo.store.users[id]
+	if !ok {
+		err := fmt.Errorf("FindOneByID: user %d not found", id)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
+	}
+	return &user, nil
+}
+
+// UpdateByID sets the non empty fields of user, like the sql repositories updating an unknown id is not an error
+// unless a version is given.
+func (repo *UserRepository) UpdateByID(ctx context.Context, user domain.UserEntity) error {
+	if err := contextError(ctx, "UpdateByID"); err != nil {
+		return err
+	}
+
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	saved, ok := repo.store.users[user.ID]
+	if user.Version != 0 && (!ok || saved.Version != user.Version) {
+		err := fmt.Errorf("UpdateByID: user %d is no longer at version %d", user.ID, user.Version)
+		return apperror.WrapError(err, apperror.ErrVersionConflict)
+	}
+	if !ok {
+		return nil
+	}
+
+	if user.NationalID != "" {
+		for id, other := range repo.store.users {
+			if id != user.ID && other.NationalID == user.NationalID {
+				err := fmt.Errorf("UpdateByID: national id %s already belongs to another user", user.NationalID)
+				return apperror.WrapError(err, apperror.ErrInternalServerError)
+			}
+		}
+		saved.NationalID = user.NationalID
+	}
+	if user.FullName != "" {
+		saved.FullName = user.FullName
+	}
+	if user.LegalName != "" {
+		saved.LegalName = user.LegalName
+	}
+	if user.BirthOfPlace.Valid {
+		saved.BirthOfPlace = user.BirthOfPlace
+	}
+	if user.BirthOfDate.Valid {
+		saved.BirthOfDate = user.BirthOfDate
+	}
+	if user.Salary.Valid {
+		saved.Salary = user.Salary
+	}
+	if user.NationalIDPhoto.Valid {
+		saved.NationalIDPhoto = user.NationalIDPhoto
+	}
+	if user.UserPhoto.Valid {
+		saved.UserPhoto = user.UserPhoto
+	}
+	saved.IsNationalIDValidated = user.IsNationalIDValidated
+	saved.IsPhotoValidated = user.IsPhotoValidated
+	saved.ISSalaryValidated = user.ISSalaryValidated
+	if user.CreatedBy != "" {
+		saved.CreatedBy = user.CreatedBy
+	}
+	if user.UpdatedBy != "" {
+		saved.UpdatedBy = user.UpdatedBy
+	}
+	saved.UpdatedAt = time.Now()
+	saved.Version++
+	repo.store.users[user.ID] = saved
+
+	return nil
+}
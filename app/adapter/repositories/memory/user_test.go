@@ -0,0 +1,11 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/repotest"
+)
+
+func TestUserRepository_Behaviour(t *testing.T) {
+	repotest.UserRepository(t, newTestStore)
+}
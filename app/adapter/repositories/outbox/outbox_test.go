@@ -0,0 +1,76 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/repotest"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	repotest.Main(m)
+}
+
+func TestOutboxRepository_Pending(t *testing.T) {
+	createdAt := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		prepareMock func(mock sqlmock.Sqlmock)
+		want        []domain.OutboxEvent
+		wantErr     bool
+	}{
+		{
+			name: "Given pending events, it should return them",
+			prepareMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(pendingEvents)).WithArgs(10).WillReturnRows(
+					sqlmock.NewRows([]string{"id", "name", "payload", "created_at"}).
+						AddRow(1, "loan.changed", []byte(`{"UserID":1}`), createdAt))
+			},
+			want: []domain.OutboxEvent{{ID: 1, Name: "loan.changed", Payload: []byte(`{"UserID":1}`), CreatedAt: createdAt}},
+		},
+		{
+			name: "Given the select fails, it should return an error",
+			prepareMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(pendingEvents)).WithArgs(10).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.prepareMock(mock)
+
+			got, err := New(infradb.NewCluster(conn)).Pending(context.Background(), 10)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOutboxRepository_MarkPublished(t *testing.T) {
+	at := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer conn.Close()
+	mock.ExpectExec(regexp.QuoteMeta(markPublished+"(?, ?, ?)")).WithArgs(at, int64(1), int64(2), int64(5)).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	err = New(infradb.NewCluster(conn)).MarkPublished(context.Background(), []int64{1, 2, 5}, at)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepository_Behaviour(t *testing.T) {
+	repotest.OutboxRepository(t, func(t *testing.T) repotest.Store {
+		return repotest.Store{Outbox: New(infradb.NewCluster(repotest.MySQL(t)))}
+	})
+}
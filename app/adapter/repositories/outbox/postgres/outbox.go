@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+type OutboxRepository struct {
+	cluster *infradb.Cluster
+}
+
+func New(cluster *infradb.Cluster) *OutboxRepository {
+	return &OutboxRepository{
+		cluster: cluster,
+	}
+}
+
+func (repo *OutboxRepository) Add(ctx context.Context, e domain.OutboxEvent) error {
+	_, err := repo.cluster.Writer(ctx).ExecContext(ctx, addEvent, e.Name, string(e.Payload))
+	if err != nil {
+		err = fmt.Errorf("Add: error insert event %s: %w", e.Name, err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return nil
+}
+
+// Pending reads the primary, a replica behind it would deliver the events late.
+func (repo *OutboxRepository) Pending(ctx context.Context, limit int) ([]domain.OutboxEvent, error) {
+	rows, err := repo.cluster.Primary().QueryContext(ctx, pendingEvents, limit)
+	if err != nil {
+		err = fmt.Errorf("Pending: error select query: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	defer rows.Close()
+
+	var events []domain.OutboxEvent
+	for rows.Next() {
+		var e domain.OutboxEvent
+		if err := rows.Scan(&e.ID, &e.Name, &e.Payload, &e.CreatedAt); err != nil {
+			err = fmt.Errorf("Pending: error scan event: %w", err)
+			return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		err = fmt.Errorf("Pending: error read events: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return events, nil
+}
+
+func (repo *OutboxRepository) MarkPublished(ctx context.Context, ids []int64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, at)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := make([]string, len(ids))
+	for i := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+	}
+	query := markPublished + "(" + strings.Join(placeholders, ", ") + ")"
+
+	if _, err := repo.cluster.Writer(ctx).ExecContext(ctx, query, args...); err != nil {
+		err = fmt.Errorf("MarkPublished: error update events: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return nil
+}
+
+func (repo *OutboxRepository) PurgePublished(ctx context.Context, before time.Time) (int64, error) {
+	res, err := repo.cluster.Writer(ctx).ExecContext(ctx, purgePublished, before)
+	if err != nil {
+		err = fmt.Errorf("PurgePublished: error delete events: %w", err)
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		err = fmt.Errorf("PurgePublished: error get deleted rows: %w", err)
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return n, nil
+}
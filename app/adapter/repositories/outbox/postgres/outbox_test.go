@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/repotest"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	repotest.Main(m)
+}
+
+func TestOutboxRepository_MarkPublished(t *testing.T) {
+	at := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer conn.Close()
+	mock.ExpectExec(regexp.QuoteMeta(markPublished+"($2, $3)")).WithArgs(at, int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = New(infradb.NewCluster(conn)).MarkPublished(context.Background(), []int64{1, 2}, at)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepository_Behaviour(t *testing.T) {
+	repotest.OutboxRepository(t, func(t *testing.T) repotest.Store {
+		return repotest.Store{Outbox: New(infradb.NewCluster(repotest.Postgres(t)))}
+	})
+}
@@ -0,0 +1,14 @@
+package postgres
+
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
+var (
+	addEvent = infradb.Named("outbox.add_event", `INSERT INTO outbox (name, payload) VALUES ($1, $2)`)
+
+	pendingEvents = infradb.Named("outbox.pending_events", `SELECT id, name, payload, created_at FROM outbox WHERE published_at IS NULL ORDER BY id LIMIT $1`)
+
+	// the placeholders of the ids are appended per call from $2, see MarkPublished.
+	markPublished = infradb.Named("outbox.mark_published", `UPDATE outbox SET published_at = $1 WHERE id IN `)
+
+	purgePublished = infradb.Named("outbox.purge_published", `DELETE FROM outbox WHERE published_at < $1`)
+)
@@ -0,0 +1,14 @@
+package outbox
+
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
+var (
+	addEvent = infradb.Named("outbox.add_event", `INSERT INTO outbox (name, payload) VALUES (?, ?)`)
+
+	pendingEvents = infradb.Named("outbox.pending_events", `SELECT id, name, payload, created_at FROM outbox WHERE published_at IS NULL ORDER BY id LIMIT ?`)
+
+	// the placeholders of the ids are appended per call, see MarkPublished.
+	markPublished = infradb.Named("outbox.mark_published", `UPDATE outbox SET published_at = ? WHERE id IN `)
+
+	purgePublished = infradb.Named("outbox.purge_published", `DELETE FROM outbox WHERE published_at < ?`)
+)
@@ -0,0 +1,9 @@
+package postgres
+
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
+var (
+	listLoanTypes = infradb.Named("reference.list_loan_types", `SELECT id, name FROM loan_type`)
+
+	listLimitTypes = infradb.Named("reference.list_limit_types", `SELECT id, amount, term FROM limit_type`)
+)
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+type ReferenceRepository struct {
+	cluster *infradb.Cluster
+}
+
+func New(cluster *infradb.Cluster) *ReferenceRepository {
+	return &ReferenceRepository{
+		cluster: cluster,
+	}
+}
+
+func (repo *ReferenceRepository) GetReferenceData(ctx context.Context) (*domain.ReferenceData, error) {
+	data := domain.ReferenceData{
+		LoanTypes:  map[int16]domain.LoanType{},
+		LimitTypes: map[int16]domain.LimitType{},
+	}
+
+	rows, err := repo.cluster.Reader(ctx).QueryContext(ctx, listLoanTypes)
+	if err != nil {
+		err = fmt.Errorf("GetReferenceData: error select loan types: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var loanType domain.LoanType
+		if err := rows.Scan(&loanType.ID, &loanType.Name); err != nil {
+			err = fmt.Errorf("GetReferenceData: error scan loan type: %w", err)
+			return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+		}
+		data.LoanTypes[int16(loanType.ID)] = loanType
+	}
+	if err := rows.Err(); err != nil {
+		err = fmt.Errorf("GetReferenceData: error read loan types: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	rows, err = repo.cluster.Reader(ctx).QueryContext(ctx, listLimitTypes)
+	if err != nil {
+		err = fmt.Errorf("GetReferenceData: error select limit types: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var limitType domain.LimitType
+		if err := rows.Scan(&limitType.ID, &limitType.Amount, &limitType.Term); err != nil {
+			err = fmt.Errorf("GetReferenceData: error scan limit type: %w", err)
+			return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+		}
+		data.LimitTypes[int16(limitType.ID)] = limitType
+	}
+	if err := rows.Err(); err != nil {
+		err = fmt.Errorf("GetReferenceData: error read limit types: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return &data, nil
+}
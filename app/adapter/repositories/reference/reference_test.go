@@ -0,0 +1,81 @@
+package reference
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/mapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferenceRepository_GetReferenceData(t *testing.T) {
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	tests := []struct {
+		name        string
+		repo        *ReferenceRepository
+		prepareMock func(m *mock)
+		want        *domain.ReferenceData
+		wantErr     bool
+	}{
+		{
+			name: "Given seeded types, it should return them by id",
+			repo: &ReferenceRepository{},
+			prepareMock: func(m *mock) {
+				m.ExpectQuery(regexp.QuoteMeta(listLoanTypes)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "CAR").AddRow(2, "BIKE"))
+				m.ExpectQuery(regexp.QuoteMeta(listLimitTypes)).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "term"}).AddRow(1, float64(100000), 1))
+			},
+			want: &domain.ReferenceData{
+				LoanTypes: map[int16]domain.LoanType{
+					1: {ID: 1, Name: domain.CAR},
+					2: {ID: 2, Name: domain.BIKE},
+				},
+				LimitTypes: map[int16]domain.LimitType{
+					1: {ID: 1, Amount: mapper.NewSQLNullableFloat64(100000), Term: 1},
+				},
+			},
+		},
+		{
+			name: "Given the loan types query fails, it should return error",
+			repo: &ReferenceRepository{},
+			prepareMock: func(m *mock) {
+				m.ExpectQuery(regexp.QuoteMeta(listLoanTypes)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Given the limit types query fails, it should return error",
+			repo: &ReferenceRepository{},
+			prepareMock: func(m *mock) {
+				m.ExpectQuery(regexp.QuoteMeta(listLoanTypes)).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+				m.ExpectQuery(regexp.QuoteMeta(listLimitTypes)).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.repo.cluster = infradb.NewCluster(conn)
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			got, err := tt.repo.GetReferenceData(context.Background())
+
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package repotest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// LoanRepository runs the behaviour expected from every port.LoanRepository.
+func LoanRepository(t *testing.T, newStore NewStore) {
+	t.Run("Given a created loan, it should return it joined with its types", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		uid := demoUserID(t, store, 0)
+		start := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240115-0001", 150000, 1, 3, start)))
+
+		got, err := store.Loans.GetLoanByUserIDAndContractNumber(ctx, uid, "JKT-20240115-0001")
+		require.NoError(t, err)
+		assert.NotZero(t, got.ID)
+		assert.Equal(t, uid, got.UserID)
+		assert.Equal(t, "JKT-20240115-0001", got.ContractNumber)
+		assert.Equal(t, float64(165000), got.OTRAmount)
+		assert.Equal(t, float64(150000), got.PrincipalAmount)
+		assert.Equal(t, "Honda Jazz", got.AssetName)
+		assert.Equal(t, domain.CAR, got.LoanType.Name)
+		assert.Equal(t, sql.NullFloat64{Float64: 500000, Valid: true}, got.LimitType.Amount)
+		assert.Equal(t, int8(3), got.LimitType.Term)
+		assert.Equal(t, sql.NullString{String: string(domain.ACIIVE), Valid: true}, got.Status)
+		assert.True(t, got.StartDate.Valid)
+		assert.True(t, start.Equal(got.StartDate.Time), got.StartDate.Time)
+		assert.Equal(t, sql.NullFloat64{Float64: 2.5, Valid: true}, got.InterestRate)
+	})
+
+	t.Run("Given a contract number already taken, it should return a contract number conflict", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		uid := demoUserID(t, store, 0)
+		require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240115-0001", 150000, 1, 3, time.Now())))
+
+		err := store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240115-0001", 90000, 2, 1, time.Now()))
+		assert.True(t, errors.Is(err, apperror.ErrContractNumberConflict), err)
+	})
+
+	t.Run("Given the loan of another user, it should return not found", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		uid, otherUID := demoUserID(t, store, 0), demoUserID(t, store, 1)
+		require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240115-0001", 150000, 1, 3, time.Now())))
+
+		got, err := store.Loans.GetLoanByUserIDAndContractNumber(ctx, otherUID, "JKT-20240115-0001")
+		assert.True(t, errors.Is(err, apperror.ErrNotFound), err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Given payments of a loan, it should return them by date", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		uid, otherUID := demoUserID(t, store, 0), demoUserID(t, store, 1)
+		require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240115-0001", 150000, 1, 3, time.Now())))
+		loan, err := store.Loans.GetLoanByUserIDAndContractNumber(ctx, uid, "JKT-20240115-0001")
+		require.NoError(t, err)
+
+		second := domain.LoanPayment{LoanID: loan.ID, Amount: 55000, Date: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), Channel: "VA"}
+		first := domain.LoanPayment{LoanID: loan.ID, Amount: 55000.5, Date: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), Channel: "TRANSFER"}
+		require.NoError(t, store.Loans.CreateLoanPayment(ctx, second))
+		require.NoError(t, store.Loans.CreateLoanPayment(ctx, first))
+
+		byLoan, err := store.Loans.GetLoanPaymentsByLoanID(ctx, loan.ID)
+		require.NoError(t, err)
+		assertPayments(t, []domain.LoanPayment{first, second}, byLoan)
+
+		byContract, err := store.Loans.GetLoanPaymentsByUserIDAndContractNumber(ctx, uid, "JKT-20240115-0001")
+		require.NoError(t, err)
+		assertPayments(t, []domain.LoanPayment{first, second}, byContract)
+
+		ofOther, err := store.Loans.GetLoanPaymentsByUserIDAndContractNumber(ctx, otherUID, "JKT-20240115-0001")
+		require.NoError(t, err)
+		assert.Empty(t, ofOther)
+	})
+
+	t.Run("Given a payment against a loan version, it should post it only while that version is current", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		uid := demoUserID(t, store, 0)
+		require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240115-0001", 150000, 1, 3, time.Now())))
+		loan, err := store.Loans.GetLoanByUserIDAndContractNumber(ctx, uid, "JKT-20240115-0001")
+		require.NoError(t, err)
+
+		payment := domain.LoanPayment{LoanID: loan.ID, Amount: 55000, Date: time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC), Channel: "VA", LoanVersion: loan.Version}
+		require.NoError(t, store.Loans.CreateLoanPayment(ctx, payment))
+		posted, err := store.Loans.GetLoanByUserIDAndContractNumber(ctx, uid, "JKT-20240115-0001")
+		require.NoError(t, err)
+		assert.Equal(t, loan.Version+1, posted.Version)
+
+		err = store.Loans.CreateLoanPayment(ctx, payment)
+		assert.True(t, errors.Is(err, apperror.ErrVersionConflict), err)
+		payments, err := store.Loans.GetLoanPaymentsByLoanID(ctx, loan.ID)
+		require.NoError(t, err)
+		assert.Len(t, payments, 1, "a refused payment must not be posted")
+	})
+
+	t.Run("Given a filter, it should list the matching loans of the user with their progress", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		uid, otherUID := demoUserID(t, store, 0), demoUserID(t, store, 1)
+		require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240101-0001", 100000, 1, 1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))))
+		require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240201-0001", 300000, 2, 3, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))))
+		require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240301-0001", 200000, 1, 2, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))))
+		require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(otherUID, "JKT-20240101-0002", 100000, 1, 1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))))
+		loan, err := store.Loans.GetLoanByUserIDAndContractNumber(ctx, uid, "JKT-20240101-0001")
+		require.NoError(t, err)
+		require.NoError(t, store.Loans.CreateLoanPayment(ctx, domain.LoanPayment{LoanID: loan.ID, Amount: 60000, Date: time.Now(), Channel: "VA"}))
+		require.NoError(t, store.Loans.CreateLoanPayment(ctx, domain.LoanPayment{LoanID: loan.ID, Amount: 50000, Date: time.Now(), Channel: "VA"}))
+
+		loans, err := store.Loans.ListLoansByUserID(ctx, domain.LoanFilter{UserID: uid, LoanType: domain.CAR, SortBy: domain.SortByStartDate, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, loans, 2)
+		assert.Equal(t, "JKT-20240101-0001", loans[0].ContractNumber)
+		assert.Equal(t, 2, loans[0].InstallmentsPaid)
+		assert.Equal(t, float64(110000), loans[0].PaidAmount)
+		assert.Equal(t, "JKT-20240301-0001", loans[1].ContractNumber)
+		assert.Equal(t, 0, loans[1].InstallmentsPaid)
+
+		loans, err = store.Loans.ListLoansByUserID(ctx, domain.LoanFilter{UserID: uid, SortBy: domain.SortByPrincipalAmount, SortDesc: true, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, loans, 2)
+		assert.Equal(t, "JKT-20240201-0001", loans[0].ContractNumber)
+		assert.Equal(t, "JKT-20240301-0001", loans[1].ContractNumber)
+
+		loans, err = store.Loans.ListLoansByUserID(ctx, domain.LoanFilter{UserID: uid, SortBy: domain.SortByPrincipalAmount, SortDesc: true, Limit: 2,
+			Cursor: &domain.LoanCursor{SortValue: "200000", ID: loans[1].ID}})
+		require.NoError(t, err)
+		require.Len(t, loans, 1)
+		assert.Equal(t, "JKT-20240101-0001", loans[0].ContractNumber)
+	})
+
+	t.Run("Given more loans than the limit, it should page through all of them in order", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		uid := demoUserID(t, store, 0)
+		// equal start dates and principals make the id break the ties
+		day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, l := range []struct {
+			start     time.Time
+			principal float64
+		}{
+			{day.AddDate(0, 0, 2), 300000},
+			{day, 100000},
+			{day.AddDate(0, 0, 1), 300000},
+			{day, 200000},
+			{day.AddDate(0, 0, 3), 100000},
+		} {
+			contractNumber := fmt.Sprintf("JKT-20240101-%04d", i+1)
+			require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, contractNumber, l.principal, 1, 3, l.start)))
+		}
+
+		for _, sortBy := range []domain.LoanSortField{domain.SortByStartDate, domain.SortByPrincipalAmount} {
+			for _, desc := range []bool{false, true} {
+				t.Run(fmt.Sprintf("%s desc %t", sortBy, desc), func(t *testing.T) {
+					all, err := store.Loans.ListLoansByUserID(ctx, domain.LoanFilter{UserID: uid, SortBy: sortBy, SortDesc: desc, Limit: 10})
+					require.NoError(t, err)
+					require.Len(t, all, 5)
+					for i := 1; i < len(all); i++ {
+						assert.True(t, inOrder(sortBy, desc, all[i-1], all[i]), "%s is listed before %s", all[i-1].ContractNumber, all[i].ContractNumber)
+					}
+
+					var paged []domain.LoanSummary
+					filter := domain.LoanFilter{UserID: uid, SortBy: sortBy, SortDesc: desc, Limit: 2}
+					for page := 0; page < 5; page++ {
+						loans, err := store.Loans.ListLoansByUserID(ctx, filter)
+						require.NoError(t, err)
+						if len(loans) == 0 {
+							break
+						}
+						assert.LessOrEqual(t, len(loans), 2)
+						paged = append(paged, loans...)
+						filter.Cursor = cursorOf(sortBy, loans[len(loans)-1])
+					}
+					assert.Equal(t, contractNumbers(all), contractNumbers(paged))
+				})
+			}
+		}
+	})
+
+	t.Run("Given concurrent writes, it should keep every loan and payment and one loan by contract number", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		uid := demoUserID(t, store, 0)
+		require.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240101-0001", 100000, 1, 1, time.Now())))
+		loan, err := store.Loans.GetLoanByUserIDAndContractNumber(ctx, uid, "JKT-20240101-0001")
+		require.NoError(t, err)
+
+		const writers = 10
+		var (
+			wg        sync.WaitGroup
+			conflicts atomic.Int32
+		)
+		for i := 0; i < writers; i++ {
+			wg.Add(3)
+			go func(i int) {
+				defer wg.Done()
+				contractNumber := fmt.Sprintf("JKT-20240102-%04d", i+1)
+				assert.NoError(t, store.Loans.CreateLoan(ctx, newLoan(uid, contractNumber, 100000, 1, 1, time.Now())))
+			}(i)
+			go func() {
+				defer wg.Done()
+				err := store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240103-0001", 100000, 1, 1, time.Now()))
+				if err != nil {
+					assert.True(t, errors.Is(err, apperror.ErrContractNumberConflict), err)
+					conflicts.Add(1)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, store.Loans.CreateLoanPayment(ctx, domain.LoanPayment{LoanID: loan.ID, Amount: 1000, Date: time.Now(), Channel: "VA"}))
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(writers-1), conflicts.Load())
+		loans, err := store.Loans.ListLoansByUserID(ctx, domain.LoanFilter{UserID: uid, SortBy: domain.SortByStartDate, Limit: 100})
+		require.NoError(t, err)
+		assert.Len(t, loans, writers+2)
+		payments, err := store.Loans.GetLoanPaymentsByLoanID(ctx, loan.ID)
+		require.NoError(t, err)
+		assert.Len(t, payments, writers)
+	})
+
+	t.Run("Given a cancelled context, every method should return the context error", func(t *testing.T) {
+		store := newStore(t)
+		uid := demoUserID(t, store, 0)
+		require.NoError(t, store.Loans.CreateLoan(context.Background(), newLoan(uid, "JKT-20240101-0001", 100000, 1, 1, time.Now())))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		calls := map[string]func() error{
+			"CreateLoan": func() error {
+				return store.Loans.CreateLoan(ctx, newLoan(uid, "JKT-20240101-0002", 100000, 1, 1, time.Now()))
+			},
+			"GetLoanByUserIDAndContractNumber": func() error {
+				_, err := store.Loans.GetLoanByUserIDAndContractNumber(ctx, uid, "JKT-20240101-0001")
+				return err
+			},
+			"ListLoansByUserID": func() error {
+				_, err := store.Loans.ListLoansByUserID(ctx, domain.LoanFilter{UserID: uid, SortBy: domain.SortByStartDate, Limit: 10})
+				return err
+			},
+			"CreateLoanPayment": func() error {
+				return store.Loans.CreateLoanPayment(ctx, domain.LoanPayment{LoanID: 1, Amount: 1000, Date: time.Now(), Channel: "VA"})
+			},
+			"GetLoanPaymentsByLoanID": func() error {
+				_, err := store.Loans.GetLoanPaymentsByLoanID(ctx, 1)
+				return err
+			},
+			"GetLoanPaymentsByUserIDAndContractNumber": func() error {
+				_, err := store.Loans.GetLoanPaymentsByUserIDAndContractNumber(ctx, uid, "JKT-20240101-0001")
+				return err
+			},
+		}
+		for name, call := range calls {
+			err := call()
+			assert.True(t, errors.Is(err, context.Canceled), "%s: %v", name, err)
+		}
+
+		_, err := store.Loans.GetLoanByUserIDAndContractNumber(context.Background(), uid, "JKT-20240101-0002")
+		assert.True(t, errors.Is(err, apperror.ErrNotFound), "a cancelled write must not be applied: %v", err)
+	})
+
+	t.Run("Given an unknown sort field, it should return bad request", func(t *testing.T) {
+		store := newStore(t)
+
+		loans, err := store.Loans.ListLoansByUserID(context.Background(), domain.LoanFilter{UserID: 1, SortBy: "asset_name", Limit: 10})
+		assert.True(t, errors.Is(err, apperror.ErrBadRequest), err)
+		assert.Nil(t, loans)
+	})
+}
+
+// newLoan returns an active loan, the otr amount is the principal with the 10% admin fee.
+func newLoan(uid int64, contractNumber string, principal float64, loanTypeID, limitTypeID int16, start time.Time) domain.Loan {
+	return domain.Loan{
+		UserID:          uid,
+		ContractNumber:  contractNumber,
+		OTRAmount:       principal + principal/10,
+		PrincipalAmount: principal,
+		AssetName:       "Honda Jazz",
+		LoanTypeID:      sql.NullInt16{Int16: loanTypeID, Valid: true},
+		LimitTypeID:     sql.NullInt16{Int16: limitTypeID, Valid: true},
+		Status:          sql.NullString{String: string(domain.ACIIVE), Valid: true},
+		StartDate:       sql.NullTime{Time: start, Valid: true},
+		InterestRate:    sql.NullFloat64{Float64: 2.5, Valid: true},
+	}
+}
+
+// assertPayments compares the payments without their generated id, the dates are compared as instants.
+func assertPayments(t *testing.T, want, got []domain.LoanPayment) {
+	t.Helper()
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.NotZero(t, got[i].ID)
+		assert.Equal(t, want[i].LoanID, got[i].LoanID)
+		assert.Equal(t, want[i].Amount, got[i].Amount)
+		assert.True(t, want[i].Date.Equal(got[i].Date), got[i].Date)
+		assert.Equal(t, want[i].Channel, got[i].Channel)
+	}
+}
+
+// cursorOf returns the cursor pointing at loan, as the loan service encodes it.
+func cursorOf(sortBy domain.LoanSortField, loan domain.LoanSummary) *domain.LoanCursor {
+	cursor := &domain.LoanCursor{Sort: string(sortBy), ID: loan.ID}
+	if sortBy == domain.SortByPrincipalAmount {
+		cursor.SortValue = strconv.FormatFloat(loan.PrincipalAmount, 'f', -1, 64)
+	} else {
+		cursor.SortValue = loan.StartDate.Time.Format(time.DateTime)
+	}
+	return cursor
+}
+
+// inOrder reports whether a may be listed before b, the id breaks the ties in the same direction.
+func inOrder(sortBy domain.LoanSortField, desc bool, a, b domain.LoanSummary) bool {
+	va, vb := a.PrincipalAmount, b.PrincipalAmount
+	if sortBy == domain.SortByStartDate {
+		va, vb = float64(a.StartDate.Time.Unix()), float64(b.StartDate.Time.Unix())
+	}
+	if va == vb {
+		va, vb = float64(a.ID), float64(b.ID)
+	}
+	if desc {
+		return va > vb
+	}
+	return va < vb
+}
+
+func contractNumbers(loans []domain.LoanSummary) []string {
+	numbers := make([]string, 0, len(loans))
+	for _, loan := range loans {
+		numbers = append(numbers, loan.ContractNumber)
+	}
+	return numbers
+}
@@ -0,0 +1,64 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// OutboxRepository runs the behaviour expected from every port.OutboxRepository.
+func OutboxRepository(t *testing.T, newStore NewStore) {
+	add := func(t *testing.T, store Store, names ...string) {
+		t.Helper()
+		for _, name := range names {
+			require.NoError(t, store.Outbox.Add(context.Background(), domain.OutboxEvent{Name: name, Payload: []byte(`{"UserID":1}`)}))
+		}
+	}
+
+	t.Run("Given added events, it should return the pending ones oldest first up to the limit", func(t *testing.T) {
+		store := newStore(t)
+		add(t, store, "loan.changed", "reference_data.changed", "loan.changed")
+
+		events, err := store.Outbox.Pending(context.Background(), 2)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, "loan.changed", events[0].Name)
+		assert.Equal(t, "reference_data.changed", events[1].Name)
+		assert.Less(t, events[0].ID, events[1].ID)
+		assert.JSONEq(t, `{"UserID":1}`, string(events[0].Payload))
+		assert.False(t, events[0].CreatedAt.IsZero())
+	})
+
+	t.Run("Given published events, it should not return them and purge them once old enough", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		add(t, store, "loan.changed", "loan.changed")
+
+		events, err := store.Outbox.Pending(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		publishedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+		require.NoError(t, store.Outbox.MarkPublished(ctx, []int64{events[0].ID}, publishedAt))
+
+		pending, err := store.Outbox.Pending(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, events[1].ID, pending[0].ID)
+
+		n, err := store.Outbox.PurgePublished(ctx, publishedAt.Add(-time.Minute))
+		require.NoError(t, err)
+		assert.Zero(t, n, "an event published after the cutoff should be kept")
+		n, err = store.Outbox.PurgePublished(ctx, publishedAt.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("Given no id, it should mark nothing", func(t *testing.T) {
+		store := newStore(t)
+		assert.NoError(t, store.Outbox.MarkPublished(context.Background(), nil, time.Now()))
+	})
+}
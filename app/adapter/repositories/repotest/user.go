@@ -0,0 +1,136 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/seed"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// UserRepository runs the behaviour expected from every port.UserRepository.
+func UserRepository(t *testing.T, newStore NewStore) {
+	demo := seed.DemoUsers[0]
+
+	t.Run("Given a seeded national id, it should return the user", func(t *testing.T) {
+		store := newStore(t)
+
+		user, err := store.Users.FindOneByNationalID(context.Background(), demo.NationalID)
+		require.NoError(t, err)
+		assert.NotZero(t, user.ID)
+		assert.Equal(t, demo.NationalID, user.NationalID)
+		assert.Equal(t, demo.FullName, user.FullName)
+		assert.Equal(t, demo.LegalName, user.LegalName)
+		assert.True(t, user.IsNationalIDValidated)
+		assert.True(t, user.IsPhotoValidated)
+		assert.True(t, user.ISSalaryValidated)
+	})
+
+	t.Run("Given an unknown national id, it should return not found", func(t *testing.T) {
+		store := newStore(t)
+
+		user, err := store.Users.FindOneByNationalID(context.Background(), "9999999999999999")
+		assert.True(t, errors.Is(err, apperror.ErrNotFound), err)
+		assert.Nil(t, user)
+	})
+
+	t.Run("Given a user id, it should return the user with its version", func(t *testing.T) {
+		store := newStore(t)
+		uid := demoUserID(t, store, 0)
+
+		user, err := store.Users.FindOneByID(context.Background(), int(uid))
+		require.NoError(t, err)
+		assert.Equal(t, int(uid), user.ID)
+		assert.Equal(t, demo.NationalID, user.NationalID)
+		assert.NotZero(t, user.Version)
+
+		_, err = store.Users.FindOneByID(context.Background(), int(uid)+1000)
+		assert.True(t, errors.Is(err, apperror.ErrNotFound), err)
+	})
+
+	t.Run("Given an update, it should change the set fields and keep the empty ones", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		user, err := store.Users.FindOneByNationalID(ctx, demo.NationalID)
+		require.NoError(t, err)
+
+		err = store.Users.UpdateByID(ctx, domain.UserEntity{
+			ID:                    user.ID,
+			LegalName:             "Budi Santoso Updated",
+			IsNationalIDValidated: true,
+		})
+		require.NoError(t, err)
+
+		got, err := store.Users.FindOneByNationalID(ctx, demo.NationalID)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, got.ID)
+		assert.Equal(t, demo.FullName, got.FullName)
+		assert.Equal(t, "Budi Santoso Updated", got.LegalName)
+		assert.True(t, got.IsNationalIDValidated)
+		assert.False(t, got.IsPhotoValidated)
+		assert.False(t, got.ISSalaryValidated)
+	})
+
+	t.Run("Given an update of the version read, it should apply it only while that version is current", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		user, err := store.Users.FindOneByNationalID(ctx, demo.NationalID)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Users.UpdateByID(ctx, domain.UserEntity{ID: user.ID, LegalName: "Budi First", Version: user.Version}))
+		err = store.Users.UpdateByID(ctx, domain.UserEntity{ID: user.ID, LegalName: "Budi Second", Version: user.Version})
+		assert.True(t, errors.Is(err, apperror.ErrVersionConflict), err)
+
+		got, err := store.Users.FindOneByNationalID(ctx, demo.NationalID)
+		require.NoError(t, err)
+		assert.Equal(t, "Budi First", got.LegalName)
+		assert.Equal(t, user.Version+1, got.Version)
+	})
+
+	t.Run("Given concurrent updates of a user, it should apply each of them", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		uid := demoUserID(t, store, 0)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				assert.NoError(t, store.Users.UpdateByID(ctx, domain.UserEntity{ID: int(uid), LegalName: fmt.Sprintf("Budi %d", i)}))
+			}(i)
+		}
+		wg.Wait()
+
+		got, err := store.Users.FindOneByNationalID(ctx, demo.NationalID)
+		require.NoError(t, err)
+		assert.Regexp(t, `^Budi \d$`, got.LegalName)
+		assert.Equal(t, demo.FullName, got.FullName)
+	})
+
+	t.Run("Given a cancelled context, every method should return the context error", func(t *testing.T) {
+		store := newStore(t)
+		uid := demoUserID(t, store, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := store.Users.FindOneByNationalID(ctx, demo.NationalID)
+		assert.True(t, errors.Is(err, context.Canceled), "FindOneByNationalID: %v", err)
+
+		_, err = store.Users.FindOneByID(ctx, int(uid))
+		assert.True(t, errors.Is(err, context.Canceled), "FindOneByID: %v", err)
+
+		err = store.Users.UpdateByID(ctx, domain.UserEntity{ID: int(uid), LegalName: "Cancelled"})
+		assert.True(t, errors.Is(err, context.Canceled), "UpdateByID: %v", err)
+
+		got, err := store.Users.FindOneByNationalID(context.Background(), demo.NationalID)
+		require.NoError(t, err)
+		assert.Equal(t, demo.LegalName, got.LegalName, "a cancelled write must not be applied")
+	})
+}
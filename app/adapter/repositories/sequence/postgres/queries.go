@@ -0,0 +1,9 @@
+package postgres
+
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
+var (
+	// the upsert takes the row lock and returns the incremented value in the same statement,
+	// so it is the only synchronisation needed between concurrent callers.
+	nextContractSequence = infradb.Named("sequence.next_contract_sequence", `INSERT INTO contract_number_sequence (prefix, seq_date, last_value) VALUES ($1, $2, 1) ON CONFLICT (prefix, seq_date) DO UPDATE SET last_value = contract_number_sequence.last_value + 1 RETURNING last_value`)
+)
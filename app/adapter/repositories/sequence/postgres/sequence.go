@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+type SequenceRepository struct {
+	cluster *infradb.Cluster
+}
+
+func New(cluster *infradb.Cluster) *SequenceRepository {
+	return &SequenceRepository{
+		cluster: cluster,
+	}
+}
+
+func (repo *SequenceRepository) NextContractSequence(ctx context.Context, prefix string, date time.Time) (int64, error) {
+	var seq int64
+	err := repo.cluster.Writer(ctx).QueryRowContext(ctx, nextContractSequence, prefix, date.Format(time.DateOnly)).Scan(&seq)
+	if err != nil {
+		err = fmt.Errorf("NextContractSequence: error increment sequence: %w", err)
+		return 0, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return seq, nil
+}
@@ -0,0 +1,9 @@
+package sequence
+
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
+var (
+	// LAST_INSERT_ID(expr) makes the incremented value available in the insert result of the same statement,
+	// so the row lock taken by the upsert is the only synchronisation needed between concurrent callers.
+	nextContractSequence = infradb.Named("sequence.next_contract_sequence", `INSERT INTO contract_number_sequence (prefix, seq_date, last_value) VALUES (?, ?, LAST_INSERT_ID(1)) ON DUPLICATE KEY UPDATE last_value = LAST_INSERT_ID(last_value + 1)`)
+)
@@ -0,0 +1,74 @@
+package sequence
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequenceRepository_NextContractSequence(t *testing.T) {
+	type args struct {
+		ctx    context.Context
+		prefix string
+		date   time.Time
+	}
+	type mock struct {
+		sqlmock.Sqlmock
+	}
+	tests := []struct {
+		name        string
+		repo        *SequenceRepository
+		args        args
+		prepareMock func(mock *mock)
+		want        int64
+		wantErr     bool
+	}{
+		{
+			name: "Given a prefix and date, it should return the incremented sequence",
+			repo: &SequenceRepository{},
+			args: args{
+				ctx:    context.Background(),
+				prefix: "XYZ-01",
+				date:   time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC),
+			},
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(nextContractSequence)).WithArgs("XYZ-01", "2021-01-01").WillReturnResult(sqlmock.NewResult(42, 2))
+			},
+			want: 42,
+		},
+		{
+			name: "Given a prefix and date, but increment it return error",
+			repo: &SequenceRepository{},
+			args: args{
+				ctx:    context.Background(),
+				prefix: "XYZ-01",
+				date:   time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC),
+			},
+			prepareMock: func(mock *mock) {
+				mock.ExpectExec(regexp.QuoteMeta(nextContractSequence)).WithArgs("XYZ-01", "2021-01-01").WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, sqlMock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.repo.cluster = infradb.NewCluster(conn)
+			tt.prepareMock(&mock{
+				Sqlmock: sqlMock,
+			})
+
+			got, err := tt.repo.NextContractSequence(tt.args.ctx, tt.args.prefix, tt.args.date)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package postgres
+
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
+var (
+	// postgres has no ON UPDATE column clause, so updated_at is set by the query.
+	queryUpdateUserById = infradb.Named("user.update_user_by_id", `UPDATE "user"
+SET 
+    national_id = COALESCE($1, national_id), 
+    full_name = COALESCE($2, full_name), 
+    legal_name = COALESCE($3, legal_name), 
+    birth_of_place = COALESCE($4, birth_of_place), 
+    birth_of_date = COALESCE($5, birth_of_date), 
+    salary = COALESCE($6, salary), 
+    nation_id_photo = COALESCE($7, nation_id_photo), 
+    user_photo = COALESCE($8, user_photo), 
+    is_nid_valid = COALESCE($9, is_nid_valid), 
+    is_photo_valid = COALESCE($10, is_photo_valid), 
+    is_salary_valid = COALESCE($11, is_salary_valid), 
+    created_by = COALESCE($12, created_by), 
+    updated_by = COALESCE($13, updated_by),
+    updated_at = CURRENT_TIMESTAMP,
+    version = version + 1
+WHERE id = $14 AND version = COALESCE($15, version)`)
+
+	// birth_of_date and salary are kept to run again the kyc checks that did not pass
+	getUserByNationalID = infradb.Named("user.get_user_by_national_id", `SELECT id, national_id, full_name, legal_name, birth_of_date, salary, is_nid_valid, is_photo_valid, is_salary_valid, version
+FROM "user"
+WHERE national_id = $1
+`)
+
+	// national_id is NULL until the kyc passes
+	getUserByID = infradb.Named("user.get_user_by_id", `SELECT id, COALESCE(national_id, ''), full_name, COALESCE(legal_name, ''), is_nid_valid, is_photo_valid, is_salary_valid, version
+FROM "user"
+WHERE id = $1
+`)
+)
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+)
+
+type UserRepository struct {
+	port.KYCProvider
+	cluster *infradb.Cluster
+}
+
+func New(cluster *infradb.Cluster, kycProvider port.KYCProvider) *UserRepository {
+	return &UserRepository{
+		KYCProvider: kycProvider,
+		cluster:     cluster,
+	}
+}
+
+func (repo *UserRepository) FindOneByNationalID(ctx context.Context, nid string) (user *domain.UserEntity, err error) {
+	user = new(domain.UserEntity)
+	err = repo.cluster.Primary().QueryRowContext(ctx, getUserByNationalID, nid).Scan(
+		&user.ID,
+		&user.NationalID,
+		&user.FullName,
+		&user.LegalName,
+		&user.BirthOfDate,
+		&user.Salary,
+		&user.IsNationalIDValidated,
+		&user.IsPhotoValidated,
+		&user.ISSalaryValidated,
+		&user.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("FindOneByNationalID: user with national id %s not found", nid)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
+	}
+	if err != nil {
+		err = fmt.Errorf("FindOneByNationalID: error select query: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return user, nil
+}
+
+// FindOneByID returns the user of id with its version, to update it only while it has not changed.
+func (repo *UserRepository) FindOneByID(ctx context.Context, id int) (user *domain.UserEntity, err error) {
+	user = new(domain.UserEntity)
+	err = repo.cluster.Primary().QueryRowContext(ctx, getUserByID, id).Scan(
+		&user.ID,
+		&user.NationalID,
+		&user.FullName,
+		&user.LegalName,
+		&user.IsNationalIDValidated,
+		&user.IsPhotoValidated,
+		&user.ISSalaryValidated,
+		&user.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("FindOneByID: user %d not found", id)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
+	}
+	if err != nil {
+		err = fmt.Errorf("FindOneByID: error select query: %w", err)
+		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return user, nil
+}
+
+func (repo *UserRepository) UpdateByID(ctx context.Context, user domain.UserEntity) error {
+	res, err := repo.cluster.Writer(ctx).ExecContext(ctx, queryUpdateUserById,
+		nullIfEmpty(user.NationalID),
+		nullIfEmpty(user.FullName),
+		nullIfEmpty(user.LegalName),
+		user.BirthOfPlace,
+		user.BirthOfDate,
+		user.Salary,
+		user.NationalIDPhoto,
+		user.UserPhoto,
+		user.IsNationalIDValidated,
+		user.IsPhotoValidated,
+		user.ISSalaryValidated,
+		nullIfEmpty(user.CreatedBy),
+		nullIfEmpty(user.UpdatedBy),
+		user.ID,
+		nullIfZero(user.Version),
+	)
+	if err != nil {
+		err = fmt.Errorf("UpdateByID: error update user: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return versionConflict(res, user)
+}
+
+// nullIfEmpty sends an empty field as NULL so the column keeps its value.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullIfZero sends no version as NULL so the update applies to any version.
+func nullIfZero(version int64) interface{} {
+	if version == 0 {
+		return nil
+	}
+	return version
+}
+
+// versionConflict reports an update of a given version that matched no row: the user changed since it was
+// read, or is gone. like before versions, an update of no version matching no row is not an error.
+func versionConflict(res sql.Result, user domain.UserEntity) error {
+	if user.Version == 0 {
+		return nil
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		err = fmt.Errorf("UpdateByID: error read affected rows: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	if n == 0 {
+		err = fmt.Errorf("UpdateByID: user %d is no longer at version %d", user.ID, user.Version)
+		return apperror.WrapError(err, apperror.ErrVersionConflict)
+	}
+	return nil
+}
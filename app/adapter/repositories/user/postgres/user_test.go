@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"testing"
+
+	loanRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/loan/postgres"
+	referenceRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/reference/postgres"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/repotest"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+)
+
+func TestMain(m *testing.M) {
+	repotest.Main(m)
+}
+
+func TestUserRepository_Behaviour(t *testing.T) {
+	repotest.UserRepository(t, func(t *testing.T) repotest.Store {
+		db := repotest.Postgres(t)
+		return repotest.Store{Loans: loanRepository.New(infradb.NewCluster(db), referenceRepository.New(infradb.NewCluster(db))), Users: New(infradb.NewCluster(db), nil)}
+	})
+}
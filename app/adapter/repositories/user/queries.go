@@ -1,7 +1,9 @@
 package repository
 
-const (
-	queryUpdateUserById = `UPDATE xyz.user
+import infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+
+var (
+	queryUpdateUserById = infradb.Named("user.update_user_by_id", `UPDATE user
 SET 
     national_id = COALESCE(?, national_id), 
     full_name = COALESCE(?, full_name), 
@@ -13,12 +15,21 @@ SET
     user_photo = COALESCE(?, user_photo), 
     is_nid_valid = COALESCE(?, is_nid_valid), 
     is_photo_valid = COALESCE(?, is_photo_valid), 
+    is_salary_valid = COALESCE(?, is_salary_valid), 
     created_by = COALESCE(?, created_by), 
-    updated_by = COALESCE(?, updated_by)
-WHERE id = ?`
+    updated_by = COALESCE(?, updated_by),
+    version = version + 1
+WHERE id = ? AND version = COALESCE(?, version)`)
 
-	getUserByNationalID = `SELECT id, national_id, full_name, legal_name, is_nid_valid, is_photo_valid
+	// birth_of_date and salary are kept to run again the kyc checks that did not pass
+	getUserByNationalID = infradb.Named("user.get_user_by_national_id", `SELECT id, national_id, full_name, legal_name, birth_of_date, salary, is_nid_valid, is_photo_valid, is_salary_valid, version
 FROM user
 WHERE national_id = ?
-`
+`)
+
+	// national_id is NULL until the kyc passes
+	getUserByID = infradb.Named("user.get_user_by_id", `SELECT id, COALESCE(national_id, ''), full_name, COALESCE(legal_name, ''), is_nid_valid, is_photo_valid, is_salary_valid, version
+FROM user
+WHERE id = ?
+`)
 )
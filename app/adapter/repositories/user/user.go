@@ -3,36 +3,39 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"io"
 
 	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
 	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
-	uhttp "github.com/mfajri11/xyz-backend-monolith/util/http"
 )
 
 type UserRepository struct {
-	dbConn    *sql.DB
-	kycClient *uhttp.HTTPClient
+	port.KYCProvider
+	cluster *infradb.Cluster
 }
 
-func New(db *sql.DB, kycClient *uhttp.HTTPClient) *UserRepository {
+func New(cluster *infradb.Cluster, kycProvider port.KYCProvider) *UserRepository {
 	return &UserRepository{
-		dbConn:    db,
-		kycClient: kycClient,
+		KYCProvider: kycProvider,
+		cluster:     cluster,
 	}
 }
 
 func (repo *UserRepository) FindOneByNationalID(ctx context.Context, nid string) (user *domain.UserEntity, err error) {
 	user = new(domain.UserEntity)
-	err = repo.dbConn.QueryRowContext(ctx, getUserByNationalID, nid).Scan(
+	err = repo.cluster.Primary().QueryRowContext(ctx, getUserByNationalID, nid).Scan(
 		&user.ID,
 		&user.NationalID,
 		&user.FullName,
 		&user.LegalName,
+		&user.BirthOfDate,
+		&user.Salary,
 		&user.IsNationalIDValidated,
 		&user.IsPhotoValidated,
+		&user.ISSalaryValidated,
+		&user.Version,
 	)
 	if err == sql.ErrNoRows {
 		err = fmt.Errorf("FindOneByNationalID: user with national id %s not found", nid)
@@ -46,92 +49,92 @@ func (repo *UserRepository) FindOneByNationalID(ctx context.Context, nid string)
 	return user, nil
 }
 
-func (repo *UserRepository) UpdateByID(ctx context.Context, user domain.UserEntity) error {
-	_, err := repo.dbConn.ExecContext(ctx, queryUpdateUserById)
-	if err != nil {
-		err = fmt.Errorf("UpdateByID: error update user: %w", err)
-		return apperror.WrapError(err, apperror.ErrInternalServerError)
+// FindOneByID returns the user of id with its version, to update it only while it has not changed.
+func (repo *UserRepository) FindOneByID(ctx context.Context, id int) (user *domain.UserEntity, err error) {
+	user = new(domain.UserEntity)
+	err = repo.cluster.Primary().QueryRowContext(ctx, getUserByID, id).Scan(
+		&user.ID,
+		&user.NationalID,
+		&user.FullName,
+		&user.LegalName,
+		&user.IsNationalIDValidated,
+		&user.IsPhotoValidated,
+		&user.ISSalaryValidated,
+		&user.Version,
+	)
+	if err == sql.ErrNoRows {
+		err = fmt.Errorf("FindOneByID: user %d not found", id)
+		return nil, apperror.WrapError(err, apperror.ErrNotFound)
 	}
-
-	return nil
-}
-
-func (repo *UserRepository) ValidateSalary(ctx context.Context, req domain.KYCValidateSalaryReq) (*domain.KYCValidateSalaryResp, error) {
-	resp, err := repo.kycClient.Post(repo.kycClient.BaseURL+"/veryfi/national-id", req)
 	if err != nil {
-		err = fmt.Errorf("ValidateSalary: error kyc request: %w", err)
+		err = fmt.Errorf("FindOneByID: error select query: %w", err)
 		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
 	}
 
-	if resp.StatusCode != 200 {
-		err = fmt.Errorf("ValidateSalary: unexpected status code: %d", resp.StatusCode)
-		return nil, apperror.WrapError(err, apperror.ErrBadRequest)
-	}
-
-	defer resp.Body.Close()
-	var kycData domain.KYCValidateSalaryResp
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		err = fmt.Errorf("ValidateSalary: error read kyc body response: %w", err)
-		return nil, err
-	}
-
-	if err = json.Unmarshal(b, &kycData); err != nil {
-		err = fmt.Errorf("ValidateSalary: error unmarshalling kyc data: %w", err)
-		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
-	}
-
-	return &kycData, nil
+	return user, nil
 }
 
-func (repo *UserRepository) ValidateNationalID(ctx context.Context, req domain.KYCValidateNationalIDReq) (*domain.KYCValidateNationalIDResp, error) {
-	resp, err := repo.kycClient.Post(repo.kycClient.BaseURL+"/veryfi/national-id", req)
+func (repo *UserRepository) UpdateByID(ctx context.Context, user domain.UserEntity) error {
+	res, err := repo.cluster.Writer(ctx).ExecContext(ctx, queryUpdateUserById, updateByIDArgs(user)...)
 	if err != nil {
-		err = fmt.Errorf("VerifyNationalID: error kyc request: %w", err)
-		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+		err = fmt.Errorf("UpdateByID: error update user: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
 	}
 
-	if resp.StatusCode != 200 {
-		err = fmt.Errorf("VerifyNationalID: unexpected status code: %d", resp.StatusCode)
-		return nil, apperror.WrapError(err, apperror.ErrBadRequest)
-	}
+	return versionConflict(res, user)
+}
 
-	defer resp.Body.Close()
-	var kycData domain.KYCValidateNationalIDResp
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		err = fmt.Errorf("VerifyNationalID: error read kyc body response: %w", err)
-		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+// updateByIDArgs returns the arguments of queryUpdateUserById, an empty or null field is sent as NULL
+// so the column keeps its value.
+func updateByIDArgs(user domain.UserEntity) []interface{} {
+	return []interface{}{
+		nullIfEmpty(user.NationalID),
+		nullIfEmpty(user.FullName),
+		nullIfEmpty(user.LegalName),
+		user.BirthOfPlace,
+		user.BirthOfDate,
+		user.Salary,
+		user.NationalIDPhoto,
+		user.UserPhoto,
+		user.IsNationalIDValidated,
+		user.IsPhotoValidated,
+		user.ISSalaryValidated,
+		nullIfEmpty(user.CreatedBy),
+		nullIfEmpty(user.UpdatedBy),
+		user.ID,
+		nullIfZero(user.Version),
 	}
+}
 
-	if err = json.Unmarshal(b, &kycData); err != nil {
-		err = fmt.Errorf("VerifyNationalID: error unmarshalling kyc data: %w", err)
-		return nil, apperror.WrapError(err, apperror.ErrInternalServerError)
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
 	}
-
-	return &kycData, nil
+	return s
 }
 
-func (repo *UserRepository) ValidatePhoto(ctx context.Context, req domain.KYCValidatePhotoReq) (*domain.KYCValidatePhotoResp, error) {
-	resp, err := repo.kycClient.Post(repo.kycClient.BaseURL+"/veryfi/national-id", req)
-	if err != nil {
-		return nil, fmt.Errorf("VerifyPhoto: error kyc request: %w", err)
+// nullIfZero sends no version as NULL so the update applies to any version.
+func nullIfZero(version int64) interface{} {
+	if version == 0 {
+		return nil
 	}
+	return version
+}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("VerifyPhoto: unexpected status code: %d", resp.StatusCode)
+// versionConflict reports an update of a given version that matched no row: the user changed since it was
+// read, or is gone. like before versions, an update of no version matching no row is not an error.
+func versionConflict(res sql.Result, user domain.UserEntity) error {
+	if user.Version == 0 {
+		return nil
 	}
-
-	defer resp.Body.Close()
-	var kycData domain.KYCValidatePhotoResp
-	b, err := io.ReadAll(resp.Body)
+	n, err := res.RowsAffected()
 	if err != nil {
-		return nil, fmt.Errorf("VerifyPhoto: error read kyc body response: %w", err)
+		err = fmt.Errorf("UpdateByID: error read affected rows: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
 	}
-
-	if err = json.Unmarshal(b, &kycData); err != nil {
-		return nil, fmt.Errorf("VerifyPhoto: error unmarshalling kyc data: %w", err)
+	if n == 0 {
+		err = fmt.Errorf("UpdateByID: user %d is no longer at version %d", user.ID, user.Version)
+		return apperror.WrapError(err, apperror.ErrVersionConflict)
 	}
-
-	return &kycData, nil
+	return nil
 }
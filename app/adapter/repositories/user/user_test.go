@@ -1,35 +1,24 @@
 package repository
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"encoding/json"
-	"io"
-	"net/http"
-	"net/http/httptest"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	loanRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/loan"
+	referenceRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/reference"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/repotest"
 	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
-	uhttp "github.com/mfajri11/xyz-backend-monolith/util/http"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
 	"github.com/stretchr/testify/assert"
 )
 
-// func newIOReader(b interface{}) io.Reader {
-// 	bb, _ := json.Marshal(b)
-// 	return io.NopCloser(bytes.NewReader(bb))
-// }
-
-func newIOReaderCloser(b interface{}) io.ReadCloser {
-	bb, _ := json.Marshal(b)
-	return io.NopCloser(bytes.NewReader(bb))
-}
-
-func newByte(v interface{}) []byte {
-	bb, _ := json.Marshal(v)
-	return bb
+func TestMain(m *testing.M) {
+	repotest.Main(m)
 }
 
 func TestUserRepository_FindOneByNationalID(t *testing.T) {
@@ -59,15 +48,19 @@ func TestUserRepository_FindOneByNationalID(t *testing.T) {
 			prepareMock: func(mock *mock) {
 				mock.
 					ExpectQuery(regexp.QuoteMeta(getUserByNationalID)).
-					WithArgs("12345678912345678").WillReturnRows(sqlmock.NewRows([]string{"id", "national_id", "full_name", "legal_name", "is_nid_valid", "is_photo_valid"}).AddRow(1, "12345678912345678", "John Doe", "John Doe", true, true))
+					WithArgs("12345678912345678").WillReturnRows(sqlmock.NewRows([]string{"id", "national_id", "full_name", "legal_name", "birth_of_date", "salary", "is_nid_valid", "is_photo_valid", "is_salary_valid", "version"}).AddRow(1, "12345678912345678", "John Doe", "John Doe", time.Date(1990, 1, 20, 0, 0, 0, 0, time.UTC), 5000000.0, true, true, true, 3))
 			},
 			wantUser: &domain.UserEntity{
 				ID:                    1,
 				NationalID:            "12345678912345678",
 				FullName:              "John Doe",
 				LegalName:             "John Doe",
+				BirthOfDate:           sql.NullTime{Time: time.Date(1990, 1, 20, 0, 0, 0, 0, time.UTC), Valid: true},
+				Salary:                sql.NullFloat64{Float64: 5000000, Valid: true},
 				IsNationalIDValidated: true,
 				IsPhotoValidated:      true,
+				ISSalaryValidated:     true,
+				Version:               3,
 			},
 		},
 		{
@@ -82,6 +75,7 @@ func TestUserRepository_FindOneByNationalID(t *testing.T) {
 					ExpectQuery(regexp.QuoteMeta(getUserByNationalID)).
 					WithArgs("0000000000000000").WillReturnError(sql.ErrNoRows)
 			},
+			wantErr: true,
 		},
 		{
 			name: "Given a invalid national id, it should return error",
@@ -108,7 +102,7 @@ func TestUserRepository_FindOneByNationalID(t *testing.T) {
 				Sqlmock: sqlMock,
 			})
 
-			tt.repo.dbConn = conn
+			tt.repo.cluster = infradb.NewCluster(conn)
 
 			gotUser, err := tt.repo.FindOneByNationalID(tt.args.ctx, tt.args.nid)
 			assert.Equal(t, tt.wantErr, err != nil, err)
@@ -117,6 +111,57 @@ func TestUserRepository_FindOneByNationalID(t *testing.T) {
 	}
 }
 
+func TestUserRepository_FindOneByID(t *testing.T) {
+	columns := []string{"id", "national_id", "full_name", "legal_name", "is_nid_valid", "is_photo_valid", "is_salary_valid", "version"}
+	tests := []struct {
+		name        string
+		id          int
+		prepareMock func(mock sqlmock.Sqlmock)
+		wantUser    *domain.UserEntity
+		wantErr     error
+	}{
+		{
+			name: "Given a registered user, it should return the user with its version",
+			id:   1,
+			prepareMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(getUserByID)).
+					WithArgs(1).WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "", "John Doe", "", false, false, false, 2))
+			},
+			wantUser: &domain.UserEntity{ID: 1, FullName: "John Doe", Version: 2},
+		},
+		{
+			name: "Given an unknown user, it should return not found",
+			id:   2,
+			prepareMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(getUserByID)).WithArgs(2).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: apperror.ErrNotFound,
+		},
+		{
+			name: "Given a failing database, it should return an internal error",
+			id:   1,
+			prepareMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(getUserByID)).WithArgs(1).WillReturnError(sql.ErrConnDone)
+			},
+			wantErr: apperror.ErrInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer conn.Close()
+			tt.prepareMock(mock)
+
+			repo := New(infradb.NewCluster(conn), nil)
+			gotUser, err := repo.FindOneByID(context.Background(), tt.id)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantUser, gotUser)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestUserRepository_UpdateByID(t *testing.T) {
 	type args struct {
 		ctx  context.Context
@@ -147,8 +192,35 @@ func TestUserRepository_UpdateByID(t *testing.T) {
 				},
 			},
 			prepareMock: func(m *mock) {
-				m.ExpectExec(regexp.QuoteMeta(queryUpdateUserById)).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(regexp.QuoteMeta(queryUpdateUserById)).
+					WithArgs("1122334455667788", "John Doe", "John Doe", nil, nil, nil, nil, nil, true, true, false, nil, nil, 1, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name: "Given the version the user was read at, it should update that version",
+			repo: &UserRepository{},
+			args: args{
+				ctx:  context.Background(),
+				user: domain.UserEntity{ID: 1, LegalName: "John Doe", Version: 3},
+			},
+			prepareMock: func(m *mock) {
+				m.ExpectExec(regexp.QuoteMeta(queryUpdateUserById)).
+					WithArgs(nil, nil, "John Doe", nil, nil, nil, nil, nil, false, false, false, nil, nil, 1, int64(3)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "Given a version changed since it was read, it should return a version conflict",
+			repo: &UserRepository{},
+			args: args{
+				ctx:  context.Background(),
+				user: domain.UserEntity{ID: 1, LegalName: "John Doe", Version: 2},
+			},
+			prepareMock: func(m *mock) {
+				m.ExpectExec(regexp.QuoteMeta(queryUpdateUserById)).WillReturnResult(sqlmock.NewResult(0, 0))
 			},
+			wantErr: true,
 		},
 		{
 			name: "Given a valid id and user data, it should return no error",
@@ -179,7 +251,7 @@ func TestUserRepository_UpdateByID(t *testing.T) {
 				Sqlmock: sqlMock,
 			})
 
-			tt.repo.dbConn = conn
+			tt.repo.cluster = infradb.NewCluster(conn)
 
 			err = tt.repo.UpdateByID(tt.args.ctx, tt.args.user)
 
@@ -189,218 +261,9 @@ func TestUserRepository_UpdateByID(t *testing.T) {
 	}
 }
 
-func TestUserRepository_ValidateNationalID(t *testing.T) {
-	type args struct {
-		ctx context.Context
-		req domain.KYCValidateNationalIDReq
-	}
-	type mock func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc
-	tests := []struct {
-		name    string
-		repo    *UserRepository
-		args    args
-		doMock  mock
-		want    *domain.KYCValidateNationalIDResp
-		wantErr bool
-	}{
-		{
-			name: "Given a valid national id, it should return true",
-			repo: &UserRepository{},
-			args: args{
-				ctx: context.Background(),
-				req: domain.KYCValidateNationalIDReq{
-					NationalID:  "1122334455667788",
-					LegalName:   "John Doe",
-					DateOfBirth: "2000-01-01",
-					ReferenceID: "12345678912345678",
-				},
-			},
-			doMock: func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc {
-				return func(r *http.Request) (*http.Response, error) {
-					w.WriteHeader(http.StatusOK)
-					w.Write(newByte(domain.KYCValidateNationalIDResp{
-						Message: "Success",
-						Data: domain.KYCData{
-							NationalID:  true,
-							LegalName:   true,
-							DateOfBirth: true,
-							ReferenceID: "12345678912345678",
-						},
-					}))
-					return w.Result(), nil
-
-				}
-			},
-			want: &domain.KYCValidateNationalIDResp{
-				Message: "Success",
-				Data: domain.KYCData{
-					NationalID:  true,
-					LegalName:   true,
-					DateOfBirth: true,
-					ReferenceID: "12345678912345678",
-				},
-			},
-		},
-		{
-			name: "Given a invalid national id, it should return false",
-			repo: &UserRepository{},
-			args: args{
-				ctx: context.Background(),
-				req: domain.KYCValidateNationalIDReq{
-					NationalID:  "1122334455667788",
-					LegalName:   "John Doe",
-					DateOfBirth: "2000-01-01",
-					ReferenceID: "12345678912345678",
-				},
-			},
-			doMock: func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc {
-				return func(r *http.Request) (*http.Response, error) {
-					w.WriteHeader(http.StatusOK)
-					w.Write(newByte(domain.KYCValidateNationalIDResp{
-						Message: "Fail validate national id",
-						Data: domain.KYCData{
-							NationalID:  false,
-							LegalName:   false,
-							DateOfBirth: false,
-							ReferenceID: "12345678912345678",
-						},
-					}))
-					return w.Result(), nil
-
-				}
-			},
-			want: &domain.KYCValidateNationalIDResp{
-				Message: "Fail validate national id",
-				Data: domain.KYCData{
-					NationalID:  false,
-					LegalName:   false,
-					DateOfBirth: false,
-					ReferenceID: "12345678912345678",
-				},
-			},
-		},
-		{
-			name: "Given a valid national id, but it should return error",
-			repo: &UserRepository{},
-			args: args{
-				ctx: context.Background(),
-				req: domain.KYCValidateNationalIDReq{
-					NationalID:  "1122334455667788",
-					LegalName:   "John Doe",
-					DateOfBirth: "2000-01-01",
-					ReferenceID: "12345678912345678",
-				},
-			},
-			doMock: func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc {
-				return func(r *http.Request) (*http.Response, error) {
-					w.WriteHeader(http.StatusInternalServerError)
-					return w.Result(), nil
-
-				}
-			},
-			wantErr: true,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			r := httptest.NewRequest(http.MethodPost, "/verify/national-id", newIOReaderCloser(tt.args.req))
-			w := httptest.NewRecorder()
-			tt.repo.kycClient = uhttp.NewMock(tt.doMock(r, w))
-
-			got, err := tt.repo.ValidateNationalID(tt.args.ctx, tt.args.req)
-
-			assert.Equal(t, tt.wantErr, err != nil, err)
-			assert.Equal(t, tt.want, got, got)
-		})
-	}
-}
-
-func TestUserRepository_ValidateSalary(t *testing.T) {
-	type args struct {
-		ctx context.Context
-		req domain.KYCValidateSalaryReq
-	}
-	type mock func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc
-	tests := []struct {
-		name    string
-		repo    *UserRepository
-		args    args
-		doMock  mock
-		want    *domain.KYCValidateSalaryResp
-		wantErr bool
-	}{
-		{
-			name: "Given a valid salary, it should return true",
-			repo: &UserRepository{},
-			args: args{
-				ctx: context.Background(),
-				req: domain.KYCValidateSalaryReq{
-					NationalID:  "1122334455667788",
-					LegalName:   "John Doe",
-					Salary:      "1000",
-					ReferenceID: "12345678912345678",
-				},
-			},
-			doMock: func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc {
-				return func(r *http.Request) (*http.Response, error) {
-					w.WriteHeader(http.StatusOK)
-					w.Write(newByte(domain.KYCValidateSalaryResp{
-						Message: "Success",
-						Data: domain.KYCData{
-							NationalID:  true,
-							LegalName:   true,
-							ReferenceID: "12345678912345678",
-							SalaryUper:  "2000",
-							SalaryLower: "500",
-						},
-					}))
-					return w.Result(), nil
-
-				}
-			},
-			want: &domain.KYCValidateSalaryResp{
-				Message: "Success",
-				Data: domain.KYCData{
-					NationalID:  true,
-					LegalName:   true,
-					ReferenceID: "12345678912345678",
-					SalaryUper:  "2000",
-					SalaryLower: "500",
-				},
-			},
-		},
-		{
-			name: "Given a valid salary, but it should return error",
-			repo: &UserRepository{},
-			args: args{
-				ctx: context.Background(),
-				req: domain.KYCValidateSalaryReq{
-					NationalID:  "1122334455667788",
-					LegalName:   "John Doe",
-					Salary:      "100000",
-					ReferenceID: "12345678912345678",
-				},
-			},
-			doMock: func(r *http.Request, w *httptest.ResponseRecorder) uhttp.DoFunc {
-				return func(r *http.Request) (*http.Response, error) {
-					w.WriteHeader(http.StatusInternalServerError)
-					return w.Result(), nil
-
-				}
-			},
-			wantErr: true,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			r := httptest.NewRequest(http.MethodPost, "/verify/income", newIOReaderCloser(tt.args.req))
-			w := httptest.NewRecorder()
-			tt.repo.kycClient = uhttp.NewMock(tt.doMock(r, w))
-
-			got, err := tt.repo.ValidateSalary(tt.args.ctx, tt.args.req)
-
-			assert.Equal(t, tt.wantErr, err != nil, err)
-			assert.Equal(t, tt.want, got, got)
-		})
-	}
+func TestUserRepository_Behaviour(t *testing.T) {
+	repotest.UserRepository(t, func(t *testing.T) repotest.Store {
+		db := repotest.MySQL(t)
+		return repotest.Store{Loans: loanRepository.New(infradb.NewCluster(db), referenceRepository.New(infradb.NewCluster(db))), Users: New(infradb.NewCluster(db), nil)}
+	})
 }
@@ -0,0 +1,294 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/handler"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	userService "github.com/mfajri11/xyz-backend-monolith/app/core/service/user"
+	"github.com/mfajri11/xyz-backend-monolith/infra/ratelimit"
+	"github.com/mfajri11/xyz-backend-monolith/util/config"
+	"github.com/mfajri11/xyz-backend-monolith/util/health"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/tracing"
+)
+
+// adminTask is a one-off operation run through the services, so it follows the same rules as the api.
+type adminTask struct {
+	usage string
+	run   func(ctx context.Context, d *deps, args []string, w io.Writer) error
+}
+
+// errAdminUsage is returned by a task given invalid arguments, its usage is then shown.
+var errAdminUsage = errors.New("invalid arguments")
+
+var adminTasks = map[string]adminTask{
+	"decide-application": {
+		usage: "decide-application <id> approve|reject [reason]",
+		run:   decideApplication,
+	},
+	"kyc-status": {
+		usage: "kyc-status <national-id>",
+		run:   kycStatus,
+	},
+	"recompute-schedule": {
+		usage: "recompute-schedule <user-id> <contract-number>",
+		run:   recomputeSchedule,
+	},
+	"retry-kyc": {
+		usage: "retry-kyc <national-id>",
+		run:   retryKYC,
+	},
+	"status": {
+		usage: "status",
+		run:   status,
+	},
+	"unlock-user": {
+		usage: "unlock-user <user-id> [national-id]",
+		run:   unlockUser,
+	},
+}
+
+// Admin runs the admin task given by args, e.g. ["kyc-status", "3171012001900001"].
+func Admin(opts config.Options, args []string) error {
+	if len(args) == 0 || adminTasks[args[0]].run == nil {
+		return fmt.Errorf("Admin: %s", adminUsage())
+	}
+
+	d, err := setup(opts)
+	if err != nil {
+		return fmt.Errorf("Admin: %w", err)
+	}
+	defer d.close(context.Background())
+
+	ctx := context.Background()
+	if err := d.checkSchema(ctx); err != nil {
+		return fmt.Errorf("Admin: %w", err)
+	}
+
+	return runAdmin(ctx, d, args, os.Stdout)
+}
+
+func runAdmin(ctx context.Context, d *deps, args []string, w io.Writer) (err error) {
+	task, ok := adminTasks[args[0]]
+	if !ok {
+		return fmt.Errorf("Admin: %s", adminUsage())
+	}
+
+	ctx, span := tracing.Start(ctx, "admin."+args[0])
+	defer func() { tracing.End(span, err) }()
+
+	// every admin task is audited, personal data in the arguments is masked by the log redaction
+	logger := log.Ctx(ctx).With().Str("task", args[0]).Strs("args", args[1:]).Logger()
+	if err = task.run(logger.WithContext(ctx), d, args[1:], w); err != nil {
+		if errors.Is(err, errAdminUsage) {
+			return fmt.Errorf("Admin: %w, usage: admin %s", err, task.usage)
+		}
+		logger.Error().Err(err).Msg("admin task failed")
+		return fmt.Errorf("Admin: %s: %w", args[0], err)
+	}
+	logger.Info().Msg("admin task done")
+
+	return nil
+}
+
+func adminUsage() string {
+	usages := make([]string, 0, len(adminTasks))
+	for _, task := range adminTasks {
+		usages = append(usages, "admin "+task.usage)
+	}
+	sort.Strings(usages)
+	return "usage:\n  " + strings.Join(usages, "\n  ")
+}
+
+func decideApplication(ctx context.Context, d *deps, args []string, w io.Writer) error {
+	if len(args) < 2 || (args[1] != "approve" && args[1] != "reject") {
+		return errAdminUsage
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid loan application id %q: %w", args[0], errAdminUsage)
+	}
+	req := domain.DecideLoanApplicationReq{
+		Approved: args[1] == "approve",
+		Reason:   strings.Join(args[2:], " "),
+	}
+	if !req.Approved && req.Reason == "" {
+		return fmt.Errorf("a rejection needs a reason: %w", errAdminUsage)
+	}
+
+	app, err := d.loanApplication.Decide(ctx, id, req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "loan application %d %s", app.ID, app.Status)
+	if app.ContractNumber.Valid {
+		fmt.Fprintf(w, ", contract number %s", app.ContractNumber.String)
+	}
+	fmt.Fprintln(w)
+
+	return nil
+}
+
+func kycStatus(ctx context.Context, d *deps, args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errAdminUsage
+	}
+
+	user, err := d.userRepo.FindOneByNationalID(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "user %d national id %s\n", user.ID, log.MaskNIK(user.NationalID))
+	fmt.Fprintf(w, "  national id valid: %t\n  photo valid: %t\n  salary valid: %t\n",
+		user.IsNationalIDValidated, user.IsPhotoValidated, user.ISSalaryValidated)
+
+	return nil
+}
+
+// recomputeSchedule prints the repayment progress of a loan computed again from its payments, the caches of
+// every instance drop the loan on the way.
+func recomputeSchedule(ctx context.Context, d *deps, args []string, w io.Writer) error {
+	if len(args) != 2 {
+		return errAdminUsage
+	}
+	uid, err := parseUserID(args[0])
+	if err != nil {
+		return err
+	}
+
+	loan, err := d.loan.RecomputeSchedule(ctx, uid, args[1])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "loan %s of user %d %s\n", loan.ContractNumber, uid, loan.Status.String)
+	fmt.Fprintf(w, "  installments paid: %d of %d\n  paid: %.2f\n  outstanding: %.2f\n",
+		loan.InstallmentsPaid, loan.InstallmentsTotal, loan.PaidAmount, loan.OutstandingAmount)
+	if loan.NextDueDate.Valid {
+		fmt.Fprintf(w, "  next due date: %s\n", loan.NextDueDate.Time.Format(time.DateOnly))
+	}
+
+	return nil
+}
+
+// retryKYC runs again, as the user, the kyc checks of a user that did not pass, e.g. while the vendor was
+// down, from the data stored for them. it is billed and capped like a check of the api.
+func retryKYC(ctx context.Context, d *deps, args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errAdminUsage
+	}
+
+	user, err := d.userRepo.FindOneByNationalID(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if user.IsNationalIDValidated && user.IsPhotoValidated && user.ISSalaryValidated {
+		fmt.Fprintf(w, "user %d passed every kyc check, nothing to retry\n", user.ID)
+		return nil
+	}
+	if (!user.IsNationalIDValidated && !user.BirthOfDate.Valid) || (!user.ISSalaryValidated && !user.Salary.Valid) {
+		return fmt.Errorf("user %d has no stored data to check, the customer has to submit the kyc again", user.ID)
+	}
+
+	req := domain.ValidateUserReq{
+		NationalID:  user.NationalID,
+		FullName:    user.FullName,
+		LegalName:   user.LegalName,
+		BirthOfDate: user.BirthOfDate.Time.Format(time.DateOnly),
+		Salary:      strconv.FormatFloat(user.Salary.Float64, 'f', -1, 64),
+	}
+	if _, err := d.user.ValidateData(userService.WithUID(ctx, int64(user.ID)), req); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "user %d passed the kyc checks retried\n", user.ID)
+	return nil
+}
+
+// unlockUser resets the rate limits of a user and, given their national id, the limits by national id and
+// the kyc daily cap, e.g. for a customer refused by mistake.
+func unlockUser(ctx context.Context, d *deps, args []string, w io.Writer) error {
+	if len(args) != 1 && len(args) != 2 {
+		return errAdminUsage
+	}
+	uid, err := parseUserID(args[0])
+	if err != nil {
+		return err
+	}
+	if d.cfg.RateLimit.Backend != rateLimitRedis {
+		return errors.New("the rate limits are kept in the memory of every server, only a restart resets them")
+	}
+
+	var keys []string
+	for _, rule := range rateLimitRules(d.cfg.RateLimit.Rules) {
+		switch {
+		case rule.By == handler.ByUser:
+			keys = append(keys, handler.RateLimitKey(rule, strconv.FormatInt(uid, 10)))
+		case rule.By == handler.ByNIK && len(args) == 2:
+			keys = append(keys, handler.RateLimitKey(rule, ratelimit.Hash(args[1])))
+		}
+	}
+	if len(args) == 2 {
+		keys = append(keys, kycQuotaKey(uid, args[1]))
+	}
+	if len(keys) == 0 {
+		fmt.Fprintf(w, "user %d has no rate limit to reset\n", uid)
+		return nil
+	}
+
+	if err := d.limiter.Reset(ctx, keys...); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "user %d unlocked, %d rate limit(s) reset\n", uid, len(keys))
+	return nil
+}
+
+// status prints the detailed health report the /status endpoint leaves out: the build, and the latency,
+// error and details of every dependency. the replicas are probed first, as the server would.
+func status(ctx context.Context, d *deps, args []string, w io.Writer) error {
+	if len(args) != 0 {
+		return errAdminUsage
+	}
+	if d.cluster != nil {
+		d.cluster.Probe(ctx)
+	}
+
+	report := health.New(version(), d.cfg.Health.Timeout, d.healthChecks()...).Ready(ctx)
+	fmt.Fprintf(w, "%s, version %s\n", report.Status, report.Version)
+	for _, result := range report.Checks {
+		fmt.Fprintf(w, "  %s: %s in %.1fms\n", result.Name, result.Status, result.LatencyMS)
+		if result.Error != "" {
+			fmt.Fprintf(w, "    error: %s\n", result.Error)
+		}
+		keys := make([]string, 0, len(result.Details))
+		for key := range result.Details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "    %s: %v\n", key, result.Details[key])
+		}
+	}
+
+	return nil
+}
+
+func parseUserID(s string) (int64, error) {
+	uid, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || uid <= 0 {
+		return 0, fmt.Errorf("invalid user id %q: %w", s, errAdminUsage)
+	}
+	return uid, nil
+}
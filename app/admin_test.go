@@ -0,0 +1,269 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/handler"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	userService "github.com/mfajri11/xyz-backend-monolith/app/core/service/user"
+	"github.com/mfajri11/xyz-backend-monolith/infra/ratelimit"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/config"
+	uhttp "github.com/mfajri11/xyz-backend-monolith/util/http"
+	"github.com/mfajri11/xyz-backend-monolith/util/mapper"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeUserRepository struct {
+	port.UserRepository
+	user *domain.UserEntity
+}
+
+func (f fakeUserRepository) FindOneByNationalID(ctx context.Context, nid string) (*domain.UserEntity, error) {
+	if f.user == nil || f.user.NationalID != nid {
+		return nil, apperror.WrapError(errors.New("not found"), apperror.ErrNotFound)
+	}
+	return f.user, nil
+}
+
+type fakeLoanApplicationService struct {
+	port.LoanApplicationService
+	gotID  int64
+	gotReq domain.DecideLoanApplicationReq
+}
+
+func (f *fakeLoanApplicationService) Decide(ctx context.Context, id int64, req domain.DecideLoanApplicationReq) (*domain.LoanApplication, error) {
+	f.gotID, f.gotReq = id, req
+	app := &domain.LoanApplication{ID: id, Status: domain.ApplicationRejected}
+	if req.Approved {
+		app.Status, app.ContractNumber = domain.ApplicationApproved, mapper.NewSQLNUllableString("XYZ-01-20210101-000001")
+	}
+	return app, nil
+}
+
+type fakeUserService struct {
+	port.UserService
+	gotUID int64
+	gotReq domain.ValidateUserReq
+}
+
+func (f *fakeUserService) ValidateData(ctx context.Context, req domain.ValidateUserReq) (bool, error) {
+	f.gotUID, _ = userService.UIDFrom(ctx)
+	f.gotReq = req
+	return true, nil
+}
+
+type fakeLoanService struct {
+	port.LoanService
+}
+
+func (fakeLoanService) RecomputeSchedule(ctx context.Context, uid int64, contractNumber string) (*domain.LoanSummary, error) {
+	if contractNumber != "XYZ-01-20210101-000001" {
+		return nil, apperror.WrapError(errors.New("not found"), apperror.ErrNotFound)
+	}
+	return &domain.LoanSummary{
+		LoanAll:           domain.LoanAll{ContractNumber: contractNumber, Status: mapper.NewSQLNUllableString("ACTIVE")},
+		InstallmentsPaid:  2,
+		InstallmentsTotal: 6,
+		PaidAmount:        200000,
+		OutstandingAmount: 400000,
+		NextDueDate:       mapper.NewSQLNullableTime(time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)),
+	}, nil
+}
+
+func TestRunAdmin(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantOut string
+		wantReq *domain.DecideLoanApplicationReq
+		wantErr error
+	}{
+		{
+			name:    "Given a known national id, kyc-status should print the masked national id and the kyc flags",
+			args:    []string{"kyc-status", "3171012001900001"},
+			wantOut: "user 7 national id ************0001\n  national id valid: true\n  photo valid: false\n  salary valid: true\n",
+		},
+		{
+			name:    "Given an unknown national id, kyc-status should return not found",
+			args:    []string{"kyc-status", "3171012001909999"},
+			wantErr: apperror.ErrNotFound,
+		},
+		{
+			name:    "Given an approval, decide-application should print the contract number",
+			args:    []string{"decide-application", "42", "approve"},
+			wantOut: "loan application 42 APPROVED, contract number XYZ-01-20210101-000001\n",
+			wantReq: &domain.DecideLoanApplicationReq{Approved: true},
+		},
+		{
+			name:    "Given a rejection, decide-application should pass the whole reason",
+			args:    []string{"decide-application", "42", "reject", "income", "too", "low"},
+			wantOut: "loan application 42 REJECTED\n",
+			wantReq: &domain.DecideLoanApplicationReq{Reason: "income too low"},
+		},
+		{
+			name:    "Given a rejection without reason, it should show the usage",
+			args:    []string{"decide-application", "42", "reject"},
+			wantErr: errAdminUsage,
+		},
+		{
+			name:    "Given a non numeric id, it should show the usage",
+			args:    []string{"decide-application", "abc", "approve"},
+			wantErr: errAdminUsage,
+		},
+		{
+			name:    "Given a loan, recompute-schedule should print its repayment progress",
+			args:    []string{"recompute-schedule", "7", "XYZ-01-20210101-000001"},
+			wantOut: "loan XYZ-01-20210101-000001 of user 7 ACTIVE\n  installments paid: 2 of 6\n  paid: 200000.00\n  outstanding: 400000.00\n  next due date: 2021-04-01\n",
+		},
+		{
+			name:    "Given an unknown loan, recompute-schedule should return not found",
+			args:    []string{"recompute-schedule", "7", "XYZ-01-20210101-999999"},
+			wantErr: apperror.ErrNotFound,
+		},
+		{
+			name:    "Given a non numeric user id, recompute-schedule should show the usage",
+			args:    []string{"recompute-schedule", "abc", "XYZ-01-20210101-000001"},
+			wantErr: errAdminUsage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appSvc := &fakeLoanApplicationService{}
+			d := &deps{
+				userRepo: fakeUserRepository{user: &domain.UserEntity{
+					ID: 7, NationalID: "3171012001900001", IsNationalIDValidated: true, ISSalaryValidated: true,
+				}},
+				services: services{loanApplication: appSvc, loan: fakeLoanService{}},
+			}
+
+			var out bytes.Buffer
+			err := runAdmin(context.Background(), d, tt.args, &out)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantOut, out.String())
+			if tt.wantReq != nil {
+				assert.Equal(t, int64(42), appSvc.gotID)
+				assert.Equal(t, *tt.wantReq, appSvc.gotReq)
+			}
+		})
+	}
+}
+
+func TestRunAdmin_RetryKYC(t *testing.T) {
+	birthDate := mapper.NewSQLNullableTime(time.Date(1990, 1, 20, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name    string
+		user    domain.UserEntity
+		wantOut string
+		wantReq *domain.ValidateUserReq
+		wantErr bool
+	}{
+		{
+			name:    "Given a user who passed every check, it should retry nothing",
+			user:    domain.UserEntity{ID: 7, NationalID: "3171012001900001", IsNationalIDValidated: true, IsPhotoValidated: true, ISSalaryValidated: true},
+			wantOut: "user 7 passed every kyc check, nothing to retry\n",
+		},
+		{
+			name: "Given a user whose photo did not pass, it should run the checks again as the user from the stored data",
+			user: domain.UserEntity{
+				ID: 7, NationalID: "3171012001900001", FullName: "Budi", LegalName: "Budi Santoso", BirthOfDate: birthDate,
+				Salary: mapper.NewSQLNullableFloat64(5000000), IsNationalIDValidated: true, ISSalaryValidated: true,
+			},
+			wantOut: "user 7 passed the kyc checks retried\n",
+			wantReq: &domain.ValidateUserReq{NationalID: "3171012001900001", FullName: "Budi", LegalName: "Budi Santoso", BirthOfDate: "1990-01-20", Salary: "5000000"},
+		},
+		{
+			name:    "Given a user whose salary did not pass and is not stored, it should return an error",
+			user:    domain.UserEntity{ID: 7, NationalID: "3171012001900001", BirthOfDate: birthDate, IsNationalIDValidated: true, IsPhotoValidated: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userSvc := &fakeUserService{}
+			d := &deps{
+				userRepo: fakeUserRepository{user: &tt.user},
+				services: services{user: userSvc},
+			}
+
+			var out bytes.Buffer
+			err := runAdmin(context.Background(), d, []string{"retry-kyc", "3171012001900001"}, &out)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantOut, out.String())
+			if tt.wantReq != nil {
+				assert.Equal(t, int64(7), userSvc.gotUID, "the checks should run as the user")
+				assert.Equal(t, *tt.wantReq, userSvc.gotReq)
+			}
+		})
+	}
+}
+
+func TestRunAdmin_UnlockUser(t *testing.T) {
+	rules := []config.RateLimitRule{
+		{Route: "POST /loan", By: "user", Algorithm: ratelimit.TokenBucket, Limit: 1, Period: time.Minute},
+		{Route: "POST /loan", By: "nik", Algorithm: ratelimit.SlidingWindow, Limit: 1, Period: time.Minute},
+		{Route: "*", By: "ip", Algorithm: ratelimit.SlidingWindow, Limit: 1, Period: time.Minute},
+	}
+	limits := rateLimitRules(rules)
+	keys := []string{
+		handler.RateLimitKey(limits[0], "7"),
+		handler.RateLimitKey(limits[1], ratelimit.Hash("3171012001900001")),
+		kycQuotaKey(7, "3171012001900001"),
+	}
+	ipKey := handler.RateLimitKey(limits[2], "10.0.0.1")
+
+	t.Run("Given a user and their national id, it should reset their limits and not the others", func(t *testing.T) {
+		store := ratelimit.NewMemory()
+		d := &deps{
+			cfg:     &config.AppConfig{RateLimit: config.RateLimit{Backend: rateLimitRedis, Rules: rules}},
+			limiter: ratelimit.New(store),
+		}
+		for _, key := range append(keys, ipKey) {
+			_, err := d.limiter.Allow(context.Background(), key, limits[0].Rule)
+			assert.NoError(t, err)
+		}
+
+		var out bytes.Buffer
+		err := runAdmin(context.Background(), d, []string{"unlock-user", "7", "3171012001900001"}, &out)
+		assert.NoError(t, err)
+		assert.Equal(t, "user 7 unlocked, 3 rate limit(s) reset\n", out.String())
+		for _, key := range keys {
+			_, ok, _ := store.Get(context.Background(), key)
+			assert.False(t, ok, key)
+		}
+		_, ok, _ := store.Get(context.Background(), ipKey)
+		assert.True(t, ok, "the limits by ip should be kept")
+	})
+
+	t.Run("Given the memory backend, it should return an error", func(t *testing.T) {
+		d := &deps{
+			cfg:     &config.AppConfig{RateLimit: config.RateLimit{Backend: "memory", Rules: rules}},
+			limiter: ratelimit.New(ratelimit.NewMemory()),
+		}
+
+		err := runAdmin(context.Background(), d, []string{"unlock-user", "7"}, &bytes.Buffer{})
+		assert.ErrorContains(t, err, "only a restart resets them")
+	})
+}
+
+func TestRunAdmin_Status(t *testing.T) {
+	d := &deps{
+		cfg:       &config.AppConfig{Health: config.Health{Timeout: time.Second}},
+		kycClient: uhttp.NewClient("http://kyc.test", "", ""),
+	}
+
+	var out bytes.Buffer
+	err := runAdmin(context.Background(), d, []string{"status"}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "up, version "+version()+"\n")
+	assert.Regexp(t, `  kyc: up in [0-9.]+ms\n    circuit_breaker: closed\n`, out.String(), "the details should be printed")
+}
@@ -66,24 +66,64 @@ type KYCValidatePhotoResp struct {
 	}
 }
 
+// request payloads are validated on binding, see util/validation for the custom tags.
+// photos are base64 encoded in json and limited to 2 MB.
+
 type CreateLoanReq struct {
-	NationalID      string  `json:"national_id"`
-	LegalName       string  `json:"legal_name"`
-	ContractNumber  string  `json:"contract_number"`
-	Amount          float64 `json:"amount"`
-	DownPayment     float64 `json:"down_payment"`
-	OTRAmount       float64
-	PrincipalAmount float64
-	AssetName       string `json:"asset_name"`
-	LoanTypeID      int    `json:"loan_type_id"`
-	LimitTypeID     int    `json:"limit_type_id"`
-	Status          string `json:"status"`
-	StartDate       string `json:"start_date"`
-	InterestRate    int    `json:"interest_rate"`
-	NationalIDPhoto []byte `json:"national_id_photo"`
-	UserPhoto       []byte `json:"user_photo"`
-	Salary          string `json:"salary"`
-	BirthOfDate     string `json:"birth_of_date"`
+	NationalID      string  `json:"national_id" binding:"required,nik"`
+	LegalName       string  `json:"legal_name" binding:"required,max=255"`
+	Amount          float64 `json:"amount" binding:"required,idr"`
+	DownPayment     float64 `json:"down_payment" binding:"omitempty,idr,ltfield=Amount"`
+	OTRAmount       float64 `json:"-"`
+	PrincipalAmount float64 `json:"-"`
+	AssetName       string  `json:"asset_name" binding:"required,max=255"`
+	LoanTypeID      int     `json:"loan_type_id" binding:"required,min=1"`
+	LimitTypeID     int     `json:"limit_type_id" binding:"required,min=1"`
+	Tenor           int     `json:"tenor" binding:"required,min=1,max=60"`
+	NationalIDPhoto []byte  `json:"national_id_photo" binding:"required,image=2048"`
+	UserPhoto       []byte  `json:"user_photo" binding:"required,image=2048"`
+	Salary          string  `json:"salary" binding:"required,idr"`
+	BirthOfDate     string  `json:"birth_of_date" binding:"required,dateonly"`
+}
+
+type UpdateLoanApplicationAssetReq struct {
+	AssetName   string  `json:"asset_name" binding:"required,max=255"`
+	Amount      float64 `json:"amount" binding:"required,idr"`
+	DownPayment float64 `json:"down_payment" binding:"omitempty,idr,ltfield=Amount"`
+	LoanTypeID  int     `json:"loan_type_id" binding:"required,min=1"`
+	OTRAmount   float64 `json:"-"`
+}
+
+type UpdateLoanApplicationTenorReq struct {
+	LimitTypeID int `json:"limit_type_id" binding:"required,min=1"`
+	Tenor       int `json:"tenor" binding:"required,min=1,max=60"`
+}
+
+type UpdateLoanApplicationDocumentsReq struct {
+	NationalIDPhoto []byte `json:"national_id_photo" binding:"required_without=UserPhoto,omitempty,image=2048"`
+	UserPhoto       []byte `json:"user_photo" binding:"required_without=NationalIDPhoto,omitempty,image=2048"`
+}
+
+type DecideLoanApplicationReq struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason" binding:"required_if=Approved false,max=255"`
+}
+
+type PostLoanPaymentReq struct {
+	Amount  float64 `json:"amount" binding:"required,idr"`
+	Channel string  `json:"channel" binding:"required,oneof=VA TRANSFER RETAIL"`
+	// Version is the version of the loan named by If-Match, zero posts the payment whatever the version.
+	Version int64 `json:"-"`
+}
+
+type ListLoansReq struct {
+	Status        string `form:"status" binding:"omitempty,oneof=ACTIVE INACTIVE REJECTED active inactive rejected"`
+	LoanType      string `form:"loan_type" binding:"omitempty,oneof=CAR BIKE WHITE_GOODS car bike white_goods"`
+	StartDateFrom string `form:"start_date_from" binding:"omitempty,dateonly"`
+	StartDateTo   string `form:"start_date_to" binding:"omitempty,dateonly"`
+	Sort          string `form:"sort" binding:"omitempty,oneof=start_date -start_date principal_amount -principal_amount"`
+	Cursor        string `form:"cursor" binding:"max=512"`
+	Limit         int    `form:"limit" binding:"omitempty,min=1,max=100"`
 }
 
 type GeneralResponse struct {
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"database/sql"
+	"time"
+)
+
+// names of the domain events, see util/event.
+const (
+	EventLoanChanged          = "loan.changed"
+	EventReferenceDataChanged = "reference_data.changed"
+)
+
+// LoanChanged is published once a loan is created, by the api or by the approval of a loan application,
+// or updated.
+type LoanChanged struct {
+	UserID         int64
+	ContractNumber string
+}
+
+func (LoanChanged) EventName() string {
+	return EventLoanChanged
+}
+
+// ReferenceDataChanged is published once the loan types or the limit types are seeded.
+type ReferenceDataChanged struct{}
+
+func (ReferenceDataChanged) EventName() string {
+	return EventReferenceDataChanged
+}
+
+// OutboxEvent is a domain event recorded in the outbox, Payload is the event in json. the worker relays the
+// events not published yet and marks them published.
+type OutboxEvent struct {
+	ID          int64
+	Name        string
+	Payload     []byte
+	CreatedAt   time.Time
+	PublishedAt sql.NullTime
+}
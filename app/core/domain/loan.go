@@ -3,6 +3,7 @@ package domain
 import (
 	"database/sql"
 	"database/sql/driver"
+	"fmt"
 	"time"
 )
 
@@ -21,6 +22,13 @@ const (
 	WHITEGOODS LoanTypeName = "WHITE_GOODS"
 )
 
+// the channels a payment is posted through, PostLoanPaymentReq accepts no other.
+const (
+	PaymentChannelVA       = "VA"
+	PaymentChannelTransfer = "TRANSFER"
+	PaymentChannelRetail   = "RETAIL"
+)
+
 type Loan struct {
 	ID              int64
 	UserID          int64
@@ -33,6 +41,8 @@ type Loan struct {
 	Status          sql.NullString
 	StartDate       sql.NullTime
 	InterestRate    sql.NullFloat64
+	// Version counts the writes of the loan, e.g. the payments posted to it.
+	Version int64
 }
 
 type LoanAll struct {
@@ -47,6 +57,48 @@ type LoanAll struct {
 	Status          sql.NullString
 	StartDate       sql.NullTime
 	InterestRate    sql.NullFloat64
+	Version         int64
+}
+
+// LoanSummary is a loan as shown in the customer loan list, along with its repayment progress.
+type LoanSummary struct {
+	LoanAll
+	InstallmentsPaid  int
+	InstallmentsTotal int
+	PaidAmount        float64
+	OutstandingAmount float64
+	NextDueDate       sql.NullTime
+}
+
+type LoanSortField string
+
+const (
+	SortByStartDate       LoanSortField = "start_date"
+	SortByPrincipalAmount LoanSortField = "principal_amount"
+)
+
+// LoanCursor points at the last loan of a page, it is the (sort value, id) pair used for keyset pagination.
+type LoanCursor struct {
+	Sort      string `json:"s"`
+	SortValue string `json:"v"`
+	ID        int64  `json:"id"`
+}
+
+type LoanFilter struct {
+	UserID        int64
+	Status        LoanStatus
+	LoanType      LoanTypeName
+	StartDateFrom sql.NullTime
+	StartDateTo   sql.NullTime
+	SortBy        LoanSortField
+	SortDesc      bool
+	Cursor        *LoanCursor
+	Limit         int
+}
+
+type LoanPage struct {
+	Loans      []LoanSummary
+	NextCursor string
 }
 
 type LoanPayment struct {
@@ -55,6 +107,9 @@ type LoanPayment struct {
 	Amount  float64
 	Date    time.Time
 	Channel string
+	// LoanVersion is the version of the loan the payment was posted against, zero posts it whatever the
+	// version. it is not stored.
+	LoanVersion int64
 }
 
 type LimitType struct {
@@ -68,13 +123,26 @@ type LoanType struct {
 	Name LoanTypeName
 }
 
+// ReferenceData are the loan and limit types the loans refer to by id.
+type ReferenceData struct {
+	LoanTypes  map[int16]LoanType
+	LimitTypes map[int16]LimitType
+}
+
 // Scan for LoanStatus (implements sql.Scanner interface)
 func (s *LoanStatus) Scan(value interface{}) error {
 	if value == nil {
 		*s = "" // Null value
 		return nil
 	}
-	*s = LoanStatus(value.(string))
+	switch v := value.(type) {
+	case string:
+		*s = LoanStatus(v)
+	case []byte:
+		*s = LoanStatus(v)
+	default:
+		return fmt.Errorf("LoanStatus.Scan: unsupported type %T", value)
+	}
 	return nil
 }
 
@@ -89,7 +157,14 @@ func (t *LoanTypeName) Scan(value interface{}) error {
 		*t = "" // Null value
 		return nil
 	}
-	*t = LoanTypeName(value.(string))
+	switch v := value.(type) {
+	case string:
+		*t = LoanTypeName(v)
+	case []byte:
+		*t = LoanTypeName(v)
+	default:
+		return fmt.Errorf("LoanTypeName.Scan: unsupported type %T", value)
+	}
 	return nil
 }
 
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"database/sql"
+	"time"
+)
+
+type LoanApplicationStatus string
+
+// a loan application starts as a draft, is filled step by step and then submitted for decision.
+// only an approved application is converted into a loan contract, rejected and abandoned
+// applications are kept as they are for reporting.
+const (
+	ApplicationDraft     LoanApplicationStatus = "DRAFT"
+	ApplicationSubmitted LoanApplicationStatus = "SUBMITTED"
+	ApplicationApproved  LoanApplicationStatus = "APPROVED"
+	ApplicationRejected  LoanApplicationStatus = "REJECTED"
+	ApplicationAbandoned LoanApplicationStatus = "ABANDONED"
+)
+
+type LoanApplication struct {
+	ID              int64
+	UserID          int64
+	Status          LoanApplicationStatus
+	AssetName       sql.NullString
+	OTRAmount       sql.NullFloat64
+	DownPayment     sql.NullFloat64
+	LoanTypeID      sql.NullInt16
+	LimitTypeID     sql.NullInt16
+	Tenor           sql.NullInt16
+	NationalIDPhoto []byte
+	UserPhoto       []byte
+	LoanID          sql.NullInt64
+	ContractNumber  sql.NullString
+	DecisionReason  sql.NullString
+	SubmittedAt     sql.NullTime
+	DecidedAt       sql.NullTime
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// IsEditable reports whether the application can still be changed by the customer.
+func (app *LoanApplication) IsEditable() bool {
+	return app.Status == ApplicationDraft
+}
+
+// IsComplete reports whether every step (asset, tenor and documents) has been filled.
+func (app *LoanApplication) IsComplete() bool {
+	return app.AssetName.Valid && app.OTRAmount.Valid && app.DownPayment.Valid && app.LoanTypeID.Valid &&
+		app.LimitTypeID.Valid && app.Tenor.Valid &&
+		len(app.NationalIDPhoto) > 0 && len(app.UserPhoto) > 0
+}
+
+// PrincipalAmount returns the amount financed once the down payment is paid.
+func (app *LoanApplication) PrincipalAmount() float64 {
+	return app.OTRAmount.Float64 - app.DownPayment.Float64
+}
@@ -22,4 +22,6 @@ type UserEntity struct {
 	UpdatedAt             time.Time
 	CreatedBy             string
 	UpdatedBy             string
+	// Version counts the writes of the user, UpdateByID only applies to the version given unless it is zero.
+	Version int64
 }
@@ -0,0 +1,15 @@
+package port
+
+import (
+	"context"
+	"time"
+)
+
+type SequenceRepository interface {
+	// NextContractSequence returns the next value of the contract number sequence identified by prefix and date.
+	NextContractSequence(ctx context.Context, prefix string, date time.Time) (int64, error)
+}
+
+type ContractNumberGenerator interface {
+	Generate(ctx context.Context, loanTypeID int16, date time.Time) (string, error)
+}
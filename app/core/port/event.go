@@ -0,0 +1,11 @@
+package port
+
+import (
+	"context"
+
+	"github.com/mfajri11/xyz-backend-monolith/util/event"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, e event.Event)
+}
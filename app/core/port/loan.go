@@ -9,15 +9,24 @@ import (
 type LoanRepository interface {
 	CreateLoan(ctx context.Context, loan domain.Loan) error
 	GetLoanByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (*domain.LoanAll, error)
+	ListLoansByUserID(ctx context.Context, filter domain.LoanFilter) ([]domain.LoanSummary, error)
 	CreateLoanPayment(ctx context.Context, loanPayment domain.LoanPayment) error
 	GetLoanPaymentsByLoanID(ctx context.Context, loanID int64) ([]domain.LoanPayment, error)
 	GetLoanPaymentsByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) ([]domain.LoanPayment, error)
 }
 
+// ReferenceRepository reads the loan and limit types, they only change when they are seeded.
+type ReferenceRepository interface {
+	GetReferenceData(ctx context.Context) (*domain.ReferenceData, error)
+}
+
 type LoanService interface {
 	CreateLoan(ctx context.Context, loan domain.Loan) error
 	CalculateOTRAmount(amount float64) float64
 	GetLoanByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (*domain.LoanAll, error)
+	ListLoans(ctx context.Context, uid int64, req domain.ListLoansReq) (*domain.LoanPage, error)
 	CreateLoanPayment(ctx context.Context, loanPayment domain.LoanPayment) error
+	PostLoanPayment(ctx context.Context, uid int64, contractNumber string, req domain.PostLoanPaymentReq) (*domain.LoanAll, error)
 	GetLoanPaymentsByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) ([]domain.LoanPayment, error)
+	RecomputeSchedule(ctx context.Context, uid int64, contractNumber string) (*domain.LoanSummary, error)
 }
@@ -0,0 +1,28 @@
+package port
+
+import (
+	"context"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+)
+
+type LoanApplicationRepository interface {
+	CreateLoanApplication(ctx context.Context, app domain.LoanApplication) (int64, error)
+	GetLoanApplicationByID(ctx context.Context, id int64) (*domain.LoanApplication, error)
+	GetLoanApplicationByUserIDAndID(ctx context.Context, uid int64, id int64) (*domain.LoanApplication, error)
+	// UpdateLoanApplication saves app as long as its stored status is still from, it fails with apperror.ErrVersionConflict otherwise.
+	UpdateLoanApplication(ctx context.Context, app domain.LoanApplication, from domain.LoanApplicationStatus) error
+	// ConvertToLoan inserts the loan contract and marks the application as approved atomically.
+	ConvertToLoan(ctx context.Context, app domain.LoanApplication, loan domain.Loan) (int64, error)
+}
+
+type LoanApplicationService interface {
+	CreateDraft(ctx context.Context, uid int64) (*domain.LoanApplication, error)
+	GetLoanApplication(ctx context.Context, uid int64, id int64) (*domain.LoanApplication, error)
+	UpdateAsset(ctx context.Context, uid int64, id int64, req domain.UpdateLoanApplicationAssetReq) (*domain.LoanApplication, error)
+	UpdateTenor(ctx context.Context, uid int64, id int64, req domain.UpdateLoanApplicationTenorReq) (*domain.LoanApplication, error)
+	UpdateDocuments(ctx context.Context, uid int64, id int64, req domain.UpdateLoanApplicationDocumentsReq) (*domain.LoanApplication, error)
+	Submit(ctx context.Context, uid int64, id int64) (*domain.LoanApplication, error)
+	Abandon(ctx context.Context, uid int64, id int64) (*domain.LoanApplication, error)
+	Decide(ctx context.Context, id int64, req domain.DecideLoanApplicationReq) (*domain.LoanApplication, error)
+}
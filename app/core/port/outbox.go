@@ -0,0 +1,19 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+)
+
+// OutboxRepository keeps the domain events until the worker relays them, so an event reaches every instance
+// even when the one publishing it goes down right after.
+type OutboxRepository interface {
+	Add(ctx context.Context, e domain.OutboxEvent) error
+	// Pending returns at most limit events not published yet, the oldest first.
+	Pending(ctx context.Context, limit int) ([]domain.OutboxEvent, error)
+	MarkPublished(ctx context.Context, ids []int64, at time.Time) error
+	// PurgePublished deletes the events published before before and returns how many.
+	PurgePublished(ctx context.Context, before time.Time) (int64, error)
+}
@@ -8,12 +8,24 @@ import (
 
 type UserRepository interface {
 	FindOneByNationalID(ctx context.Context, nid string) (user *domain.UserEntity, err error)
+	FindOneByID(ctx context.Context, id int) (user *domain.UserEntity, err error)
 	UpdateByID(ctx context.Context, user domain.UserEntity) error
+	KYCProvider
+}
+
+// KYCProvider verifies the data of a customer against the kyc vendor.
+type KYCProvider interface {
 	ValidateSalary(ctx context.Context, req domain.KYCValidateSalaryReq) (*domain.KYCValidateSalaryResp, error)
 	ValidateNationalID(ctx context.Context, req domain.KYCValidateNationalIDReq) (*domain.KYCValidateNationalIDResp, error)
 	ValidatePhoto(ctx context.Context, req domain.KYCValidatePhotoReq) (*domain.KYCValidatePhotoResp, error)
 }
 
+// KYCQuota caps the kyc checks of a national id by a user, Take fails with apperror.ErrKYCDailyCapReached
+// once the checks of the day are used up.
+type KYCQuota interface {
+	Take(ctx context.Context, uid int64, nationalID string) error
+}
+
 type UserService interface {
 	ValidateData(ctx context.Context, req domain.ValidateUserReq) (bool, error)
 }
@@ -0,0 +1,105 @@
+package contractnumber
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/tracing"
+)
+
+// contract numbers look like JKT-01-20261019-0001237:
+// branch code, product (loan type) code, contract date, a daily sequence and a luhn check digit.
+const (
+	dateLayout  = "20060102"
+	maxSequence = 999999
+)
+
+var (
+	contractNumberPattern = regexp.MustCompile(`^([A-Z]{3})-(\d{2})-(\d{8})-(\d{6})(\d)$`)
+	branchCodePattern     = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+type Generator struct {
+	repo       port.SequenceRepository
+	branchCode string
+}
+
+func New(repo port.SequenceRepository, branchCode string) *Generator {
+	return &Generator{
+		repo:       repo,
+		branchCode: strings.ToUpper(branchCode),
+	}
+}
+
+func (gen *Generator) Generate(ctx context.Context, loanTypeID int16, date time.Time) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "Generator.Generate")
+	defer func() { tracing.End(span, err) }()
+
+	// a number of another branch code would not pass Validate, and be refused everywhere it is looked up
+	if !branchCodePattern.MatchString(gen.branchCode) {
+		err := fmt.Errorf("Generate: invalid branch code %q", gen.branchCode)
+		return "", apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	if loanTypeID <= 0 || loanTypeID > 99 {
+		err := fmt.Errorf("Generate: invalid loan type id %d", loanTypeID)
+		return "", apperror.WrapError(err, apperror.ErrBadRequest)
+	}
+
+	prefix := fmt.Sprintf("%s-%02d", gen.branchCode, loanTypeID)
+	seq, err := gen.repo.NextContractSequence(ctx, prefix, date)
+	if err != nil {
+		return "", fmt.Errorf("Generate: error get next sequence: %w", err)
+	}
+
+	if seq > maxSequence {
+		err = fmt.Errorf("Generate: sequence %s for %s is exhausted", prefix, date.Format(time.DateOnly))
+		return "", apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+
+	return Format(gen.branchCode, loanTypeID, date, seq), nil
+}
+
+// Format builds a contract number including its check digit.
+func Format(branchCode string, loanTypeID int16, date time.Time, seq int64) string {
+	body := fmt.Sprintf("%02d%s%06d", loanTypeID, date.Format(dateLayout), seq)
+	return fmt.Sprintf("%s-%s-%s-%s%d", branchCode, body[:2], body[2:10], body[10:], checkDigit(body))
+}
+
+// Validate reports whether contractNumber is well formed and its check digit matches.
+func Validate(contractNumber string) bool {
+	m := contractNumberPattern.FindStringSubmatch(contractNumber)
+	if m == nil {
+		return false
+	}
+
+	if _, err := time.Parse(dateLayout, m[3]); err != nil {
+		return false
+	}
+
+	return int(m[5][0]-'0') == checkDigit(m[2]+m[3]+m[4])
+}
+
+// checkDigit computes the luhn check digit of digits.
+func checkDigit(digits string) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+
+	return (10 - sum%10) % 10
+}
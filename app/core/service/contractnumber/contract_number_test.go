@@ -0,0 +1,107 @@
+package contractnumber
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+type sequenceRepositoryFake struct {
+	mu     sync.Mutex
+	values map[string]int64
+	err    error
+}
+
+func (f *sequenceRepositoryFake) NextContractSequence(ctx context.Context, prefix string, date time.Time) (int64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := prefix + date.Format(time.DateOnly)
+	f.values[key]++
+	return f.values[key], nil
+}
+
+func TestFormat(t *testing.T) {
+	got := Format("JKT", 1, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), 123)
+	assert.Equal(t, "JKT-01-20261019-0001230", got)
+	assert.True(t, Validate(got))
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		contractNumber string
+		want           bool
+	}{
+		{name: "Given a generated contract number, it should be valid", contractNumber: "JKT-01-20261019-0001230", want: true},
+		{name: "Given a wrong check digit, it should be invalid", contractNumber: "JKT-01-20261019-0001234"},
+		{name: "Given a transposed sequence, it should be invalid", contractNumber: "JKT-01-20261019-0002130"},
+		{name: "Given an impossible date, it should be invalid", contractNumber: "JKT-01-20261339-0001230"},
+		{name: "Given a client supplied number, it should be invalid", contractNumber: "XYZ-LAI-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Validate(tt.contractNumber))
+		})
+	}
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	date := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Given an invalid loan type, it should return error", func(t *testing.T) {
+		gen := New(&sequenceRepositoryFake{values: map[string]int64{}}, "jkt")
+		_, err := gen.Generate(context.Background(), 0, date)
+		assert.Error(t, err)
+	})
+
+	t.Run("Given an invalid branch code, it should return error without taking a sequence", func(t *testing.T) {
+		repo := &sequenceRepositoryFake{values: map[string]int64{}}
+		gen := New(repo, "JKT1")
+		_, err := gen.Generate(context.Background(), 1, date)
+		assert.ErrorIs(t, err, apperror.ErrInternalServerError)
+		assert.Empty(t, repo.values)
+	})
+
+	t.Run("Given a failing sequence, it should return error", func(t *testing.T) {
+		gen := New(&sequenceRepositoryFake{err: errors.New("oops!")}, "jkt")
+		_, err := gen.Generate(context.Background(), 1, date)
+		assert.Error(t, err)
+	})
+
+	t.Run("Given an exhausted sequence, it should return error", func(t *testing.T) {
+		gen := New(&sequenceRepositoryFake{values: map[string]int64{"JKT-012026-10-19": maxSequence}}, "jkt")
+		_, err := gen.Generate(context.Background(), 1, date)
+		assert.Error(t, err)
+	})
+
+	t.Run("Given parallel callers, it should generate unique numbers", func(t *testing.T) {
+		gen := New(&sequenceRepositoryFake{values: map[string]int64{}}, "jkt")
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			seen = map[string]bool{}
+		)
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got, err := gen.Generate(context.Background(), 2, date)
+				assert.NoError(t, err)
+				assert.True(t, Validate(got), got)
+				mu.Lock()
+				seen[got] = true
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		assert.Len(t, seen, 100)
+	})
+}
@@ -2,24 +2,79 @@ package loan
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
 	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/event"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/mapper"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+	"github.com/mfajri11/xyz-backend-monolith/util/tracing"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+	defaultListSort  = "-start_date"
+	cursorTimeLayout = time.DateTime
 )
 
 type LoanService struct {
-	repo port.LoanRepository
+	repo       port.LoanRepository
+	references port.ReferenceRepository
+	events     port.EventPublisher
+	now        func() time.Time
 }
 
-func New(repo port.LoanRepository) *LoanService {
-	return &LoanService{
-		repo: repo,
+type Option func(svc *LoanService)
+
+// WithEventPublisher publishes the domain events of the service to events instead of dropping them.
+func WithEventPublisher(events port.EventPublisher) Option {
+	return func(svc *LoanService) {
+		svc.events = events
+	}
+}
+
+// WithClock dates the payments with now instead of the wall clock.
+func WithClock(now func() time.Time) Option {
+	return func(svc *LoanService) {
+		svc.now = now
+	}
+}
+
+// WithReferences labels the loans created by the name of their loan type rather than its id.
+func WithReferences(references port.ReferenceRepository) Option {
+	return func(svc *LoanService) {
+		svc.references = references
+	}
+}
+
+func New(repo port.LoanRepository, opts ...Option) *LoanService {
+	svc := &LoanService{
+		repo:   repo,
+		events: event.NewBus(),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(svc)
 	}
+	return svc
 }
 
-func (svc *LoanService) CreateLoan(ctx context.Context, loan domain.Loan) error {
-	err := svc.repo.CreateLoan(ctx, domain.Loan{
+func (svc *LoanService) CreateLoan(ctx context.Context, loan domain.Loan) (err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.CreateLoan")
+	defer func() { tracing.End(span, err) }()
+
+	err = svc.repo.CreateLoan(ctx, domain.Loan{
 		UserID:          loan.UserID,
 		ContractNumber:  loan.ContractNumber,
 		OTRAmount:       loan.OTRAmount,
@@ -35,34 +90,295 @@ func (svc *LoanService) CreateLoan(ctx context.Context, loan domain.Loan) error
 	if err != nil {
 		return fmt.Errorf("CreateLoan: error insert loan: %w", err)
 	}
+	metrics.IncLoansCreated(LoanTypeLabel(ctx, svc.references, loan.LoanTypeID.Int16))
+	svc.events.Publish(ctx, domain.LoanChanged{UserID: loan.UserID, ContractNumber: loan.ContractNumber})
 
 	return nil
 }
 
-func (svc *LoanService) CreateLoanPayment(ctx context.Context, loanPayment domain.LoanPayment) error {
-	err := svc.repo.CreateLoanPayment(ctx, domain.LoanPayment{
-		LoanID:  loanPayment.LoanID,
-		Amount:  loanPayment.Amount,
-		Date:    loanPayment.Date,
-		Channel: loanPayment.Channel,
+// LoanTypeLabel is the name of the loan type id the loans created are counted by, its id when the name
+// cannot be read. the loans converted from the applications are counted by it as well.
+func LoanTypeLabel(ctx context.Context, references port.ReferenceRepository, id int16) string {
+	if references != nil {
+		data, err := references.GetReferenceData(ctx)
+		if err == nil {
+			if loanType, ok := data.LoanTypes[id]; ok {
+				return string(loanType.Name)
+			}
+		} else {
+			log.Ctx(ctx).Warn().Err(err).Int16("loanTypeID", id).Msg("loan type name not read for the metrics")
+		}
+	}
+	return strconv.Itoa(int(id))
+}
+
+func (svc *LoanService) CreateLoanPayment(ctx context.Context, loanPayment domain.LoanPayment) (err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.CreateLoanPayment")
+	defer func() { tracing.End(span, err) }()
+
+	err = svc.repo.CreateLoanPayment(ctx, domain.LoanPayment{
+		LoanID:      loanPayment.LoanID,
+		Amount:      loanPayment.Amount,
+		Date:        loanPayment.Date,
+		Channel:     loanPayment.Channel,
+		LoanVersion: loanPayment.LoanVersion,
 	})
 
 	if err != nil {
 		return fmt.Errorf("CreateLoanPayment: error insert loan payment: %w", err)
 	}
+	metrics.IncPaymentsPosted(loanPayment.Channel)
+	log.Ctx(ctx).Info().Int64("loanID", loanPayment.LoanID).Str("channel", loanPayment.Channel).Msg("loan payment posted")
 
 	return nil
 }
 
-func (svc *LoanService) GetLoanByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (*domain.LoanAll, error) {
+// PostLoanPayment posts a payment to a loan of the user and returns the loan as it is after the payment. given
+// a version, the payment is refused with a version conflict once the loan has changed since that version.
+func (svc *LoanService) PostLoanPayment(ctx context.Context, uid int64, contractNumber string, req domain.PostLoanPaymentReq) (_ *domain.LoanAll, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.PostLoanPayment")
+	defer func() { tracing.End(span, err) }()
+
+	summary, err := svc.schedule(ctx, uid, contractNumber)
+	if err != nil {
+		return nil, fmt.Errorf("PostLoanPayment: %w", err)
+	}
+	if summary.Status.String != string(domain.ACIIVE) {
+		return nil, apperror.WrapError(fmt.Errorf("PostLoanPayment: loan %s is %s", contractNumber, summary.Status.String), apperror.ErrLoanNotActive)
+	}
+	// amounts are compared in cents, the outstanding amount is rounded to them
+	if math.Round(req.Amount*100) > math.Round(summary.OutstandingAmount*100) {
+		return nil, apperror.WrapError(fmt.Errorf("PostLoanPayment: amount %.2f exceeds the outstanding %.2f", req.Amount, summary.OutstandingAmount), apperror.ErrPaymentExceedsOutstanding)
+	}
+
+	err = svc.CreateLoanPayment(ctx, domain.LoanPayment{
+		LoanID:      summary.ID,
+		Amount:      req.Amount,
+		Date:        svc.now(),
+		Channel:     req.Channel,
+		LoanVersion: req.Version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("PostLoanPayment: %w", err)
+	}
+	svc.events.Publish(ctx, domain.LoanChanged{UserID: uid, ContractNumber: contractNumber})
+
+	loan, err := svc.repo.GetLoanByUserIDAndContractNumber(ctx, uid, contractNumber)
+	if err != nil {
+		return nil, fmt.Errorf("PostLoanPayment: error get paid loan: %w", err)
+	}
+
+	return loan, nil
+}
+
+func (svc *LoanService) GetLoanByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (_ *domain.LoanAll, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.GetLoanByUserIDAndContractNumber")
+	defer func() { tracing.End(span, err) }()
+
 	return svc.repo.GetLoanByUserIDAndContractNumber(ctx, uid, contractNumber)
 }
 
-func (svc *LoanService) GetLoanPaymentsByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) ([]domain.LoanPayment, error) {
+func (svc *LoanService) GetLoanPaymentsByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (_ []domain.LoanPayment, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.GetLoanPaymentsByUserIDAndContractNumber")
+	defer func() { tracing.End(span, err) }()
+
 	return svc.repo.GetLoanPaymentsByUserIDAndContractNumber(ctx, uid, contractNumber)
 }
 
+// RecomputeSchedule returns the repayment progress of a loan computed from its payments as they are stored,
+// the loan changed event makes every cache drop what it kept of the loan first.
+func (svc *LoanService) RecomputeSchedule(ctx context.Context, uid int64, contractNumber string) (_ *domain.LoanSummary, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.RecomputeSchedule")
+	defer func() { tracing.End(span, err) }()
+
+	svc.events.Publish(ctx, domain.LoanChanged{UserID: uid, ContractNumber: contractNumber})
+
+	summary, err := svc.schedule(ctx, uid, contractNumber)
+	if err != nil {
+		return nil, fmt.Errorf("RecomputeSchedule: %w", err)
+	}
+
+	return summary, nil
+}
+
+// schedule reads a loan of the user along with its payments and returns its repayment progress.
+func (svc *LoanService) schedule(ctx context.Context, uid int64, contractNumber string) (*domain.LoanSummary, error) {
+	loan, err := svc.repo.GetLoanByUserIDAndContractNumber(ctx, uid, contractNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error get loan: %w", err)
+	}
+	payments, err := svc.repo.GetLoanPaymentsByUserIDAndContractNumber(ctx, uid, contractNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error get payments: %w", err)
+	}
+
+	summary := &domain.LoanSummary{LoanAll: *loan, InstallmentsPaid: len(payments)}
+	for _, payment := range payments {
+		summary.PaidAmount += payment.Amount
+	}
+	summarize(summary)
+
+	return summary, nil
+}
+
+func (svc *LoanService) ListLoans(ctx context.Context, uid int64, req domain.ListLoansReq) (_ *domain.LoanPage, err error) {
+	ctx, span := tracing.Start(ctx, "LoanService.ListLoans")
+	defer func() { tracing.End(span, err) }()
+
+	filter, err := newLoanFilter(uid, req)
+	if err != nil {
+		return nil, apperror.WrapError(fmt.Errorf("ListLoans: %w", err), apperror.ErrBadRequest)
+	}
+
+	// fetch one more loan than requested to know whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+	loans, err := svc.repo.ListLoansByUserID(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("ListLoans: error list loans: %w", err)
+	}
+
+	page := &domain.LoanPage{Loans: loans}
+	if len(loans) > limit {
+		page.Loans = loans[:limit]
+		page.NextCursor = encodeCursor(sortParam(filter), filter.SortBy, page.Loans[limit-1])
+	}
+
+	for i := range page.Loans {
+		summarize(&page.Loans[i])
+	}
+
+	return page, nil
+}
+
 func (svc *LoanService) CalculateOTRAmount(amount float64) float64 {
 	// not yet implemented
 	return amount + amount*0.3
 }
+
+// summarize fills the repayment progress of a loan, installments are due monthly starting one month after the start date.
+func summarize(loan *domain.LoanSummary) {
+	loan.InstallmentsTotal = int(loan.LimitType.Term)
+	loan.OutstandingAmount = math.Max(totalDue(loan)-loan.PaidAmount, 0)
+	if loan.StartDate.Valid && loan.Status.String == string(domain.ACIIVE) && loan.InstallmentsPaid < loan.InstallmentsTotal {
+		loan.NextDueDate = mapper.NewSQLNullableTime(loan.StartDate.Time.AddDate(0, loan.InstallmentsPaid+1, 0))
+	}
+}
+
+// totalDue is the principal of a loan plus its interest. the interest is flat: the yearly rate, in percent,
+// is charged on the whole principal for every month of the term.
+func totalDue(loan *domain.LoanSummary) float64 {
+	interest := 0.0
+	if loan.InterestRate.Valid {
+		interest = loan.PrincipalAmount * loan.InterestRate.Float64 / 100 * float64(loan.LimitType.Term) / 12
+	}
+	return math.Round((loan.PrincipalAmount+interest)*100) / 100
+}
+
+func newLoanFilter(uid int64, req domain.ListLoansReq) (domain.LoanFilter, error) {
+	filter := domain.LoanFilter{
+		UserID: uid,
+		Limit:  req.Limit,
+	}
+
+	switch status := domain.LoanStatus(strings.ToUpper(req.Status)); status {
+	case "", domain.ACIIVE, domain.INCATIVE, domain.REJECTED:
+		filter.Status = status
+	default:
+		return filter, fmt.Errorf("invalid status %s", req.Status)
+	}
+
+	switch loanType := domain.LoanTypeName(strings.ToUpper(req.LoanType)); loanType {
+	case "", domain.CAR, domain.BIKE, domain.WHITEGOODS:
+		filter.LoanType = loanType
+	default:
+		return filter, fmt.Errorf("invalid loan type %s", req.LoanType)
+	}
+
+	if req.StartDateFrom != "" {
+		t, err := mapper.NewSQLNUllableTime(req.StartDateFrom)
+		if err != nil {
+			return filter, fmt.Errorf("invalid start date from: %w", err)
+		}
+		filter.StartDateFrom = t
+	}
+
+	if req.StartDateTo != "" {
+		t, err := mapper.NewSQLNUllableTime(req.StartDateTo)
+		if err != nil {
+			return filter, fmt.Errorf("invalid start date to: %w", err)
+		}
+		// the range is inclusive of the whole end day
+		t.Time = t.Time.AddDate(0, 0, 1)
+		filter.StartDateTo = t
+	}
+
+	sort := req.Sort
+	if sort == "" {
+		sort = defaultListSort
+	}
+	filter.SortDesc = strings.HasPrefix(sort, "-")
+	switch field := domain.LoanSortField(strings.TrimPrefix(sort, "-")); field {
+	case domain.SortByStartDate, domain.SortByPrincipalAmount:
+		filter.SortBy = field
+	default:
+		return filter, fmt.Errorf("invalid sort %s", req.Sort)
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return filter, err
+		}
+		if cursor.Sort != sortParam(filter) {
+			return filter, errors.New("cursor does not match the requested sort")
+		}
+		filter.Cursor = cursor
+	}
+
+	return filter, nil
+}
+
+func sortParam(filter domain.LoanFilter) string {
+	if filter.SortDesc {
+		return "-" + string(filter.SortBy)
+	}
+	return string(filter.SortBy)
+}
+
+func encodeCursor(sort string, sortBy domain.LoanSortField, loan domain.LoanSummary) string {
+	cursor := domain.LoanCursor{Sort: sort, ID: loan.ID}
+	switch sortBy {
+	case domain.SortByPrincipalAmount:
+		cursor.SortValue = strconv.FormatFloat(loan.PrincipalAmount, 'f', -1, 64)
+	default:
+		cursor.SortValue = time.Unix(0, 0).UTC().Format(cursorTimeLayout)
+		if loan.StartDate.Valid {
+			cursor.SortValue = loan.StartDate.Time.Format(cursorTimeLayout)
+		}
+	}
+
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*domain.LoanCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	var cursor domain.LoanCursor
+	if err = json.Unmarshal(b, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	return &cursor, nil
+}
@@ -0,0 +1,136 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/mapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize_OutstandingAmount(t *testing.T) {
+	tests := []struct {
+		name         string
+		interestRate float64
+		noInterest   bool
+		paid         float64
+		want         float64
+	}{
+		{name: "Given no interest rate, it should owe the principal left", noInterest: true, paid: 2_000_000, want: 10_000_000},
+		{name: "Given an interest rate, it should owe the accrued interest as well", interestRate: 12, paid: 2_000_000, want: 11_440_000},
+		{name: "Given a fractional interest rate, it should round to the cent", interestRate: 2.35, want: 12_282_000},
+		{name: "Given a loan paid off with its interest, it should owe nothing", interestRate: 12, paid: 13_440_000, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loan := domain.LoanSummary{
+				LoanAll: domain.LoanAll{
+					PrincipalAmount: 12_000_000,
+					LimitType:       domain.LimitType{Term: 12},
+					InterestRate:    mapper.NewSQLNullableFloat64(tt.interestRate),
+				},
+				PaidAmount: tt.paid,
+			}
+			if tt.noInterest {
+				loan.InterestRate.Valid = false
+			}
+
+			summarize(&loan)
+			assert.Equal(t, tt.want, loan.OutstandingAmount)
+		})
+	}
+}
+
+type referenceRepositoryFake struct {
+	data *domain.ReferenceData
+	err  error
+}
+
+func (f referenceRepositoryFake) GetReferenceData(ctx context.Context) (*domain.ReferenceData, error) {
+	return f.data, f.err
+}
+
+func TestLoanTypeLabel(t *testing.T) {
+	references := referenceRepositoryFake{data: &domain.ReferenceData{LoanTypes: map[int16]domain.LoanType{1: {ID: 1, Name: domain.CAR}}}}
+	tests := []struct {
+		name       string
+		references port.ReferenceRepository
+		id         int16
+		want       string
+	}{
+		{name: "Given a known loan type, it should return its name", references: references, id: 1, want: "CAR"},
+		{name: "Given an unknown loan type, it should return its id", references: references, id: 9, want: "9"},
+		{name: "Given failing reference data, it should return the id", references: referenceRepositoryFake{err: errors.New("timeout")}, id: 1, want: "1"},
+		{name: "Given no reference data, it should return the id", id: 1, want: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, LoanTypeLabel(context.Background(), tt.references, tt.id))
+		})
+	}
+}
+
+// loanRepositoryFake holds a single loan, the methods the tests do not reach are left to the embedded nil interface.
+type loanRepositoryFake struct {
+	port.LoanRepository
+	loan     domain.LoanAll
+	payments []domain.LoanPayment
+}
+
+func (f *loanRepositoryFake) GetLoanByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) (*domain.LoanAll, error) {
+	loan := f.loan
+	return &loan, nil
+}
+
+func (f *loanRepositoryFake) GetLoanPaymentsByUserIDAndContractNumber(ctx context.Context, uid int64, contractNumber string) ([]domain.LoanPayment, error) {
+	return f.payments, nil
+}
+
+func (f *loanRepositoryFake) CreateLoanPayment(ctx context.Context, loanPayment domain.LoanPayment) error {
+	f.payments = append(f.payments, loanPayment)
+	return nil
+}
+
+func TestLoanService_PostLoanPayment(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  domain.LoanStatus
+		paid    float64
+		amount  float64
+		wantErr error
+	}{
+		{name: "Given an active loan, it should post the payment", status: domain.ACIIVE, amount: 1_000_000},
+		{name: "Given the whole outstanding amount, it should post the payment", status: domain.ACIIVE, paid: 11_000_000, amount: 1_120_000},
+		{name: "Given an inactive loan, it should refuse the payment", status: domain.INCATIVE, amount: 1_000_000, wantErr: apperror.ErrLoanNotActive},
+		{name: "Given more than the outstanding amount, it should refuse the payment", status: domain.ACIIVE, paid: 11_000_000, amount: 1_120_000.01, wantErr: apperror.ErrPaymentExceedsOutstanding},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &loanRepositoryFake{
+				loan: domain.LoanAll{
+					ID:              1,
+					PrincipalAmount: 12_000_000,
+					LimitType:       domain.LimitType{Term: 12},
+					InterestRate:    mapper.NewSQLNullableFloat64(1),
+					Status:          mapper.NewSQLNUllableString(string(tt.status)),
+				},
+				payments: []domain.LoanPayment{{LoanID: 1, Amount: tt.paid}},
+			}
+			svc := New(repo)
+
+			_, err := svc.PostLoanPayment(context.Background(), 1, "XYZ-LAI-01", domain.PostLoanPaymentReq{Amount: tt.amount, Channel: domain.PaymentChannelVA})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Len(t, repo.payments, 1)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, repo.payments, 2)
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package loanapplication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	loansvc "github.com/mfajri11/xyz-backend-monolith/app/core/service/loan"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/event"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/mapper"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+	"github.com/mfajri11/xyz-backend-monolith/util/tracing"
+)
+
+type LoanApplicationService struct {
+	repo                    port.LoanApplicationRepository
+	contractNumberGenerator port.ContractNumberGenerator
+	references              port.ReferenceRepository
+	now                     func() time.Time
+	events                  port.EventPublisher
+}
+
+type Option func(svc *LoanApplicationService)
+
+// WithEventPublisher publishes the domain events of the service to events instead of dropping them.
+func WithEventPublisher(events port.EventPublisher) Option {
+	return func(svc *LoanApplicationService) {
+		svc.events = events
+	}
+}
+
+// WithClock dates the submissions, the decisions and the contracts with now instead of the wall clock.
+func WithClock(now func() time.Time) Option {
+	return func(svc *LoanApplicationService) {
+		svc.now = now
+	}
+}
+
+// WithReferences labels the loans converted by the name of their loan type.
+func WithReferences(references port.ReferenceRepository) Option {
+	return func(svc *LoanApplicationService) {
+		svc.references = references
+	}
+}
+
+func New(repo port.LoanApplicationRepository, contractNumberGenerator port.ContractNumberGenerator, opts ...Option) *LoanApplicationService {
+	svc := &LoanApplicationService{
+		repo:                    repo,
+		contractNumberGenerator: contractNumberGenerator,
+		now:                     time.Now,
+		events:                  event.NewBus(),
+	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
+}
+
+func (svc *LoanApplicationService) CreateDraft(ctx context.Context, uid int64) (_ *domain.LoanApplication, err error) {
+	ctx, span := tracing.Start(ctx, "LoanApplicationService.CreateDraft")
+	defer func() { tracing.End(span, err) }()
+
+	app := domain.LoanApplication{
+		UserID: uid,
+		Status: domain.ApplicationDraft,
+	}
+
+	id, err := svc.repo.CreateLoanApplication(ctx, app)
+	if err != nil {
+		return nil, fmt.Errorf("CreateDraft: error insert loan application: %w", err)
+	}
+
+	return svc.repo.GetLoanApplicationByUserIDAndID(ctx, uid, id)
+}
+
+func (svc *LoanApplicationService) GetLoanApplication(ctx context.Context, uid int64, id int64) (_ *domain.LoanApplication, err error) {
+	ctx, span := tracing.Start(ctx, "LoanApplicationService.GetLoanApplication")
+	defer func() { tracing.End(span, err) }()
+
+	return svc.repo.GetLoanApplicationByUserIDAndID(ctx, uid, id)
+}
+
+func (svc *LoanApplicationService) UpdateAsset(ctx context.Context, uid int64, id int64, req domain.UpdateLoanApplicationAssetReq) (_ *domain.LoanApplication, err error) {
+	ctx, span := tracing.Start(ctx, "LoanApplicationService.UpdateAsset")
+	defer func() { tracing.End(span, err) }()
+
+	if strings.TrimSpace(req.AssetName) == "" || req.OTRAmount <= 0 || req.DownPayment < 0 || req.DownPayment >= req.OTRAmount || req.LoanTypeID <= 0 {
+		return nil, apperror.WrapError(errors.New("UpdateAsset: invalid asset"), apperror.ErrBadRequest)
+	}
+
+	return svc.update(ctx, uid, id, "UpdateAsset", func(app *domain.LoanApplication) {
+		app.AssetName = mapper.NewSQLNUllableString(req.AssetName)
+		app.OTRAmount = mapper.NewSQLNullableFloat64(req.OTRAmount)
+		app.DownPayment = mapper.NewSQLNullableFloat64(req.DownPayment)
+		app.LoanTypeID = mapper.NewSQLNUllableInt16(int16(req.LoanTypeID))
+	})
+}
+
+func (svc *LoanApplicationService) UpdateTenor(ctx context.Context, uid int64, id int64, req domain.UpdateLoanApplicationTenorReq) (_ *domain.LoanApplication, err error) {
+	ctx, span := tracing.Start(ctx, "LoanApplicationService.UpdateTenor")
+	defer func() { tracing.End(span, err) }()
+
+	if req.LimitTypeID <= 0 || req.Tenor <= 0 {
+		return nil, apperror.WrapError(errors.New("UpdateTenor: invalid tenor"), apperror.ErrBadRequest)
+	}
+
+	return svc.update(ctx, uid, id, "UpdateTenor", func(app *domain.LoanApplication) {
+		app.LimitTypeID = mapper.NewSQLNUllableInt16(int16(req.LimitTypeID))
+		app.Tenor = mapper.NewSQLNUllableInt16(int16(req.Tenor))
+	})
+}
+
+func (svc *LoanApplicationService) UpdateDocuments(ctx context.Context, uid int64, id int64, req domain.UpdateLoanApplicationDocumentsReq) (_ *domain.LoanApplication, err error) {
+	ctx, span := tracing.Start(ctx, "LoanApplicationService.UpdateDocuments")
+	defer func() { tracing.End(span, err) }()
+
+	if len(req.NationalIDPhoto) == 0 && len(req.UserPhoto) == 0 {
+		return nil, apperror.WrapError(errors.New("UpdateDocuments: no document uploaded"), apperror.ErrBadRequest)
+	}
+
+	return svc.update(ctx, uid, id, "UpdateDocuments", func(app *domain.LoanApplication) {
+		if len(req.NationalIDPhoto) > 0 {
+			app.NationalIDPhoto = req.NationalIDPhoto
+		}
+		if len(req.UserPhoto) > 0 {
+			app.UserPhoto = req.UserPhoto
+		}
+	})
+}
+
+func (svc *LoanApplicationService) Submit(ctx context.Context, uid int64, id int64) (_ *domain.LoanApplication, err error) {
+	ctx, span := tracing.Start(ctx, "LoanApplicationService.Submit")
+	defer func() { tracing.End(span, err) }()
+
+	app, err := svc.repo.GetLoanApplicationByUserIDAndID(ctx, uid, id)
+	if err != nil {
+		return nil, fmt.Errorf("Submit: error get loan application: %w", err)
+	}
+
+	if !app.IsEditable() {
+		err = fmt.Errorf("Submit: loan application with status %s can not be submitted", app.Status)
+		return nil, apperror.WrapError(err, apperror.ErrLoanApplicationNotEditable)
+	}
+
+	if !app.IsComplete() {
+		return nil, apperror.WrapError(errors.New("Submit: loan application is incomplete"), apperror.ErrLoanApplicationIncomplete)
+	}
+
+	from := app.Status
+	app.Status = domain.ApplicationSubmitted
+	app.SubmittedAt = mapper.NewSQLNullableTime(svc.now())
+	if err = svc.repo.UpdateLoanApplication(ctx, *app, from); err != nil {
+		return nil, fmt.Errorf("Submit: error update loan application: %w", err)
+	}
+	log.Ctx(ctx).Info().Int64("loanApplicationID", app.ID).Msg("loan application submitted")
+
+	return app, nil
+}
+
+func (svc *LoanApplicationService) Abandon(ctx context.Context, uid int64, id int64) (_ *domain.LoanApplication, err error) {
+	ctx, span := tracing.Start(ctx, "LoanApplicationService.Abandon")
+	defer func() { tracing.End(span, err) }()
+
+	app, err := svc.repo.GetLoanApplicationByUserIDAndID(ctx, uid, id)
+	if err != nil {
+		return nil, fmt.Errorf("Abandon: error get loan application: %w", err)
+	}
+
+	if app.Status != domain.ApplicationDraft && app.Status != domain.ApplicationSubmitted {
+		err = fmt.Errorf("Abandon: loan application with status %s can not be abandoned", app.Status)
+		return nil, wrapStatusError(err, app.Status)
+	}
+
+	from := app.Status
+	app.Status = domain.ApplicationAbandoned
+	if err = svc.repo.UpdateLoanApplication(ctx, *app, from); err != nil {
+		return nil, fmt.Errorf("Abandon: error update loan application: %w", err)
+	}
+
+	return app, nil
+}
+
+func (svc *LoanApplicationService) Decide(ctx context.Context, id int64, req domain.DecideLoanApplicationReq) (_ *domain.LoanApplication, err error) {
+	ctx, span := tracing.Start(ctx, "LoanApplicationService.Decide")
+	defer func() { tracing.End(span, err) }()
+
+	app, err := svc.repo.GetLoanApplicationByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("Decide: error get loan application: %w", err)
+	}
+
+	if app.Status != domain.ApplicationSubmitted {
+		err = fmt.Errorf("Decide: loan application with status %s can not be decided", app.Status)
+		return nil, wrapStatusError(err, app.Status)
+	}
+
+	now := svc.now()
+	app.DecidedAt = mapper.NewSQLNullableTime(now)
+	if req.Reason != "" {
+		app.DecisionReason = mapper.NewSQLNUllableString(req.Reason)
+	}
+
+	if !req.Approved {
+		app.Status = domain.ApplicationRejected
+		if err = svc.repo.UpdateLoanApplication(ctx, *app, domain.ApplicationSubmitted); err != nil {
+			return nil, fmt.Errorf("Decide: error update loan application: %w", err)
+		}
+		log.Ctx(ctx).Info().Int64("loanApplicationID", app.ID).Msg("loan application rejected")
+
+		return app, nil
+	}
+
+	contractNumber, err := svc.contractNumberGenerator.Generate(ctx, app.LoanTypeID.Int16, now)
+	if err != nil {
+		return nil, fmt.Errorf("Decide: error generate contract number: %w", err)
+	}
+
+	loan := domain.Loan{
+		UserID:          app.UserID,
+		ContractNumber:  contractNumber,
+		OTRAmount:       app.OTRAmount.Float64,
+		PrincipalAmount: app.PrincipalAmount(),
+		AssetName:       app.AssetName.String,
+		LoanTypeID:      app.LoanTypeID,
+		LimitTypeID:     app.LimitTypeID,
+		Status:          mapper.NewSQLNUllableString(string(domain.ACIIVE)),
+		StartDate:       mapper.NewSQLNullableTime(now),
+	}
+
+	loanID, err := svc.repo.ConvertToLoan(ctx, *app, loan)
+	if err != nil {
+		return nil, fmt.Errorf("Decide: error convert loan application: %w", err)
+	}
+	metrics.IncLoansCreated(loansvc.LoanTypeLabel(ctx, svc.references, loan.LoanTypeID.Int16))
+	svc.events.Publish(ctx, domain.LoanChanged{UserID: loan.UserID, ContractNumber: loan.ContractNumber})
+
+	app.Status = domain.ApplicationApproved
+	app.LoanID = mapper.NewSQLNullableInt64(loanID)
+	app.ContractNumber = mapper.NewSQLNUllableString(loan.ContractNumber)
+	log.Ctx(ctx).Info().Int64("loanApplicationID", app.ID).Int64("loanID", loanID).Str("contractNumber", loan.ContractNumber).
+		Msg("loan application approved")
+
+	return app, nil
+}
+
+// update loads the application owned by uid, applies fn and saves it back as long as it is still a draft, fn
+// does not change the status.
+func (svc *LoanApplicationService) update(ctx context.Context, uid int64, id int64, op string, fn func(app *domain.LoanApplication)) (*domain.LoanApplication, error) {
+	app, err := svc.repo.GetLoanApplicationByUserIDAndID(ctx, uid, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: error get loan application: %w", op, err)
+	}
+
+	if !app.IsEditable() {
+		err = fmt.Errorf("%s: loan application with status %s can not be changed", op, app.Status)
+		return nil, apperror.WrapError(err, apperror.ErrLoanApplicationNotEditable)
+	}
+
+	fn(app)
+	if err = svc.repo.UpdateLoanApplication(ctx, *app, app.Status); err != nil {
+		return nil, fmt.Errorf("%s: error update loan application: %w", op, err)
+	}
+
+	return app, nil
+}
+
+// wrapStatusError tells apart an application that was already decided from one that is in any other wrong state.
+func wrapStatusError(err error, status domain.LoanApplicationStatus) error {
+	if status == domain.ApplicationApproved || status == domain.ApplicationRejected {
+		return apperror.WrapError(err, apperror.ErrLoanApplicationAlreadyDecided)
+	}
+	return apperror.WrapError(err, apperror.ErrLoanApplicationNotEditable)
+}
@@ -10,39 +10,115 @@ import (
 	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
 	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
 	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
 	"github.com/mfajri11/xyz-backend-monolith/util/mapper"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+	"github.com/mfajri11/xyz-backend-monolith/util/tracing"
 )
 
 type UserService struct {
-	repo port.UserRepository
+	repo  port.UserRepository
+	kyc   port.KYCProvider
+	quota port.KYCQuota
+	newID func() string
 }
 
-func New(repo port.UserRepository) *UserService {
-	return &UserService{
-		repo: repo,
+type Option func(svc *UserService)
+
+// WithKYCProvider verifies the customers with kycProvider instead of the kyc provider of the repository.
+func WithKYCProvider(kycProvider port.KYCProvider) Option {
+	return func(svc *UserService) {
+		svc.kyc = kycProvider
+	}
+}
+
+// WithKYCQuota takes a kyc check of quota before calling the kyc vendor, instead of calling it without bound.
+func WithKYCQuota(quota port.KYCQuota) Option {
+	return func(svc *UserService) {
+		svc.quota = quota
+	}
+}
+
+// WithIDGenerator generates the reference id of the kyc checks of a request without a request id.
+func WithIDGenerator(newID func() string) Option {
+	return func(svc *UserService) {
+		svc.newID = newID
+	}
+}
+
+func New(repo port.UserRepository, opts ...Option) *UserService {
+	svc := &UserService{
+		repo:  repo,
+		kyc:   repo,
+		quota: unlimited{},
+		newID: uuid.NewString,
+	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
+}
+
+// uidKey keys the id of the user acting in a context.
+type uidKey struct{}
+
+// WithUID returns ctx acting as the user uid, for the callers outside of an api request e.g. an admin task.
+func WithUID(ctx context.Context, uid int64) context.Context {
+	return context.WithValue(ctx, uidKey{}, uid)
+}
+
+// UIDFrom returns the id of the user acting in ctx, given by WithUID or, for an api request, set by the
+// handlers as the int64 "uid" of the gin context.
+func UIDFrom(ctx context.Context) (int64, bool) {
+	if uid, ok := ctx.Value(uidKey{}).(int64); ok {
+		return uid, true
 	}
+	uid, ok := ctx.Value("uid").(int64)
+	return uid, ok
 }
 
-func (svc *UserService) ValidateData(ctx context.Context, req domain.ValidateUserReq) (bool, error) {
-	uid, ok := ctx.Value("uid").(int)
+func (svc *UserService) ValidateData(ctx context.Context, req domain.ValidateUserReq) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.ValidateData")
+	defer func() { tracing.End(span, err) }()
+
+	uid, ok := UIDFrom(ctx)
 	if !ok {
 		return false, apperror.WrapError(errors.New("invalid request"), apperror.ErrBadRequest)
 	}
 
 	refId, ok := ctx.Value("requestID").(string)
 	if !ok {
-		refId = uuid.New().String()
+		refId = svc.newID()
 	}
 
 	user, err := svc.repo.FindOneByNationalID(ctx, req.NationalID)
-	if err != nil {
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
 		return false, fmt.Errorf("ValidateData: error while find user: %w", err)
 	}
 
-	userToSave := domain.UserEntity{
-		ID: uid,
+	// a national id verified by another user is not theirs to verify again
+	if err == nil && user.ID != int(uid) {
+		err = fmt.Errorf("ValidateData: national id belongs to user %d, not %d", user.ID, uid)
+		return false, apperror.WrapError(err, apperror.ErrKYCNationalIDInvalid)
 	}
+
+	// the vendor is called for a new user and for the data not verified yet, each call is billed
+	if errors.Is(err, apperror.ErrNotFound) || !user.IsNationalIDValidated || !user.ISSalaryValidated || !user.IsPhotoValidated {
+		if err := svc.quota.Take(ctx, uid, req.NationalID); err != nil {
+			return false, fmt.Errorf("ValidateData: %w", err)
+		}
+	}
+
 	if errors.Is(err, apperror.ErrNotFound) {
+		// the kyc is saved on the user of the caller, at the version read so a concurrent change is not lost
+		caller, err := svc.repo.FindOneByID(ctx, int(uid))
+		if err != nil {
+			return false, fmt.Errorf("ValidateData: error while find user %d: %w", uid, err)
+		}
+		userToSave := domain.UserEntity{
+			ID:      caller.ID,
+			Version: caller.Version,
+		}
 
 		if _, err := svc.validateNationalID(ctx, refId, req, &userToSave); err != nil {
 			// already wrapped
@@ -58,21 +134,25 @@ func (svc *UserService) ValidateData(ctx context.Context, req domain.ValidateUse
 			return false, err
 		}
 
-		err = svc.repo.UpdateByID(ctx, userToSave)
-		if err != nil {
-			err = fmt.Errorf("ValidateData: error while update user: %w", err)
-			return false, apperror.WrapError(err, apperror.ErrInternalServerError)
-		}
-
-		if _, err := svc.validatePhoto(ctx, refId, req, &userToSave); err != nil {
+		if err := svc.save(ctx, userToSave); err != nil {
 			return false, err
 		}
 
 		return true, nil
 	}
 
-	// check and re validate data
+	// re validate the data not verified yet and keep the result, so the vendor is not asked again
+	if user.IsNationalIDValidated && user.ISSalaryValidated && user.IsPhotoValidated {
+		return true, nil
+	}
+
 	if !user.IsNationalIDValidated {
+		if _, err := svc.validateNationalID(ctx, refId, req, user); err != nil {
+			return false, err
+		}
+	}
+
+	if !user.ISSalaryValidated {
 		if _, err := svc.validateSalary(ctx, refId, req, user); err != nil {
 			return false, err
 		}
@@ -84,12 +164,29 @@ func (svc *UserService) ValidateData(ctx context.Context, req domain.ValidateUse
 		}
 	}
 
+	if err := svc.save(ctx, *user); err != nil {
+		return false, err
+	}
+
 	return true, nil
+}
 
+// save updates the user at its version. a user changed since it was read is a conflict, the client retries
+// the request on fresh data.
+func (svc *UserService) save(ctx context.Context, user domain.UserEntity) error {
+	err := svc.repo.UpdateByID(ctx, user)
+	if errors.Is(err, apperror.ErrVersionConflict) {
+		return fmt.Errorf("ValidateData: user changed while validated: %w", err)
+	}
+	if err != nil {
+		err = fmt.Errorf("ValidateData: error while update user: %w", err)
+		return apperror.WrapError(err, apperror.ErrInternalServerError)
+	}
+	return nil
 }
 
 func (svc *UserService) validateNationalID(ctx context.Context, refId string, req domain.ValidateUserReq, userToSave *domain.UserEntity) (bool, error) {
-	validatedNID, err := svc.repo.ValidateNationalID(ctx, domain.KYCValidateNationalIDReq{
+	validatedNID, err := svc.kyc.ValidateNationalID(ctx, domain.KYCValidateNationalIDReq{
 		NationalID:  req.NationalID,
 		LegalName:   req.LegalName,
 		DateOfBirth: req.BirthOfDate,
@@ -99,22 +196,31 @@ func (svc *UserService) validateNationalID(ctx context.Context, refId string, re
 	if err != nil {
 		return false, fmt.Errorf("ValidateData: error while validate national id: %w", err)
 	}
-	if validatedNID.Data.NationalID {
-		t, err := mapper.NewSQLNUllableTime(req.BirthOfDate)
-		if err == nil {
-			userToSave.BirthOfDate = t
-		}
-		userToSave.NationalID = req.NationalID
-		userToSave.NationalID = req.NationalID
-		userToSave.LegalName = req.LegalName
-		userToSave.IsNationalIDValidated = true
+	passed := validatedNID.Data.NationalID && validatedNID.Data.LegalName && validatedNID.Data.DateOfBirth
+	metrics.IncKYCVerdict("national_id", passed)
+	log.Ctx(ctx).Info().Str("check", "national_id").Bool("passed", passed).Str("national_id", log.MaskNIK(req.NationalID)).Msg("kyc check done")
+	switch {
+	case !validatedNID.Data.NationalID:
+		return false, apperror.WrapError(errors.New("ValidateData: national id is not registered"), apperror.ErrKYCNationalIDInvalid)
+	case !validatedNID.Data.LegalName:
+		return false, apperror.WrapError(errors.New("ValidateData: legal name does not match"), apperror.ErrKYCNameMismatch)
+	case !validatedNID.Data.DateOfBirth:
+		return false, apperror.WrapError(errors.New("ValidateData: birth date does not match"), apperror.ErrKYCBirthDateMismatch)
+	}
+
+	t, err := mapper.NewSQLNUllableTime(req.BirthOfDate)
+	if err == nil {
+		userToSave.BirthOfDate = t
 	}
+	userToSave.NationalID = req.NationalID
+	userToSave.LegalName = req.LegalName
+	userToSave.IsNationalIDValidated = true
 
 	return true, nil
 }
 
 func (svc *UserService) validateSalary(ctx context.Context, refId string, req domain.ValidateUserReq, userToSave *domain.UserEntity) (bool, error) {
-	validatedSalary, err := svc.repo.ValidateSalary(ctx, domain.KYCValidateSalaryReq{
+	validatedSalary, err := svc.kyc.ValidateSalary(ctx, domain.KYCValidateSalaryReq{
 		NationalID: req.NationalID,
 		LegalName:  req.LegalName,
 		Salary:     req.Salary,
@@ -139,16 +245,21 @@ func (svc *UserService) validateSalary(ctx context.Context, refId string, req do
 		return false, apperror.WrapError(errors.New("error converting range lower salary from kyc response"), apperror.ErrInternalServerError)
 	}
 
-	if userSalary > lowerRangeSalary && userSalary < upperRangeSalary {
-		userToSave.Salary = mapper.NewSQLNullableFloat64(userSalary)
-		userToSave.ISSalaryValidated = true
+	passed := userSalary > lowerRangeSalary && userSalary < upperRangeSalary
+	metrics.IncKYCVerdict("salary", passed)
+	log.Ctx(ctx).Info().Str("check", "salary").Bool("passed", passed).Str("national_id", log.MaskNIK(req.NationalID)).Msg("kyc check done")
+	if !passed {
+		return false, apperror.WrapError(errors.New("ValidateData: salary is out of the verified range"), apperror.ErrKYCSalaryMismatch)
 	}
 
+	userToSave.Salary = mapper.NewSQLNullableFloat64(userSalary)
+	userToSave.ISSalaryValidated = true
+
 	return true, nil
 }
 
 func (svc *UserService) validatePhoto(ctx context.Context, refId string, req domain.ValidateUserReq, userToSave *domain.UserEntity) (bool, error) {
-	validatedPhoto, err := svc.repo.ValidatePhoto(ctx, domain.KYCValidatePhotoReq{
+	validatedPhoto, err := svc.kyc.ValidatePhoto(ctx, domain.KYCValidatePhotoReq{
 		NationalID:  req.NationalID,
 		ReferenceID: refId,
 	})
@@ -157,8 +268,20 @@ func (svc *UserService) validatePhoto(ctx context.Context, refId string, req dom
 		return false, fmt.Errorf("ValidateData: error while validate photo: %w", err)
 	}
 
-	if validatedPhoto.Data.Status == "valid" {
-		userToSave.IsPhotoValidated = true
+	passed := validatedPhoto.Data.Status == "valid"
+	metrics.IncKYCVerdict("photo", passed)
+	log.Ctx(ctx).Info().Str("check", "photo").Bool("passed", passed).Str("national_id", log.MaskNIK(req.NationalID)).Msg("kyc check done")
+	if !passed {
+		return false, apperror.WrapError(errors.New("ValidateData: photo does not match"), apperror.ErrKYCPhotoMismatch)
 	}
+
+	userToSave.IsPhotoValidated = true
 	return true, nil
 }
+
+// unlimited is the quota of a service without one.
+type unlimited struct{}
+
+func (unlimited) Take(ctx context.Context, uid int64, nationalID string) error {
+	return nil
+}
@@ -0,0 +1,223 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/cached"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/kyc"
+	loanRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/loan"
+	pgLoanRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/loan/postgres"
+	loanApplicationRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/loanapplication"
+	pgLoanApplicationRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/loanapplication/postgres"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/memory"
+	outboxRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/outbox"
+	pgOutboxRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/outbox/postgres"
+	referenceRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/reference"
+	pgReferenceRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/reference/postgres"
+	sequenceRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/sequence"
+	pgSequenceRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/sequence/postgres"
+	userRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/user"
+	pgUserRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/user/postgres"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	userService "github.com/mfajri11/xyz-backend-monolith/app/core/service/user"
+	"github.com/mfajri11/xyz-backend-monolith/infra/cache"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/migrate"
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/mysql"
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/postgres"
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/seed"
+	"github.com/mfajri11/xyz-backend-monolith/util/config"
+)
+
+// the drivers of config.Database, the validation of the configuration rejects any other.
+const (
+	driverMySQL    = "mysql"
+	driverPostgres = "postgres"
+)
+
+// storageMemory runs without a database, see config.AppConfig.Storage.
+const storageMemory = "memory"
+
+// the cache drivers of config.Cache, none caches nothing.
+const (
+	cacheMemory = "memory"
+	cacheRedis  = "redis"
+)
+
+// errNoDatabase is returned by the commands that need a database when the storage is in memory.
+var errNoDatabase = errors.New("the memory storage has no database")
+
+// mysqlTLS maps config.Database.TLS to the tls of the mysql driver.
+var mysqlTLS = map[string]string{
+	"disable":     "false",
+	"prefer":      "preferred",
+	"require":     "skip-verify",
+	"verify-full": "true",
+}
+
+// connect opens the database of the configured driver at host and port, its pool is exported as name.
+func connect(ctx context.Context, cfg config.Database, host string, port int, name string) (*sql.DB, error) {
+	var passwordFunc func(ctx context.Context) (string, error)
+	if cfg.PasswordFile != "" {
+		passwordFunc = infradb.SecretFile(cfg.PasswordFile)
+	}
+	retry := infradb.Retry{Attempts: cfg.ConnectRetries, Backoff: cfg.ConnectBackoff, MaxBackoff: cfg.ConnectMaxBackoff}
+
+	if cfg.Driver == driverPostgres {
+		return postgres.Open(ctx, postgres.Config{
+			Host:           host,
+			Port:           port,
+			Username:       cfg.Username,
+			Password:       cfg.Password,
+			Database:       cfg.DataBaseName,
+			PasswordFunc:   passwordFunc,
+			SSLMode:        cfg.TLS,
+			Timezone:       cfg.Timezone,
+			ConnectTimeout: cfg.ConnectTimeout,
+			Retry:          retry,
+			Lazy:           cfg.LazyConnect,
+		}, postgres.WithMaxIdleConns(cfg.IdleConnection), postgres.WithMaxOpenConns(cfg.OpenConnection), postgres.WithMaxLifetimeConn(cfg.ConnectionMaxLifeTime), postgres.WithMetrics(name))
+	}
+
+	// the validation of the configuration made sure the time zone loads
+	loc, _ := time.LoadLocation(cfg.Timezone)
+	return mysql.Open(ctx, mysql.Config{
+		Host:           host,
+		Port:           port,
+		Username:       cfg.Username,
+		Password:       cfg.Password,
+		Database:       cfg.DataBaseName,
+		PasswordFunc:   passwordFunc,
+		TLS:            mysqlTLS[cfg.TLS],
+		Location:       loc,
+		ConnectTimeout: cfg.ConnectTimeout,
+		ReadTimeout:    cfg.ReadTimeout,
+		WriteTimeout:   cfg.WriteTimeout,
+		Retry:          retry,
+		Lazy:           cfg.LazyConnect,
+	}, mysql.WithMaxIdleConns(cfg.IdleConnection), mysql.WithMaxOpenConns(cfg.OpenConnection), mysql.WithMaxLifetimeConn(cfg.ConnectionMaxLifeTime), mysql.WithMetrics(name))
+}
+
+// connectCluster opens the primary and the replicas of the configuration, the validation of the
+// configuration made sure every replica is a host:port. the databases already opened are closed
+// when one fails.
+func connectCluster(ctx context.Context, cfg config.Database) (*infradb.Cluster, error) {
+	lag := mysql.ReplicaLag
+	if cfg.Driver == driverPostgres {
+		lag = postgres.ReplicaLag
+	}
+
+	primary, err := connect(ctx, cfg, cfg.Host, cfg.Port, cfg.DataBaseName)
+	if err != nil {
+		return nil, fmt.Errorf("connectCluster: error connect primary: %w", err)
+	}
+	opts := []infradb.Option{
+		infradb.WithMaxLag(lag, cfg.MaxReplicaLag),
+		infradb.WithReadYourWrites(cfg.ReadYourWrites, sessionOf),
+		infradb.WithStatementTimeout(cfg.StatementTimeout),
+		infradb.WithSlowQueryLog(cfg.SlowQueryThreshold),
+	}
+	for _, replica := range cfg.Replicas {
+		host, p, _ := net.SplitHostPort(replica)
+		port, _ := strconv.Atoi(p)
+		db, err := connect(ctx, cfg, host, port, cfg.DataBaseName+"@"+replica)
+		if err != nil {
+			infradb.NewCluster(primary, opts...).Close()
+			return nil, fmt.Errorf("connectCluster: error connect replica %s: %w", replica, err)
+		}
+		opts = append(opts, infradb.WithReplica(replica, db))
+	}
+
+	return infradb.NewCluster(primary, opts...), nil
+}
+
+// sessionOf keys the read your writes window by the user of the request, Identify sets its id as uid.
+func sessionOf(ctx context.Context) string {
+	if uid, ok := userService.UIDFrom(ctx); ok && uid != 0 {
+		return strconv.FormatInt(uid, 10)
+	}
+	return ""
+}
+
+// wireRepositories builds the repositories of the configured storage and driver.
+func (d *deps) wireRepositories() {
+	if d.cfg.Storage == storageMemory {
+		store := memory.NewStore()
+		d.userRepo = memory.NewUserRepository(store, memory.NewKYCProvider())
+		d.loanRepo = memory.NewLoanRepository(store)
+		d.loanApplicationRepo = memory.NewLoanApplicationRepository(store)
+		d.sequenceRepo = memory.NewSequenceRepository(store)
+		d.referenceRepo = memory.NewReferenceRepository(store)
+		return
+	}
+
+	kycProvider := kyc.New(d.kycClient)
+	c := d.cache()
+	if d.cfg.Database.Driver == driverPostgres {
+		d.referenceRepo = d.cacheReferences(pgReferenceRepository.New(d.cluster), c)
+		d.userRepo = pgUserRepository.New(d.cluster, kycProvider)
+		d.loanRepo = d.cacheLoans(pgLoanRepository.New(d.cluster, d.referenceRepo), c)
+		d.loanApplicationRepo = pgLoanApplicationRepository.New(d.cluster)
+		d.sequenceRepo = pgSequenceRepository.New(d.cluster)
+		d.outboxRepo = pgOutboxRepository.New(d.cluster)
+		return
+	}
+
+	d.referenceRepo = d.cacheReferences(referenceRepository.New(d.cluster), c)
+	d.userRepo = userRepository.New(d.cluster, kycProvider)
+	d.loanRepo = d.cacheLoans(loanRepository.New(d.cluster, d.referenceRepo), c)
+	d.loanApplicationRepo = loanApplicationRepository.New(d.cluster)
+	d.sequenceRepo = sequenceRepository.New(d.cluster)
+	d.outboxRepo = outboxRepository.New(d.cluster)
+}
+
+// cache returns the cache of the configured driver.
+func (d *deps) cache() cache.Cache {
+	switch d.cfg.Cache.Driver {
+	case cacheRedis:
+		return d.redis
+	case cacheMemory:
+		return cache.NewLRU(d.cfg.Cache.Size)
+	}
+	return cache.Nop{}
+}
+
+// cacheReferences keeps the reference data in c until its ttl is over or it is seeded again.
+func (d *deps) cacheReferences(next port.ReferenceRepository, c cache.Cache) port.ReferenceRepository {
+	references := cached.NewReferenceRepository(next, c, d.cfg.Cache.ReferenceTTL)
+	d.events.Subscribe(domain.EventReferenceDataChanged, references.Invalidate)
+	return references
+}
+
+// cacheLoans keeps the loans read in c until their ttl is over or they change.
+func (d *deps) cacheLoans(next port.LoanRepository, c cache.Cache) port.LoanRepository {
+	loans := cached.NewLoanRepository(next, c, d.cfg.Cache.LoanTTL)
+	d.events.Subscribe(domain.EventLoanChanged, loans.Invalidate)
+	return loans
+}
+
+// migrator returns the migrator of the configured driver with its embedded migrations.
+func (d *deps) migrator() (*migrate.Migrator, error) {
+	if d.cfg.Storage == storageMemory {
+		return nil, errNoDatabase
+	}
+	if d.cfg.Database.Driver == driverPostgres {
+		return postgres.NewMigrator(d.db)
+	}
+	return mysql.NewMigrator(d.db)
+}
+
+// seedStatements returns the seed upserts in the dialect of the configured driver.
+func (d *deps) seedStatements() seed.Statements {
+	if d.cfg.Database.Driver == driverPostgres {
+		return postgres.SeedStatements
+	}
+	return mysql.SeedStatements
+}
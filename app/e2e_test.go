@@ -0,0 +1,499 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/handler"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/memory"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/service/contractnumber"
+	"github.com/mfajri11/xyz-backend-monolith/infra/ratelimit"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the api has no sign up, simulation nor payment routes yet: the scenarios register the customer in the
+// store, simulate nothing and post the payments through the loan repository like the payment callback would.
+
+var (
+	e2eNow      = time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	e2ePNG      = []byte("\x89PNG\r\n\x1a\n")
+	e2eIdentity = kycIdentity{
+		NationalID:  "3171011501950003",
+		LegalName:   "Citra Lestari",
+		BirthOfDate: "1995-01-15",
+		SalaryLower: 8000000,
+		SalaryUpper: 10000000,
+	}
+)
+
+type kycIdentity struct {
+	NationalID, LegalName, BirthOfDate string
+	SalaryLower, SalaryUpper           float64
+}
+
+// fakeKYC answers like the kyc vendor for a single identity and records the reference ids it is given.
+type fakeKYC struct {
+	identity kycIdentity
+
+	mu           sync.Mutex
+	referenceIDs []string
+}
+
+func (f *fakeKYC) record(referenceID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.referenceIDs = append(f.referenceIDs, referenceID)
+}
+
+func (f *fakeKYC) ValidateSalary(ctx context.Context, req domain.KYCValidateSalaryReq) (*domain.KYCValidateSalaryResp, error) {
+	resp := &domain.KYCValidateSalaryResp{Data: domain.KYCData{SalaryLower: "0", SalaryUper: "0"}}
+	if req.NationalID == f.identity.NationalID {
+		resp.Data.SalaryLower = strconv.FormatFloat(f.identity.SalaryLower, 'f', 2, 64)
+		resp.Data.SalaryUper = strconv.FormatFloat(f.identity.SalaryUpper, 'f', 2, 64)
+	}
+	return resp, nil
+}
+
+func (f *fakeKYC) ValidateNationalID(ctx context.Context, req domain.KYCValidateNationalIDReq) (*domain.KYCValidateNationalIDResp, error) {
+	f.record(req.ReferenceID)
+	resp := &domain.KYCValidateNationalIDResp{}
+	if req.NationalID == f.identity.NationalID {
+		resp.Data.NationalID = true
+		resp.Data.LegalName = req.LegalName == f.identity.LegalName
+		resp.Data.DateOfBirth = req.DateOfBirth == f.identity.BirthOfDate
+	}
+	return resp, nil
+}
+
+func (f *fakeKYC) ValidatePhoto(ctx context.Context, req domain.KYCValidatePhotoReq) (*domain.KYCValidatePhotoResp, error) {
+	f.record(req.ReferenceID)
+	resp := &domain.KYCValidatePhotoResp{}
+	resp.Data.Status = "invalid"
+	if req.NationalID == f.identity.NationalID {
+		resp.Data.Status = "valid"
+	}
+	return resp, nil
+}
+
+// e2e is the api assembled with in-process fakes, the caller is identified by the X-User-ID header.
+type e2e struct {
+	t       *testing.T
+	handler http.Handler
+	// services share the store of the api, they stand in for the admin tasks of the back office
+	services services
+	store    *memory.Store
+	loans    *memory.LoanRepository
+	kyc      *fakeKYC
+	// rules and kycCap are the rate limits, none until a scenario sets them.
+	rules  []handler.RateLimitRule
+	kycCap int
+}
+
+func newE2E(t *testing.T) *e2e {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	kyc := &fakeKYC{identity: e2eIdentity}
+	loans := memory.NewLoanRepository(store)
+
+	e := &e2e{t: t, store: store, loans: loans, kyc: kyc}
+	var ids int
+	deps := Dependencies{
+		UserRepository:            memory.NewUserRepository(store, memory.NewKYCProvider()),
+		LoanRepository:            loans,
+		LoanApplicationRepository: memory.NewLoanApplicationRepository(store),
+		SequenceRepository:        memory.NewSequenceRepository(store),
+		ReferenceRepository:       memory.NewReferenceRepository(store),
+		KYCProvider:               kyc,
+		Clock:                     func() time.Time { return e2eNow },
+		NewID: func() string {
+			ids++
+			return "ref-" + strconv.Itoa(ids)
+		},
+		BranchCode:  "JKT",
+		RateLimiter: ratelimit.New(ratelimit.NewMemory(), ratelimit.WithClock(func() time.Time { return e2eNow })),
+		RateLimits:  func() []handler.RateLimitRule { return e.rules },
+		KYCDailyCap: func() int { return e.kycCap },
+		Identify: func(c *gin.Context) {
+			if uid, err := strconv.ParseInt(c.GetHeader("X-User-ID"), 10, 64); err == nil {
+				c.Set("uid", uid)
+			}
+		},
+	}
+	e.handler = NewHandler(deps)
+	e.services = newServices(deps)
+
+	return e
+}
+
+// decide takes the credit decision of an application like the decide-application admin task.
+func (e *e2e) decide(id int64, req domain.DecideLoanApplicationReq) *domain.LoanApplication {
+	e.t.Helper()
+	app, err := e.services.loanApplication.Decide(context.Background(), id, req)
+	require.NoError(e.t, err)
+	return app
+}
+
+// register signs up a customer who has not been through the kyc yet.
+func (e *e2e) register(fullName string) int64 {
+	return int64(e.store.AddUser(domain.UserEntity{FullName: fullName, CreatedBy: "e2e"}))
+}
+
+// do sends the request as uid, or anonymously when uid is 0, and decodes the data of a successful
+// response into out or the problem of a failed one into problem.
+func (e *e2e) do(uid int64, method, path string, body any, out any) (status int, problem apperror.Problem) {
+	e.t.Helper()
+	rec, problem := e.send(uid, method, path, nil, body, out)
+	return rec.Code, problem
+}
+
+// send is do with the headers of the request and the whole response, e.g. to follow the entity tags.
+func (e *e2e) send(uid int64, method, path string, header http.Header, body any, out any) (rec *httptest.ResponseRecorder, problem apperror.Problem) {
+	e.t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		require.NoError(e.t, json.NewEncoder(&reqBody).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &reqBody)
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if uid != 0 {
+		req.Header.Set("X-User-ID", strconv.FormatInt(uid, 10))
+	}
+	rec = httptest.NewRecorder()
+	e.handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		require.NoError(e.t, json.Unmarshal(rec.Body.Bytes(), &problem), rec.Body.String())
+		return rec, problem
+	}
+	if out != nil {
+		resp := struct {
+			Data any `json:"data"`
+		}{Data: out}
+		require.NoError(e.t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+	}
+	return rec, problem
+}
+
+func (e *e2e) pay(loanID int64, amount float64, daysAfterStart int) {
+	e.t.Helper()
+	require.NoError(e.t, e.loans.CreateLoanPayment(context.Background(), domain.LoanPayment{
+		LoanID:  loanID,
+		Amount:  amount,
+		Date:    e2eNow.AddDate(0, 0, daysAfterStart),
+		Channel: "VA",
+	}))
+}
+
+func (e *e2e) loanSummary(uid int64) handler.LoanSummaryV1 {
+	e.t.Helper()
+	var page handler.LoanPageV1
+	status, problem := e.do(uid, http.MethodGet, "/loans", nil, &page)
+	require.Equal(e.t, http.StatusOK, status, problem.Detail)
+	require.Len(e.t, page.Loans, 1)
+	return page.Loans[0]
+}
+
+func loanRequest(identity kycIdentity, salary string) map[string]any {
+	return map[string]any{
+		"national_id":       identity.NationalID,
+		"legal_name":        identity.LegalName,
+		"birth_of_date":     identity.BirthOfDate,
+		"salary":            salary,
+		"asset_name":        "Refrigerator",
+		"amount":            100000,
+		"down_payment":      30000,
+		"loan_type_id":      3,
+		"limit_type_id":     3,
+		"tenor":             3,
+		"national_id_photo": e2ePNG,
+		"user_photo":        e2ePNG,
+	}
+}
+
+func TestE2E_Scenarios(t *testing.T) {
+	contractNumber := contractnumber.Format("JKT", 3, e2eNow, 1)
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, e *e2e)
+	}{
+		{
+			name: "Given a registered customer passing the kyc, it should take a loan from the application to the payoff",
+			run: func(t *testing.T, e *e2e) {
+				uid := e.register("Citra")
+
+				// kyc and loan application, the quotation of the otr amount stands in for the simulation
+				var app handler.LoanApplicationV1
+				status, problem := e.do(uid, http.MethodPost, "/loan", loanRequest(e2eIdentity, "9000000"), &app)
+				require.Equal(t, http.StatusOK, status, problem.Detail)
+				assert.Equal(t, string(domain.ApplicationSubmitted), app.Status)
+				assert.Equal(t, "130000.00", *app.OTRAmount)
+				assert.Equal(t, "30000.00", *app.DownPayment)
+				assert.Equal(t, "2024-03-15T10:00:00Z", *app.SubmittedAt)
+				require.NotEmpty(t, e.kyc.referenceIDs)
+				for _, referenceID := range e.kyc.referenceIDs {
+					assert.Equal(t, "ref-1", referenceID)
+				}
+
+				user, err := memory.NewUserRepository(e.store, nil).FindOneByNationalID(context.Background(), e2eIdentity.NationalID)
+				require.NoError(t, err)
+				assert.Equal(t, int(uid), user.ID)
+				assert.True(t, user.IsNationalIDValidated && user.ISSalaryValidated && user.IsPhotoValidated)
+
+				// credit decision, it creates the loan
+				decided := e.decide(app.ID, domain.DecideLoanApplicationReq{Approved: true})
+				assert.Equal(t, domain.ApplicationApproved, decided.Status)
+				assert.Equal(t, contractNumber, decided.ContractNumber.String)
+
+				var loan handler.LoanV1
+				status, problem = e.do(uid, http.MethodGet, "/loans/"+contractNumber, nil, &loan)
+				require.Equal(t, http.StatusOK, status, problem.Detail)
+				assert.Equal(t, "100000.00", loan.PrincipalAmount)
+				assert.Equal(t, "2024-03-15", *loan.StartDate)
+				assert.Equal(t, int8(3), loan.Limit.Term)
+
+				// first installment
+				e.pay(loan.ID, 33000, 30)
+				summary := e.loanSummary(uid)
+				assert.Equal(t, "33000.00", summary.PaidAmount)
+				assert.Equal(t, "67000.00", summary.OutstandingAmount)
+				assert.Equal(t, 1, summary.InstallmentsPaid)
+				assert.Equal(t, "2024-05-15", *summary.NextDueDate)
+
+				// payoff
+				e.pay(loan.ID, 33000, 60)
+				e.pay(loan.ID, 34000, 90)
+				summary = e.loanSummary(uid)
+				assert.Equal(t, "100000.00", summary.PaidAmount)
+				assert.Equal(t, "0.00", summary.OutstandingAmount)
+				assert.Equal(t, 3, summary.InstallmentsPaid)
+				assert.Equal(t, 3, summary.InstallmentsTotal)
+				assert.Nil(t, summary.NextDueDate)
+
+				var payments []handler.LoanPaymentV1
+				status, problem = e.do(uid, http.MethodGet, "/loan/"+contractNumber+"/payments", nil, &payments)
+				require.Equal(t, http.StatusOK, status, problem.Detail)
+				require.Len(t, payments, 3)
+				assert.Equal(t, "34000.00", payments[2].Amount)
+			},
+		},
+		{
+			name: "Given two payments against the same version of a loan, it should post the first and refuse the second",
+			run: func(t *testing.T, e *e2e) {
+				uid := e.register("Citra")
+				var app handler.LoanApplicationV1
+				status, problem := e.do(uid, http.MethodPost, "/loan", loanRequest(e2eIdentity, "9000000"), &app)
+				require.Equal(t, http.StatusOK, status, problem.Detail)
+				e.decide(app.ID, domain.DecideLoanApplicationReq{Approved: true})
+
+				rec, problem := e.send(uid, http.MethodGet, "/loans/"+contractNumber, nil, nil, nil)
+				require.Equal(t, http.StatusOK, rec.Code, problem.Detail)
+				etag := rec.Header().Get("ETag")
+				require.Equal(t, `"1"`, etag)
+
+				// both clients read the loan at the same version, the second one to pay is refused
+				payment := map[string]any{"amount": 33000, "channel": "VA"}
+				rec, problem = e.send(uid, http.MethodPost, "/loan/"+contractNumber+"/payments", http.Header{"If-Match": {etag}}, payment, nil)
+				require.Equal(t, http.StatusOK, rec.Code, problem.Detail)
+				assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+				rec, problem = e.send(uid, http.MethodPost, "/loan/"+contractNumber+"/payments", http.Header{"If-Match": {etag}}, payment, nil)
+				assert.Equal(t, http.StatusConflict, rec.Code)
+				assert.Equal(t, "VERSION_CONFLICT", problem.Code)
+
+				var payments []handler.LoanPaymentV1
+				status, problem = e.do(uid, http.MethodGet, "/loan/"+contractNumber+"/payments", nil, &payments)
+				require.Equal(t, http.StatusOK, status, problem.Detail)
+				require.Len(t, payments, 1)
+				assert.Equal(t, "2024-03-15", payments[0].Date[:10])
+
+				// without If-Match the payment is posted whatever the version
+				rec, problem = e.send(uid, http.MethodPost, "/loan/"+contractNumber+"/payments", nil, payment, nil)
+				require.Equal(t, http.StatusOK, rec.Code, problem.Detail)
+				assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+			},
+		},
+		{
+			name: "Given a salary outside of the verified range, it should refuse the loan and leave the customer unverified",
+			run: func(t *testing.T, e *e2e) {
+				uid := e.register("Citra")
+
+				status, problem := e.do(uid, http.MethodPost, "/loan", loanRequest(e2eIdentity, "20000000"), nil)
+				assert.Equal(t, http.StatusUnprocessableEntity, status)
+				assert.Equal(t, "KYC_SALARY_MISMATCH", problem.Code)
+
+				_, err := memory.NewUserRepository(e.store, nil).FindOneByNationalID(context.Background(), e2eIdentity.NationalID)
+				assert.ErrorIs(t, err, apperror.ErrNotFound)
+
+				var page handler.LoanPageV1
+				status, _ = e.do(uid, http.MethodGet, "/loans", nil, &page)
+				require.Equal(t, http.StatusOK, status)
+				assert.Empty(t, page.Loans)
+			},
+		},
+		{
+			name: "Given a rejected application, it should not create a loan",
+			run: func(t *testing.T, e *e2e) {
+				uid := e.register("Citra")
+
+				var app handler.LoanApplicationV1
+				status, problem := e.do(uid, http.MethodPost, "/loan", loanRequest(e2eIdentity, "9000000"), &app)
+				require.Equal(t, http.StatusOK, status, problem.Detail)
+
+				decided := e.decide(app.ID, domain.DecideLoanApplicationReq{Reason: "debt to income"})
+				assert.Equal(t, domain.ApplicationRejected, decided.Status)
+				assert.False(t, decided.ContractNumber.Valid)
+
+				status, problem = e.do(uid, http.MethodGet, "/loans/"+contractNumber, nil, nil)
+				assert.Equal(t, http.StatusNotFound, status)
+				assert.Equal(t, "NOT_FOUND", problem.Code)
+			},
+		},
+		{
+			name: "Given a customer over the rate limit of the loan route, it should answer 429 with when to retry",
+			run: func(t *testing.T, e *e2e) {
+				e.rules = []handler.RateLimitRule{{
+					Route: "POST /loan",
+					By:    handler.ByUser,
+					Rule:  ratelimit.Rule{Algorithm: ratelimit.TokenBucket, Limit: 1, Period: time.Minute},
+				}}
+				uid := e.register("Citra")
+
+				rec, problem := e.send(uid, http.MethodPost, "/loan", nil, loanRequest(e2eIdentity, "9000000"), nil)
+				require.Equal(t, http.StatusOK, rec.Code, problem.Detail)
+				assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Limit"))
+				assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+				assert.Equal(t, "60", rec.Header().Get("X-RateLimit-Reset"))
+
+				rec, problem = e.send(uid, http.MethodPost, "/loan", nil, loanRequest(e2eIdentity, "9000000"), nil)
+				assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+				assert.Equal(t, "TOO_MANY_REQUESTS", problem.Code)
+				assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+				status, problem := e.do(e.register("Budi"), http.MethodPost, "/loan", loanRequest(e2eIdentity, "9000000"), nil)
+				assert.NotEqual(t, http.StatusTooManyRequests, status, "another customer should have a limit of their own")
+				status, _ = e.do(uid, http.MethodGet, "/loans", nil, nil)
+				assert.Equal(t, http.StatusOK, status, "the other routes should not be limited")
+			},
+		},
+		{
+			name: "Given a national id failing the kyc again and again, it should stop calling the vendor at the daily cap",
+			run: func(t *testing.T, e *e2e) {
+				e.kycCap = 2
+				uid := e.register("Citra")
+
+				for range 2 {
+					status, problem := e.do(uid, http.MethodPost, "/loan", loanRequest(e2eIdentity, "20000000"), nil)
+					require.Equal(t, http.StatusUnprocessableEntity, status, problem.Detail)
+				}
+				calls := len(e.kyc.referenceIDs)
+
+				rec, problem := e.send(uid, http.MethodPost, "/loan", nil, loanRequest(e2eIdentity, "9000000"), nil)
+				assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+				assert.Equal(t, "KYC_DAILY_CAP_REACHED", problem.Code)
+				assert.Equal(t, "50400", rec.Header().Get("Retry-After"), "the cap should be over at midnight UTC")
+				assert.Len(t, e.kyc.referenceIDs, calls, "the vendor should not be called over the cap")
+			},
+		},
+		{
+			name: "Given a customer, it should not let them decide their own application",
+			run: func(t *testing.T, e *e2e) {
+				uid := e.register("Citra")
+				var app handler.LoanApplicationV1
+				status, problem := e.do(uid, http.MethodPost, "/loan", loanRequest(e2eIdentity, "9000000"), &app)
+				require.Equal(t, http.StatusOK, status, problem.Detail)
+
+				// the route is not served at all, gin answers its plain text 404
+				req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/loan-applications/%d/decision", app.ID), strings.NewReader(`{"approved":true}`))
+				req.Header.Set("X-User-ID", strconv.FormatInt(uid, 10))
+				rec := httptest.NewRecorder()
+				e.handler.ServeHTTP(rec, req)
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+
+				status, _ = e.do(uid, http.MethodGet, "/loans/"+contractNumber, nil, nil)
+				assert.Equal(t, http.StatusNotFound, status, "no loan should be created")
+			},
+		},
+		{
+			name: "Given an unknown status or loan type filter, it should refuse it as the other filters",
+			run: func(t *testing.T, e *e2e) {
+				uid := e.register("Citra")
+
+				status, problem := e.do(uid, http.MethodGet, "/loans?status=PAID", nil, nil)
+				assert.Equal(t, http.StatusUnprocessableEntity, status)
+				assert.Equal(t, "VALIDATION_FAILED", problem.Code)
+				status, _ = e.do(uid, http.MethodGet, "/loans?loan_type=BOAT", nil, nil)
+				assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+				status, _ = e.do(uid, http.MethodGet, "/loans?status=active&loan_type=CAR", nil, nil)
+				assert.Equal(t, http.StatusOK, status, "a known value should be accepted in either case")
+			},
+		},
+		{
+			name: "Given an anonymous caller, it should refuse the customer routes",
+			run: func(t *testing.T, e *e2e) {
+				status, _ := e.do(0, http.MethodPost, "/loan", loanRequest(e2eIdentity, "9000000"), nil)
+				assert.Equal(t, http.StatusBadRequest, status)
+
+				status, _ = e.do(0, http.MethodGet, "/loans", nil, nil)
+				assert.Equal(t, http.StatusBadRequest, status)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newE2E(t))
+		})
+	}
+}
+
+func TestNewHandler_Probes(t *testing.T) {
+	e := newE2E(t)
+	for _, path := range []string{"/healthz", "/readyz", "/metrics"} {
+		rec := httptest.NewRecorder()
+		e.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+}
+
+func TestNewHandler_SchemaCheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	checked := errors.New("database not reached")
+	h := NewHandler(Dependencies{
+		UserRepository:            memory.NewUserRepository(store, memory.NewKYCProvider()),
+		LoanRepository:            memory.NewLoanRepository(store),
+		LoanApplicationRepository: memory.NewLoanApplicationRepository(store),
+		SequenceRepository:        memory.NewSequenceRepository(store),
+		SchemaCheck:               func(ctx context.Context) error { return checked },
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/loans", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "the api should refuse the requests until the schema is checked")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "the probes should answer before the schema is checked")
+
+	checked = nil
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/loans", nil))
+	assert.NotEqual(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/handler"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/service/contractnumber"
+	loanService "github.com/mfajri11/xyz-backend-monolith/app/core/service/loan"
+	loanApplicationService "github.com/mfajri11/xyz-backend-monolith/app/core/service/loanapplication"
+	userService "github.com/mfajri11/xyz-backend-monolith/app/core/service/user"
+	"github.com/mfajri11/xyz-backend-monolith/infra/ratelimit"
+	"github.com/mfajri11/xyz-backend-monolith/util/health"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// Dependencies are what the api is assembled from. Serve fills them from the configuration, a test
+// fills them with fakes to run the api in process.
+type Dependencies struct {
+	UserRepository            port.UserRepository
+	LoanRepository            port.LoanRepository
+	LoanApplicationRepository port.LoanApplicationRepository
+	SequenceRepository        port.SequenceRepository
+	// ReferenceRepository names the loan types the loans created are counted by, nil counts them by id.
+	ReferenceRepository port.ReferenceRepository
+	// KYCProvider verifies the customers, nil uses the kyc provider of UserRepository.
+	KYCProvider port.KYCProvider
+	// Clock dates the submissions, the decisions and the contracts, nil uses the wall clock.
+	Clock func() time.Time
+	// NewID generates the reference id of a kyc check, nil uses a random uuid.
+	NewID func() string
+	// BranchCode prefixes the contract numbers, see config.ContractNumber.
+	BranchCode string
+	// Events receives the domain events of the services, nil drops them.
+	Events port.EventPublisher
+	// Checker answers the health probes, nil reports up without checking anything.
+	Checker *health.Checker
+	// RateLimiter counts the requests of RateLimits and the kyc checks of KYCDailyCap, nil counts nothing.
+	RateLimiter *ratelimit.Limiter
+	// RateLimits returns the rules the requests are counted by, it is called for every request so a reload
+	// applies at once.
+	RateLimits func() []handler.RateLimitRule
+	// KYCDailyCap returns the kyc checks allowed per national id and user per UTC day, 0 is no cap.
+	KYCDailyCap func() int
+	// Identify sets the id of the caller as the int64 "uid" of the gin context before the api routes
+	// run, it also keys the read your writes window of the database. the api does not authenticate its
+	// callers yet so nil leaves every caller anonymous.
+	Identify gin.HandlerFunc
+	// SchemaCheck fails while the database is not known to be migrated, the api routes answer 503 until
+	// it passes. nil checks nothing, the schema was checked at start.
+	SchemaCheck func(ctx context.Context) error
+}
+
+// services are the use cases shared by the api and the other commands.
+type services struct {
+	user            port.UserService
+	loan            port.LoanService
+	loanApplication port.LoanApplicationService
+}
+
+func newServices(deps Dependencies) services {
+	var (
+		userOpts            []userService.Option
+		loanOpts            []loanService.Option
+		loanApplicationOpts []loanApplicationService.Option
+	)
+	if deps.KYCProvider != nil {
+		userOpts = append(userOpts, userService.WithKYCProvider(deps.KYCProvider))
+	}
+	if deps.NewID != nil {
+		userOpts = append(userOpts, userService.WithIDGenerator(deps.NewID))
+	}
+	if deps.RateLimiter != nil && deps.KYCDailyCap != nil {
+		userOpts = append(userOpts, userService.WithKYCQuota(kycQuota{limiter: deps.RateLimiter, limit: deps.KYCDailyCap}))
+	}
+	if deps.Events != nil {
+		loanOpts = append(loanOpts, loanService.WithEventPublisher(deps.Events))
+		loanApplicationOpts = append(loanApplicationOpts, loanApplicationService.WithEventPublisher(deps.Events))
+	}
+	if deps.ReferenceRepository != nil {
+		loanOpts = append(loanOpts, loanService.WithReferences(deps.ReferenceRepository))
+		loanApplicationOpts = append(loanApplicationOpts, loanApplicationService.WithReferences(deps.ReferenceRepository))
+	}
+	if deps.Clock != nil {
+		loanOpts = append(loanOpts, loanService.WithClock(deps.Clock))
+		loanApplicationOpts = append(loanApplicationOpts, loanApplicationService.WithClock(deps.Clock))
+	}
+
+	return services{
+		user: userService.New(deps.UserRepository, userOpts...),
+		loan: loanService.New(deps.LoanRepository, loanOpts...),
+		loanApplication: loanApplicationService.New(deps.LoanApplicationRepository,
+			contractnumber.New(deps.SequenceRepository, deps.BranchCode), loanApplicationOpts...),
+	}
+}
+
+// NewHandler assembles the api, the probes and the metrics endpoint are served before the request
+// middlewares so they are neither traced nor logged. the credit decisions are not part of the api, the back
+// office takes them with the decide-application admin task.
+func NewHandler(deps Dependencies) http.Handler {
+	svc := newServices(deps)
+	checker := deps.Checker
+	if checker == nil {
+		checker = health.New(version(), time.Second)
+	}
+
+	loanHandler := handler.New(svc.loan, svc.user, svc.loanApplication)
+	healthHandler := handler.NewHealthHandler(checker)
+	router := gin.New()
+	// lets the services read the request context, e.g. the span and the logger, from the gin context
+	router.ContextWithFallback = true
+	router.Use(gin.Recovery())
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+	router.GET("/status", healthHandler.Status)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.Use(otelgin.Middleware(serviceName), requestid.New(), log.Middleware(), metrics.Middleware())
+	if deps.SchemaCheck != nil {
+		router.Use(handler.RequireSchema(deps.SchemaCheck))
+	}
+	if deps.Identify != nil {
+		router.Use(deps.Identify)
+	}
+	// after Identify, which the limits by user need
+	if deps.RateLimiter != nil && deps.RateLimits != nil {
+		router.Use(handler.RateLimit(deps.RateLimiter, deps.RateLimits))
+	}
+	router.POST("/loan", loanHandler.CreateLoan)
+	router.GET("/loans", loanHandler.ListLoans)
+	router.GET("/loans/:contractNumber", loanHandler.GetLoanByContractNumber)
+	router.GET("/loan/:contractNumber/payments", loanHandler.GetLoanPaymentsByContractNumber)
+	router.POST("/loan/:contractNumber/payments", loanHandler.PostLoanPayment)
+	router.POST("/loan-applications", loanHandler.CreateLoanApplication)
+	router.GET("/loan-applications/:id", loanHandler.GetLoanApplication)
+	router.PUT("/loan-applications/:id/asset", loanHandler.UpdateLoanApplicationAsset)
+	router.PUT("/loan-applications/:id/tenor", loanHandler.UpdateLoanApplicationTenor)
+	router.PUT("/loan-applications/:id/documents", loanHandler.UpdateLoanApplicationDocuments)
+	router.POST("/loan-applications/:id/submit", loanHandler.SubmitLoanApplication)
+	router.POST("/loan-applications/:id/abandon", loanHandler.AbandonLoanApplication)
+
+	return router
+}
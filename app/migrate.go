@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/migrate"
+	"github.com/mfajri11/xyz-backend-monolith/util/config"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status | goto <version>"
+
+// Migrate runs the migrate command given by args, e.g. ["down", "2"], against the configured database.
+func Migrate(opts config.Options, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("Migrate: %s", migrateUsage)
+	}
+
+	d, err := setup(opts)
+	if err != nil {
+		return fmt.Errorf("Migrate: %w", err)
+	}
+	defer d.close(context.Background())
+
+	migrator, err := d.migrator()
+	if err != nil {
+		return fmt.Errorf("Migrate: %w", err)
+	}
+
+	return runMigrate(context.Background(), migrator, args, os.Stdout)
+}
+
+func runMigrate(ctx context.Context, migrator *migrate.Migrator, args []string, w io.Writer) error {
+	var (
+		done []migrate.Migration
+		err  error
+	)
+	switch args[0] {
+	case "up":
+		done, err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("Migrate: steps must be a positive number, got %q", args[1])
+			}
+		}
+		done, err = migrator.Down(ctx, steps)
+	case "goto":
+		if len(args) < 2 {
+			return fmt.Errorf("Migrate: %s", migrateUsage)
+		}
+		version, perr := strconv.ParseInt(args[1], 10, 64)
+		if perr != nil || version < 0 {
+			return fmt.Errorf("Migrate: version must be a number, got %q", args[1])
+		}
+		done, err = migrator.Goto(ctx, version)
+	case "status":
+		return printMigrationStatus(ctx, migrator, w)
+	default:
+		return fmt.Errorf("Migrate: unknown command %q, %s", args[0], migrateUsage)
+	}
+
+	for _, m := range done {
+		fmt.Fprintf(w, "%s %04d_%s\n", args[0], m.Version, m.Name)
+	}
+	if len(done) == 0 && err == nil {
+		fmt.Fprintln(w, "no change")
+	}
+	if err != nil {
+		return fmt.Errorf("Migrate: %w", err)
+	}
+
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrate.Migrator, w io.Writer) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("Migrate: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", ""
+		if s.Applied {
+			state, appliedAt = "applied", s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Modified {
+			state = "modified"
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunMigrate_InvalidArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "Given an unknown command, it should return an error", args: []string{"sideways"}},
+		{name: "Given down with a non numeric steps, it should return an error", args: []string{"down", "two"}},
+		{name: "Given down with zero steps, it should return an error", args: []string{"down", "0"}},
+		{name: "Given goto without a version, it should return an error", args: []string{"goto"}},
+		{name: "Given goto with a negative version, it should return an error", args: []string{"goto", "-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := runMigrate(context.Background(), nil, tt.args, &out)
+			assert.Error(t, err)
+			assert.Empty(t, out.String())
+		})
+	}
+}
+
+func TestSchemaGate(t *testing.T) {
+	calls := 0
+	err := errors.New("connection refused")
+	gate := &schemaGate{check: func(ctx context.Context) error {
+		calls++
+		return err
+	}}
+
+	assert.ErrorIs(t, gate.Check(context.Background()), err, "a failing check should fail the gate")
+	err = nil
+	assert.NoError(t, gate.Check(context.Background()))
+	assert.NoError(t, gate.Check(context.Background()))
+	assert.Equal(t, 2, calls, "the check should run until it passes once")
+}
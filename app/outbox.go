@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	"github.com/mfajri11/xyz-backend-monolith/util/event"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+)
+
+// outboxEvents decodes the events recorded in the outbox by name, the other events are not recorded.
+var outboxEvents = map[string]func(payload []byte) (event.Event, error){
+	domain.EventLoanChanged:          decodeEvent[domain.LoanChanged],
+	domain.EventReferenceDataChanged: decodeEvent[domain.ReferenceDataChanged],
+}
+
+func decodeEvent[E event.Event](payload []byte) (event.Event, error) {
+	var e E
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("error decode %s: %w", e.EventName(), err)
+	}
+	return e, nil
+}
+
+// relayedKey marks the context of the events delivered by the relay, they are in the outbox already.
+type relayedKey struct{}
+
+// recordEvents records the events published on the bus in the outbox, so the worker relays them even when
+// this process goes down right after.
+func (d *deps) recordEvents() {
+	record := func(ctx context.Context, e event.Event) {
+		if ctx.Value(relayedKey{}) != nil {
+			return
+		}
+
+		payload, err := json.Marshal(e)
+		if err == nil {
+			err = d.outboxRepo.Add(ctx, domain.OutboxEvent{Name: e.EventName(), Payload: payload})
+		}
+		if err != nil {
+			// the change is stored already, the caches it leaves stale catch up at their ttl
+			log.Ctx(ctx).Warn().Err(err).Str("event", e.EventName()).Msg("event not recorded in the outbox")
+		}
+	}
+	for name := range outboxEvents {
+		d.events.Subscribe(name, record)
+	}
+}
+
+// relayOutbox publishes the pending events of outbox on bus, at most batch per run, and marks them published.
+// an event is relayed at least once, a run failing before marking them relays them again on the next one.
+func relayOutbox(outbox port.OutboxRepository, bus port.EventPublisher, batch int, now func() time.Time) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		events, err := outbox.Pending(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("relayOutbox: %w", err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		relayed := context.WithValue(ctx, relayedKey{}, true)
+		ids := make([]int64, 0, len(events))
+		for _, recorded := range events {
+			ids = append(ids, recorded.ID)
+
+			decode, ok := outboxEvents[recorded.Name]
+			if !ok {
+				// recorded by a newer build, it is skipped rather than holding up the others
+				log.Ctx(ctx).Warn().Int64("id", recorded.ID).Str("event", recorded.Name).Msg("unknown event skipped")
+				continue
+			}
+			e, err := decode(recorded.Payload)
+			if err != nil {
+				log.Ctx(ctx).Warn().Err(err).Int64("id", recorded.ID).Msg("invalid event skipped")
+				continue
+			}
+			bus.Publish(relayed, e)
+		}
+
+		if err := outbox.MarkPublished(ctx, ids, now()); err != nil {
+			return fmt.Errorf("relayOutbox: %w", err)
+		}
+		log.Ctx(ctx).Debug().Int("events", len(ids)).Msg("outbox relayed")
+		return nil
+	}
+}
+
+// purgeOutbox deletes the events published longer than retention ago.
+func purgeOutbox(outbox port.OutboxRepository, retention time.Duration, now func() time.Time) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := outbox.PurgePublished(ctx, now().Add(-retention))
+		if err != nil {
+			return fmt.Errorf("purgeOutbox: %w", err)
+		}
+		if n > 0 {
+			log.Ctx(ctx).Info().Int64("events", n).Msg("outbox purged")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/adapter/handler"
+	"github.com/mfajri11/xyz-backend-monolith/infra/ratelimit"
+	"github.com/mfajri11/xyz-backend-monolith/util/apperror"
+	"github.com/mfajri11/xyz-backend-monolith/util/config"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+)
+
+// rateLimitRedis keeps the rate limits in the redis of the cache, see config.RateLimit.Backend.
+const rateLimitRedis = "redis"
+
+// rateLimitStore returns the store of the configured backend.
+func (d *deps) rateLimitStore() ratelimit.Store {
+	if d.cfg.RateLimit.Backend == rateLimitRedis {
+		return d.redis
+	}
+	return ratelimit.NewMemory()
+}
+
+// rateLimits returns the rules of the configuration last loaded or reloaded.
+func rateLimits() []handler.RateLimitRule {
+	return rateLimitRules(config.Get().RateLimit.Rules)
+}
+
+func rateLimitRules(rules []config.RateLimitRule) []handler.RateLimitRule {
+	limits := make([]handler.RateLimitRule, len(rules))
+	for i, rule := range rules {
+		limits[i] = handler.RateLimitRule{
+			Route: rule.Route,
+			By:    rule.By,
+			Rule: ratelimit.Rule{
+				Algorithm: rule.Algorithm,
+				Limit:     rule.Limit,
+				Period:    rule.Period,
+				Burst:     rule.Burst,
+			},
+		}
+	}
+	return limits
+}
+
+// kycDailyCap returns the cap of the configuration last loaded or reloaded.
+func kycDailyCap() int {
+	return config.Get().RateLimit.KYCDailyCap
+}
+
+// kycQuota caps the kyc checks of a national id by a user per UTC day, whichever instance runs them. the
+// cap is per user so nobody can use up the checks of a national id that is not theirs.
+type kycQuota struct {
+	limiter *ratelimit.Limiter
+	// limit returns the checks allowed per day, 0 is no cap.
+	limit func() int
+}
+
+func (q kycQuota) Take(ctx context.Context, uid int64, nationalID string) error {
+	limit := q.limit()
+	if limit == 0 {
+		return nil
+	}
+
+	decision, err := q.limiter.Allow(ctx, kycQuotaKey(uid, nationalID), ratelimit.Rule{
+		Algorithm: ratelimit.FixedWindow,
+		Limit:     limit,
+		Period:    24 * time.Hour,
+	})
+	if err != nil {
+		// the cap guards the bill, it is not worth refusing the customer for
+		log.Ctx(ctx).Warn().Err(err).Msg("kyc daily cap skipped")
+		return nil
+	}
+	if !decision.Allowed {
+		metrics.IncRateLimited("kyc", "nik")
+		err = fmt.Errorf("Take: %w", &ratelimit.Error{Decision: decision})
+		return apperror.WrapError(err, apperror.ErrKYCDailyCapReached)
+	}
+	return nil
+}
+
+// kycQuotaKey returns the key of the kyc checks of nationalID by the user uid, the national id is hashed.
+func kycQuotaKey(uid int64, nationalID string) string {
+	return fmt.Sprintf("kyc:%d:%s", uid, ratelimit.Hash(nationalID))
+}
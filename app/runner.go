@@ -1,36 +1,97 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/gin-gonic/gin"
-	"github.com/mfajri11/xyz-backend-monolith/app/adapter/handler"
-	loanRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/loan"
-	userRepository "github.com/mfajri11/xyz-backend-monolith/app/adapter/repositories/user"
-	loanService "github.com/mfajri11/xyz-backend-monolith/app/core/service/loan"
-	userService "github.com/mfajri11/xyz-backend-monolith/app/core/service/user"
-	"github.com/mfajri11/xyz-backend-monolith/infra/db/mysql"
 	"github.com/mfajri11/xyz-backend-monolith/util/config"
-	uhttp "github.com/mfajri11/xyz-backend-monolith/util/http"
+	"github.com/mfajri11/xyz-backend-monolith/util/health"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
 )
 
-func Run() error {
+// Serve loads the configuration and serves the api until SIGINT or SIGTERM.
+func Serve(opts config.Options) error {
+	d, err := setup(opts)
+	if err != nil {
+		return fmt.Errorf("Serve: %w", err)
+	}
+	cfg := d.cfg
 
-	cfg := config.Get()
+	var checks []health.Check
+	var schema *schemaGate
+	if cfg.Database.LazyConnect && d.db != nil {
+		// checking connects, the lazy connect puts it off to the first request or probe
+		schema = &schemaGate{check: d.checkSchema}
+		checks = append(checks, health.Check{Name: "schema", Critical: true, Probe: func(ctx context.Context) (map[string]interface{}, error) {
+			return nil, schema.Check(ctx)
+		}})
+	} else if err := d.checkSchema(context.Background()); err != nil {
+		d.close(context.Background())
+		return fmt.Errorf("Serve: refuse to serve: %w", err)
+	}
 
-	db := mysql.MustNew(mysql.DataSource(cfg.Database.Username, cfg.Database.Password, cfg.Database.Host, cfg.Database.Port, cfg.Database.DataBaseName), mysql.WithMaxIdleConns(cfg.Database.IdleConnection), mysql.WithMaxOpenConns(cfg.Database.OpenConnection), mysql.WithMaxLifetimeConn(cfg.Database.ConnectionMaxLifeTime))
+	checker := health.New(version(), cfg.Health.Timeout, append(checks, d.healthChecks()...)...)
 
-	kycClient := uhttp.NewClient(cfg.KYCClient.BaseURL, cfg.KYCClient.APIKey, cfg.KYCClient.APPID)
-	userRepo := userRepository.New(db, kycClient)
-	loanRepo := loanRepository.New(db)
+	deps := d.dependencies(checker)
+	if schema != nil {
+		deps.SchemaCheck = schema.Check
+	}
+	router := NewHandler(deps)
 
-	userSvc := userService.New(userRepo)
-	loanSerice := loanService.New(loanRepo)
+	srv := newServer(cfg.Server, router)
+	l, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		d.close(context.Background())
+		return fmt.Errorf("Serve: error listen on %s: %w", srv.Addr, err)
+	}
 
-	loanHandler := handler.New(loanSerice, userSvc)
-	router := gin.Default()
-	router.POST("/loan", loanHandler.CreateLoan)
-	router.GET("/loans/:contractNumber", loanHandler.GetLoanByContractNumber)
-	router.GET("/loan/:contractNumber/payments", loanHandler.GetLoanPaymentsByContractNumber)
-	return router.Run(fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go watchConfig(ctx, opts)
+	if d.cluster != nil {
+		go d.cluster.Run(ctx, cfg.Database.ReplicaCheckInterval)
+	}
+
+	drain := func() {
+		checker.Drain()
+		time.Sleep(cfg.Server.DrainDelay)
+	}
+	return serve(ctx, srv, l, drain, cfg.Server.ShutDownTimeout, d.closers...)
+}
+
+// healthChecks checks the dependencies of d.
+func (d *deps) healthChecks() []health.Check {
+	var checks []health.Check
+	if d.db != nil {
+		checks = append(checks, health.DBCheck(d.cfg.Database.Driver, d.db, d.cfg.Health.PoolSaturation))
+		for i, replica := range d.cfg.Database.Replicas {
+			checks = append(checks, health.ReplicaCheck(d.cfg.Database.Driver+" replica "+replica, func() (time.Duration, error) {
+				state := d.cluster.Replicas()[i]
+				return state.Lag, state.Err
+			}))
+		}
+	}
+	if d.redis != nil {
+		checks = append(checks, health.CacheCheck("cache", d.redis.Ping))
+	}
+	return append(checks, health.BreakerCheck("kyc", func() string { return string(d.kycClient.BreakerState()) }))
+}
+
+// watchConfig applies the reloadable settings of the configuration file until ctx is done.
+func watchConfig(ctx context.Context, opts config.Options) {
+	config.Watch(ctx, opts,
+		func(cfg *config.AppConfig) {
+			if err := log.SetLevel(cfg.Log.Level); err != nil {
+				log.Error(err, "error apply reloaded log level")
+			}
+			log.Info("configuration reloaded")
+		},
+		func(paths []string) { log.Warn("configuration changes need a restart: %v", paths) },
+		func(err error) { log.Error(err, "error reload configuration") },
+	)
 }
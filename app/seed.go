@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/seed"
+	"github.com/mfajri11/xyz-backend-monolith/util/config"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+)
+
+// Seed upserts the reference data, and the demo users with --demo.
+func Seed(opts config.Options, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	demo := fs.Bool("demo", false, "also seed the demo users, never use it in production")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("Seed: %w", err)
+	}
+
+	d, err := setup(opts)
+	if err != nil {
+		return fmt.Errorf("Seed: %w", err)
+	}
+	defer d.close(context.Background())
+
+	if d.db == nil {
+		return fmt.Errorf("Seed: %w, it starts seeded", errNoDatabase)
+	}
+
+	ctx := context.Background()
+	if err := d.checkSchema(ctx); err != nil {
+		return fmt.Errorf("Seed: %w", err)
+	}
+
+	if err := seed.Reference(ctx, d.db, d.seedStatements()); err != nil {
+		return fmt.Errorf("Seed: %w", err)
+	}
+	// the running servers see it through the redis cache only, a memory cache serves the old data until
+	// its ttl, which is why it is for a single instance
+	d.events.Publish(ctx, domain.ReferenceDataChanged{})
+	log.Info("reference data seeded")
+
+	if *demo {
+		if err := seed.Demo(ctx, d.db, d.seedStatements()); err != nil {
+			return fmt.Errorf("Seed: %w", err)
+		}
+		log.Info("demo users seeded")
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/util/config"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+)
+
+// closer releases a resource once the server stopped accepting requests, e.g. stopping a background worker,
+// flushing the outbox relay or closing the database.
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+func newServer(cfg config.Server, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+	}
+}
+
+// serve runs srv on l until ctx is done. it then calls drain, which should stop new traffic from coming,
+// drains the in-flight requests and runs closers in order. the shutdown, drain included, has to finish within
+// shutdownTimeout.
+func serve(ctx context.Context, srv *http.Server, l net.Listener, drain func(), shutdownTimeout time.Duration, closers ...closer) error {
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info("server listening on %s", l.Addr())
+		errCh <- srv.Serve(l)
+	}()
+
+	var serveErr error
+	var shutdownCtx context.Context
+	var cancel context.CancelFunc
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			serveErr = fmt.Errorf("serve: error serve http: %w", err)
+		}
+		shutdownCtx, cancel = context.WithTimeout(context.Background(), shutdownTimeout)
+	case <-ctx.Done():
+		log.Info("shutting down server")
+		shutdownCtx, cancel = context.WithTimeout(context.Background(), shutdownTimeout)
+		if drain != nil {
+			drain()
+		}
+	}
+	defer cancel()
+
+	errs := []error{serveErr}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("serve: error drain in-flight requests: %w", err))
+	}
+	for _, c := range closers {
+		if err := c.close(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("serve: error close %s: %w", c.name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	log.Info("server stopped")
+	return nil
+}
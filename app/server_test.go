@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServe(t *testing.T) {
+	tests := []struct {
+		name            string
+		handlerDelay    time.Duration
+		drainDelay      time.Duration
+		shutdownTimeout time.Duration
+		wantStatus      int
+		wantErr         bool
+	}{
+		{
+			name:            "Given an in-flight request when shutting down, it should finish the request before closing",
+			handlerDelay:    100 * time.Millisecond,
+			shutdownTimeout: time.Second,
+			wantStatus:      http.StatusOK,
+		},
+		{
+			name:            "Given a request slower than the shutdown timeout, it should return an error",
+			handlerDelay:    500 * time.Millisecond,
+			shutdownTimeout: 50 * time.Millisecond,
+			wantStatus:      http.StatusOK,
+			wantErr:         true,
+		},
+		{
+			name:            "Given a drain delay, it should count it in the shutdown timeout",
+			handlerDelay:    300 * time.Millisecond,
+			drainDelay:      200 * time.Millisecond,
+			shutdownTimeout: 250 * time.Millisecond,
+			wantStatus:      http.StatusOK,
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				time.Sleep(tt.handlerDelay)
+				io.WriteString(w, "ok")
+			})
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			var closed []string
+			drained := false
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() {
+				done <- serve(ctx, &http.Server{Handler: mux}, l, func() { time.Sleep(tt.drainDelay); drained = true }, tt.shutdownTimeout,
+					closer{name: "worker", close: func(context.Context) error { closed = append(closed, "worker"); return nil }},
+					closer{name: "database", close: func(context.Context) error { closed = append(closed, "database"); return nil }},
+				)
+			}()
+
+			respCh := make(chan *http.Response, 1)
+			go func() {
+				resp, err := http.Get("http://" + l.Addr().String())
+				if err != nil {
+					respCh <- nil
+					return
+				}
+				respCh <- resp
+			}()
+
+			<-started
+			cancel()
+			err = <-done
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.True(t, drained)
+			assert.Equal(t, []string{"worker", "database"}, closed)
+
+			resp := <-respCh
+			require.NotNil(t, resp)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			resp.Body.Close()
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package app
+
+import "runtime/debug"
+
+// Version is the build version, set at link time with -ldflags "-X github.com/mfajri11/xyz-backend-monolith/app.Version=v1.2.3".
+// it falls back to the vcs revision stamped by the go toolchain.
+var Version = ""
+
+func version() string {
+	if Version != "" {
+		return Version
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return "dev"
+}
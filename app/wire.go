@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/port"
+	"github.com/mfajri11/xyz-backend-monolith/infra/cache"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/infra/ratelimit"
+	"github.com/mfajri11/xyz-backend-monolith/util/config"
+	"github.com/mfajri11/xyz-backend-monolith/util/event"
+	"github.com/mfajri11/xyz-backend-monolith/util/health"
+	uhttp "github.com/mfajri11/xyz-backend-monolith/util/http"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/tracing"
+)
+
+const serviceName = "xyz-backend-monolith"
+
+// deps is the wiring shared by every command, so a one-off admin task goes through
+// the same services, and the same rules, as the api.
+type deps struct {
+	cfg *config.AppConfig
+	// db is the primary of cluster, both are nil with the memory storage.
+	db        *sql.DB
+	cluster   *infradb.Cluster
+	kycClient *uhttp.HTTPClient
+	// events delivers the domain events, e.g. to the caches of the repositories.
+	events *event.Bus
+	// redis is shared by the redis cache driver and the redis rate limit backend, nil when neither is used.
+	redis *cache.Redis
+	// limiter counts the requests and the kyc checks against their limits.
+	limiter *ratelimit.Limiter
+
+	userRepo            port.UserRepository
+	loanRepo            port.LoanRepository
+	loanApplicationRepo port.LoanApplicationRepository
+	sequenceRepo        port.SequenceRepository
+	referenceRepo       port.ReferenceRepository
+	// outboxRepo records the domain events for the worker to relay, nil with the memory storage.
+	outboxRepo port.OutboxRepository
+
+	services
+
+	// closers release the dependencies in order, the database goes after the others
+	// as they may still need it, tracing and logs are flushed last.
+	closers []closer
+}
+
+// setup loads the configuration, initializes the logs and tracing, connects to the database
+// and wires the repositories and services.
+func setup(opts config.Options) (*deps, error) {
+	cfg, err := config.Load(opts)
+	if err != nil {
+		return nil, fmt.Errorf("setup: error load configuration: %w", err)
+	}
+
+	if err := log.Init(log.Config{
+		Level:       cfg.Log.Level,
+		Format:      cfg.Log.Format,
+		Output:      cfg.Log.Output,
+		SampleEvery: cfg.Log.SampleEvery,
+	}); err != nil {
+		return nil, fmt.Errorf("setup: error init log: %w", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		ServiceName: serviceName,
+		Version:     version(),
+		Exporter:    cfg.Tracing.Exporter,
+		FilePath:    cfg.Tracing.FilePath,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("setup: error init tracing: %w", err)
+	}
+
+	d := &deps{
+		cfg:    cfg,
+		events: event.NewBus(),
+		kycClient: uhttp.NewClient(cfg.KYCClient.BaseURL, cfg.KYCClient.APIKey, cfg.KYCClient.APPID).
+			WithBreaker(cfg.KYCClient.BreakerThreshold, cfg.KYCClient.BreakerCooldown),
+	}
+	if cfg.Storage != storageMemory {
+		cluster, err := connectCluster(context.Background(), cfg.Database)
+		if err != nil {
+			shutdownTracing(context.Background())
+			log.Close()
+			return nil, fmt.Errorf("setup: error connect database: %w", err)
+		}
+		d.cluster, d.db = cluster, cluster.Primary().DB
+		d.closers = append(d.closers, closer{name: "database", close: func(context.Context) error { return cluster.Close() }})
+	}
+	if cfg.Storage != storageMemory && cfg.Cache.Driver == cacheRedis || cfg.RateLimit.Backend == rateLimitRedis {
+		d.redis = cache.NewRedis(cfg.Cache.RedisAddr, cache.WithRedisPassword(cfg.Cache.RedisPassword), cache.WithRedisDB(cfg.Cache.RedisDB),
+			cache.WithRedisTimeout(cfg.Cache.RedisTimeout))
+		d.closers = append(d.closers, closer{name: "cache", close: func(context.Context) error { return d.redis.Close() }})
+	}
+	d.limiter = ratelimit.New(d.rateLimitStore())
+	d.closers = append(d.closers,
+		closer{name: "tracing", close: shutdownTracing},
+		closer{name: "log", close: func(context.Context) error { return log.Close() }},
+	)
+
+	d.wireRepositories()
+	if d.outboxRepo != nil {
+		d.recordEvents()
+	}
+	d.services = newServices(d.dependencies(nil))
+
+	return d, nil
+}
+
+// dependencies returns the wired repositories as the dependencies of the api.
+func (d *deps) dependencies(checker *health.Checker) Dependencies {
+	return Dependencies{
+		UserRepository:            d.userRepo,
+		LoanRepository:            d.loanRepo,
+		LoanApplicationRepository: d.loanApplicationRepo,
+		SequenceRepository:        d.sequenceRepo,
+		ReferenceRepository:       d.referenceRepo,
+		BranchCode:                d.cfg.ContractNumber.BranchCode,
+		Events:                    d.events,
+		Checker:                   checker,
+		RateLimiter:               d.limiter,
+		RateLimits:                rateLimits,
+		KYCDailyCap:               kycDailyCap,
+	}
+}
+
+// checkSchema fails when the database is not migrated to the version this build expects,
+// the memory storage has no schema to check. it connects to the database, the lazy connect of
+// the api puts it off with schemaGate.
+func (d *deps) checkSchema(ctx context.Context) error {
+	if d.cfg.Storage == storageMemory {
+		return nil
+	}
+	migrator, err := d.migrator()
+	if err != nil {
+		return fmt.Errorf("checkSchema: %w", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		return fmt.Errorf("checkSchema: run the migrate command first: %w", err)
+	}
+	return nil
+}
+
+// schemaGate runs check until it passes once, on the first request or readiness probe of an api whose
+// database connects lazily. the api is not ready and refuses the requests until then.
+type schemaGate struct {
+	check  func(ctx context.Context) error
+	mu     sync.Mutex
+	passed atomic.Bool
+}
+
+func (g *schemaGate) Check(ctx context.Context) error {
+	if g.passed.Load() {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.passed.Load() {
+		return nil
+	}
+	if err := g.check(ctx); err != nil {
+		return err
+	}
+	g.passed.Store(true)
+	log.Ctx(ctx).Info().Msg("schema checked on first connect")
+	return nil
+}
+
+// close runs the closers in order, it is used by the commands that do not serve.
+func (d *deps) close(ctx context.Context) error {
+	var errs []error
+	for _, c := range d.closers {
+		if err := c.close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("close: error close %s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
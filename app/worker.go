@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/util/config"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/tracing"
+)
+
+// job is a background task run by the worker every interval, e.g. the outbox relay or a scheduler.
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+	// flush runs the job once more when the worker stops, e.g. the relay so the events recorded since its
+	// last tick do not wait for the next worker.
+	flush bool
+}
+
+// jobs lists the background tasks of the worker, they run outside the request path of the api
+// so a slow or failing job never affects the requests. the memory storage has none, its events
+// never leave the process.
+func (d *deps) jobs() []job {
+	if d.outboxRepo == nil {
+		return nil
+	}
+
+	cfg := d.cfg.Worker
+	return []job{
+		{name: "outbox-relay", interval: cfg.RelayInterval, run: relayOutbox(d.outboxRepo, d.events, cfg.RelayBatch, time.Now), flush: true},
+		{name: "outbox-purge", interval: cfg.PurgeInterval, run: purgeOutbox(d.outboxRepo, cfg.OutboxRetention, time.Now)},
+	}
+}
+
+// Worker runs the background jobs until SIGINT or SIGTERM.
+func Worker(opts config.Options) error {
+	d, err := setup(opts)
+	if err != nil {
+		return fmt.Errorf("Worker: %w", err)
+	}
+	defer d.close(context.Background())
+
+	if err := d.checkSchema(context.Background()); err != nil {
+		return fmt.Errorf("Worker: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go watchConfig(ctx, opts)
+
+	jobs := d.jobs()
+	log.Info("worker started with %d job(s)", len(jobs))
+	runJobs(ctx, jobs, d.cfg.Server.ShutDownTimeout)
+	log.Info("worker stopped")
+
+	return nil
+}
+
+// runJobs runs every job on its own ticker until ctx is done, then waits for the running ones to return and
+// runs the jobs to flush once more, within flushTimeout. a failing run is logged and retried on the next tick.
+func runJobs(ctx context.Context, jobs []job, flushTimeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j job) {
+			defer wg.Done()
+
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					runJob(ctx, j)
+				}
+			}
+		}(j)
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+	defer cancel()
+	for _, j := range jobs {
+		if j.flush {
+			runJob(flushCtx, j)
+		}
+	}
+}
+
+func runJob(ctx context.Context, j job) {
+	var err error
+	ctx, span := tracing.Start(ctx, "job."+j.name)
+	defer func() { tracing.End(span, err) }()
+
+	start := time.Now()
+	if err = j.run(ctx); err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("job", j.name).Msg("job failed")
+		return
+	}
+	log.Ctx(ctx).Debug().Str("job", j.name).Dur("duration", time.Since(start)).Msg("job done")
+}
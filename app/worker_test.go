@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/app/core/domain"
+	"github.com/mfajri11/xyz-backend-monolith/util/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunJobs(t *testing.T) {
+	var ok, failing, flushed atomic.Int32
+	var flushLive atomic.Bool
+	jobs := []job{
+		{name: "ok", interval: 10 * time.Millisecond, run: func(context.Context) error { ok.Add(1); return nil }},
+		{name: "failing", interval: 10 * time.Millisecond, run: func(context.Context) error { failing.Add(1); return errors.New("boom") }},
+		{name: "flushed", interval: time.Hour, flush: true, run: func(ctx context.Context) error {
+			flushed.Add(1)
+			flushLive.Store(ctx.Err() == nil)
+			return nil
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		runJobs(ctx, jobs, time.Second)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runJobs did not return after the context was done")
+	}
+	assert.GreaterOrEqual(t, ok.Load(), int32(2), "a job should run on every tick")
+	assert.GreaterOrEqual(t, failing.Load(), int32(2), "a failing job should be retried on the next tick")
+	assert.Equal(t, int32(1), flushed.Load(), "a job to flush should run once more on stop")
+	assert.True(t, flushLive.Load(), "the flush should not run with the done context")
+}
+
+// fakeOutbox keeps the events in memory, published or not.
+type fakeOutbox struct {
+	events      []domain.OutboxEvent
+	purgeBefore time.Time
+}
+
+func (f *fakeOutbox) Add(ctx context.Context, e domain.OutboxEvent) error {
+	e.ID = int64(len(f.events) + 1)
+	f.events = append(f.events, e)
+	return nil
+}
+
+func (f *fakeOutbox) Pending(ctx context.Context, limit int) ([]domain.OutboxEvent, error) {
+	var pending []domain.OutboxEvent
+	for _, e := range f.events {
+		if !e.PublishedAt.Valid && len(pending) < limit {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (f *fakeOutbox) MarkPublished(ctx context.Context, ids []int64, at time.Time) error {
+	for _, id := range ids {
+		f.events[id-1].PublishedAt.Time, f.events[id-1].PublishedAt.Valid = at, true
+	}
+	return nil
+}
+
+func (f *fakeOutbox) PurgePublished(ctx context.Context, before time.Time) (int64, error) {
+	f.purgeBefore = before
+	return 0, nil
+}
+
+func TestRelayOutbox(t *testing.T) {
+	now := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	outbox := &fakeOutbox{}
+	d := &deps{events: event.NewBus(), outboxRepo: outbox}
+	var got []event.Event
+	d.events.Subscribe(domain.EventLoanChanged, func(ctx context.Context, e event.Event) { got = append(got, e) })
+	d.events.Subscribe(domain.EventReferenceDataChanged, func(ctx context.Context, e event.Event) { got = append(got, e) })
+	d.recordEvents()
+
+	d.events.Publish(context.Background(), domain.LoanChanged{UserID: 1, ContractNumber: "XYZ-01-20210101-000001"})
+	d.events.Publish(context.Background(), domain.ReferenceDataChanged{})
+	require.NoError(t, outbox.Add(context.Background(), domain.OutboxEvent{Name: "loan.archived", Payload: []byte(`{}`)}))
+	require.Len(t, outbox.events, 3, "the events published should be recorded")
+	got = nil
+
+	relay := relayOutbox(outbox, d.events, 2, func() time.Time { return now })
+	require.NoError(t, relay(context.Background()))
+	require.NoError(t, relay(context.Background()))
+
+	assert.Equal(t, []event.Event{domain.LoanChanged{UserID: 1, ContractNumber: "XYZ-01-20210101-000001"}, domain.ReferenceDataChanged{}}, got,
+		"the recorded events should be published again in order")
+	assert.Len(t, outbox.events, 3, "the relayed events should not be recorded again")
+	for _, e := range outbox.events {
+		assert.Equal(t, now, e.PublishedAt.Time, "every event should be marked published, the unknown ones too")
+	}
+}
+
+func TestPurgeOutbox(t *testing.T) {
+	now := time.Date(2021, 1, 8, 10, 0, 0, 0, time.UTC)
+	outbox := &fakeOutbox{}
+
+	require.NoError(t, purgeOutbox(outbox, 7*24*time.Hour, func() time.Time { return now })(context.Background()))
+	assert.Equal(t, time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), outbox.purgeBefore)
+}
@@ -0,0 +1,122 @@
+// Package cache keeps the results of slow reads, e.g. the reference data and the hot loans, in memory or in
+// a redis shared by every instance. a cache is best effort: when it fails the read goes to its source.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache stores values by key until their ttl is over, Get reports a missing or expired key as a miss.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Loader reads the values of type T through a cache, the concurrent misses of a key share a single load
+// so an expired hot key does not stampede its source.
+type Loader[T any] struct {
+	cache Cache
+	name  string
+	ttl   time.Duration
+	group singleflight.Group
+}
+
+// NewLoader returns a loader keeping its values in cache for ttl, name prefixes its keys and labels its
+// metrics.
+func NewLoader[T any](cache Cache, name string, ttl time.Duration) *Loader[T] {
+	return &Loader[T]{
+		cache: cache,
+		name:  name,
+		ttl:   ttl,
+	}
+}
+
+// Key returns the key of parts in the cache, e.g. loan:1:JKT-01-20240315-0000001.
+func (l *Loader[T]) Key(parts ...string) string {
+	key := l.name
+	for _, part := range parts {
+		key += ":" + part
+	}
+	return key
+}
+
+// Get returns the value of key, from the cache or else from load. an error of load is returned and not
+// cached, e.g. a not found is read again next time.
+func (l *Loader[T]) Get(ctx context.Context, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	if value, ok := l.lookup(ctx, key); ok {
+		metrics.IncCacheLookup(l.name, true)
+		return value, nil
+	}
+	metrics.IncCacheLookup(l.name, false)
+
+	v, err, _ := l.group.Do(key, func() (any, error) {
+		// the load outlives the caller that started it when others wait for it, it must not be cut by
+		// the caller giving up
+		ctx := context.WithoutCancel(ctx)
+		value, err := load(ctx)
+		if err != nil {
+			return value, err
+		}
+		l.store(ctx, key, value)
+		return value, nil
+	})
+	value, _ := v.(T)
+	return value, err
+}
+
+// Invalidate removes keys so their next read loads them again.
+func (l *Loader[T]) Invalidate(ctx context.Context, keys ...string) {
+	if err := l.cache.Delete(ctx, keys...); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("cache", l.name).Strs("keys", keys).Msg("cache invalidation failed")
+	}
+}
+
+func (l *Loader[T]) lookup(ctx context.Context, key string) (T, bool) {
+	var value T
+	b, ok, err := l.cache.Get(ctx, key)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("cache", l.name).Str("key", key).Msg("cache read failed")
+		return value, false
+	}
+	if !ok {
+		return value, false
+	}
+	if err := json.Unmarshal(b, &value); err != nil {
+		// e.g. a value written by a release with another shape, it is replaced by the load
+		log.Ctx(ctx).Warn().Err(err).Str("cache", l.name).Str("key", key).Msg("cache value unreadable")
+		return value, false
+	}
+	return value, true
+}
+
+func (l *Loader[T]) store(ctx context.Context, key string, value T) {
+	b, err := json.Marshal(value)
+	if err == nil {
+		err = l.cache.Set(ctx, key, b, l.ttl)
+	}
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("cache", l.name).Str("key", key).Msg("cache write failed")
+	}
+}
+
+// Nop caches nothing, every read goes to its source.
+type Nop struct{}
+
+func (Nop) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (Nop) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (Nop) Delete(ctx context.Context, keys ...string) error {
+	return nil
+}
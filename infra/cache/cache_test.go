@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type loan struct {
+	ID             int64  `json:"id"`
+	ContractNumber string `json:"contract_number"`
+}
+
+// brokenCache fails every call, like a redis that is down.
+type brokenCache struct{}
+
+func (brokenCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (brokenCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (brokenCache) Delete(ctx context.Context, keys ...string) error {
+	return errors.New("connection refused")
+}
+
+func TestLoader_Get(t *testing.T) {
+	want := loan{ID: 1, ContractNumber: "JKT-01-20240315-0000001"}
+
+	tests := []struct {
+		name      string
+		cache     Cache
+		loadErr   error
+		wantLoads int
+		wantErr   bool
+	}{
+		{
+			name:      "Given a cache, it should load once and read the cache afterwards",
+			cache:     NewLRU(10),
+			wantLoads: 1,
+		},
+		{
+			name:      "Given a failing load, it should not cache the error",
+			cache:     NewLRU(10),
+			loadErr:   errors.New("not found"),
+			wantLoads: 2,
+			wantErr:   true,
+		},
+		{
+			name:      "Given a failing cache, it should load every time",
+			cache:     brokenCache{},
+			wantLoads: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoader[loan](tt.cache, "loan", time.Minute)
+			loads := 0
+			load := func(ctx context.Context) (loan, error) {
+				loads++
+				return want, tt.loadErr
+			}
+
+			for range 2 {
+				got, err := l.Get(context.Background(), l.Key("1", want.ContractNumber), load)
+				if tt.wantErr {
+					assert.Error(t, err)
+					continue
+				}
+				require.NoError(t, err)
+				assert.Equal(t, want, got)
+			}
+			assert.Equal(t, tt.wantLoads, loads)
+		})
+	}
+}
+
+func TestLoader_Get_Singleflight(t *testing.T) {
+	l := NewLoader[loan](NewLRU(10), "loan", time.Minute)
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (loan, error) {
+		loads.Add(1)
+		<-release
+		return loan{ID: 1}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := l.Get(context.Background(), l.Key("1"), load)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), got.ID)
+		}()
+	}
+	// let the readers pile up on the missing key before the load returns
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load(), "the concurrent misses should share one load")
+}
+
+func TestLoader_Invalidate(t *testing.T) {
+	c := NewLRU(10)
+	l := NewLoader[loan](c, "loan", time.Minute)
+	key := l.Key("1", "JKT-01-20240315-0000001")
+	require.NoError(t, c.Set(context.Background(), key, []byte(`{"id":1}`), time.Minute))
+	require.NoError(t, c.Set(context.Background(), "loan:unreadable", []byte(`{`), time.Minute))
+
+	l.Invalidate(context.Background(), key)
+	_, ok, _ := c.Get(context.Background(), key)
+	assert.False(t, ok)
+
+	got, err := l.Get(context.Background(), "loan:unreadable", func(ctx context.Context) (loan, error) { return loan{ID: 2}, nil })
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), got.ID, "an unreadable value should be loaded again")
+}
@@ -0,0 +1,289 @@
+// Package cachetest provides an in-process stand-in of redis, so the redis cache and the code using it
+// can be tested without a server.
+package cachetest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type value struct {
+	data    string
+	expires time.Time // zero is never
+}
+
+// RedisServer speaks enough of the redis protocol for cache.Redis: PING, AUTH, SELECT, GET, SET with
+// EX or PX, DEL, and the transactions of WATCH, UNWATCH, MULTI, EXEC and DISCARD. the logical databases
+// share one keyspace.
+type RedisServer struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]value
+	versions map[string]uint64 // bumped on every write of a key, for WATCH
+	commands []string
+	now      func() time.Time
+}
+
+// NewRedisServer starts a server on a random local port, it is stopped with the test. password, when not
+// empty, has to be sent with AUTH before any other command.
+func NewRedisServer(t testing.TB, password string) *RedisServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("NewRedisServer: error listen: %v", err)
+	}
+
+	s := &RedisServer{
+		listener: listener,
+		password: password,
+		values:   map[string]value{},
+		versions: map[string]uint64{},
+		now:      time.Now,
+	}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *RedisServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Commands returns the names of the commands received so far, in order.
+func (s *RedisServer) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Has reports whether key holds a value that has not expired.
+func (s *RedisServer) Has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.get(key)
+	return ok
+}
+
+// FastForward moves the clock of the server by d, to expire the values without waiting.
+func (s *RedisServer) FastForward(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now
+	s.now = func() time.Time { return now().Add(d) }
+}
+
+func (s *RedisServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *RedisServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+
+	authenticated := s.password == ""
+	var tx transaction
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+		var reply string
+		switch {
+		case cmd == "AUTH":
+			authenticated = len(args) == 2 && args[1] == s.password
+			reply = "+OK\r\n"
+			if !authenticated {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			reply = "-NOAUTH Authentication required.\r\n"
+		default:
+			reply = s.exec(&tx, cmd, args[1:])
+		}
+
+		if _, err := w.WriteString(reply); err != nil {
+			return
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// transaction is the state of WATCH and MULTI of a connection.
+type transaction struct {
+	watched map[string]uint64 // the versions of the keys when they were watched
+	multi   bool
+	queued  [][]string
+}
+
+func (s *RedisServer) exec(tx *transaction, cmd string, args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, cmd)
+
+	switch cmd {
+	case "WATCH":
+		if tx.multi {
+			return "-ERR WATCH inside MULTI is not allowed\r\n"
+		}
+		if tx.watched == nil {
+			tx.watched = map[string]uint64{}
+		}
+		for _, key := range args {
+			tx.watched[key] = s.versions[key]
+		}
+		return "+OK\r\n"
+	case "UNWATCH":
+		tx.watched = nil
+		return "+OK\r\n"
+	case "MULTI":
+		if tx.multi {
+			return "-ERR MULTI calls can not be nested\r\n"
+		}
+		tx.multi = true
+		return "+OK\r\n"
+	case "DISCARD":
+		if !tx.multi {
+			return "-ERR DISCARD without MULTI\r\n"
+		}
+		*tx = transaction{}
+		return "+OK\r\n"
+	case "EXEC":
+		if !tx.multi {
+			return "-ERR EXEC without MULTI\r\n"
+		}
+		watched, queued := tx.watched, tx.queued
+		*tx = transaction{}
+		for key, version := range watched {
+			if s.versions[key] != version {
+				return "*-1\r\n"
+			}
+		}
+		reply := fmt.Sprintf("*%d\r\n", len(queued))
+		for _, args := range queued {
+			reply += s.run(args[0], args[1:])
+		}
+		return reply
+	}
+
+	if tx.multi {
+		tx.queued = append(tx.queued, append([]string{cmd}, args...))
+		return "+QUEUED\r\n"
+	}
+	return s.run(cmd, args)
+}
+
+// run runs a command reading or writing the values, s.mu must be held.
+func (s *RedisServer) run(cmd string, args []string) string {
+	switch cmd {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		if len(args) != 1 {
+			return "-ERR wrong number of arguments for 'get' command\r\n"
+		}
+		v, ok := s.get(args[0])
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v.data), v.data)
+	case "SET":
+		if len(args) != 2 && len(args) != 4 {
+			return "-ERR syntax error\r\n"
+		}
+		v := value{data: args[1]}
+		if len(args) == 4 {
+			n, err := strconv.ParseInt(args[3], 10, 64)
+			if err != nil || n <= 0 {
+				return "-ERR invalid expire time in 'set' command\r\n"
+			}
+			switch strings.ToUpper(args[2]) {
+			case "PX":
+				v.expires = s.now().Add(time.Duration(n) * time.Millisecond)
+			case "EX":
+				v.expires = s.now().Add(time.Duration(n) * time.Second)
+			default:
+				return "-ERR syntax error\r\n"
+			}
+		}
+		s.values[args[0]] = v
+		s.versions[args[0]]++
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args {
+			if _, ok := s.get(key); ok {
+				deleted++
+			}
+			delete(s.values, key)
+			s.versions[key]++
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", cmd)
+}
+
+// get returns the value of key, s.mu must be held.
+func (s *RedisServer) get(key string) (value, bool) {
+	v, ok := s.values[key]
+	if ok && !v.expires.IsZero() && !s.now().Before(v.expires) {
+		delete(s.values, key)
+		return value{}, false
+	}
+	return v, ok
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if !strings.HasPrefix(line, "*") {
+		return nil, errors.New("inline commands are not supported")
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid array length %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(header[1:], "\r\n"))
+		if err != nil || header[0] != '$' {
+			return nil, fmt.Errorf("invalid bulk string header %q", header)
+		}
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
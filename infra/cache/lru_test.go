@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), time.Second))
+	_, ok, _ := c.Get(ctx, "a")
+	assert.True(t, ok)
+
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), time.Minute))
+	_, ok, _ = c.Get(ctx, "b")
+	assert.False(t, ok, "the least recently used value should be evicted when the cache is full")
+	assert.Equal(t, 2, c.Len())
+
+	now = now.Add(time.Minute)
+	_, ok, _ = c.Get(ctx, "a")
+	assert.False(t, ok, "an expired value should be a miss")
+	assert.Equal(t, 1, c.Len(), "an expired value should be dropped when read")
+
+	require.NoError(t, c.Delete(ctx, "c", "unknown"))
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRU_SetReplaces(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(1)
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, c.Set(ctx, "a", []byte("2"), time.Minute))
+
+	got, ok, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("2"), got)
+	assert.Equal(t, 1, c.Len())
+}
@@ -0,0 +1,370 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// errRedis is a reply of redis reporting a failed command, the connection stays usable.
+type errRedis string
+
+func (e errRedis) Error() string {
+	return "redis: " + string(e)
+}
+
+// Redis is a cache in redis, or any server speaking its protocol, shared by every instance. it needs GET,
+// SET with PX and DEL only, plus WATCH, MULTI and EXEC for Update, and keeps a small pool of connections.
+type Redis struct {
+	addr        string
+	password    string
+	db          int
+	dialTimeout time.Duration
+	timeout     time.Duration
+
+	conns chan *redisConn
+}
+
+type RedisOption func(r *Redis)
+
+func WithRedisPassword(password string) RedisOption {
+	return func(r *Redis) {
+		r.password = password
+	}
+}
+
+// WithRedisDB selects the logical database db instead of 0.
+func WithRedisDB(db int) RedisOption {
+	return func(r *Redis) {
+		r.db = db
+	}
+}
+
+func WithRedisDialTimeout(timeout time.Duration) RedisOption {
+	return func(r *Redis) {
+		r.dialTimeout = timeout
+	}
+}
+
+// WithRedisTimeout bounds every command, a shorter deadline of its context still applies. 0 leaves the
+// commands to the context only.
+func WithRedisTimeout(timeout time.Duration) RedisOption {
+	return func(r *Redis) {
+		r.timeout = timeout
+	}
+}
+
+// WithRedisPoolSize keeps at most size idle connections.
+func WithRedisPoolSize(size int) RedisOption {
+	return func(r *Redis) {
+		r.conns = make(chan *redisConn, max(size, 1))
+	}
+}
+
+// NewRedis returns the cache of the redis at addr, it connects on first use.
+func NewRedis(addr string, opts ...RedisOption) *Redis {
+	r := &Redis{
+		addr:        addr,
+		dialTimeout: time.Second,
+		timeout:     time.Second,
+		conns:       make(chan *redisConn, 8),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, fmt.Errorf("Get: %w", err)
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	b, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("Get: unexpected reply %T", reply)
+	}
+	return b, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	// redis rejects a zero expiry, a ttl under a millisecond is rounded up
+	px := strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)
+	if _, err := r.do(ctx, "SET", key, string(value), "PX", px); err != nil {
+		return fmt.Errorf("Set: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if _, err := r.do(ctx, "DEL", keys...); err != nil {
+		return fmt.Errorf("Delete: %w", err)
+	}
+	return nil
+}
+
+// maxUpdateAttempts bounds the retries of an Update losing the race for its key.
+const maxUpdateAttempts = 8
+
+// Update replaces the value of key with the one fn returns for it, nil when the key is missing, and keeps
+// it for ttl. it is atomic across the instances: when the key changes while fn runs, fn runs again on the
+// new value. an error of fn leaves the key as it is.
+func (r *Redis) Update(ctx context.Context, key string, ttl time.Duration, fn func(value []byte) ([]byte, error)) error {
+	c, err := r.conn(ctx)
+	if err != nil {
+		return fmt.Errorf("Update: %w", err)
+	}
+
+	for range maxUpdateAttempts {
+		done, err := c.update(ctx, key, ttl, fn)
+		var fnErr updateError
+		switch {
+		case errors.As(err, &fnErr):
+			r.put(c)
+			return fmt.Errorf("Update: %w", fnErr.err)
+		case err != nil:
+			// the connection may be left in a transaction, it is not reused
+			c.Close()
+			return fmt.Errorf("Update: %w", err)
+		case done:
+			r.put(c)
+			return nil
+		}
+	}
+	r.put(c)
+	return fmt.Errorf("Update: key %s kept changing after %d attempts", key, maxUpdateAttempts)
+}
+
+// updateError is an error of the fn of an Update, it tells it apart from the errors of redis.
+type updateError struct {
+	err error
+}
+
+func (e updateError) Error() string {
+	return e.err.Error()
+}
+
+// Ping checks the connection to redis, for the health checks.
+func (r *Redis) Ping(ctx context.Context) error {
+	if _, err := r.do(ctx, "PING"); err != nil {
+		return fmt.Errorf("Ping: %w", err)
+	}
+	return nil
+}
+
+// Close closes the idle connections, the ones in use are closed when they are put back.
+func (r *Redis) Close() error {
+	var errs []error
+	for {
+		select {
+		case c := <-r.conns:
+			errs = append(errs, c.Close())
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// do sends a command on a pooled connection, a connection failing at the protocol level is dropped.
+func (r *Redis) do(ctx context.Context, cmd string, args ...string) (any, error) {
+	c, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.do(ctx, cmd, args...)
+	var replyErr errRedis
+	if err != nil && !errors.As(err, &replyErr) {
+		c.Close()
+		return nil, err
+	}
+	r.put(c)
+	return reply, err
+}
+
+func (r *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-r.conns:
+		return c, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: r.dialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, fmt.Errorf("error dial %s: %w", r.addr, err)
+	}
+	c := &redisConn{Conn: netConn, r: bufio.NewReader(netConn), w: bufio.NewWriter(netConn), timeout: r.timeout}
+
+	if r.password != "" {
+		if _, err := c.do(ctx, "AUTH", r.password); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("error auth: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := c.do(ctx, "SELECT", strconv.Itoa(r.db)); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("error select db %d: %w", r.db, err)
+		}
+	}
+	return c, nil
+}
+
+func (r *Redis) put(c *redisConn) {
+	select {
+	case r.conns <- c:
+	default:
+		c.Close()
+	}
+}
+
+type redisConn struct {
+	net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	timeout time.Duration
+}
+
+// update runs one attempt of Update, it reports false when the key changed before the new value was set.
+func (c *redisConn) update(ctx context.Context, key string, ttl time.Duration, fn func(value []byte) ([]byte, error)) (bool, error) {
+	if _, err := c.do(ctx, "WATCH", key); err != nil {
+		return false, err
+	}
+	reply, err := c.do(ctx, "GET", key)
+	if err != nil {
+		return false, err
+	}
+	old, _ := reply.([]byte)
+
+	value, err := fn(old)
+	if err != nil {
+		if _, unwatchErr := c.do(ctx, "UNWATCH"); unwatchErr != nil {
+			return false, unwatchErr
+		}
+		return false, updateError{err: err}
+	}
+
+	if _, err := c.do(ctx, "MULTI"); err != nil {
+		return false, err
+	}
+	px := strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)
+	if _, err := c.do(ctx, "SET", key, string(value), "PX", px); err != nil {
+		return false, err
+	}
+	// an aborted transaction is a nil reply
+	reply, err = c.do(ctx, "EXEC")
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+func (c *redisConn) do(ctx context.Context, cmd string, args ...string) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("error %s: %w", cmd, err)
+	}
+	// no deadline is the zero time, which clears the one of the previous command
+	deadline, _ := ctx.Deadline()
+	if c.timeout > 0 {
+		if d := time.Now().Add(c.timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	// a cancelled ctx expires the deadline to unblock the command, the connection is then dropped
+	stop := context.AfterFunc(ctx, func() { c.SetDeadline(time.Now()) })
+	defer stop()
+
+	fmt.Fprintf(c.w, "*%d\r\n", len(args)+1)
+	for _, arg := range append([]string{cmd}, args...) {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, fmt.Errorf("error send %s: %w", cmd, contextErr(ctx, err))
+	}
+
+	reply, err := readReply(c.r)
+	if err != nil {
+		return nil, fmt.Errorf("error %s: %w", cmd, contextErr(ctx, err))
+	}
+	return reply, nil
+}
+
+// contextErr returns the error of ctx when it is the reason a command failed, rather than the deadline it
+// expired.
+func contextErr(ctx context.Context, err error) error {
+	var replyErr errRedis
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.As(err, &replyErr) {
+		return ctxErr
+	}
+	return err
+}
+
+// readReply reads a reply of the redis protocol: a simple string, an error, an integer, a bulk string,
+// nil for a missing value, or an array of those.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errRedis(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		replies := make([]any, n)
+		for i := range replies {
+			if replies[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	}
+	return nil, fmt.Errorf("unexpected reply %q", line)
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("malformed reply %q", line)
+	}
+	return line[:len(line)-2], nil
+}
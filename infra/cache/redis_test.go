@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/infra/cache/cachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	server := cachetest.NewRedisServer(t, "secret")
+	c := NewRedis(server.Addr(), WithRedisPassword("secret"), WithRedisDB(1))
+	t.Cleanup(func() { c.Close() })
+
+	_, ok, err := c.Get(ctx, "loan:1:JKT")
+	require.NoError(t, err)
+	assert.False(t, ok, "a missing key should be a miss")
+
+	require.NoError(t, c.Set(ctx, "loan:1:JKT", []byte(`{"id":1}`), time.Minute))
+	got, ok, err := c.Get(ctx, "loan:1:JKT")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte(`{"id":1}`), got)
+
+	server.FastForward(time.Minute)
+	_, ok, err = c.Get(ctx, "loan:1:JKT")
+	require.NoError(t, err)
+	assert.False(t, ok, "an expired key should be a miss")
+
+	require.NoError(t, c.Set(ctx, "reference", []byte("{}"), time.Minute))
+	require.NoError(t, c.Delete(ctx, "reference"))
+	assert.False(t, server.Has("reference"))
+
+	require.NoError(t, c.Ping(ctx))
+	assert.Equal(t, []string{"SELECT", "GET", "SET", "GET", "GET", "SET", "DEL", "PING"}, server.Commands(),
+		"the connection should be authenticated once and reused")
+}
+
+func TestRedis_Errors(t *testing.T) {
+	ctx := context.Background()
+	server := cachetest.NewRedisServer(t, "secret")
+
+	_, _, err := NewRedis(server.Addr(), WithRedisPassword("wrong")).Get(ctx, "key")
+	assert.ErrorContains(t, err, "WRONGPASS")
+
+	_, _, err = NewRedis(server.Addr()).Get(ctx, "key")
+	assert.ErrorContains(t, err, "NOAUTH", "a command failing in redis should be returned")
+
+	_, _, err = NewRedis("127.0.0.1:1", WithRedisDialTimeout(100*time.Millisecond)).Get(ctx, "key")
+	assert.ErrorContains(t, err, "error dial")
+}
+
+func TestRedis_Unresponsive(t *testing.T) {
+	// a redis accepting connections but never answering
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	tests := []struct {
+		name    string
+		ctx     func() (context.Context, context.CancelFunc)
+		timeout time.Duration
+		wantErr error
+	}{
+		{
+			name:    "Given a command not answered within the timeout, it should fail it",
+			ctx:     func() (context.Context, context.CancelFunc) { return context.Background(), func() {} },
+			timeout: 50 * time.Millisecond,
+			wantErr: os.ErrDeadlineExceeded,
+		},
+		{
+			name: "Given a context cancelled while the command waits, it should return the context error",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			timeout: time.Minute,
+			wantErr: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			c := NewRedis(l.Addr().String(), WithRedisTimeout(tt.timeout))
+			t.Cleanup(func() { c.Close() })
+
+			start := time.Now()
+			_, _, err := c.Get(ctx, "key")
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+func TestRedis_Update(t *testing.T) {
+	ctx := context.Background()
+	server := cachetest.NewRedisServer(t, "")
+	c := NewRedis(server.Addr())
+	other := NewRedis(server.Addr())
+	t.Cleanup(func() { c.Close(); other.Close() })
+
+	increment := func(value []byte) ([]byte, error) {
+		return append(value, 'x'), nil
+	}
+	require.NoError(t, c.Update(ctx, "counter", time.Minute, increment))
+	require.NoError(t, c.Update(ctx, "counter", time.Minute, increment))
+	got, _, err := c.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("xx"), got)
+
+	attempts := 0
+	err = c.Update(ctx, "counter", time.Minute, func(value []byte) ([]byte, error) {
+		attempts++
+		if attempts == 1 {
+			require.NoError(t, other.Set(ctx, "counter", []byte("xxxxx"), time.Minute))
+		}
+		return append(value, 'x'), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts, "a key changed by another client should be updated again")
+	got, _, err = c.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("xxxxxx"), got)
+
+	err = c.Update(ctx, "counter", time.Minute, func(value []byte) ([]byte, error) {
+		return nil, errors.New("over the limit")
+	})
+	assert.ErrorContains(t, err, "over the limit")
+	got, _, err = c.Get(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("xxxxxx"), got, "an error of fn should leave the key as it is")
+	require.NoError(t, c.Ping(ctx), "the connection should be usable after an error of fn")
+}
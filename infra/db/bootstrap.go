@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+)
+
+// Retry bounds the attempts to reach a database at startup, the wait doubles after every failed
+// attempt up to MaxBackoff.
+type Retry struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// next returns the wait following backoff.
+func (r Retry) next(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return backoff
+}
+
+// WaitReady pings db until it answers, at most retry.Attempts times and each ping within timeout, a
+// zero timeout waits as long as ctx. name identifies the database in the logs, it must not hold a secret.
+func WaitReady(ctx context.Context, db *sql.DB, retry Retry, timeout time.Duration, name string) error {
+	attempts := max(retry.Attempts, 1)
+	backoff := retry.Backoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = ping(ctx, db, timeout)
+		if err == nil {
+			log.Ctx(ctx).Info().Str("database", name).Int("attempt", attempt).Msg("database reachable")
+			return nil
+		}
+		if attempt >= attempts {
+			break
+		}
+
+		log.Ctx(ctx).Warn().Err(err).Str("database", name).Int("attempt", attempt).Int("attempts", attempts).
+			Dur("retry_in", backoff).Msg("database not reachable")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("WaitReady: %s not reachable: %w", name, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = retry.next(backoff)
+	}
+
+	return fmt.Errorf("WaitReady: %s not reachable after %d attempt(s): %w", name, attempts, err)
+}
+
+func ping(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return db.PingContext(ctx)
+}
+
+// SecretFile returns a function reading the secret in path every time it is called, so the secret
+// can be rotated by replacing the file without a restart.
+func SecretFile(path string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("SecretFile: error read %s: %w", path, err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+}
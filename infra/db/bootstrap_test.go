@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitReady(t *testing.T) {
+	refused := errors.New("connection refused")
+	retry := Retry{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	tests := []struct {
+		name     string
+		pingErrs []error
+		wantErr  string
+	}{
+		{
+			name:     "Given a reachable database, it should return at once",
+			pingErrs: []error{nil},
+		},
+		{
+			name:     "Given a database reachable after failures, it should retry until it answers",
+			pingErrs: []error{refused, refused, nil},
+		},
+		{
+			name:     "Given an unreachable database, it should give up after the attempts",
+			pingErrs: []error{refused, refused, refused},
+			wantErr:  "not reachable after 3 attempt(s): connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newDB(t)
+			for _, err := range tt.pingErrs {
+				mock.ExpectPing().WillReturnError(err)
+			}
+
+			err := WaitReady(context.Background(), db, retry, time.Second, "xyz")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWaitReady_Canceled(t *testing.T) {
+	db, mock := newDB(t)
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := WaitReady(ctx, db, Retry{Attempts: 3, Backoff: time.Hour}, time.Second, "xyz")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRetry_Next(t *testing.T) {
+	retry := Retry{Backoff: time.Second, MaxBackoff: 3 * time.Second}
+	assert.Equal(t, 2*time.Second, retry.next(time.Second))
+	assert.Equal(t, 3*time.Second, retry.next(2*time.Second))
+}
+
+func TestSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db-password")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+	password := SecretFile(path)
+
+	got, err := password(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "first", got)
+
+	require.NoError(t, os.WriteFile(path, []byte("rotated\n"), 0o600))
+	got, err = password(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", got, "the file should be read again on every call")
+
+	_, err = SecretFile(path + ".missing")(context.Background())
+	assert.Error(t, err)
+}
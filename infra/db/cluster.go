@@ -0,0 +1,269 @@
+// Package db routes the statements of the repositories between a primary database and its read replicas.
+// writes, transactions and the reads feeding a write go to the primary, the other reads go to a replica
+// that is up and close enough to the primary, or to the primary when there is none. every statement is
+// timed out, measured by its name and logged when slow, see Named.
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LagFunc returns how far behind its primary the replica db is.
+type LagFunc func(ctx context.Context, db *sql.DB) (time.Duration, error)
+
+// ReplicaState is a replica as last probed, Err is set while it is down or its lag is unknown.
+type ReplicaState struct {
+	Name string
+	Lag  time.Duration
+	Err  error
+}
+
+type replica struct {
+	name string
+	db   *DB
+
+	mu    sync.RWMutex
+	state ReplicaState
+	// probed is false until the first probe, an unprobed replica takes no reads.
+	probed bool
+}
+
+func (r *replica) load() (ReplicaState, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.state, r.probed
+}
+
+func (r *replica) store(state ReplicaState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state, r.probed = state, true
+}
+
+type Cluster struct {
+	primary  *DB
+	in       instrumentation
+	replicas []*replica
+	lag      LagFunc
+	maxLag   time.Duration
+	now      func() time.Time
+
+	// read your writes: the reads of a session stay on the primary for stickiness after its last write.
+	stickiness time.Duration
+	session    func(ctx context.Context) string
+	mu         sync.Mutex
+	writes     map[string]time.Time
+
+	next atomic.Uint64
+}
+
+type Option func(c *Cluster)
+
+// WithReplica adds a read replica named name, e.g. its host and port.
+func WithReplica(name string, db *sql.DB) Option {
+	return func(c *Cluster) {
+		c.replicas = append(c.replicas, &replica{name: name, db: &DB{DB: db, name: name, in: &c.in}})
+	}
+}
+
+// WithMaxLag takes a replica out of the reads while lag reports it more than maxLag behind the primary.
+func WithMaxLag(lag LagFunc, maxLag time.Duration) Option {
+	return func(c *Cluster) {
+		c.lag, c.maxLag = lag, maxLag
+	}
+}
+
+// WithReadYourWrites keeps the reads of a session on the primary for stickiness after each of its writes,
+// session returns the session of a context, e.g. the id of the user, or "" when it has none.
+func WithReadYourWrites(stickiness time.Duration, session func(ctx context.Context) string) Option {
+	return func(c *Cluster) {
+		c.stickiness, c.session = stickiness, session
+	}
+}
+
+// WithStatementTimeout bounds every statement to timeout, unless its context sets another with
+// StatementTimeout. a query is bounded until its rows are read.
+func WithStatementTimeout(timeout time.Duration) Option {
+	return func(c *Cluster) {
+		c.in.timeout = timeout
+	}
+}
+
+// WithSlowQueryLog logs the statements taking threshold or more, with their arguments redacted.
+func WithSlowQueryLog(threshold time.Duration) Option {
+	return func(c *Cluster) {
+		c.in.slow = threshold
+	}
+}
+
+// NewCluster returns the cluster of primary, every read goes to primary until replicas are added.
+func NewCluster(primary *sql.DB, opts ...Option) *Cluster {
+	c := &Cluster{
+		now:    time.Now,
+		writes: map[string]time.Time{},
+	}
+	c.primary = &DB{DB: primary, name: "primary", in: &c.in}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Primary returns the primary, for the reads whose result is written back, e.g. a read-modify-write.
+func (c *Cluster) Primary() *DB {
+	return c.primary
+}
+
+// Writer returns the primary and starts the read your writes window of the session of ctx. without replica
+// every read is on the primary already, the session is not recorded.
+func (c *Cluster) Writer(ctx context.Context) *DB {
+	if session := c.sessionOf(ctx); session != "" && len(c.replicas) > 0 {
+		c.mu.Lock()
+		c.writes[session] = c.now()
+		c.mu.Unlock()
+	}
+	return c.primary
+}
+
+// BeginTx starts a transaction on the primary, the reads of the transaction see its writes.
+func (c *Cluster) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	return c.Writer(ctx).BeginTx(ctx, opts)
+}
+
+// Reader returns the database a read of ctx runs on, the replicas take turns.
+func (c *Cluster) Reader(ctx context.Context) *DB {
+	if len(c.replicas) == 0 || c.wroteRecently(ctx) {
+		return c.primary
+	}
+
+	start := c.next.Add(1)
+	for i := range c.replicas {
+		r := c.replicas[(start+uint64(i))%uint64(len(c.replicas))]
+		if state, probed := r.load(); probed && state.Err == nil {
+			return r.db
+		}
+	}
+
+	return c.primary
+}
+
+func (c *Cluster) sessionOf(ctx context.Context) string {
+	if c.session == nil || c.stickiness <= 0 {
+		return ""
+	}
+	return c.session(ctx)
+}
+
+func (c *Cluster) wroteRecently(ctx context.Context) bool {
+	session := c.sessionOf(ctx)
+	if session == "" {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	wrote, ok := c.writes[session]
+	if ok && c.now().Sub(wrote) >= c.stickiness {
+		delete(c.writes, session)
+		return false
+	}
+	return ok
+}
+
+// Replicas returns the state of every replica as last probed.
+func (c *Cluster) Replicas() []ReplicaState {
+	states := make([]ReplicaState, 0, len(c.replicas))
+	for _, r := range c.replicas {
+		state, probed := r.load()
+		if !probed {
+			state = ReplicaState{Name: r.name, Err: errors.New("not probed yet")}
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// Probe pings every replica and measures its lag, a replica failing either, or lagging more than the max lag,
+// takes no reads until the next probe. it also forgets the sessions whose read your writes window is over.
+func (c *Cluster) Probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, r := range c.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.store(c.probe(ctx, r))
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for session, wrote := range c.writes {
+		if now.Sub(wrote) >= c.stickiness {
+			delete(c.writes, session)
+		}
+	}
+}
+
+func (c *Cluster) probe(ctx context.Context, r *replica) ReplicaState {
+	state := ReplicaState{Name: r.name}
+	if err := r.db.PingContext(ctx); err != nil {
+		state.Err = fmt.Errorf("Probe: error ping replica %s: %w", r.name, err)
+		return state
+	}
+	if c.lag == nil {
+		return state
+	}
+
+	lag, err := c.lag(ctx, r.db.DB)
+	state.Lag = lag
+	switch {
+	case err != nil:
+		state.Err = fmt.Errorf("Probe: error get lag of replica %s: %w", r.name, err)
+	case lag > c.maxLag:
+		state.Err = fmt.Errorf("Probe: replica %s is %s behind the primary, more than %s", r.name, lag, c.maxLag)
+	}
+	return state
+}
+
+// Run probes the replicas every interval until ctx is done, starting right away.
+func (c *Cluster) Run(ctx context.Context, interval time.Duration) {
+	if len(c.replicas) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		probeCtx, cancel := context.WithTimeout(ctx, interval)
+		c.Probe(probeCtx)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes the replicas and the primary.
+func (c *Cluster) Close() error {
+	var errs []error
+	for _, r := range c.replicas {
+		if err := r.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("Close: error close replica %s: %w", r.name, err))
+		}
+	}
+	if err := c.primary.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("Close: error close primary: %w", err))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sessionKey struct{}
+
+func withSession(session string) context.Context {
+	return context.WithValue(context.Background(), sessionKey{}, session)
+}
+
+func sessionOf(ctx context.Context) string {
+	session, _ := ctx.Value(sessionKey{}).(string)
+	return session
+}
+
+// newDB returns a mocked database whose pings have to be expected.
+func newDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
+
+func lagOf(lags map[*sql.DB]time.Duration) LagFunc {
+	return func(ctx context.Context, db *sql.DB) (time.Duration, error) {
+		lag, ok := lags[db]
+		if !ok {
+			return 0, errors.New("not a replica")
+		}
+		return lag, nil
+	}
+}
+
+func TestCluster_Reader(t *testing.T) {
+	tests := []struct {
+		name string
+		// pingErrs has the ping error of each replica, its length is the number of replicas.
+		pingErrs []error
+		// lags has the lag of each replica, a missing one fails to be measured.
+		lags  []time.Duration
+		probe bool
+		want  []int // the replica serving each read, -1 for the primary
+	}{
+		{
+			name: "Given no replica, it should read from the primary",
+			want: []int{-1, -1},
+		},
+		{
+			name:     "Given replicas not probed yet, it should read from the primary",
+			pingErrs: []error{nil},
+			lags:     []time.Duration{0},
+			want:     []int{-1},
+		},
+		{
+			name:     "Given healthy replicas, it should read from them in turn",
+			pingErrs: []error{nil, nil},
+			lags:     []time.Duration{0, time.Second},
+			probe:    true,
+			want:     []int{1, 0, 1},
+		},
+		{
+			name:     "Given a replica failing its ping, it should read from the others",
+			pingErrs: []error{errors.New("connection refused"), nil},
+			lags:     []time.Duration{0, 0},
+			probe:    true,
+			want:     []int{1, 1},
+		},
+		{
+			name:     "Given a replica lagging more than the max lag, it should read from the others",
+			pingErrs: []error{nil, nil},
+			lags:     []time.Duration{time.Minute, 0},
+			probe:    true,
+			want:     []int{1, 1},
+		},
+		{
+			name:     "Given every replica unusable, it should read from the primary",
+			pingErrs: []error{errors.New("connection refused"), nil},
+			lags:     []time.Duration{0},
+			probe:    true,
+			want:     []int{-1, -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, _ := newDB(t)
+			lags := map[*sql.DB]time.Duration{}
+			replicas := make([]*sql.DB, len(tt.pingErrs))
+			opts := []Option{}
+			for i, pingErr := range tt.pingErrs {
+				db, mock := newDB(t)
+				if tt.probe {
+					mock.ExpectPing().WillReturnError(pingErr)
+				}
+				if i < len(tt.lags) {
+					lags[db] = tt.lags[i]
+				}
+				replicas[i] = db
+				opts = append(opts, WithReplica("replica", db))
+			}
+			c := NewCluster(primary, append(opts, WithMaxLag(lagOf(lags), 5*time.Second))...)
+			if tt.probe {
+				c.Probe(context.Background())
+			}
+
+			for i, want := range tt.want {
+				wantDB := primary
+				if want >= 0 {
+					wantDB = replicas[want]
+				}
+				assert.Same(t, wantDB, c.Reader(context.Background()).DB, "read %d", i)
+			}
+		})
+	}
+}
+
+func TestCluster_ReadYourWrites(t *testing.T) {
+	primary, _ := newDB(t)
+	replica, mock := newDB(t)
+	mock.ExpectPing()
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	c := NewCluster(primary, WithReplica("replica", replica), WithReadYourWrites(5*time.Second, sessionOf))
+	c.now = func() time.Time { return now }
+	c.Probe(context.Background())
+
+	assert.Same(t, replica, c.Reader(withSession("1")).DB, "a session without write should read from the replica")
+
+	assert.Same(t, primary, c.Writer(withSession("1")).DB)
+	assert.Same(t, primary, c.Reader(withSession("1")).DB, "the writing session should read from the primary")
+	assert.Same(t, replica, c.Reader(withSession("2")).DB, "another session should read from the replica")
+	assert.Same(t, replica, c.Reader(context.Background()).DB, "a read without session should read from the replica")
+
+	now = now.Add(5 * time.Second)
+	assert.Same(t, replica, c.Reader(withSession("1")).DB, "the writing session should read from the replica once the window is over")
+}
+
+func TestCluster_Probe_ForgetsWrites(t *testing.T) {
+	primary, _ := newDB(t)
+	replica, mock := newDB(t)
+	mock.ExpectPing()
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	c := NewCluster(primary, WithReplica("replica", replica), WithReadYourWrites(time.Second, sessionOf))
+	c.now = func() time.Time { return now }
+
+	c.Writer(withSession("1"))
+	now = now.Add(time.Second)
+	c.Writer(withSession("2"))
+	c.Probe(context.Background())
+
+	assert.Len(t, c.writes, 1)
+	assert.Contains(t, c.writes, "2")
+}
+
+func TestCluster_Writer_WithoutReplica(t *testing.T) {
+	primary, _ := newDB(t)
+	c := NewCluster(primary, WithReadYourWrites(time.Second, sessionOf))
+
+	c.Writer(withSession("1"))
+	assert.Empty(t, c.writes, "the sessions need not be recorded while every read is on the primary")
+}
+
+func TestCluster_BeginTx(t *testing.T) {
+	primary, mock := newDB(t)
+	replica, _ := newDB(t)
+	c := NewCluster(primary, WithReplica("replica", replica))
+	mock.ExpectBegin()
+
+	tx, err := c.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	mock.ExpectRollback()
+	require.NoError(t, tx.Rollback())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCluster_Replicas(t *testing.T) {
+	primary, _ := newDB(t)
+	up, upMock := newDB(t)
+	down, downMock := newDB(t)
+	upMock.ExpectPing()
+	downMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	c := NewCluster(primary, WithReplica("up", up), WithReplica("down", down),
+		WithMaxLag(lagOf(map[*sql.DB]time.Duration{up: 2 * time.Second}), 5*time.Second))
+
+	states := c.Replicas()
+	require.Len(t, states, 2)
+	assert.Error(t, states[0].Err, "a replica not probed yet should be reported as failing")
+
+	c.Probe(context.Background())
+	states = c.Replicas()
+	assert.Equal(t, ReplicaState{Name: "up", Lag: 2 * time.Second}, states[0])
+	assert.Equal(t, "down", states[1].Name)
+	assert.ErrorContains(t, states[1].Err, "connection refused")
+}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+)
+
+// unnamed labels the statements not built with Named.
+const unnamed = "unnamed"
+
+// Querier is the surface the repositories run their statements through, DB and Tx implement it.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *Row
+}
+
+// sqlQuerier is the surface of sql.DB and sql.Tx the instrumentation wraps.
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Rows are the rows of a query, closing them releases the context of the statement.
+type Rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+func (r *Rows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+// Row is the row of a query returning at most one, scanning it releases the context of the statement.
+type Row struct {
+	*sql.Row
+	cancel context.CancelFunc
+}
+
+func (r *Row) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.Row.Scan(dest...)
+}
+
+// Named tags query with name in a leading comment, e.g. /* loan.create */ INSERT INTO loan..., so the
+// statement can be found in the processlist and its latency is recorded under name.
+func Named(name, query string) string {
+	return "/* " + name + " */ " + query
+}
+
+// queryName returns the name Named tagged query with.
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(query, "/* ")
+	if !ok {
+		return unnamed
+	}
+	name, _, ok := strings.Cut(rest, " */")
+	if !ok || name == "" {
+		return unnamed
+	}
+	return name
+}
+
+type statementTimeoutKey struct{}
+
+// StatementTimeout returns a context whose statements run within timeout instead of the default of the
+// cluster, e.g. for a report known to be slow. 0 lets them run as long as ctx.
+func StatementTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, statementTimeoutKey{}, timeout)
+}
+
+// instrumentation is shared by every database of a cluster.
+type instrumentation struct {
+	// timeout bounds every statement unless its context says otherwise, 0 is none.
+	timeout time.Duration
+	// slow is the duration from which a statement is logged, 0 logs none.
+	slow time.Duration
+}
+
+// statementContext returns the context a statement runs with.
+func (in *instrumentation) statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := in.timeout
+	if t, ok := ctx.Value(statementTimeoutKey{}).(time.Duration); ok {
+		timeout = t
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// observe records a statement run on db, it is logged when slow. the arguments are never logged, only
+// their types, as they hold personal data.
+func (in *instrumentation) observe(ctx, stmtCtx context.Context, db, query string, args []any, start time.Time, err error) {
+	name := queryName(query)
+	// a deadline of ctx itself is the caller giving up, not the statement timing out
+	timedOut := errors.Is(stmtCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+	metrics.ObserveDBQuery(name, db, start, err, timedOut)
+
+	elapsed := time.Since(start)
+	if in.slow <= 0 || elapsed < in.slow {
+		return
+	}
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = fmt.Sprintf("%T", arg)
+	}
+	log.Ctx(ctx).Warn().Err(err).Str("query", name).Str("db", db).Dur("elapsed", elapsed).
+		Str("statement", query).Strs("args", types).Msg("slow query")
+}
+
+func (in *instrumentation) exec(ctx context.Context, q sqlQuerier, db, query string, args []any) (sql.Result, error) {
+	stmtCtx, cancel := in.statementContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	res, err := q.ExecContext(stmtCtx, query, args...)
+	in.observe(ctx, stmtCtx, db, query, args, start, err)
+	return res, err
+}
+
+func (in *instrumentation) query(ctx context.Context, q sqlQuerier, db, query string, args []any) (*Rows, error) {
+	stmtCtx, cancel := in.statementContext(ctx)
+
+	start := time.Now()
+	rows, err := q.QueryContext(stmtCtx, query, args...)
+	in.observe(ctx, stmtCtx, db, query, args, start, err)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &Rows{Rows: rows, cancel: cancel}, nil
+}
+
+func (in *instrumentation) queryRow(ctx context.Context, q sqlQuerier, db, query string, args []any) *Row {
+	stmtCtx, cancel := in.statementContext(ctx)
+
+	start := time.Now()
+	row := q.QueryRowContext(stmtCtx, query, args...)
+	in.observe(ctx, stmtCtx, db, query, args, start, row.Err())
+	return &Row{Row: row, cancel: cancel}
+}
+
+// DB is a database of a cluster, its statements are timed out, measured and logged when slow.
+type DB struct {
+	*sql.DB
+	name string
+	in   *instrumentation
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.in.exec(ctx, db.DB, db.name, query, args)
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	return db.in.query(ctx, db.DB, db.name, query, args)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	return db.in.queryRow(ctx, db.DB, db.name, query, args)
+}
+
+// BeginTx starts a transaction whose statements are instrumented as the ones of db.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, db: db.name, in: db.in}, nil
+}
+
+// Tx is a transaction of a DB.
+type Tx struct {
+	*sql.Tx
+	db string
+	in *instrumentation
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.in.exec(ctx, tx.Tx, tx.db, query, args)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	return tx.in.query(ctx, tx.Tx, tx.db, query, args)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	return tx.in.queryRow(ctx, tx.Tx, tx.db, query, args)
+}
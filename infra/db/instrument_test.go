@@ -0,0 +1,150 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "Given a named query, it should return its name", query: Named("loan.create_loan", "INSERT INTO loan VALUES (?)"), want: "loan.create_loan"},
+		{name: "Given a query without comment, it should be unnamed", query: "SELECT 1", want: unnamed},
+		{name: "Given an unterminated comment, it should be unnamed", query: "/* loan.create_loan SELECT 1", want: unnamed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, queryName(tt.query))
+		})
+	}
+}
+
+func TestDB_StatementTimeout(t *testing.T) {
+	query := Named("loan.create_loan_payment", "INSERT INTO loan_payment (loan_id, amount) VALUES (?, ?)")
+
+	tests := []struct {
+		name    string
+		ctx     func() context.Context
+		wantErr bool
+	}{
+		{
+			name:    "Given a statement slower than the timeout, it should cancel it",
+			ctx:     context.Background,
+			wantErr: true,
+		},
+		{
+			name:    "Given a context lifting the timeout, it should let the statement run",
+			ctx:     func() context.Context { return StatementTimeout(context.Background(), 0) },
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, mock := newDB(t)
+			mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(1, 100).WillDelayFor(50 * time.Millisecond).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			c := NewCluster(primary, WithStatementTimeout(10*time.Millisecond))
+
+			_, err := c.Writer(tt.ctx()).ExecContext(tt.ctx(), query, 1, 100)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDB_SlowQueryLog(t *testing.T) {
+	query := Named("user.get_user_by_national_id", "SELECT id FROM user WHERE national_id = ?")
+	primary, mock := newDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs("3171011501950003").WillDelayFor(20 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs("3171011501950004").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	c := NewCluster(primary, WithSlowQueryLog(10*time.Millisecond))
+
+	var buf bytes.Buffer
+	ctx := zerolog.New(&buf).WithContext(context.Background())
+	var id int64
+	require.NoError(t, c.Reader(ctx).QueryRowContext(ctx, query, "3171011501950003").Scan(&id))
+	require.NoError(t, c.Reader(ctx).QueryRowContext(ctx, query, "3171011501950004").Scan(&id))
+
+	logs := buf.String()
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("slow query")), "only the slow statement should be logged")
+	assert.Contains(t, logs, `"query":"user.get_user_by_national_id"`)
+	assert.Contains(t, logs, `"db":"primary"`)
+	assert.Contains(t, logs, `"args":["string"]`)
+	assert.NotContains(t, logs, "3171011501950003", "the arguments should be redacted")
+}
+
+func TestTx_Instrumented(t *testing.T) {
+	query := Named("loan.create_loan_payment", "INSERT INTO loan_payment (loan_id, amount) VALUES (?, ?)")
+	primary, mock := newDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(1, 100).WillDelayFor(50 * time.Millisecond).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectRollback()
+	c := NewCluster(primary, WithStatementTimeout(10*time.Millisecond))
+
+	tx, err := c.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(context.Background(), query, 1, 100)
+	assert.Error(t, err, "the statements of a transaction should be timed out as well")
+	tx.Rollback()
+}
+
+// ctxRecorder runs the statements on db and keeps the context of the last one.
+type ctxRecorder struct {
+	*sql.DB
+	ctx context.Context
+}
+
+func (r *ctxRecorder) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	r.ctx = ctx
+	return r.DB.QueryContext(ctx, query, args...)
+}
+
+func (r *ctxRecorder) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	r.ctx = ctx
+	return r.DB.QueryRowContext(ctx, query, args...)
+}
+
+func TestInstrumentation_ReleaseStatementContext(t *testing.T) {
+	query := Named("loan.get_loans", "SELECT id FROM loan WHERE user_id = ?")
+	in := &instrumentation{timeout: time.Minute}
+
+	t.Run("Given the rows of a query, it should release the context of the statement on close", func(t *testing.T) {
+		db, mock := newDB(t)
+		mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		q := &ctxRecorder{DB: db}
+
+		rows, err := in.query(context.Background(), q, "primary", query, []any{1})
+		require.NoError(t, err)
+		assert.NoError(t, q.ctx.Err(), "the rows are read after the query returns")
+		require.NoError(t, rows.Close())
+		assert.ErrorIs(t, q.ctx.Err(), context.Canceled)
+	})
+
+	t.Run("Given the row of a query, it should release the context of the statement on scan", func(t *testing.T) {
+		db, mock := newDB(t)
+		mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		q := &ctxRecorder{DB: db}
+
+		var id int64
+		require.NoError(t, in.queryRow(context.Background(), q, "primary", query, []any{1}).Scan(&id))
+		assert.ErrorIs(t, q.ctx.Err(), context.Canceled)
+	})
+}
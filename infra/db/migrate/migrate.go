@@ -0,0 +1,461 @@
+// Package migrate applies versioned sql migrations and records them in a schema_migrations table.
+// the statements that differ between databases, e.g. the locking, are given by a Dialect.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// LockName identifies the lock taken while migrating, it is database wide so concurrent
+	// instances of the application never migrate at the same time.
+	LockName    = "xyz-backend-monolith.schema_migrations"
+	LockTimeout = 30 * time.Second
+
+	createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL, name VARCHAR(255) NOT NULL, checksum CHAR(64) NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY(version))`
+	listSchemaMigrations   = `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`
+	insertSchemaMigration  = `INSERT INTO schema_migrations (version, name, checksum) VALUES (%s, %s, %s)`
+	deleteSchemaMigration  = `DELETE FROM schema_migrations WHERE version = %s`
+)
+
+// Dialect holds what differs between the databases.
+type Dialect struct {
+	// Lock takes the migration lock for the session of conn, waiting at most LockTimeout. it returns
+	// false when another session holds the lock.
+	Lock func(ctx context.Context, conn *sql.Conn) (bool, error)
+	// Unlock releases the lock taken by Lock.
+	Unlock func(ctx context.Context, conn *sql.Conn) error
+	// Placeholder returns the nth, starting at 1, bind parameter of a statement.
+	Placeholder func(n int) string
+	// TransactionalDDL runs each migration and its record in one transaction, so a failing
+	// migration leaves no trace.
+	TransactionalDDL bool
+}
+
+// execer is a connection or a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+var (
+	ErrSchemaBehind     = errors.New("database schema is behind, run the pending migrations")
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	ErrUnknownMigration = errors.New("database has a migration this build does not know")
+	ErrMigrationLocked  = errors.New("another migration is running")
+
+	migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+)
+
+// Migration is a versioned schema change, Checksum is the sha256 of its up script.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus is a migration along with its state in the database.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Modified reports the up script changed after the migration was applied.
+	Modified bool
+}
+
+type appliedMigration struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator applies the migrations of a directory and records them in schema_migrations.
+// without transactional DDL, e.g. on mysql, a migration failing halfway is not rolled back: it stays
+// unrecorded and its up script must be safe to fix and run again.
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// New reads the <version>_<name>.(up|down).sql files at the root of fsys.
+func New(db *sql.DB, dialect Dialect, fsys fs.FS) (*Migrator, error) {
+	ms, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("New: %w", err)
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: ms}, nil
+}
+
+// Migrations returns every known migration, oldest first.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, base := range files {
+		match := migrationFile.FindStringSubmatch(base)
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s, want <version>_<name>.(up|down).sql", base)
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		b, err := fs.ReadFile(fsys, base)
+		if err != nil {
+			return nil, fmt.Errorf("error read %s: %w", base, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			sum := sha256.Sum256(b)
+			m.Up, m.Checksum = string(b), hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	ms := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down script", m.Version, m.Name)
+		}
+		ms = append(ms, *m)
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+
+	return ms, nil
+}
+
+// Latest returns the version of the newest migration, or 0 when there is none.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) (err error) {
+		done, err = m.migrate(ctx, conn, m.Latest())
+		return err
+	})
+	if err != nil {
+		return done, fmt.Errorf("Up: %w", err)
+	}
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := listApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		target := int64(0)
+		if steps < len(applied) {
+			target = applied[len(applied)-steps-1].version
+		}
+		done, err = m.migrate(ctx, conn, target)
+		return err
+	})
+	if err != nil {
+		return done, fmt.Errorf("Down: %w", err)
+	}
+	return done, nil
+}
+
+// Goto migrates up or down until version is the last applied migration, 0 reverts everything.
+func (m *Migrator) Goto(ctx context.Context, version int64) ([]Migration, error) {
+	if version != 0 && m.find(version) == nil {
+		return nil, fmt.Errorf("Goto: migration %d does not exist", version)
+	}
+
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) (err error) {
+		done, err = m.migrate(ctx, conn, version)
+		return err
+	})
+	if err != nil {
+		return done, fmt.Errorf("Goto: %w", err)
+	}
+	return done, nil
+}
+
+// Status lists every known migration and whether it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Status: error get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, createSchemaMigrations); err != nil {
+		return nil, fmt.Errorf("Status: error create schema_migrations: %w", err)
+	}
+	applied, err := listApplied(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("Status: %w", err)
+	}
+
+	byVersion := make(map[int64]appliedMigration, len(applied))
+	for _, a := range applied {
+		if m.find(a.version) == nil {
+			return nil, fmt.Errorf("Status: version %d: %w", a.version, ErrUnknownMigration)
+		}
+		byVersion[a.version] = a
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Migration: mig}
+		if a, ok := byVersion[mig.Version]; ok {
+			s.Applied, s.AppliedAt, s.Modified = true, a.appliedAt, a.checksum != mig.Checksum
+		}
+		statuses = append(statuses, s)
+	}
+
+	return statuses, nil
+}
+
+// Check fails when the schema does not match the embedded migrations, it is run before serving.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("Check: %w", err)
+	}
+
+	pending := 0
+	for _, s := range statuses {
+		if s.Modified {
+			return fmt.Errorf("Check: migration %d_%s: %w", s.Version, s.Name, ErrChecksumMismatch)
+		}
+		if !s.Applied {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("Check: %d pending migration(s): %w", pending, ErrSchemaBehind)
+	}
+
+	return nil
+}
+
+// withLock runs fn on a single connection holding the migration lock, the lock belongs to the session
+// so the lock, the migrations and the release must all use the same connection.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error get connection: %w", err)
+	}
+	defer conn.Close()
+
+	locked, err := m.dialect.Lock(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("error get migration lock: %w", err)
+	}
+	if !locked {
+		return ErrMigrationLocked
+	}
+	// released even when ctx is cancelled, the lock would otherwise live as long as the pooled connection
+	defer m.dialect.Unlock(context.Background(), conn)
+
+	if _, err := conn.ExecContext(ctx, createSchemaMigrations); err != nil {
+		return fmt.Errorf("error create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// migrate applies the pending migrations up to target and reverts the applied ones after it.
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, target int64) ([]Migration, error) {
+	applied, err := listApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	isApplied := make(map[int64]bool, len(applied))
+	for _, a := range applied {
+		mig := m.find(a.version)
+		if mig == nil {
+			return nil, fmt.Errorf("version %d: %w", a.version, ErrUnknownMigration)
+		}
+		if mig.Checksum != a.checksum {
+			return nil, fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, ErrChecksumMismatch)
+		}
+		isApplied[a.version] = true
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version <= target || !isApplied[mig.Version] {
+			continue
+		}
+		err := m.inTx(ctx, conn, func(ex execer) error {
+			if err := execScript(ctx, ex, mig.Down); err != nil {
+				return fmt.Errorf("error revert %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			if _, err := ex.ExecContext(ctx, m.statement(deleteSchemaMigration, 1), mig.Version); err != nil {
+				return fmt.Errorf("error unrecord %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return done, err
+		}
+		done = append(done, mig)
+	}
+
+	for _, mig := range m.migrations {
+		if mig.Version > target || isApplied[mig.Version] {
+			continue
+		}
+		err := m.inTx(ctx, conn, func(ex execer) error {
+			if err := execScript(ctx, ex, mig.Up); err != nil {
+				return fmt.Errorf("error apply %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			if _, err := ex.ExecContext(ctx, m.statement(insertSchemaMigration, 3), mig.Version, mig.Name, mig.Checksum); err != nil {
+				return fmt.Errorf("error record %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return done, err
+		}
+		done = append(done, mig)
+	}
+
+	return done, nil
+}
+
+// inTx runs fn in a transaction when the dialect supports transactional DDL, on conn otherwise.
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, fn func(ex execer) error) error {
+	if !m.dialect.TransactionalDDL {
+		return fn(conn)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error commit: %w", err)
+	}
+	return nil
+}
+
+// statement fills the n placeholders of format with the ones of the dialect.
+func (m *Migrator) statement(format string, n int) string {
+	placeholders := make([]interface{}, n)
+	for i := range placeholders {
+		placeholders[i] = m.dialect.Placeholder(i + 1)
+	}
+	return fmt.Sprintf(format, placeholders...)
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+func listApplied(ctx context.Context, conn *sql.Conn) ([]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, listSchemaMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("error list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var applied []appliedMigration
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("error scan applied migration: %w", err)
+		}
+		applied = append(applied, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error list applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// execScript runs the statements of script one by one, the drivers do not all allow multi statements.
+func execScript(ctx context.Context, ex execer, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := ex.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a script on the semicolons ending a line, -- comment lines are dropped.
+func splitStatements(script string) []string {
+	var (
+		stmts []string
+		b     strings.Builder
+	)
+	flush := func() {
+		if stmt := strings.TrimSpace(b.String()); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		b.Reset()
+	}
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		if strings.HasSuffix(trimmed, ";") {
+			b.WriteString(strings.TrimSuffix(strings.TrimRight(line, " \t\r"), ";"))
+			flush()
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	flush()
+
+	return stmts
+}
@@ -0,0 +1,241 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMigrations = fstest.MapFS{
+	"0001_create_a.up.sql":   {Data: []byte("-- table a\nCREATE TABLE a (id BIGINT);\n")},
+	"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;\n")},
+	"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (\n\tid BIGINT\n);\nCREATE INDEX b_id_idx ON b (id);\n")},
+	"0002_create_b.down.sql": {Data: []byte("DROP TABLE b;\n")},
+}
+
+const (
+	getLock     = `SELECT GET_LOCK(?, ?)`
+	releaseLock = `SELECT RELEASE_LOCK(?)`
+)
+
+// testDialect locks like mysql.
+var testDialect = Dialect{
+	Lock: func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		var locked sql.NullInt64
+		err := conn.QueryRowContext(ctx, getLock, LockName, int(LockTimeout.Seconds())).Scan(&locked)
+		return locked.Valid && locked.Int64 == 1, err
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, releaseLock, LockName)
+		return err
+	},
+	Placeholder: func(int) string { return "?" },
+}
+
+func appliedRows(m *Migrator, versions ...int64) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"})
+	for _, v := range versions {
+		mig := m.find(v)
+		rows.AddRow(mig.Version, mig.Name, mig.Checksum, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+	return rows
+}
+
+func expectLock(mock sqlmock.Sqlmock, got interface{}) {
+	mock.ExpectQuery(regexp.QuoteMeta(getLock)).WithArgs(LockName, 30).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(got))
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := splitStatements("-- comment\nCREATE TABLE b (\n\tid BIGINT\n);\n\nCREATE INDEX b_id_idx ON b (id);\nDROP TABLE c")
+	assert.Equal(t, []string{"CREATE TABLE b (\n\tid BIGINT\n)", "CREATE INDEX b_id_idx ON b (id)", "DROP TABLE c"}, got)
+}
+
+func TestMigrator_Migrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         func(m *Migrator) ([]Migration, error)
+		prepareMock func(m *Migrator, mock sqlmock.Sqlmock)
+		wantDone    []int64
+		wantErr     error
+	}{
+		{
+			name: "Given an empty schema, up should apply every migration in order",
+			run:  func(m *Migrator) ([]Migration, error) { return m.Up(context.Background()) },
+			prepareMock: func(m *Migrator, mock sqlmock.Sqlmock) {
+				expectLock(mock, 1)
+				mock.ExpectExec(regexp.QuoteMeta(createSchemaMigrations)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(listSchemaMigrations)).WillReturnRows(appliedRows(m))
+				mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (id BIGINT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)")).WithArgs(int64(1), "create_a", m.find(1).Checksum).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX b_id_idx ON b (id)")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)")).WithArgs(int64(2), "create_b", m.find(2).Checksum).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(releaseLock)).WithArgs(LockName).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantDone: []int64{1, 2},
+		},
+		{
+			name: "Given every migration applied, down should revert the last one",
+			run:  func(m *Migrator) ([]Migration, error) { return m.Down(context.Background(), 1) },
+			prepareMock: func(m *Migrator, mock sqlmock.Sqlmock) {
+				expectLock(mock, 1)
+				mock.ExpectExec(regexp.QuoteMeta(createSchemaMigrations)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(listSchemaMigrations)).WillReturnRows(appliedRows(m, 1, 2))
+				mock.ExpectQuery(regexp.QuoteMeta(listSchemaMigrations)).WillReturnRows(appliedRows(m, 1, 2))
+				mock.ExpectExec(regexp.QuoteMeta("DROP TABLE b")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = ?")).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(releaseLock)).WithArgs(LockName).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantDone: []int64{2},
+		},
+		{
+			name: "Given every migration applied, goto 0 should revert them all in reverse order",
+			run:  func(m *Migrator) ([]Migration, error) { return m.Goto(context.Background(), 0) },
+			prepareMock: func(m *Migrator, mock sqlmock.Sqlmock) {
+				expectLock(mock, 1)
+				mock.ExpectExec(regexp.QuoteMeta(createSchemaMigrations)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(listSchemaMigrations)).WillReturnRows(appliedRows(m, 1, 2))
+				mock.ExpectExec(regexp.QuoteMeta("DROP TABLE b")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = ?")).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta("DROP TABLE a")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = ?")).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(releaseLock)).WithArgs(LockName).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantDone: []int64{2, 1},
+		},
+		{
+			name: "Given the lock is held by another instance, it should not migrate",
+			run:  func(m *Migrator) ([]Migration, error) { return m.Up(context.Background()) },
+			prepareMock: func(m *Migrator, mock sqlmock.Sqlmock) {
+				expectLock(mock, 0)
+			},
+			wantErr: ErrMigrationLocked,
+		},
+		{
+			name: "Given an applied migration was modified, it should refuse to migrate",
+			run:  func(m *Migrator) ([]Migration, error) { return m.Up(context.Background()) },
+			prepareMock: func(m *Migrator, mock sqlmock.Sqlmock) {
+				expectLock(mock, 1)
+				mock.ExpectExec(regexp.QuoteMeta(createSchemaMigrations)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(listSchemaMigrations)).WillReturnRows(sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}).
+					AddRow(1, "create_a", "0000", time.Now()))
+				mock.ExpectExec(regexp.QuoteMeta(releaseLock)).WithArgs(LockName).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: ErrChecksumMismatch,
+		},
+		{
+			name: "Given the database has a migration unknown to the build, it should refuse to migrate",
+			run:  func(m *Migrator) ([]Migration, error) { return m.Up(context.Background()) },
+			prepareMock: func(m *Migrator, mock sqlmock.Sqlmock) {
+				expectLock(mock, 1)
+				mock.ExpectExec(regexp.QuoteMeta(createSchemaMigrations)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(listSchemaMigrations)).WillReturnRows(sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"}).
+					AddRow(3, "create_c", "0000", time.Now()))
+				mock.ExpectExec(regexp.QuoteMeta(releaseLock)).WithArgs(LockName).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: ErrUnknownMigration,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			m, err := New(db, testDialect, testMigrations)
+			assert.NoError(t, err)
+			tt.prepareMock(m, mock)
+
+			done, err := tt.run(m)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			versions := []int64{}
+			for _, mig := range done {
+				versions = append(versions, mig.Version)
+			}
+			if tt.wantDone == nil {
+				tt.wantDone = []int64{}
+			}
+			assert.Equal(t, tt.wantDone, versions)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigrator_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied []int64
+		wantErr error
+	}{
+		{
+			name:    "Given every migration applied, it should pass",
+			applied: []int64{1, 2},
+		},
+		{
+			name:    "Given a pending migration, it should report the schema is behind",
+			applied: []int64{1},
+			wantErr: ErrSchemaBehind,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			m, err := New(db, testDialect, testMigrations)
+			assert.NoError(t, err)
+			mock.ExpectExec(regexp.QuoteMeta(createSchemaMigrations)).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(listSchemaMigrations)).WillReturnRows(appliedRows(m, tt.applied...))
+
+			err = m.Check(context.Background())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigrator_TransactionalDDL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	dialect := testDialect
+	dialect.TransactionalDDL = true
+	dialect.Placeholder = func(n int) string { return fmt.Sprintf("$%d", n) }
+	m, err := New(db, dialect, testMigrations)
+	assert.NoError(t, err)
+
+	expectLock(mock, 1)
+	mock.ExpectExec(regexp.QuoteMeta(createSchemaMigrations)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(listSchemaMigrations)).WillReturnRows(appliedRows(m))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (id BIGINT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)")).
+		WithArgs(int64(1), "create_a", m.find(1).Checksum).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b")).WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	mock.ExpectExec(regexp.QuoteMeta(releaseLock)).WithArgs(LockName).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := m.Up(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, done, 1, "the migrations before the failing one should stay applied")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,13 +1,25 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
+	"strconv"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/XSAM/otelsql"
+	driver "github.com/go-sql-driver/mysql"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
+// errDuplicateEntry is the mysql error number raised when a unique key is violated.
+const errDuplicateEntry = 1062
+
 type Option func(db *sql.DB)
 
 func WithMaxIdleConns(maxIdleConns int) Option {
@@ -28,34 +40,118 @@ func WithMaxLifetimeConn(connMaxLifetime time.Duration) Option {
 	}
 }
 
-func DataSource(username, password, host string, port int, databaseName string) string {
-	dataSource := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-		username, password, host, port, databaseName)
-	return dataSource
+// WithMetrics exports the pool statistics of the database as name.
+func WithMetrics(name string) Option {
+	return func(db *sql.DB) {
+		if err := metrics.RegisterDB(name, db); err != nil {
+			panic(err)
+		}
+	}
 }
 
-func MustNew(source string, opts ...Option) *sql.DB {
+// Config is a connection to mysql, it is given to the driver as its own config so no value ever needs escaping.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Database string
+	// PasswordFunc, when set, returns the password of every new connection instead of Password, so the
+	// password can be rotated without a restart. the open connections keep working until they are recycled.
+	PasswordFunc func(ctx context.Context) (string, error)
+	// TLS is false, true, skip-verify, preferred or the name of a tls config registered with the driver.
+	TLS string
+	// Location is the time zone of the DATETIME columns, nil is UTC.
+	Location       *time.Location
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	// Retry bounds the attempts to reach the database in Open.
+	Retry infradb.Retry
+	// Lazy makes Open return without reaching the database, the first statement connects instead.
+	Lazy bool
+}
 
-	fmt.Println(source)
-	db, err := sql.Open("mysql", source)
-	if err != nil {
-		panic(err)
+func (cfg Config) driverConfig() *driver.Config {
+	dc := driver.NewConfig()
+	dc.User = cfg.Username
+	dc.Passwd = cfg.Password
+	dc.Net = "tcp"
+	dc.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	dc.DBName = cfg.Database
+	dc.TLSConfig = cfg.TLS
+	// parseTime scans DATE, DATETIME and TIMESTAMP columns into time.Time
+	dc.ParseTime = true
+	dc.Loc = time.UTC
+	if cfg.Location != nil {
+		dc.Loc = cfg.Location
 	}
+	dc.Timeout = cfg.ConnectTimeout
+	dc.ReadTimeout = cfg.ReadTimeout
+	dc.WriteTimeout = cfg.WriteTimeout
+	return dc
+}
 
-	for _, opt := range opts {
-		opt(db)
+// DataSource returns the dsn of cfg.
+func (cfg Config) DataSource() string {
+	return cfg.driverConfig().FormatDSN()
+}
+
+// String returns the dsn of cfg with the password masked, it is safe to log.
+func (cfg Config) String() string {
+	dc := cfg.driverConfig()
+	if dc.Passwd != "" || cfg.PasswordFunc != nil {
+		dc.Passwd = "******"
 	}
+	return dc.FormatDSN()
+}
 
-	for i := 0; i < 10; i++ {
-		err = db.Ping()
-		if err == nil {
-			continue
+// Open returns the database of cfg once it answers a ping, retrying as cfg.Retry allows. the
+// attempts are logged with the dsn masked.
+func Open(ctx context.Context, cfg Config, opts ...Option) (*sql.DB, error) {
+	dc := cfg.driverConfig()
+	if cfg.PasswordFunc != nil {
+		err := dc.Apply(driver.BeforeConnect(func(ctx context.Context, dc *driver.Config) error {
+			password, err := cfg.PasswordFunc(ctx)
+			if err != nil {
+				return fmt.Errorf("Open: error get password of %s: %w", cfg, err)
+			}
+			dc.Passwd = password
+			return nil
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("Open: invalid config %s: %w", cfg, err)
 		}
 	}
 
+	connector, err := driver.NewConnector(dc)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("Open: invalid config %s: %w", cfg, err)
+	}
+
+	// every statement gets a span under the span of its context
+	db := otelsql.OpenDB(connector,
+		otelsql.WithAttributes(semconv.DBSystemMySQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
+	for _, opt := range opts {
+		opt(db)
 	}
 
-	return db
+	if cfg.Lazy {
+		log.Ctx(ctx).Info().Str("database", cfg.String()).Msg("database connects on first use")
+		return db, nil
+	}
+	if err := infradb.WaitReady(ctx, db, cfg.Retry, cfg.ConnectTimeout, cfg.String()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Open: %w", err)
+	}
+
+	return db, nil
+}
+
+// IsDuplicateEntry reports whether err is caused by a unique key violation.
+func IsDuplicateEntry(err error) bool {
+	var mysqlErr *driver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry
 }
@@ -0,0 +1,54 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		wantDSN    string
+		wantString string
+	}{
+		{
+			name:       "Given the defaults, it should parse the times in UTC",
+			cfg:        Config{Host: "localhost", Port: 3306, Username: "root", Password: "p@ss/word", Database: "xyz"},
+			wantDSN:    "root:p@ss/word@tcp(localhost:3306)/xyz?parseTime=true",
+			wantString: "root:******@tcp(localhost:3306)/xyz?parseTime=true",
+		},
+		{
+			name: "Given tls, a location and timeouts, it should put them in the dsn",
+			cfg: Config{Host: "db.internal", Port: 3307, Username: "xyz", Password: "secret", Database: "xyz", TLS: "true",
+				Location: jakarta, ConnectTimeout: 5 * time.Second, ReadTimeout: 2 * time.Second, WriteTimeout: 3 * time.Second},
+			wantDSN:    "xyz:secret@tcp(db.internal:3307)/xyz?loc=Asia%2FJakarta&parseTime=true&readTimeout=2s&timeout=5s&tls=true&writeTimeout=3s",
+			wantString: "xyz:******@tcp(db.internal:3307)/xyz?loc=Asia%2FJakarta&parseTime=true&readTimeout=2s&timeout=5s&tls=true&writeTimeout=3s",
+		},
+		{
+			name: "Given a password function, it should mask the password all the same",
+			cfg: Config{Host: "localhost", Port: 3306, Username: "root", Database: "xyz",
+				PasswordFunc: func(ctx context.Context) (string, error) { return "rotated", nil }},
+			wantDSN:    "root@tcp(localhost:3306)/xyz?parseTime=true",
+			wantString: "root:******@tcp(localhost:3306)/xyz?parseTime=true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantDSN, tt.cfg.DataSource())
+			assert.Equal(t, tt.wantString, tt.cfg.String())
+		})
+	}
+}
+
+func TestOpen_Lazy(t *testing.T) {
+	db, err := Open(context.Background(), Config{Host: "127.0.0.1", Port: 1, Username: "root", Password: "secret", Database: "xyz", Lazy: true})
+	assert.NoError(t, err, "a lazy open should not reach the database")
+	assert.NoError(t, db.Close())
+}
@@ -0,0 +1,40 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// MigrationDialect takes the migration lock with GET_LOCK, mysql commits DDL implicitly so
+// the migrations can not run in a transaction.
+var MigrationDialect = migrate.Dialect{
+	Lock: func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		var locked sql.NullInt64
+		if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, migrate.LockName, int(migrate.LockTimeout.Seconds())).Scan(&locked); err != nil {
+			return false, err
+		}
+		// GET_LOCK returns 0 on timeout and NULL on error
+		return locked.Valid && locked.Int64 == 1, nil
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, migrate.LockName)
+		return err
+	},
+	Placeholder: func(int) string { return "?" },
+}
+
+// NewMigrator returns a migrator of the mysql migrations embedded in the binary.
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, MigrationDialect, sub)
+}
@@ -0,0 +1,17 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMigrator_Embedded(t *testing.T) {
+	m, err := NewMigrator(nil)
+	assert.NoError(t, err)
+	for i, mig := range m.Migrations() {
+		assert.Equal(t, int64(i+1), mig.Version, "migration versions must be contiguous")
+		assert.NotEmpty(t, mig.Up)
+		assert.NotEmpty(t, mig.Down)
+	}
+}
@@ -0,0 +1,18 @@
+CREATE TABLE `user` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`national_id` VARCHAR(16) UNIQUE,
+	`full_name` VARCHAR(255) NOT NULL,
+	`legal_name` VARCHAR(255),
+	`birth_of_place` VARCHAR(255),
+	`birth_of_date` DATE,
+	`salary` DECIMAL(15, 2),
+	`nation_id_photo` MEDIUMBLOB,
+	`user_photo` MEDIUMBLOB,
+	`is_nid_valid` BOOLEAN NOT NULL DEFAULT FALSE,
+	`is_photo_valid` BOOLEAN NOT NULL DEFAULT FALSE,
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`created_by` VARCHAR(255) NOT NULL,
+	`updated_at` TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	`updated_by` BIGINT,
+	PRIMARY KEY(`id`)
+);
@@ -0,0 +1,2 @@
+DROP TABLE `limit_type`;
+DROP TABLE `loan_type`;
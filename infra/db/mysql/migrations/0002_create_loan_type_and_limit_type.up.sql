@@ -0,0 +1,14 @@
+CREATE TABLE `loan_type` (
+	`id` TINYINT NOT NULL AUTO_INCREMENT,
+	`name` ENUM('CAR', 'BIKE', 'WHITE_GOODS') NOT NULL,
+	PRIMARY KEY(`id`)
+);
+
+CREATE TABLE `limit_type` (
+	`id` TINYINT NOT NULL AUTO_INCREMENT,
+	`amount` DECIMAL(15, 2),
+	`term` TINYINT NOT NULL,
+	PRIMARY KEY(`id`)
+);
+
+INSERT INTO `loan_type` (`id`, `name`) VALUES (1, 'CAR'), (2, 'BIKE'), (3, 'WHITE_GOODS');
@@ -0,0 +1,29 @@
+CREATE TABLE `loan` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`user_id` BIGINT NOT NULL,
+	`contract_number` VARCHAR(255) NOT NULL UNIQUE,
+	`otr_amount` DECIMAL(15, 2) NOT NULL,
+	`principal_amount` DECIMAL(15, 2) NOT NULL,
+	`asset_name` VARCHAR(255) NOT NULL,
+	`loan_type_id` TINYINT NOT NULL,
+	`limit_type_id` TINYINT NOT NULL,
+	`status` ENUM('ACTIVE', 'INACTIVE', 'REJECTED'),
+	`start_date` DATETIME,
+	`interest_rate` DECIMAL(5, 2) DEFAULT 0,
+	PRIMARY KEY(`id`),
+	FOREIGN KEY(`user_id`) REFERENCES `user`(`id`) ON UPDATE NO ACTION ON DELETE CASCADE,
+	FOREIGN KEY(`loan_type_id`) REFERENCES `loan_type`(`id`) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	FOREIGN KEY(`limit_type_id`) REFERENCES `limit_type`(`id`) ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+
+CREATE INDEX `loan_user_id_start_date_idx` ON `loan` (`user_id`, `start_date`, `id`);
+
+CREATE TABLE `loan_payment` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`loan_id` BIGINT NOT NULL,
+	`amount` DECIMAL(15, 2),
+	`date` TIMESTAMP NOT NULL,
+	`channel` VARCHAR(255) NOT NULL,
+	PRIMARY KEY(`id`),
+	FOREIGN KEY(`loan_id`) REFERENCES `loan`(`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+);
@@ -0,0 +1,32 @@
+CREATE TABLE `loan_application` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`user_id` BIGINT NOT NULL,
+	`status` ENUM('DRAFT', 'SUBMITTED', 'APPROVED', 'REJECTED', 'ABANDONED') NOT NULL DEFAULT 'DRAFT',
+	`asset_name` VARCHAR(255),
+	`otr_amount` DECIMAL(15, 2),
+	`down_payment` DECIMAL(15, 2),
+	`loan_type_id` TINYINT,
+	`limit_type_id` TINYINT,
+	`tenor` SMALLINT,
+	`national_id_photo` MEDIUMBLOB,
+	`user_photo` MEDIUMBLOB,
+	`loan_id` BIGINT,
+	`contract_number` VARCHAR(255),
+	`decision_reason` VARCHAR(255),
+	`submitted_at` TIMESTAMP NULL,
+	`decided_at` TIMESTAMP NULL,
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	PRIMARY KEY(`id`),
+	FOREIGN KEY(`user_id`) REFERENCES `user`(`id`) ON UPDATE NO ACTION ON DELETE NO ACTION,
+	FOREIGN KEY(`loan_id`) REFERENCES `loan`(`id`) ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+
+CREATE INDEX `loan_application_status_idx` ON `loan_application` (`status`);
+
+CREATE TABLE `contract_number_sequence` (
+	`prefix` VARCHAR(16) NOT NULL,
+	`seq_date` DATE NOT NULL,
+	`last_value` BIGINT NOT NULL,
+	PRIMARY KEY(`prefix`, `seq_date`)
+);
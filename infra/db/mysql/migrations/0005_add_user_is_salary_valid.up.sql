@@ -0,0 +1,2 @@
+-- the kyc salary verdict was kept in memory only, UserEntity.ISSalaryValidated is now persisted
+ALTER TABLE `user` ADD COLUMN `is_salary_valid` BOOLEAN NOT NULL DEFAULT FALSE AFTER `is_photo_valid`;
@@ -0,0 +1,2 @@
+ALTER TABLE `loan` DROP COLUMN `version`;
+ALTER TABLE `user` DROP COLUMN `version`;
@@ -0,0 +1,4 @@
+-- the loan and limit types every environment needs, the seed command keeps them up to date.
+-- the databases migrated before already have the loan types of 0002, their rows are kept.
+INSERT IGNORE INTO `loan_type` (`id`, `name`) VALUES (1, 'CAR'), (2, 'BIKE'), (3, 'WHITE_GOODS');
+INSERT IGNORE INTO `limit_type` (`id`, `amount`, `term`) VALUES (1, 100000, 1), (2, 200000, 2), (3, 500000, 3), (4, 700000, 6);
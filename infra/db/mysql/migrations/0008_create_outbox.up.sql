@@ -0,0 +1,10 @@
+CREATE TABLE `outbox` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`name` VARCHAR(64) NOT NULL,
+	`payload` JSON NOT NULL,
+	`created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`published_at` TIMESTAMP NULL,
+	PRIMARY KEY(`id`)
+);
+
+CREATE INDEX `outbox_published_at_idx` ON `outbox` (`published_at`);
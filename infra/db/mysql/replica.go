@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const showReplicaStatus = `SHOW REPLICA STATUS`
+
+// ReplicaLag returns Seconds_Behind_Source of the replica db, it fails when db is not a replica or its
+// replication is stopped. the lag is whole seconds, a replica in sync reports 0.
+func ReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, showReplicaStatus)
+	if err != nil {
+		return 0, fmt.Errorf("ReplicaLag: error show replica status: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("ReplicaLag: error get columns: %w", err)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, fmt.Errorf("ReplicaLag: error show replica status: %w", err)
+		}
+		return 0, errors.New("ReplicaLag: not a replica")
+	}
+
+	// the status has dozens of columns, which ones depends on the version
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, fmt.Errorf("ReplicaLag: error scan replica status: %w", err)
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errors.New("ReplicaLag: replication is not running")
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("ReplicaLag: invalid Seconds_Behind_Source %q: %w", values[i], err)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, errors.New("ReplicaLag: no Seconds_Behind_Source in replica status")
+}
@@ -0,0 +1,57 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaLag(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    func() *sqlmock.Rows
+		want    time.Duration
+		wantErr string
+	}{
+		{
+			name: "Given a running replica, it should return its seconds behind the source",
+			rows: func() *sqlmock.Rows {
+				return sqlmock.NewRows([]string{"Replica_IO_State", "Seconds_Behind_Source"}).AddRow("Waiting for source to send event", "3")
+			},
+			want: 3 * time.Second,
+		},
+		{
+			name: "Given a stopped replication, it should return an error",
+			rows: func() *sqlmock.Rows {
+				return sqlmock.NewRows([]string{"Replica_IO_State", "Seconds_Behind_Source"}).AddRow("", nil)
+			},
+			wantErr: "replication is not running",
+		},
+		{
+			name:    "Given a database that is not a replica, it should return an error",
+			rows:    func() *sqlmock.Rows { return sqlmock.NewRows([]string{"Replica_IO_State", "Seconds_Behind_Source"}) },
+			wantErr: "not a replica",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			mock.ExpectQuery(showReplicaStatus).WillReturnRows(tt.rows())
+
+			got, err := ReplicaLag(context.Background(), db)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
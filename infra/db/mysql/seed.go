@@ -0,0 +1,9 @@
+package mysql
+
+import "github.com/mfajri11/xyz-backend-monolith/infra/db/seed"
+
+var SeedStatements = seed.Statements{
+	LoanType:  `INSERT INTO loan_type (id, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name)`,
+	LimitType: `INSERT INTO limit_type (id, amount, term) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE amount = VALUES(amount), term = VALUES(term)`,
+	DemoUser:  `INSERT INTO user (national_id, full_name, legal_name, birth_of_place, birth_of_date, salary, is_nid_valid, is_photo_valid, is_salary_valid, created_by) VALUES (?, ?, ?, ?, ?, ?, TRUE, TRUE, TRUE, 'seed') ON DUPLICATE KEY UPDATE full_name = VALUES(full_name)`,
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	infradb "github.com/mfajri11/xyz-backend-monolith/infra/db"
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+	"github.com/mfajri11/xyz-backend-monolith/util/metrics"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// errUniqueViolation is the postgres error code raised when a unique key is violated.
+const errUniqueViolation = "23505"
+
+type Option func(db *sql.DB)
+
+func WithMaxIdleConns(maxIdleConns int) Option {
+	return func(db *sql.DB) {
+		db.SetMaxIdleConns(maxIdleConns)
+	}
+}
+
+func WithMaxOpenConns(maxOpenConns int) Option {
+	return func(db *sql.DB) {
+		db.SetMaxOpenConns(maxOpenConns)
+	}
+}
+
+func WithMaxLifetimeConn(connMaxLifetime time.Duration) Option {
+	return func(db *sql.DB) {
+		db.SetConnMaxLifetime(connMaxLifetime)
+	}
+}
+
+// WithMetrics exports the pool statistics of the database as name.
+func WithMetrics(name string) Option {
+	return func(db *sql.DB) {
+		if err := metrics.RegisterDB(name, db); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Config is a connection to postgres.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Database string
+	// PasswordFunc, when set, returns the password of every new connection instead of Password, so the
+	// password can be rotated without a restart. the open connections keep working until they are recycled.
+	PasswordFunc func(ctx context.Context) (string, error)
+	// SSLMode is the sslmode of libpq: disable, allow, prefer, require, verify-ca or verify-full.
+	SSLMode string
+	// Timezone is the TimeZone of the session, empty is UTC.
+	Timezone       string
+	ConnectTimeout time.Duration
+	// Retry bounds the attempts to reach the database in Open.
+	Retry infradb.Retry
+	// Lazy makes Open return without reaching the database, the first statement connects instead.
+	Lazy bool
+}
+
+func (cfg Config) url(password string) *url.URL {
+	query := url.Values{}
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "prefer"
+	}
+	query.Set("sslmode", sslMode)
+	timezone := cfg.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	query.Set("timezone", timezone)
+	if cfg.ConnectTimeout > 0 {
+		// libpq takes whole seconds, a shorter timeout would mean none
+		query.Set("connect_timeout", strconv.Itoa(max(int(cfg.ConnectTimeout/time.Second), 1)))
+	}
+
+	return &url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Username, password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     "/" + cfg.Database,
+		RawQuery: query.Encode(),
+	}
+}
+
+// DataSource returns the dsn of cfg.
+func (cfg Config) DataSource() string {
+	return cfg.url(cfg.Password).String()
+}
+
+// String returns the dsn of cfg with the password masked, it is safe to log.
+func (cfg Config) String() string {
+	u := cfg.url(cfg.Password)
+	if cfg.PasswordFunc != nil {
+		u.User = url.UserPassword(cfg.Username, "xxxxx")
+	}
+	return u.Redacted()
+}
+
+// Open returns the database of cfg once it answers a ping, retrying as cfg.Retry allows. the
+// attempts are logged with the dsn masked.
+func Open(ctx context.Context, cfg Config, opts ...Option) (*sql.DB, error) {
+	connConfig, err := pgx.ParseConfig(cfg.DataSource())
+	if err != nil {
+		return nil, fmt.Errorf("Open: invalid config %s: %w", cfg, err)
+	}
+
+	var stdlibOpts []stdlib.OptionOpenDB
+	if cfg.PasswordFunc != nil {
+		stdlibOpts = append(stdlibOpts, stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+			password, err := cfg.PasswordFunc(ctx)
+			if err != nil {
+				return fmt.Errorf("Open: error get password of %s: %w", cfg, err)
+			}
+			cc.Password = password
+			return nil
+		}))
+	}
+
+	// every statement gets a span under the span of its context
+	db := otelsql.OpenDB(stdlib.GetConnector(*connConfig, stdlibOpts...),
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
+	for _, opt := range opts {
+		opt(db)
+	}
+
+	if cfg.Lazy {
+		log.Ctx(ctx).Info().Str("database", cfg.String()).Msg("database connects on first use")
+		return db, nil
+	}
+	if err := infradb.WaitReady(ctx, db, cfg.Retry, cfg.ConnectTimeout, cfg.String()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Open: %w", err)
+	}
+
+	return db, nil
+}
+
+// IsUniqueViolation reports whether err is caused by a unique key violation.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == errUniqueViolation
+}
+
+// Rebind numbers the ? placeholders of query as $1, $2... so a query can be built without
+// counting its arguments. query must not contain a literal ?.
+func Rebind(query string) string {
+	var (
+		b strings.Builder
+		n int
+	)
+	b.Grow(len(query) + 8)
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"strconv"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/infra/db/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// lockRetry is how often the advisory lock is tried again while another session holds it.
+const lockRetry = 500 * time.Millisecond
+
+// MigrationDialect takes the migration lock with a session advisory lock, postgres DDL is
+// transactional so each migration is applied atomically along with its record.
+var MigrationDialect = migrate.Dialect{
+	Lock: func(ctx context.Context, conn *sql.Conn) (bool, error) {
+		deadline := time.Now().Add(migrate.LockTimeout)
+		for {
+			var locked bool
+			if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, migrate.LockName).Scan(&locked); err != nil {
+				return false, err
+			}
+			if locked || time.Now().After(deadline) {
+				return locked, nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(lockRetry):
+			}
+		}
+	},
+	Unlock: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, migrate.LockName)
+		return err
+	},
+	Placeholder:      func(n int) string { return "$" + strconv.Itoa(n) },
+	TransactionalDDL: true,
+}
+
+// NewMigrator returns a migrator of the postgres migrations embedded in the binary.
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, MigrationDialect, sub)
+}
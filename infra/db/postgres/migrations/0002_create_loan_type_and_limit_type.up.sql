@@ -0,0 +1,10 @@
+CREATE TABLE loan_type (
+	id SMALLINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	name VARCHAR(16) NOT NULL CHECK (name IN ('CAR', 'BIKE', 'WHITE_GOODS'))
+);
+
+CREATE TABLE limit_type (
+	id SMALLINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	amount NUMERIC(15, 2),
+	term SMALLINT NOT NULL
+);
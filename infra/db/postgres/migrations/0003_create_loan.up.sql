@@ -0,0 +1,26 @@
+CREATE TABLE loan (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user" (id) ON DELETE CASCADE,
+	contract_number VARCHAR(255) NOT NULL UNIQUE,
+	otr_amount NUMERIC(15, 2) NOT NULL,
+	principal_amount NUMERIC(15, 2) NOT NULL,
+	asset_name VARCHAR(255) NOT NULL,
+	loan_type_id SMALLINT NOT NULL REFERENCES loan_type (id),
+	limit_type_id SMALLINT NOT NULL REFERENCES limit_type (id),
+	status VARCHAR(16) CHECK (status IN ('ACTIVE', 'INACTIVE', 'REJECTED')),
+	start_date TIMESTAMP,
+	interest_rate NUMERIC(5, 2) DEFAULT 0
+);
+
+CREATE INDEX loan_user_id_start_date_idx ON loan (user_id, start_date, id);
+
+CREATE TABLE loan_payment (
+	id BIGSERIAL PRIMARY KEY,
+	loan_id BIGINT NOT NULL REFERENCES loan (id) ON DELETE CASCADE,
+	amount NUMERIC(15, 2),
+	date TIMESTAMP NOT NULL,
+	channel VARCHAR(255) NOT NULL
+);
+
+-- postgres does not index foreign keys by itself
+CREATE INDEX loan_payment_loan_id_idx ON loan_payment (loan_id);
@@ -0,0 +1,2 @@
+DROP TABLE contract_number_sequence;
+DROP TABLE loan_application;
@@ -0,0 +1,30 @@
+CREATE TABLE loan_application (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES "user" (id),
+	status VARCHAR(16) NOT NULL DEFAULT 'DRAFT' CHECK (status IN ('DRAFT', 'SUBMITTED', 'APPROVED', 'REJECTED', 'ABANDONED')),
+	asset_name VARCHAR(255),
+	otr_amount NUMERIC(15, 2),
+	down_payment NUMERIC(15, 2),
+	loan_type_id SMALLINT,
+	limit_type_id SMALLINT,
+	tenor SMALLINT,
+	national_id_photo BYTEA,
+	user_photo BYTEA,
+	loan_id BIGINT REFERENCES loan (id),
+	contract_number VARCHAR(255),
+	decision_reason VARCHAR(255),
+	submitted_at TIMESTAMP NULL,
+	decided_at TIMESTAMP NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX loan_application_user_id_idx ON loan_application (user_id);
+CREATE INDEX loan_application_status_idx ON loan_application (status);
+
+CREATE TABLE contract_number_sequence (
+	prefix VARCHAR(16) NOT NULL,
+	seq_date DATE NOT NULL,
+	last_value BIGINT NOT NULL,
+	PRIMARY KEY (prefix, seq_date)
+);
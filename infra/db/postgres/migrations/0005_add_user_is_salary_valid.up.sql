@@ -0,0 +1 @@
+ALTER TABLE "user" ADD COLUMN is_salary_valid BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- every write of a loan or a user increments its version, a conditional write names the version it read
+ALTER TABLE "user" ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE loan ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
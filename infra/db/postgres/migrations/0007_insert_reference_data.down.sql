@@ -0,0 +1 @@
+-- the reference data is kept, the loans refer to it and 0002 drops it with its tables.
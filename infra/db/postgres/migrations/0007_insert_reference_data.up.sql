@@ -0,0 +1,3 @@
+-- the loan and limit types every environment needs, the seed command keeps them up to date.
+INSERT INTO loan_type (id, name) VALUES (1, 'CAR'), (2, 'BIKE'), (3, 'WHITE_GOODS') ON CONFLICT (id) DO NOTHING;
+INSERT INTO limit_type (id, amount, term) VALUES (1, 100000, 1), (2, 200000, 2), (3, 500000, 3), (4, 700000, 6) ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,9 @@
+CREATE TABLE outbox (
+	id BIGSERIAL PRIMARY KEY,
+	name VARCHAR(64) NOT NULL,
+	payload JSONB NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	published_at TIMESTAMP NULL
+);
+
+CREATE INDEX outbox_published_at_idx ON outbox (published_at);
@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// replicaLag is null on a primary. the replay timestamp is the one of the last transaction replayed, so the lag
+// of a replica in sync grows while the primary has nothing to write; keep the max lag above the write interval.
+const replicaLag = `SELECT CASE WHEN pg_is_in_recovery() THEN COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`
+
+// ReplicaLag returns the time since the last transaction the standby db replayed, it fails when db is not a standby.
+func ReplicaLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	var seconds sql.NullFloat64
+	if err := db.QueryRowContext(ctx, replicaLag).Scan(&seconds); err != nil {
+		return 0, fmt.Errorf("ReplicaLag: error get replay lag: %w", err)
+	}
+	if !seconds.Valid {
+		return 0, errors.New("ReplicaLag: not a standby")
+	}
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
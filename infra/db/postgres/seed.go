@@ -0,0 +1,9 @@
+package postgres
+
+import "github.com/mfajri11/xyz-backend-monolith/infra/db/seed"
+
+var SeedStatements = seed.Statements{
+	LoanType:  `INSERT INTO loan_type (id, name) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name`,
+	LimitType: `INSERT INTO limit_type (id, amount, term) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET amount = EXCLUDED.amount, term = EXCLUDED.term`,
+	DemoUser:  `INSERT INTO "user" (national_id, full_name, legal_name, birth_of_place, birth_of_date, salary, is_nid_valid, is_photo_valid, is_salary_valid, created_by) VALUES ($1, $2, $3, $4, $5, $6, TRUE, TRUE, TRUE, 'seed') ON CONFLICT (national_id) DO UPDATE SET full_name = EXCLUDED.full_name`,
+}
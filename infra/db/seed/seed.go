@@ -0,0 +1,105 @@
+// Package seed upserts the reference data, and the demo users of local environments, with the
+// statements of the database in use.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Statements are the upserts of a database, keyed by id for the types and by national id for the users.
+// their arguments are in the order of the columns of the tables.
+type Statements struct {
+	// LoanType takes id and name.
+	LoanType string
+	// LimitType takes id, amount and term.
+	LimitType string
+	// DemoUser takes national_id, full_name, legal_name, birth_of_place, birth_of_date and salary.
+	DemoUser string
+}
+
+// LoanType is a row of loan_type.
+type LoanType struct {
+	ID   int
+	Name string
+}
+
+// LimitType is a row of limit_type, a credit limit by tenor in months.
+type LimitType struct {
+	ID     int
+	Amount float64
+	Term   int
+}
+
+// DemoUser is a fictitious customer with an already passed kyc, for local development only.
+type DemoUser struct {
+	NationalID, FullName, LegalName, BirthOfPlace, BirthOfDate string
+	Salary                                                     float64
+}
+
+// the seeded data, exported so a store without sql, or a test, can load the same rows.
+var (
+	LoanTypes = []LoanType{
+		{1, "CAR"},
+		{2, "BIKE"},
+		{3, "WHITE_GOODS"},
+	}
+
+	LimitTypes = []LimitType{
+		{1, 100000, 1},
+		{2, 200000, 2},
+		{3, 500000, 3},
+		{4, 700000, 6},
+	}
+
+	DemoUsers = []DemoUser{
+		{"3171012001900001", "Budi", "Budi Santoso", "Jakarta", "1990-01-20", 8500000},
+		{"3273014505920002", "Annisa", "Annisa Rahma", "Bandung", "1992-05-05", 12000000},
+	}
+)
+
+// Reference upserts the loan and limit types, it can be run any number of times.
+func Reference(ctx context.Context, db *sql.DB, stmts Statements) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Reference: error begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range LoanTypes {
+		if _, err := tx.ExecContext(ctx, stmts.LoanType, t.ID, t.Name); err != nil {
+			return fmt.Errorf("Reference: error upsert loan type %s: %w", t.Name, err)
+		}
+	}
+	for _, t := range LimitTypes {
+		if _, err := tx.ExecContext(ctx, stmts.LimitType, t.ID, t.Amount, t.Term); err != nil {
+			return fmt.Errorf("Reference: error upsert limit type %d: %w", t.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Reference: error commit: %w", err)
+	}
+	return nil
+}
+
+// Demo upserts the demo users, keyed by their national id.
+func Demo(ctx context.Context, db *sql.DB, stmts Statements) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Demo: error begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, u := range DemoUsers {
+		if _, err := tx.ExecContext(ctx, stmts.DemoUser, u.NationalID, u.FullName, u.LegalName, u.BirthOfPlace, u.BirthOfDate, u.Salary); err != nil {
+			return fmt.Errorf("Demo: error upsert demo user: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Demo: error commit: %w", err)
+	}
+	return nil
+}
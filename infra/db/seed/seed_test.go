@@ -0,0 +1,60 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var stmts = Statements{
+	LoanType:  `INSERT INTO loan_type (id, name) VALUES (?, ?)`,
+	LimitType: `INSERT INTO limit_type (id, amount, term) VALUES (?, ?, ?)`,
+	DemoUser:  `INSERT INTO user (national_id, full_name, legal_name, birth_of_place, birth_of_date, salary) VALUES (?, ?, ?, ?, ?, ?)`,
+}
+
+func TestReference(t *testing.T) {
+	tests := []struct {
+		name        string
+		prepareMock func(mock sqlmock.Sqlmock)
+		wantErr     bool
+	}{
+		{
+			name: "Given an empty database, it should upsert every loan and limit type in one transaction",
+			prepareMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				for _, lt := range LoanTypes {
+					mock.ExpectExec(regexp.QuoteMeta(stmts.LoanType)).WithArgs(lt.ID, lt.Name).WillReturnResult(sqlmock.NewResult(int64(lt.ID), 1))
+				}
+				for _, lt := range LimitTypes {
+					mock.ExpectExec(regexp.QuoteMeta(stmts.LimitType)).WithArgs(lt.ID, lt.Amount, lt.Term).WillReturnResult(sqlmock.NewResult(int64(lt.ID), 1))
+				}
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Given an upsert fails, it should roll back",
+			prepareMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(stmts.LoanType)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			tt.prepareMock(mock)
+
+			err = Reference(context.Background(), db, stmts)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// minSweep is the number of keys a memory store holds before it first sweeps the expired ones.
+const minSweep = 1024
+
+type memoryState struct {
+	value   []byte
+	expires time.Time
+}
+
+// Memory is a store in process, the limits of an instance are its own. the expired keys are swept as the
+// store grows. it is safe for concurrent use.
+type Memory struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	states  map[string]memoryState
+	sweepAt int
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		now:     time.Now,
+		states:  map[string]memoryState{},
+		sweepAt: minSweep,
+	}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.states[key]
+	if !ok || !m.now().Before(s.expires) {
+		return nil, false, nil
+	}
+	return s.value, true, nil
+}
+
+func (m *Memory) Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var old []byte
+	if s, ok := m.states[key]; ok && now.Before(s.expires) {
+		old = s.value
+	}
+
+	value, err := fn(old)
+	if err != nil {
+		return err
+	}
+	m.states[key] = memoryState{value: value, expires: now.Add(ttl)}
+
+	if len(m.states) >= m.sweepAt {
+		for key, s := range m.states {
+			if !now.Before(s.expires) {
+				delete(m.states, key)
+			}
+		}
+		m.sweepAt = max(2*len(m.states), minSweep)
+	}
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.states, key)
+	}
+	return nil
+}
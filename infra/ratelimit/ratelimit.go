@@ -0,0 +1,290 @@
+// Package ratelimit counts the requests of a key, e.g. the loan applications of a user, against a limit per
+// period. the counts are kept in memory or in a redis shared by every instance.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// the algorithms of a rule.
+const (
+	// TokenBucket refills Limit tokens per Period into a bucket of Burst tokens, a request takes one.
+	TokenBucket = "token-bucket"
+	// SlidingWindow counts the requests of the last Period, weighing the ones of the previous window by how
+	// much of it is still in the last Period.
+	SlidingWindow = "sliding-window"
+	// FixedWindow counts the requests of windows aligned on multiples of Period since the unix epoch, e.g. a
+	// Period of 24h counts per UTC day.
+	FixedWindow = "fixed-window"
+)
+
+// Rule allows a key Limit requests per Period.
+type Rule struct {
+	Algorithm string
+	Limit     int
+	Period    time.Duration
+	// Burst is the size of the bucket of TokenBucket, Limit when 0.
+	Burst int
+}
+
+// Decision is the outcome of a request against a rule, with what the client needs to pace itself.
+type Decision struct {
+	Allowed bool
+	// Limit is the number of requests allowed at once.
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the whole limit is available again.
+	ResetAfter time.Duration
+	// RetryAfter is the time a refused request has to wait, 0 when it is allowed.
+	RetryAfter time.Duration
+}
+
+// Error is the error of a request refused over a limit, the caller is told when to retry with Decision.
+type Error struct {
+	Decision Decision
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("over the limit of %d, retry after %s", e.Decision.Limit, e.Decision.RetryAfter)
+}
+
+// Store keeps the state of the keys. Get returns the state of key, false when it is missing. Update replaces
+// the state of key with the one fn returns for it, nil when the key is missing, and keeps it for ttl; it has
+// to be atomic, fn may run again when it is not. cache.Redis is a store shared by every instance.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) ([]byte, error)) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+type Option func(l *Limiter)
+
+// WithClock times the requests with now instead of the wall clock.
+func WithClock(now func() time.Time) Option {
+	return func(l *Limiter) {
+		l.now = now
+	}
+}
+
+func New(store Store, opts ...Option) *Limiter {
+	l := &Limiter{
+		store: store,
+		now:   time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// state is the state of a key, Tokens and At for TokenBucket, the others for the windows.
+type state struct {
+	Tokens float64 `json:"tokens,omitempty"`
+	At     int64   `json:"at,omitempty"`
+	Start  int64   `json:"start,omitempty"`
+	Count  int     `json:"count,omitempty"`
+	Prev   int     `json:"prev,omitempty"`
+}
+
+// Allow takes a request of key under rule, a refused request is not counted. the key has to be unique to
+// the rule, the state of one algorithm means nothing to another.
+func (l *Limiter) Allow(ctx context.Context, key string, rule Rule) (Decision, error) {
+	take, err := rule.take()
+	if err != nil {
+		return Decision{}, fmt.Errorf("Allow: %w", err)
+	}
+
+	var decision Decision
+	err = l.store.Update(ctx, key, rule.ttl(), func(b []byte) ([]byte, error) {
+		s, err := decodeState(b)
+		if err != nil {
+			return nil, err
+		}
+		decision = take(&s, l.now())
+		return json.Marshal(s)
+	})
+	if err != nil {
+		return Decision{}, fmt.Errorf("Allow: %w", err)
+	}
+
+	return decision, nil
+}
+
+// Check returns the decision Allow would take for a request of key under rule, without counting it. a
+// request under several rules is checked against all of them first, so the ones allowing it are not used up
+// when another refuses it.
+func (l *Limiter) Check(ctx context.Context, key string, rule Rule) (Decision, error) {
+	take, err := rule.take()
+	if err != nil {
+		return Decision{}, fmt.Errorf("Check: %w", err)
+	}
+
+	b, _, err := l.store.Get(ctx, key)
+	if err != nil {
+		return Decision{}, fmt.Errorf("Check: %w", err)
+	}
+	s, err := decodeState(b)
+	if err != nil {
+		return Decision{}, fmt.Errorf("Check: %w", err)
+	}
+
+	return take(&s, l.now()), nil
+}
+
+// Reset forgets the requests of keys, e.g. to unlock a user refused by mistake.
+func (l *Limiter) Reset(ctx context.Context, keys ...string) error {
+	if err := l.store.Delete(ctx, keys...); err != nil {
+		return fmt.Errorf("Reset: %w", err)
+	}
+	return nil
+}
+
+// Hash returns the key part of a personal value, e.g. a national id, so the store never holds the value.
+func Hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// decodeState decodes the state of a key, a missing key is a zero state which every algorithm reads as
+// unused.
+func decodeState(b []byte) (state, error) {
+	var s state
+	if b != nil {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return state{}, fmt.Errorf("error decode state: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// take returns the algorithm of the rule.
+func (r Rule) take() (func(s *state, now time.Time) Decision, error) {
+	if r.Limit <= 0 || r.Period <= 0 {
+		return nil, fmt.Errorf("invalid rule, limit %d per %s", r.Limit, r.Period)
+	}
+
+	switch r.Algorithm {
+	case TokenBucket:
+		return r.takeToken, nil
+	case SlidingWindow:
+		return r.takeSlidingWindow, nil
+	case FixedWindow:
+		return r.takeFixedWindow, nil
+	}
+	return nil, fmt.Errorf("unknown algorithm %q", r.Algorithm)
+}
+
+// ttl is how long the state of a key matters, past it the key is as good as unused.
+func (r Rule) ttl() time.Duration {
+	switch r.Algorithm {
+	case TokenBucket:
+		return r.Period * time.Duration(r.capacity()) / time.Duration(r.Limit)
+	case SlidingWindow:
+		return 2 * r.Period
+	}
+	return r.Period
+}
+
+func (r Rule) capacity() int {
+	if r.Algorithm == TokenBucket && r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+func (r Rule) takeToken(s *state, now time.Time) Decision {
+	capacity := float64(r.capacity())
+	perToken := float64(r.Period) / float64(r.Limit)
+
+	tokens := capacity
+	if s.At != 0 {
+		elapsed := float64(now.UnixNano() - s.At)
+		tokens = math.Min(capacity, s.Tokens+max(elapsed, 0)/perToken)
+	}
+
+	d := Decision{Limit: r.capacity()}
+	if tokens >= 1 {
+		tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+	d.Remaining = int(tokens)
+	d.ResetAfter = time.Duration((capacity - tokens) * perToken)
+
+	s.Tokens, s.At = tokens, now.UnixNano()
+	return d
+}
+
+func (r Rule) takeSlidingWindow(s *state, now time.Time) Decision {
+	start := r.windowStart(now)
+	switch {
+	case s.Start == start:
+	case s.Start == start-int64(r.Period):
+		s.Prev, s.Count = s.Count, 0
+	default:
+		s.Prev, s.Count = 0, 0
+	}
+	s.Start = start
+
+	period := float64(r.Period)
+	elapsed := float64(now.UnixNano() - start)
+	estimate := float64(s.Prev)*(1-elapsed/period) + float64(s.Count)
+
+	d := Decision{Limit: r.Limit}
+	if estimate+1 <= float64(r.Limit) {
+		s.Count++
+		estimate++
+		d.Allowed = true
+	} else if s.Count+1 <= r.Limit {
+		// the previous window has to weigh less, until prev*(1-t/period) <= limit-1-count
+		t := period * (1 - float64(r.Limit-1-s.Count)/float64(s.Prev))
+		d.RetryAfter = time.Duration(t - elapsed)
+	} else {
+		// the current window is full, it has to weigh less as the previous window of the next one
+		t := period * (1 - float64(r.Limit-1)/float64(s.Count))
+		d.RetryAfter = time.Duration(period - elapsed + max(t, 0))
+	}
+	d.Remaining = max(int(float64(r.Limit)-estimate), 0)
+
+	// the requests of the current window weigh until the end of the next one
+	d.ResetAfter = time.Duration(period - elapsed)
+	if s.Count > 0 {
+		d.ResetAfter += r.Period
+	}
+	return d
+}
+
+func (r Rule) takeFixedWindow(s *state, now time.Time) Decision {
+	start := r.windowStart(now)
+	if s.Start != start {
+		s.Start, s.Count = start, 0
+	}
+
+	d := Decision{Limit: r.Limit, ResetAfter: time.Duration(start + int64(r.Period) - now.UnixNano())}
+	if s.Count < r.Limit {
+		s.Count++
+		d.Allowed = true
+	} else {
+		d.RetryAfter = d.ResetAfter
+	}
+	d.Remaining = r.Limit - s.Count
+	return d
+}
+
+// windowStart returns the start of the window of now in unix nanoseconds.
+func (r Rule) windowStart(now time.Time) int64 {
+	ns := now.UnixNano()
+	return ns - ns%int64(r.Period)
+}
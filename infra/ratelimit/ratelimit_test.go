@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mfajri11/xyz-backend-monolith/infra/cache"
+	"github.com/mfajri11/xyz-backend-monolith/infra/cache/cachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	type step struct {
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name  string
+		rule  Rule
+		steps []step
+	}{
+		{
+			name: "Given a token bucket, it should allow a burst and then one request per refill",
+			rule: Rule{Algorithm: TokenBucket, Limit: 2, Period: time.Minute, Burst: 3},
+			steps: []step{
+				{allowed: true, remaining: 2},
+				{allowed: true, remaining: 1},
+				{allowed: true, remaining: 0},
+				{allowed: false, remaining: 0, retryAfter: 30 * time.Second},
+				{after: 30 * time.Second, allowed: true, remaining: 0},
+			},
+		},
+		{
+			name: "Given a sliding window, it should weigh the previous window",
+			rule: Rule{Algorithm: SlidingWindow, Limit: 2, Period: time.Minute},
+			steps: []step{
+				{allowed: true, remaining: 1},
+				{allowed: true, remaining: 0},
+				{allowed: false, remaining: 0, retryAfter: 90 * time.Second},
+				{after: time.Minute, allowed: false, remaining: 0, retryAfter: 30 * time.Second},
+				{after: 30 * time.Second, allowed: true, remaining: 0},
+			},
+		},
+		{
+			name: "Given a daily fixed window, it should reset at midnight UTC",
+			rule: Rule{Algorithm: FixedWindow, Limit: 2, Period: 24 * time.Hour},
+			steps: []step{
+				{allowed: true, remaining: 1},
+				{allowed: true, remaining: 0},
+				{allowed: false, remaining: 0, retryAfter: 14 * time.Hour},
+				{after: 14 * time.Hour, allowed: true, remaining: 1},
+			},
+		},
+	}
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemory() },
+		"redis": func(t *testing.T) Store {
+			r := cache.NewRedis(cachetest.NewRedisServer(t, "").Addr())
+			t.Cleanup(func() { r.Close() })
+			return r
+		},
+	}
+	for storeName, newStore := range stores {
+		for _, tt := range tests {
+			t.Run(storeName+"/"+tt.name, func(t *testing.T) {
+				now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+				l := New(newStore(t), WithClock(func() time.Time { return now }))
+
+				for i, step := range tt.steps {
+					now = now.Add(step.after)
+					got, err := l.Allow(context.Background(), "loan:user:1", tt.rule)
+					require.NoError(t, err)
+					msg := fmt.Sprintf("request %d", i+1)
+					assert.Equal(t, step.allowed, got.Allowed, msg)
+					assert.Equal(t, step.remaining, got.Remaining, msg)
+					assert.Equal(t, step.retryAfter, got.RetryAfter, msg)
+				}
+			})
+		}
+	}
+}
+
+func TestLimiter_Allow_Keys(t *testing.T) {
+	l := New(NewMemory())
+	rule := Rule{Algorithm: FixedWindow, Limit: 1, Period: time.Hour}
+
+	got, err := l.Allow(context.Background(), "loan:user:1", rule)
+	require.NoError(t, err)
+	assert.True(t, got.Allowed)
+	got, err = l.Allow(context.Background(), "loan:user:2", rule)
+	require.NoError(t, err)
+	assert.True(t, got.Allowed, "the keys should be limited apart")
+}
+
+func TestLimiter_Check(t *testing.T) {
+	l := New(NewMemory())
+	rule := Rule{Algorithm: FixedWindow, Limit: 1, Period: time.Hour}
+
+	for range 2 {
+		got, err := l.Check(context.Background(), "loan:user:1", rule)
+		require.NoError(t, err)
+		assert.True(t, got.Allowed, "a check should not count the request")
+	}
+	_, err := l.Allow(context.Background(), "loan:user:1", rule)
+	require.NoError(t, err)
+	got, err := l.Check(context.Background(), "loan:user:1", rule)
+	require.NoError(t, err)
+	assert.False(t, got.Allowed)
+}
+
+func TestLimiter_Reset(t *testing.T) {
+	l := New(NewMemory())
+	rule := Rule{Algorithm: FixedWindow, Limit: 1, Period: time.Hour}
+
+	_, err := l.Allow(context.Background(), "loan:user:1", rule)
+	require.NoError(t, err)
+	require.NoError(t, l.Reset(context.Background(), "loan:user:1"))
+	got, err := l.Allow(context.Background(), "loan:user:1", rule)
+	require.NoError(t, err)
+	assert.True(t, got.Allowed, "a reset key should start over")
+}
+
+func TestLimiter_Allow_InvalidRule(t *testing.T) {
+	l := New(NewMemory())
+
+	_, err := l.Allow(context.Background(), "key", Rule{Algorithm: TokenBucket, Period: time.Minute})
+	assert.ErrorContains(t, err, "invalid rule")
+
+	_, err = l.Allow(context.Background(), "key", Rule{Algorithm: "leaky-bucket", Limit: 1, Period: time.Minute})
+	assert.ErrorContains(t, err, "unknown algorithm")
+}
+
+func TestMemory_Sweep(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	keep := func(state []byte) ([]byte, error) { return []byte("1"), nil }
+
+	for i := range minSweep - 1 {
+		require.NoError(t, m.Update(context.Background(), fmt.Sprint(i), time.Second, keep))
+	}
+	now = now.Add(time.Second)
+	require.NoError(t, m.Update(context.Background(), "last", time.Minute, keep))
+	assert.Len(t, m.states, 1, "the expired keys should be swept once the store is full")
+}
@@ -1,10 +1,95 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	"github.com/mfajri11/xyz-backend-monolith/app"
+	"github.com/mfajri11/xyz-backend-monolith/util/config"
 	"github.com/mfajri11/xyz-backend-monolith/util/log"
 )
 
+// overrides collects the repeatable --set flag.
+type overrides map[string]string
+
+func (o overrides) String() string {
+	return fmt.Sprint(map[string]string(o))
+}
+
+func (o overrides) Set(s string) error {
+	path, value, err := config.ParseOverride(s)
+	if err != nil {
+		return err
+	}
+	o[path] = value
+	return nil
+}
+
+const usage = `usage: xyz-backend-monolith [flags] <command> [args]
+
+commands:
+  serve    serve the api, the default
+  worker   run the background jobs
+  migrate  up | down [steps] | status | goto <version>
+  seed     upsert the reference data, --demo also adds demo users
+  admin    run a one-off operation, run admin without args to list them
+
+flags:
+`
+
 func main() {
-	log.Fatal(app.Run(), "fail to start application")
+	opts := config.Options{Overrides: overrides{}}
+	dump, storage := false, ""
+	flag.StringVar(&opts.Path, "config", "", "path of the yaml configuration, defaults to $CONFIG_PATH")
+	flag.Var(overrides(opts.Overrides), "set", "override a configuration key, e.g. --set server.port=9001 (repeatable)")
+	flag.BoolVar(&dump, "dump-config", false, "print the effective configuration with the secrets masked and exit")
+	flag.StringVar(&storage, "storage", "", "sql or memory, memory runs without a database and loses the data on exit, shorthand for --set storage=...")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if storage != "" {
+		opts.Overrides["storage"] = storage
+	}
+
+	if dump {
+		cfg, err := config.Load(opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := config.Dump(os.Stdout, cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	command, args := "serve", []string(nil)
+	if flag.NArg() > 0 {
+		command, args = flag.Arg(0), flag.Args()[1:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		err = app.Serve(opts)
+	case "worker":
+		err = app.Worker(opts)
+	case "migrate":
+		err = app.Migrate(opts, args)
+	case "seed":
+		err = app.Seed(opts, args)
+	case "admin":
+		err = app.Admin(opts, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err, "fail to run %s", command)
+	}
 }
@@ -0,0 +1,43 @@
+package apperror
+
+import "net/http"
+
+// the error catalog. codes are part of the api contract: clients branch on them, so a code must never be
+// renamed or reused for a different meaning. messages are safe to show to the customer, they are the english
+// defaults of the localized messages in util/i18n where every new code needs an entry in each bundle.
+
+// generic errors, one per http status the api answers with.
+var (
+	ErrInternalServerError = newSentinel(http.StatusInternalServerError, "INTERNAL_ERROR", "oops! something went wrong")
+	ErrNotFound            = newSentinel(http.StatusNotFound, "NOT_FOUND", "resource not found")
+	ErrBadRequest          = newSentinel(http.StatusBadRequest, "BAD_REQUEST", "bad request")
+	ErrUnauthorized        = newSentinel(http.StatusUnauthorized, "UNAUTHORIZED", "authentication is required")
+	ErrForbidden           = newSentinel(http.StatusForbidden, "FORBIDDEN", "you are not allowed to access this resource")
+	ErrConflict            = newSentinel(http.StatusConflict, "CONFLICT", "resource already exists")
+	ErrUnprocessableEntity = newSentinel(http.StatusUnprocessableEntity, "VALIDATION_FAILED", "request is invalid")
+	ErrTooManyRequests     = newSentinel(http.StatusTooManyRequests, "TOO_MANY_REQUESTS", "too many requests, please try again later")
+	ErrServiceUnavailable  = newSentinel(http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "service is temporarily unavailable")
+	ErrVersionConflict     = newSentinel(http.StatusConflict, "VERSION_CONFLICT", "the resource has been changed by someone else, reload it and try again")
+)
+
+// loan errors.
+var (
+	ErrLoanLimitExceeded             = newSentinel(http.StatusUnprocessableEntity, "LOAN_LIMIT_EXCEEDED", "the amount exceeds your loan limit")
+	ErrContractNumberConflict        = newSentinel(http.StatusConflict, "CONTRACT_NUMBER_CONFLICT", "contract number already exists")
+	ErrLoanApplicationNotEditable    = newSentinel(http.StatusConflict, "LOAN_APPLICATION_NOT_EDITABLE", "the loan application can no longer be changed")
+	ErrLoanApplicationIncomplete     = newSentinel(http.StatusUnprocessableEntity, "LOAN_APPLICATION_INCOMPLETE", "the loan application is not complete yet")
+	ErrLoanApplicationAlreadyDecided = newSentinel(http.StatusConflict, "LOAN_APPLICATION_ALREADY_DECIDED", "the loan application has already been decided")
+	ErrLoanNotActive                 = newSentinel(http.StatusConflict, "LOAN_NOT_ACTIVE", "the loan is not active")
+	ErrPaymentExceedsOutstanding     = newSentinel(http.StatusUnprocessableEntity, "PAYMENT_EXCEEDS_OUTSTANDING", "the amount exceeds the outstanding amount of the loan")
+)
+
+// kyc errors.
+var (
+	ErrKYCNationalIDInvalid = newSentinel(http.StatusUnprocessableEntity, "KYC_NIK_INVALID", "the national id could not be verified")
+	ErrKYCNameMismatch      = newSentinel(http.StatusUnprocessableEntity, "KYC_NAME_MISMATCH", "the name does not match the national id")
+	ErrKYCBirthDateMismatch = newSentinel(http.StatusUnprocessableEntity, "KYC_BIRTH_DATE_MISMATCH", "the birth date does not match the national id")
+	ErrKYCSalaryMismatch    = newSentinel(http.StatusUnprocessableEntity, "KYC_SALARY_MISMATCH", "the salary could not be verified")
+	ErrKYCPhotoMismatch     = newSentinel(http.StatusUnprocessableEntity, "KYC_PHOTO_MISMATCH", "the photo does not match the national id")
+	ErrKYCUnavailable       = newSentinel(http.StatusServiceUnavailable, "KYC_UNAVAILABLE", "identity verification is temporarily unavailable")
+	ErrKYCDailyCapReached   = newSentinel(http.StatusTooManyRequests, "KYC_DAILY_CAP_REACHED", "the national id has been verified too many times today")
+)
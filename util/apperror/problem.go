@@ -0,0 +1,44 @@
+package apperror
+
+import (
+	"net/http"
+	"strings"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body. it only carries what is safe to show to the client,
+// the internal cause of the error has to be logged by the caller.
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      string      `json:"code"`
+	TraceID   string      `json:"trace_id,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	Errors    interface{} `json:"errors,omitempty"`
+}
+
+// NewProblem maps err to the problem of its sentinel, errors without a sentinel are internal server errors.
+func NewProblem(err error, instance string) Problem {
+	sentinel := SentinelError(err)
+	if sentinel == nil {
+		sentinel = ErrInternalServerError
+	}
+
+	return Problem{
+		Type:     ProblemType(sentinel.code),
+		Title:    http.StatusText(sentinel.statusCode),
+		Status:   sentinel.statusCode,
+		Detail:   sentinel.message,
+		Instance: instance,
+		Code:     sentinel.code,
+	}
+}
+
+// ProblemType returns the problem type uri of an error code, e.g. urn:problem-type:xyz:loan-limit-exceeded.
+func ProblemType(code string) string {
+	return "urn:problem-type:xyz:" + strings.ReplaceAll(strings.ToLower(code), "_", "-")
+}
@@ -2,14 +2,6 @@ package apperror
 
 import (
 	"errors"
-	"fmt"
-	"net/http"
-)
-
-var (
-	ErrInternalServerError = &sentinelError{statusCode: http.StatusInternalServerError, message: "oops! something went wrong"}
-	ErrNotFound            = &sentinelError{statusCode: http.StatusNotFound, message: "resource not found"}
-	ErrBadRequest          = &sentinelError{statusCode: http.StatusBadRequest, message: "bad request"}
 )
 
 type APIError interface {
@@ -20,8 +12,10 @@ type APIError interface {
 type Causer interface {
 	Cause() error
 }
+
 type sentinelError struct {
 	statusCode int
+	code       string
 	message    string
 }
 
@@ -37,6 +31,11 @@ func (s sentinelError) APIError() (int, string) {
 	return s.statusCode, s.message
 }
 
+// Code returns the stable machine readable code of the sentinel.
+func (s sentinelError) Code() string {
+	return s.code
+}
+
 type sentinelWrappedError struct {
 	err      error
 	sentinel *sentinelError
@@ -70,6 +69,8 @@ func (e sentinelWrappedError) Cause() error {
 	}
 }
 
+// WrapError attaches sentinel to err. when err already carries a sentinel the new one takes precedence,
+// errors.Is still matches both.
 func WrapError(err error, sentinel *sentinelError) error {
 	if err == nil {
 		return nil
@@ -79,17 +80,11 @@ func WrapError(err error, sentinel *sentinelError) error {
 		sentinel = ErrInternalServerError
 	}
 
-	var apiErr APIError
-	if errors.As(err, &apiErr) {
-		return fmt.Errorf("%w: %w", err, sentinel)
-	}
-
 	return sentinelWrappedError{err: err, sentinel: sentinel}
-
 }
 
 func WrapErrorWithCode(err error, code int, message string) error {
-	return WrapError(err, &sentinelError{statusCode: code, message: message})
+	return WrapError(err, &sentinelError{statusCode: code, code: codeFromStatus(code), message: message})
 }
 
 func Cause(err error) error {
@@ -102,10 +97,39 @@ func Cause(err error) error {
 	}
 }
 
+// SentinelError returns the outermost sentinel carried by err, or nil when there is none.
 func SentinelError(err error) *sentinelError {
-	var sentinelErr *sentinelError
-	if errors.As(err, &sentinelErr) {
-		return sentinelErr
+	for err != nil {
+		switch e := err.(type) {
+		case sentinelWrappedError:
+			return e.sentinel
+		case *sentinelError:
+			return e
+		case interface{ Unwrap() []error }:
+			for _, err := range e.Unwrap() {
+				if sentinel := SentinelError(err); sentinel != nil {
+					return sentinel
+				}
+			}
+			return nil
+		}
+		err = errors.Unwrap(err)
 	}
+
 	return nil
 }
+
+func codeFromStatus(statusCode int) string {
+	for _, sentinel := range []*sentinelError{ErrBadRequest, ErrUnauthorized, ErrForbidden, ErrNotFound, ErrConflict,
+		ErrUnprocessableEntity, ErrTooManyRequests, ErrServiceUnavailable} {
+		if sentinel.statusCode == statusCode {
+			return sentinel.code
+		}
+	}
+
+	return ErrInternalServerError.code
+}
+
+func newSentinel(statusCode int, code, message string) *sentinelError {
+	return &sentinelError{statusCode: statusCode, code: code, message: message}
+}
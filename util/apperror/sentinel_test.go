@@ -0,0 +1,97 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentinelError(t *testing.T) {
+	cause := errors.New("sql: connection refused")
+	tests := []struct {
+		name string
+		err  error
+		want *sentinelError
+	}{
+		{name: "Given a sentinel, it should return the sentinel", err: ErrNotFound, want: ErrNotFound},
+		{name: "Given a wrapped error, it should return its sentinel", err: WrapError(cause, ErrNotFound), want: ErrNotFound},
+		{
+			name: "Given a wrapped error wrapped again with fmt, it should return its sentinel",
+			err:  fmt.Errorf("service: %w", WrapError(cause, ErrConflict)),
+			want: ErrConflict,
+		},
+		{
+			name: "Given an error wrapped twice, it should return the outermost sentinel",
+			err:  WrapError(fmt.Errorf("service: %w", WrapError(cause, ErrNotFound)), ErrLoanLimitExceeded),
+			want: ErrLoanLimitExceeded,
+		},
+		{name: "Given a joined error, it should return the first sentinel", err: errors.Join(cause, WrapError(cause, ErrForbidden)), want: ErrForbidden},
+		{name: "Given an error without sentinel, it should return nil", err: cause},
+		{name: "Given no error, it should return nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SentinelError(tt.err))
+		})
+	}
+}
+
+func TestWrapError_Is(t *testing.T) {
+	err := WrapError(fmt.Errorf("service: %w", WrapError(errors.New("cause"), ErrNotFound)), ErrLoanLimitExceeded)
+	assert.ErrorIs(t, err, ErrLoanLimitExceeded)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrConflict)
+}
+
+func TestNewProblem(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Problem
+	}{
+		{
+			name: "Given a catalog error, it should map its code and status",
+			err:  WrapError(errors.New("name score 0.2"), ErrKYCNameMismatch),
+			want: Problem{
+				Type:     "urn:problem-type:xyz:kyc-name-mismatch",
+				Title:    "Unprocessable Entity",
+				Status:   http.StatusUnprocessableEntity,
+				Detail:   "the name does not match the national id",
+				Instance: "/loan",
+				Code:     "KYC_NAME_MISMATCH",
+			},
+		},
+		{
+			name: "Given an error without sentinel, it should not leak the cause",
+			err:  errors.New("dial tcp 10.0.0.1:3306: connection refused"),
+			want: Problem{
+				Type:     "urn:problem-type:xyz:internal-error",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "oops! something went wrong",
+				Instance: "/loan",
+				Code:     "INTERNAL_ERROR",
+			},
+		},
+		{
+			name: "Given an error with a custom status, it should map the generic code of the status",
+			err:  WrapErrorWithCode(errors.New("cause"), http.StatusTooManyRequests, "slow down"),
+			want: Problem{
+				Type:     "urn:problem-type:xyz:too-many-requests",
+				Title:    "Too Many Requests",
+				Status:   http.StatusTooManyRequests,
+				Detail:   "slow down",
+				Instance: "/loan",
+				Code:     "TOO_MANY_REQUESTS",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewProblem(tt.err, "/loan"))
+		})
+	}
+}
@@ -1,75 +1,264 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
-	"path/filepath"
-	"runtime"
+	"sync/atomic"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
-var (
-	cfg AppConfig
-)
+// the configuration is layered, each layer overriding the previous one:
+//
+//	defaults  the env-default tags
+//	file      the yaml file given by --config or CONFIG_PATH, optional
+//	env       the env tags
+//	secrets   <ENV>_FILE variables, e.g. KYC_API_KEY_FILE=/run/secrets/kyc-api-key, read from the file once,
+//	          but DB_PASSWORD_FILE which is database.password-file and read for every new connection
+//	flags     --set overrides keyed by the yaml path, e.g. --set server.port=9001
+//
+// fields tagged secret are masked by Dump and fields tagged reload are taken on hot reload, see Watch.
+
+var current atomic.Pointer[AppConfig]
 
 type AppConfig struct {
-	Server    Server    `yaml:"server"`
-	Database  Database  `yaml:"database"`
-	KYCClient KYCClient `yaml:"kyc-client"`
-	path      string
+	// Storage is sql for the configured database, or memory to run without one for local development,
+	// the memory storage starts with the seed data and loses everything on restart.
+	Storage        string         `yaml:"storage" env:"STORAGE" env-default:"sql"`
+	Server         Server         `yaml:"server"`
+	Database       Database       `yaml:"database"`
+	KYCClient      KYCClient      `yaml:"kyc-client"`
+	ContractNumber ContractNumber `yaml:"contract-number"`
+	Cache          Cache          `yaml:"cache"`
+	RateLimit      RateLimit      `yaml:"rate-limit"`
+	Worker         Worker         `yaml:"worker"`
+	Health         Health         `yaml:"health"`
+	Tracing        Tracing        `yaml:"tracing"`
+	Log            Log            `yaml:"log"`
+	// ReloadInterval is how often the file is checked for changes, 0 disables hot reload.
+	ReloadInterval time.Duration `yaml:"reload-interval" env:"CONFIG_RELOAD_INTERVAL" env-default:"0s"`
 }
 
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
-	}
-	return fallback
+type Options struct {
+	// Path of the yaml file, CONFIG_PATH is used when it is empty.
+	Path string
+	// Overrides are the --set flags keyed by yaml path.
+	Overrides map[string]string
 }
 
-func init() {
-
-	env := getEnv("ENV", "dev")
-	configFile := "config." + env + ".yaml"
-	// little trick to change to working directory, really useful for testing
-	// see https://brandur.org/fragments/testing-go-project-root
-	cfg = AppConfig{}
-	_, fname, _, _ := runtime.Caller(0)
-	dir := filepath.Join(fname, "..")
-	cfg.path = dir
-	configPath := filepath.Join(dir, configFile)
-	err := cleanenv.ReadConfig(configPath, &cfg)
+// Load reads the configuration through every layer and validates it, all the invalid fields are reported at once.
+func Load(opts Options) (*AppConfig, error) {
+	cfg, err := load(opts)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
+	current.Store(cfg)
+	return cfg, nil
 }
 
+// Get returns the configuration last loaded or reloaded, nil before Load.
 func Get() *AppConfig {
-	return &cfg
+	return current.Load()
+}
+
+func load(opts Options) (*AppConfig, error) {
+	if opts.Path == "" {
+		opts.Path = os.Getenv("CONFIG_PATH")
+	}
+
+	cfg := new(AppConfig)
+	if opts.Path != "" {
+		if err := cleanenv.ReadConfig(opts.Path, cfg); err != nil {
+			return nil, fmt.Errorf("Load: error read %s: %w", opts.Path, err)
+		}
+	} else if err := cleanenv.ReadEnv(cfg); err != nil {
+		return nil, fmt.Errorf("Load: error read env: %w", err)
+	}
+
+	// a list of structs has no env-default, its defaults are set here
+	if cfg.RateLimit.Rules == nil {
+		cfg.RateLimit.Rules = defaultRateLimitRules()
+	}
+
+	var errs []error
+	if err := applySecretFiles(cfg); err != nil {
+		errs = append(errs, err)
+	}
+	if err := applyOverrides(cfg, opts.Overrides); err != nil {
+		errs = append(errs, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("Load: invalid configuration:\n%w", err)
+	}
+
+	return cfg, nil
 }
 
 type Server struct {
-	Host            string        `yaml:"host" env-required:"true"`
-	Port            int           `yaml:"port" env-default:"9000" env-layout:"int"`
+	Host            string        `yaml:"host" env:"SERVER_HOST"`
+	Port            int           `yaml:"port" env:"SERVER_PORT" env-default:"9000" env-layout:"int"`
 	ReadTimeout     time.Duration `yaml:"read-timeout" env-default:"10s" env-layout:"time.Duration"`
 	WriteTimeout    time.Duration `yaml:"write-timeout" env-default:"10s" env-layout:"time.Duration"`
 	ShutDownTimeout time.Duration `yaml:"shutdown-timeout" env-default:"30s" env-layout:"time.Duration"`
+	// DrainDelay keeps serving after readiness started failing, giving the load balancer time to notice. it
+	// counts in ShutDownTimeout, the in-flight requests are drained in what is left.
+	DrainDelay time.Duration `yaml:"drain-delay" env-default:"0s" env-layout:"time.Duration"`
 }
 
 type Database struct {
-	Host                  string        `yaml:"host" env-required:"true"`
-	Port                  int           `yaml:"port" env-required:"true"`
-	Username              string        `env:"DB_USER"`
-	Password              string        `env:"DB_PASSWORD" env-layout:"string"`
-	DataBaseName          string        `env:"DB_NAME" env-layout:"string"`
+	// Driver selects the backend, mysql or postgres, the migrations and repositories follow it.
+	Driver                string        `yaml:"driver" env:"DB_DRIVER" env-default:"mysql"`
+	Host                  string        `yaml:"host" env:"DB_HOST"`
+	Port                  int           `yaml:"port" env:"DB_PORT" env-default:"3306"`
+	Username              string        `yaml:"username" env:"DB_USER"`
+	Password              string        `yaml:"password" env:"DB_PASSWORD" env-layout:"string" secret:"true"`
+	DataBaseName          string        `yaml:"name" env:"DB_NAME" env-layout:"string"`
 	OpenConnection        int           `yaml:"max-open-connection" env-layout:"int"`
 	IdleConnection        int           `yaml:"max-idle" env-layout:"int"`
 	ConnectionMaxLifeTime time.Duration `yaml:"max-lifetime" env-layout:"time.Duration"`
+	// Replicas are the host:port of the read replicas, they share the credentials, the database name
+	// and the pool settings of the primary. every read goes to the primary when there is none.
+	Replicas []string `yaml:"replicas" env:"DB_REPLICAS" env-separator:","`
+	// MaxReplicaLag takes a replica out of the reads while it is further behind the primary.
+	MaxReplicaLag time.Duration `yaml:"max-replica-lag" env-default:"5s" env-layout:"time.Duration"`
+	// ReplicaCheckInterval is how often the replicas are pinged and their lag measured.
+	ReplicaCheckInterval time.Duration `yaml:"replica-check-interval" env-default:"5s" env-layout:"time.Duration"`
+	// ReadYourWrites keeps the reads of a user on the primary for this long after a write of the same user,
+	// so a replica behind the primary never hides them. 0 disables it.
+	ReadYourWrites time.Duration `yaml:"read-your-writes" env-default:"5s" env-layout:"time.Duration"`
+	// PasswordFile is read again for every new connection and takes over Password, so the password can be
+	// rotated by replacing the file without a restart. it is the DB_PASSWORD_FILE of the secrets layer.
+	PasswordFile string `yaml:"password-file" env:"DB_PASSWORD_FILE"`
+	// TLS is disable, prefer, require or verify-full, require encrypts without verifying the server.
+	TLS string `yaml:"tls" env:"DB_TLS" env-default:"prefer"`
+	// Timezone is the location of the dates stored without one.
+	Timezone       string        `yaml:"timezone" env:"DB_TIMEZONE" env-default:"UTC"`
+	ConnectTimeout time.Duration `yaml:"connect-timeout" env-default:"5s" env-layout:"time.Duration"`
+	// ReadTimeout and WriteTimeout bound the i/o of a statement, 0 is none. postgres ignores them.
+	ReadTimeout  time.Duration `yaml:"read-timeout" env-default:"0s" env-layout:"time.Duration"`
+	WriteTimeout time.Duration `yaml:"write-timeout" env-default:"0s" env-layout:"time.Duration"`
+	// the database is pinged at most ConnectRetries times at startup, the wait between two pings starts
+	// at ConnectBackoff and doubles up to ConnectMaxBackoff.
+	ConnectRetries    int           `yaml:"connect-retries" env-default:"5" env-layout:"int"`
+	ConnectBackoff    time.Duration `yaml:"connect-backoff" env-default:"500ms" env-layout:"time.Duration"`
+	ConnectMaxBackoff time.Duration `yaml:"connect-max-backoff" env-default:"10s" env-layout:"time.Duration"`
+	// StatementTimeout bounds every statement of the repositories, 0 is none.
+	StatementTimeout time.Duration `yaml:"statement-timeout" env:"DB_STATEMENT_TIMEOUT" env-default:"5s" env-layout:"time.Duration"`
+	// SlowQueryThreshold logs the statements taking that long or more, 0 logs none.
+	SlowQueryThreshold time.Duration `yaml:"slow-query-threshold" env:"DB_SLOW_QUERY_THRESHOLD" env-default:"200ms" env-layout:"time.Duration"`
+	// LazyConnect starts without reaching the database, the first request or readiness probe connects instead
+	// and checks the schema, the api refuses the requests until the schema is the expected one.
+	LazyConnect bool `yaml:"lazy-connect" env:"DB_LAZY_CONNECT" env-default:"false"`
 }
 
 type KYCClient struct {
-	BaseURL string `yaml:"base-url" env-required:"true"`
-	APIKey  string `yaml:"api-key" env-required:"true"`
-	APPID   string `yaml:"app-id" env-required:"true" env-layout:"string" env-default:"xyz"`
+	BaseURL string `yaml:"base-url" env:"KYC_BASE_URL"`
+	APIKey  string `yaml:"api-key" env:"KYC_API_KEY" secret:"true"`
+	APPID   string `yaml:"app-id" env:"KYC_APP_ID" env-layout:"string" env-default:"xyz"`
+	// the circuit breaker opens after BreakerThreshold consecutive failures and stays open for BreakerCooldown.
+	BreakerThreshold int           `yaml:"breaker-threshold" env-default:"5" env-layout:"int"`
+	BreakerCooldown  time.Duration `yaml:"breaker-cooldown" env-default:"30s" env-layout:"time.Duration"`
+}
+
+type ContractNumber struct {
+	BranchCode string `yaml:"branch-code" env:"CONTRACT_NUMBER_BRANCH_CODE" env-default:"XYZ"`
+}
+
+// Cache keeps the reference data and the loans read recently, the sql storage only uses it.
+type Cache struct {
+	// Driver is none, memory for a cache per instance, or redis for a cache shared by the instances. the
+	// changes are only invalidated in the cache of the instance making them, so memory is for a single
+	// instance: the others would serve the loans, and their etags, as they were until the ttl.
+	Driver string `yaml:"driver" env:"CACHE_DRIVER" env-default:"none"`
+	// Size is the number of values the memory cache holds.
+	Size         int           `yaml:"size" env-default:"10000" env-layout:"int"`
+	ReferenceTTL time.Duration `yaml:"reference-ttl" env-default:"1h" env-layout:"time.Duration"`
+	LoanTTL      time.Duration `yaml:"loan-ttl" env-default:"1m" env-layout:"time.Duration"`
+	// RedisAddr is the host:port of the redis of the redis driver.
+	RedisAddr     string `yaml:"redis-addr" env:"CACHE_REDIS_ADDR"`
+	RedisPassword string `yaml:"redis-password" env:"CACHE_REDIS_PASSWORD" secret:"true"`
+	RedisDB       int    `yaml:"redis-db" env:"CACHE_REDIS_DB" env-default:"0"`
+	// RedisTimeout bounds every command sent to redis, a redis that stopped answering fails the command
+	// rather than the request.
+	RedisTimeout time.Duration `yaml:"redis-timeout" env:"CACHE_REDIS_TIMEOUT" env-default:"1s" env-layout:"time.Duration"`
+}
+
+// Worker runs the background jobs of the worker command, the sql storage only uses it.
+type Worker struct {
+	// RelayInterval is how often the events recorded in the outbox are relayed, RelayBatch at most per run.
+	RelayInterval time.Duration `yaml:"relay-interval" env:"WORKER_RELAY_INTERVAL" env-default:"1s" env-layout:"time.Duration"`
+	RelayBatch    int           `yaml:"relay-batch" env-default:"100" env-layout:"int"`
+	// OutboxRetention keeps the relayed events for inspection, the purge deletes the older ones every
+	// PurgeInterval.
+	OutboxRetention time.Duration `yaml:"outbox-retention" env-default:"168h" env-layout:"time.Duration"`
+	PurgeInterval   time.Duration `yaml:"purge-interval" env-default:"1h" env-layout:"time.Duration"`
+}
+
+type RateLimit struct {
+	// Backend keeps the counts, memory for counts per instance or redis for counts shared by the instances,
+	// in the redis of cache.redis-addr.
+	Backend string `yaml:"backend" env:"RATE_LIMIT_BACKEND" env-default:"memory"`
+	// Rules are set in the file only. every rule matching a request counts it apart and the request is
+	// refused when one of them is over its limit. the default rules apply when the key is missing and
+	// none when it is empty.
+	Rules []RateLimitRule `yaml:"rules" reload:"true"`
+	// KYCDailyCap is the number of kyc checks of a national id by a user per UTC day, to bound the bill of
+	// the vendor. 0 disables it.
+	KYCDailyCap int `yaml:"kyc-daily-cap" env:"RATE_LIMIT_KYC_DAILY_CAP" env-default:"5" env-layout:"int" reload:"true"`
+}
+
+type RateLimitRule struct {
+	// Route is the method and path of the routes counted, as they are registered e.g. GET /loans/:contractNumber,
+	// or * for every route.
+	Route string `yaml:"route"`
+	// By is what the requests are counted by: ip, user, or nik for the national_id of the body. a request
+	// without it, e.g. an anonymous one for user, is not counted.
+	By string `yaml:"by"`
+	// Algorithm is token-bucket or sliding-window.
+	Algorithm string        `yaml:"algorithm"`
+	Limit     int           `yaml:"limit"`
+	Period    time.Duration `yaml:"period"`
+	// Burst is the size of the bucket of token-bucket, limit when 0.
+	Burst int `yaml:"burst"`
+}
+
+// defaultRateLimitRules guard the loan applications, each one costs three calls to the kyc vendor, and
+// bound what a single address can send to the rest.
+func defaultRateLimitRules() []RateLimitRule {
+	return []RateLimitRule{
+		{Route: "POST /loan", By: "user", Algorithm: "token-bucket", Limit: 5, Period: time.Minute, Burst: 3},
+		{Route: "POST /loan", By: "nik", Algorithm: "sliding-window", Limit: 3, Period: 10 * time.Minute},
+		{Route: "POST /loan", By: "ip", Algorithm: "sliding-window", Limit: 20, Period: time.Minute},
+		{Route: "*", By: "ip", Algorithm: "sliding-window", Limit: 600, Period: time.Minute},
+	}
+}
+
+type Health struct {
+	// Timeout bounds every dependency check of the readiness probe.
+	Timeout time.Duration `yaml:"timeout" env-default:"2s" env-layout:"time.Duration"`
+	// PoolSaturation is the share of max open connections in use from which the database is not ready.
+	PoolSaturation float64 `yaml:"pool-saturation" env-default:"0.9"`
+}
+
+type Tracing struct {
+	// Exporter is one of none, stdout, file or otlp.
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	FilePath    string  `yaml:"file-path" env:"TRACING_FILE_PATH" env-default:"traces.json"`
+	Endpoint    string  `yaml:"endpoint" env:"TRACING_ENDPOINT" env-default:"localhost:4318"`
+	SampleRatio float64 `yaml:"sample-ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+}
+
+type Log struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" env-default:"info" reload:"true"`
+	// Format is json or console.
+	Format string `yaml:"format" env:"LOG_FORMAT" env-default:"json"`
+	// Output is stdout, stderr or the path of a file.
+	Output      string `yaml:"output" env:"LOG_OUTPUT" env-default:"stderr"`
+	SampleEvery uint32 `yaml:"sample-every" env:"LOG_SAMPLE_EVERY" env-default:"1"`
 }
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validYAML = `
+server:
+  host: localhost
+  port: 9000
+database:
+  host: localhost
+  name: xyz
+  password: from-file
+kyc-client:
+  base-url: http://e-kyc.example.com/api/ekyc
+  api-key: secret
+contract-number:
+  branch-code: JKT
+log:
+  level: info
+`
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "db-password")
+	require.NoError(t, os.WriteFile(secret, []byte("from-secret-file\n"), 0o600))
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		yaml    string
+		opts    func(path string) Options
+		check   func(t *testing.T, cfg *AppConfig)
+		wantErr []string
+	}{
+		{
+			name: "Given only the file, it should apply the defaults for the missing keys",
+			opts: func(path string) Options { return Options{Path: path} },
+			check: func(t *testing.T, cfg *AppConfig) {
+				assert.Equal(t, 9000, cfg.Server.Port)
+				assert.Equal(t, 3306, cfg.Database.Port)
+				assert.Equal(t, "mysql", cfg.Database.Driver)
+				assert.Equal(t, "sql", cfg.Storage)
+				assert.Equal(t, 10*time.Second, cfg.Server.ReadTimeout)
+				assert.Equal(t, "from-file", cfg.Database.Password)
+				assert.Equal(t, "none", cfg.Cache.Driver)
+				assert.Equal(t, time.Hour, cfg.Cache.ReferenceTTL)
+				assert.Equal(t, defaultRateLimitRules(), cfg.RateLimit.Rules)
+				assert.Equal(t, 5, cfg.RateLimit.KYCDailyCap)
+			},
+		},
+		{
+			name: "Given env, secret files and overrides, it should apply them in order",
+			env: map[string]string{
+				"SERVER_PORT":      "9100",
+				"LOG_LEVEL":        "warn",
+				"KYC_API_KEY_FILE": secret,
+			},
+			opts: func(path string) Options {
+				return Options{Path: path, Overrides: map[string]string{"server.port": "9200", "health.timeout": "5s"}}
+			},
+			check: func(t *testing.T, cfg *AppConfig) {
+				assert.Equal(t, 9200, cfg.Server.Port)
+				assert.Equal(t, "warn", cfg.Log.Level)
+				assert.Equal(t, "from-secret-file", cfg.KYCClient.APIKey)
+				assert.Equal(t, 5*time.Second, cfg.Health.Timeout)
+			},
+		},
+		{
+			name: "Given the path in CONFIG_PATH, it should read the file",
+			opts: func(path string) Options {
+				t.Setenv("CONFIG_PATH", path)
+				return Options{}
+			},
+			check: func(t *testing.T, cfg *AppConfig) {
+				assert.Equal(t, "JKT", cfg.ContractNumber.BranchCode)
+			},
+		},
+		{
+			name: "Given the memory storage, it should not require the database",
+			opts: func(path string) Options {
+				return Options{Path: path, Overrides: map[string]string{"storage": "memory", "database.host": "", "database.name": ""}}
+			},
+			check: func(t *testing.T, cfg *AppConfig) {
+				assert.Equal(t, "memory", cfg.Storage)
+			},
+		},
+		{
+			name: "Given several invalid values, it should report all of them",
+			env:  map[string]string{"LOG_LEVEL": "verbose"},
+			opts: func(path string) Options {
+				return Options{Path: path, Overrides: map[string]string{
+					"server.port":          "70000",
+					"health.timeout":       "soon",
+					"tracing.sample-ratio": "2",
+					"server.unknown":       "1",
+					"database.driver":      "sqlite",
+				}}
+			},
+			wantErr: []string{
+				"server.port: must be between 1 and 65535",
+				"health.timeout: invalid override",
+				"tracing.sample-ratio: must be in [0, 1]",
+				"server.unknown: unknown configuration key",
+				"log.level: is not a log level",
+				"database.driver: must be mysql or postgres",
+			},
+		},
+		{
+			name: "Given replicas in env, it should split them",
+			env:  map[string]string{"DB_REPLICAS": "replica-1:3306,replica-2:3307"},
+			opts: func(path string) Options { return Options{Path: path} },
+			check: func(t *testing.T, cfg *AppConfig) {
+				assert.Equal(t, []string{"replica-1:3306", "replica-2:3307"}, cfg.Database.Replicas)
+				assert.Equal(t, 5*time.Second, cfg.Database.MaxReplicaLag)
+				assert.Equal(t, 5*time.Second, cfg.Database.ReadYourWrites)
+			},
+		},
+		{
+			name: "Given an invalid replica, it should report it",
+			env:  map[string]string{"DB_REPLICAS": "replica-1:3306,replica-2"},
+			opts: func(path string) Options {
+				return Options{Path: path, Overrides: map[string]string{"database.max-replica-lag": "0s"}}
+			},
+			wantErr: []string{
+				`database.replicas[1]: must be host:port, got "replica-2"`,
+				"database.max-replica-lag: must be positive",
+			},
+		},
+		{
+			name: "Given the connection settings in env, it should apply them over the defaults",
+			env:  map[string]string{"DB_TLS": "verify-full", "DB_LAZY_CONNECT": "true", "DB_PASSWORD_FILE": secret},
+			opts: func(path string) Options { return Options{Path: path} },
+			check: func(t *testing.T, cfg *AppConfig) {
+				assert.Equal(t, "verify-full", cfg.Database.TLS)
+				assert.True(t, cfg.Database.LazyConnect)
+				assert.Equal(t, secret, cfg.Database.PasswordFile)
+				assert.Equal(t, "from-file", cfg.Database.Password, "the password file should be read per connection, not at load")
+				assert.Equal(t, "UTC", cfg.Database.Timezone)
+				assert.Equal(t, 5, cfg.Database.ConnectRetries)
+				assert.Equal(t, 500*time.Millisecond, cfg.Database.ConnectBackoff)
+				assert.Equal(t, 5*time.Second, cfg.Database.StatementTimeout)
+				assert.Equal(t, 200*time.Millisecond, cfg.Database.SlowQueryThreshold)
+			},
+		},
+		{
+			name: "Given invalid connection settings, it should report them",
+			opts: func(path string) Options {
+				return Options{Path: path, Overrides: map[string]string{
+					"database.tls":                 "always",
+					"database.timezone":            "Mars/Olympus",
+					"database.connect-retries":     "0",
+					"database.connect-max-backoff": "100ms",
+				}}
+			},
+			wantErr: []string{
+				`database.tls: must be disable, prefer, require or verify-full, got "always"`,
+				`database.timezone: must be a time zone, got "Mars/Olympus"`,
+				"database.connect-retries: must be positive",
+				"database.connect-max-backoff: must not be less than connect-backoff",
+			},
+		},
+		{
+			name: "Given the redis cache without its address, it should report it",
+			env:  map[string]string{"CACHE_DRIVER": "redis"},
+			opts: func(path string) Options {
+				return Options{Path: path, Overrides: map[string]string{"cache.loan-ttl": "0s"}}
+			},
+			wantErr: []string{
+				`cache.redis-addr: must be host:port, got ""`,
+				"cache.loan-ttl: must be positive",
+			},
+		},
+		{
+			name: "Given rate limit rules in the file, it should take them over the defaults",
+			yaml: `
+rate-limit:
+  rules:
+    - route: GET /loans
+      by: user
+      algorithm: sliding-window
+      limit: 10
+      period: 1s
+`,
+			opts: func(path string) Options { return Options{Path: path} },
+			check: func(t *testing.T, cfg *AppConfig) {
+				assert.Equal(t, []RateLimitRule{{Route: "GET /loans", By: "user", Algorithm: "sliding-window", Limit: 10, Period: time.Second}}, cfg.RateLimit.Rules)
+			},
+		},
+		{
+			name: "Given invalid rate limit rules, it should report each field",
+			env:  map[string]string{"RATE_LIMIT_BACKEND": "redis"},
+			yaml: `
+rate-limit:
+  rules:
+    - route: /loan
+      by: device
+      algorithm: leaky-bucket
+      period: 0s
+`,
+			opts: func(path string) Options { return Options{Path: path} },
+			wantErr: []string{
+				`cache.redis-addr: must be host:port, got ""`,
+				`rate-limit.rules[0].route: must be * or a method and a path e.g. POST /loan, got "/loan"`,
+				`rate-limit.rules[0].by: must be ip, user or nik, got "device"`,
+				`rate-limit.rules[0].algorithm: must be token-bucket or sliding-window, got "leaky-bucket"`,
+				"rate-limit.rules[0].limit: must be positive",
+				"rate-limit.rules[0].period: must be positive",
+			},
+		},
+		{
+			name:    "Given a missing file, it should return an error",
+			opts:    func(path string) Options { return Options{Path: path + ".missing"} },
+			wantErr: []string{"Load: error read"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load(tt.opts(writeFile(t, validYAML+tt.yaml)))
+			if len(tt.wantErr) > 0 {
+				require.Error(t, err)
+				for _, want := range tt.wantErr {
+					assert.Contains(t, err.Error(), want)
+				}
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, cfg)
+			assert.Same(t, cfg, Get())
+		})
+	}
+}
+
+func TestLoad_DevConfig(t *testing.T) {
+	_, err := Load(Options{Path: "config.dev.yaml"})
+	assert.NoError(t, err)
+}
+
+func TestDump(t *testing.T) {
+	cfg, err := Load(Options{Path: writeFile(t, validYAML)})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Dump(&buf, cfg))
+	assert.NotContains(t, buf.String(), "from-file")
+	assert.NotContains(t, buf.String(), "api-key: secret")
+	assert.Contains(t, buf.String(), "branch-code: JKT")
+	assert.Equal(t, "from-file", cfg.Database.Password, "the dump must not change the configuration")
+}
+
+func TestMerge(t *testing.T) {
+	old, err := load(Options{Path: writeFile(t, validYAML)})
+	require.NoError(t, err)
+	loaded := *old
+	loaded.Log.Level = "debug"
+	loaded.Server.Port = 9100
+	loaded.RateLimit.Rules = []RateLimitRule{{Route: "*", By: "ip", Algorithm: "token-bucket", Limit: 1, Period: time.Second}}
+
+	cfg, ignored := merge(old, &loaded)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, loaded.RateLimit.Rules, cfg.RateLimit.Rules)
+	assert.Equal(t, 9000, cfg.Server.Port)
+	assert.Equal(t, []string{"server.port"}, ignored)
+	assert.Equal(t, "info", old.Log.Level, "the current configuration must not be changed in place")
+}
+
+func TestWatch(t *testing.T) {
+	path := writeFile(t, validYAML+"reload-interval: 10ms\n")
+	_, err := Load(Options{Path: path})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan *AppConfig, 1)
+	ignoredCh := make(chan []string, 1)
+	go Watch(ctx, Options{Path: path},
+		func(cfg *AppConfig) { reloaded <- cfg },
+		func(paths []string) { ignoredCh <- paths },
+		func(err error) { t.Error(err) },
+	)
+
+	// let the watcher take the modification time of the file first
+	time.Sleep(50 * time.Millisecond)
+	changed := strings.NewReplacer("port: 9000", "port: 9100", "level: info", "level: debug").Replace(validYAML)
+	require.NoError(t, os.WriteFile(path, []byte(changed+"reload-interval: 10ms\n"), 0o600))
+	future := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, future, future))
+
+	select {
+	case cfg := <-reloaded:
+		assert.Equal(t, "debug", cfg.Log.Level)
+		assert.Equal(t, 9000, cfg.Server.Port)
+		assert.Equal(t, []string{"server.port"}, <-ignoredCh)
+		assert.Same(t, cfg, Get())
+	case <-time.After(2 * time.Second):
+		t.Fatal("configuration was not reloaded")
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const masked = "******"
+
+// Dump writes the effective configuration as yaml with the secret fields masked, it is safe to share.
+func Dump(w io.Writer, cfg *AppConfig) error {
+	redacted := *cfg
+	_ = walk(reflect.ValueOf(&redacted), "", func(field reflect.StructField, value reflect.Value, path string) error {
+		if field.Tag.Get("secret") == "true" && value.Kind() == reflect.String && value.String() != "" {
+			value.SetString(masked)
+		}
+		return nil
+	})
+
+	b, err := yaml.Marshal(redacted)
+	if err != nil {
+		return fmt.Errorf("Dump: error marshal configuration: %w", err)
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// walk calls fn with every leaf field of v, a pointer to a struct, and its yaml path e.g. server.port.
+func walk(v reflect.Value, prefix string, fn func(field reflect.StructField, value reflect.Value, path string) error) error {
+	v = reflect.Indirect(v)
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.SplitN(field.Tag.Get("yaml"), ",", 2)[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := walk(v.Field(i), path, fn); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := fn(field, v.Field(i), path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// applySecretFiles sets every field whose <ENV>_FILE variable is set to the content of that file,
+// so secrets mounted as files never have to be put in the environment. a field whose <ENV>_FILE is the
+// env of another field, e.g. DB_PASSWORD_FILE of database.password-file, is left to that field.
+func applySecretFiles(cfg *AppConfig) error {
+	envs := map[string]bool{}
+	_ = walk(reflect.ValueOf(cfg), "", func(field reflect.StructField, value reflect.Value, path string) error {
+		envs[field.Tag.Get("env")] = true
+		return nil
+	})
+
+	return walk(reflect.ValueOf(cfg), "", func(field reflect.StructField, value reflect.Value, path string) error {
+		env := field.Tag.Get("env")
+		if env == "" || envs[env+"_FILE"] {
+			return nil
+		}
+		file, ok := os.LookupEnv(env + "_FILE")
+		if !ok {
+			return nil
+		}
+
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: error read %s_FILE: %w", path, env, err)
+		}
+		if err := setValue(value, strings.TrimSpace(string(b))); err != nil {
+			return fmt.Errorf("%s: invalid value in %s_FILE: %w", path, env, err)
+		}
+		return nil
+	})
+}
+
+// applyOverrides sets the fields given by --set, keyed by their yaml path.
+func applyOverrides(cfg *AppConfig, overrides map[string]string) error {
+	remaining := make(map[string]string, len(overrides))
+	for k, v := range overrides {
+		remaining[k] = v
+	}
+
+	err := walk(reflect.ValueOf(cfg), "", func(field reflect.StructField, value reflect.Value, path string) error {
+		raw, ok := remaining[path]
+		if !ok {
+			return nil
+		}
+		delete(remaining, path)
+
+		if err := setValue(value, raw); err != nil {
+			return fmt.Errorf("%s: invalid override %q: %w", path, raw, err)
+		}
+		return nil
+	})
+
+	errs := []error{err}
+	unknown := make([]string, 0, len(remaining))
+	for path := range remaining {
+		unknown = append(unknown, path)
+	}
+	sort.Strings(unknown)
+	for _, path := range unknown {
+		errs = append(errs, fmt.Errorf("%s: unknown configuration key", path))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParseOverride splits a --set flag value such as server.port=9001.
+func ParseOverride(s string) (path, value string, err error) {
+	path, value, ok := strings.Cut(s, "=")
+	if !ok || path == "" {
+		return "", "", fmt.Errorf("ParseOverride: %q is not key=value", s)
+	}
+	return strings.TrimSpace(path), value, nil
+}
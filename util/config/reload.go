@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Watch polls the configuration file every cfg.ReloadInterval and reloads it when it changed. only the fields
+// tagged reload are taken from the new file, changes to any other field need a restart and are given to onIgnored.
+// onReload is called with the new configuration, onError with every file that failed to load; the current
+// configuration is kept in that case. it returns when ctx is done.
+func Watch(ctx context.Context, opts Options, onReload func(cfg *AppConfig), onIgnored func(paths []string), onError func(err error)) {
+	if opts.Path == "" {
+		opts.Path = os.Getenv("CONFIG_PATH")
+	}
+	interval := Get().ReloadInterval
+	if opts.Path == "" || interval <= 0 {
+		return
+	}
+
+	modTime := func() time.Time {
+		info, err := os.Stat(opts.Path)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := modTime()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		mt := modTime()
+		if mt.Equal(last) {
+			continue
+		}
+		last = mt
+
+		loaded, err := load(opts)
+		if err != nil {
+			onError(err)
+			continue
+		}
+
+		cfg, ignored := merge(Get(), loaded)
+		current.Store(cfg)
+		if len(ignored) > 0 {
+			onIgnored(ignored)
+		}
+		onReload(cfg)
+	}
+}
+
+// merge returns a copy of old with the reloadable fields of loaded, and the paths of the other fields that changed.
+func merge(old, loaded *AppConfig) (*AppConfig, []string) {
+	cfg := *old
+	oldValues := map[string]interface{}{}
+	_ = walk(reflect.ValueOf(old), "", func(field reflect.StructField, value reflect.Value, path string) error {
+		oldValues[path] = value.Interface()
+		return nil
+	})
+
+	reloadable := map[string]reflect.Value{}
+	_ = walk(reflect.ValueOf(&cfg), "", func(field reflect.StructField, value reflect.Value, path string) error {
+		if field.Tag.Get("reload") == "true" {
+			reloadable[path] = value
+		}
+		return nil
+	})
+
+	var ignored []string
+	_ = walk(reflect.ValueOf(loaded), "", func(field reflect.StructField, value reflect.Value, path string) error {
+		if dst, ok := reloadable[path]; ok {
+			dst.Set(value)
+			return nil
+		}
+		if !reflect.DeepEqual(oldValues[path], value.Interface()) {
+			ignored = append(ignored, path)
+		}
+		return nil
+	})
+
+	return &cfg, ignored
+}
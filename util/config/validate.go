@@ -0,0 +1,141 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	branchCodePattern   = regexp.MustCompile(`^[A-Za-z]{3}$`)
+	logLevels           = map[string]bool{"trace": true, "debug": true, "info": true, "warn": true, "error": true, "fatal": true, "panic": true, "disabled": true}
+	logFormats          = map[string]bool{"json": true, "console": true}
+	tracingExporters    = map[string]bool{"none": true, "stdout": true, "file": true, "otlp": true}
+	databaseDrivers     = map[string]bool{"mysql": true, "postgres": true}
+	storages            = map[string]bool{"sql": true, "memory": true}
+	cacheDrivers        = map[string]bool{"none": true, "memory": true, "redis": true}
+	databaseTLS         = map[string]bool{"disable": true, "prefer": true, "require": true, "verify-full": true}
+	rateLimitBackends   = map[string]bool{"memory": true, "redis": true}
+	rateLimitKeys       = map[string]bool{"ip": true, "user": true, "nik": true}
+	rateLimitAlgorithms = map[string]bool{"token-bucket": true, "sliding-window": true}
+	routePattern        = regexp.MustCompile(`^(\*|[A-Z]+ /\S*)$`)
+)
+
+// Validate checks the semantic of every field and reports all the invalid ones together.
+func (cfg *AppConfig) Validate() error {
+	v := validator{}
+
+	v.check(cfg.Server.Port > 0 && cfg.Server.Port <= 65535, "server.port", "must be between 1 and 65535, got %d", cfg.Server.Port)
+	v.check(cfg.Server.ReadTimeout > 0, "server.read-timeout", "must be positive")
+	v.check(cfg.Server.WriteTimeout > 0, "server.write-timeout", "must be positive")
+	v.check(cfg.Server.ShutDownTimeout > 0, "server.shutdown-timeout", "must be positive")
+	v.check(cfg.Server.DrainDelay >= 0 && cfg.Server.DrainDelay < cfg.Server.ShutDownTimeout, "server.drain-delay", "must be at least 0 and under server.shutdown-timeout")
+
+	v.check(storages[cfg.Storage], "storage", "must be sql or memory, got %q", cfg.Storage)
+	if cfg.Storage != "memory" {
+		v.check(databaseDrivers[cfg.Database.Driver], "database.driver", "must be mysql or postgres, got %q", cfg.Database.Driver)
+		v.check(cfg.Database.Host != "", "database.host", "is required")
+		v.check(cfg.Database.Port > 0 && cfg.Database.Port <= 65535, "database.port", "must be between 1 and 65535, got %d", cfg.Database.Port)
+		v.check(cfg.Database.DataBaseName != "", "database.name", "is required")
+		v.check(cfg.Database.OpenConnection >= 0, "database.max-open-connection", "must not be negative")
+		v.check(cfg.Database.IdleConnection >= 0, "database.max-idle", "must not be negative")
+		v.check(cfg.Database.OpenConnection == 0 || cfg.Database.IdleConnection <= cfg.Database.OpenConnection,
+			"database.max-idle", "must not exceed max-open-connection")
+		v.check(cfg.Database.ConnectionMaxLifeTime >= 0, "database.max-lifetime", "must not be negative")
+		for i, replica := range cfg.Database.Replicas {
+			host, port, err := net.SplitHostPort(replica)
+			n, _ := strconv.Atoi(port)
+			v.check(err == nil && host != "" && n > 0 && n <= 65535, fmt.Sprintf("database.replicas[%d]", i), "must be host:port, got %q", replica)
+		}
+		if len(cfg.Database.Replicas) > 0 {
+			v.check(cfg.Database.MaxReplicaLag > 0, "database.max-replica-lag", "must be positive")
+			v.check(cfg.Database.ReplicaCheckInterval > 0, "database.replica-check-interval", "must be positive")
+		}
+		v.check(cfg.Database.ReadYourWrites >= 0, "database.read-your-writes", "must not be negative")
+		v.check(databaseTLS[cfg.Database.TLS], "database.tls", "must be disable, prefer, require or verify-full, got %q", cfg.Database.TLS)
+		_, err := time.LoadLocation(cfg.Database.Timezone)
+		v.check(cfg.Database.Timezone != "" && err == nil, "database.timezone", "must be a time zone, got %q", cfg.Database.Timezone)
+		v.check(cfg.Database.ConnectTimeout >= 0, "database.connect-timeout", "must not be negative")
+		v.check(cfg.Database.ReadTimeout >= 0, "database.read-timeout", "must not be negative")
+		v.check(cfg.Database.WriteTimeout >= 0, "database.write-timeout", "must not be negative")
+		v.check(cfg.Database.StatementTimeout >= 0, "database.statement-timeout", "must not be negative")
+		v.check(cfg.Database.SlowQueryThreshold >= 0, "database.slow-query-threshold", "must not be negative")
+		v.check(cfg.Database.ConnectRetries > 0, "database.connect-retries", "must be positive")
+		v.check(cfg.Database.ConnectBackoff >= 0, "database.connect-backoff", "must not be negative")
+		v.check(cfg.Database.ConnectMaxBackoff >= cfg.Database.ConnectBackoff, "database.connect-max-backoff", "must not be less than connect-backoff")
+	}
+
+	u, err := url.Parse(cfg.KYCClient.BaseURL)
+	v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "kyc-client.base-url", "must be an http(s) url")
+	v.check(cfg.KYCClient.APIKey != "", "kyc-client.api-key", "is required")
+	v.check(cfg.KYCClient.APPID != "", "kyc-client.app-id", "is required")
+	v.check(cfg.KYCClient.BreakerThreshold > 0, "kyc-client.breaker-threshold", "must be positive")
+	v.check(cfg.KYCClient.BreakerCooldown > 0, "kyc-client.breaker-cooldown", "must be positive")
+
+	v.check(branchCodePattern.MatchString(cfg.ContractNumber.BranchCode), "contract-number.branch-code", "must be 3 letters, got %q", cfg.ContractNumber.BranchCode)
+
+	v.check(cacheDrivers[cfg.Cache.Driver], "cache.driver", "must be none, memory or redis, got %q", cfg.Cache.Driver)
+	if cfg.Cache.Driver != "none" {
+		v.check(cfg.Cache.ReferenceTTL > 0, "cache.reference-ttl", "must be positive")
+		v.check(cfg.Cache.LoanTTL > 0, "cache.loan-ttl", "must be positive")
+	}
+	if cfg.Cache.Driver == "memory" {
+		v.check(cfg.Cache.Size > 0, "cache.size", "must be positive")
+	}
+	if cfg.Cache.Driver == "redis" || cfg.RateLimit.Backend == "redis" {
+		host, port, err := net.SplitHostPort(cfg.Cache.RedisAddr)
+		n, _ := strconv.Atoi(port)
+		v.check(err == nil && host != "" && n > 0 && n <= 65535, "cache.redis-addr", "must be host:port, got %q", cfg.Cache.RedisAddr)
+		v.check(cfg.Cache.RedisDB >= 0, "cache.redis-db", "must not be negative")
+		v.check(cfg.Cache.RedisTimeout > 0, "cache.redis-timeout", "must be positive")
+	}
+
+	v.check(rateLimitBackends[cfg.RateLimit.Backend], "rate-limit.backend", "must be memory or redis, got %q", cfg.RateLimit.Backend)
+	for i, rule := range cfg.RateLimit.Rules {
+		path := fmt.Sprintf("rate-limit.rules[%d]", i)
+		v.check(routePattern.MatchString(rule.Route), path+".route", "must be * or a method and a path e.g. POST /loan, got %q", rule.Route)
+		v.check(rateLimitKeys[rule.By], path+".by", "must be ip, user or nik, got %q", rule.By)
+		v.check(rateLimitAlgorithms[rule.Algorithm], path+".algorithm", "must be token-bucket or sliding-window, got %q", rule.Algorithm)
+		v.check(rule.Limit > 0, path+".limit", "must be positive")
+		v.check(rule.Period > 0, path+".period", "must be positive")
+		v.check(rule.Burst >= 0, path+".burst", "must not be negative")
+	}
+	v.check(cfg.RateLimit.KYCDailyCap >= 0, "rate-limit.kyc-daily-cap", "must not be negative")
+
+	if cfg.Storage != "memory" {
+		v.check(cfg.Worker.RelayInterval > 0, "worker.relay-interval", "must be positive")
+		v.check(cfg.Worker.RelayBatch > 0, "worker.relay-batch", "must be positive")
+		v.check(cfg.Worker.OutboxRetention > 0, "worker.outbox-retention", "must be positive")
+		v.check(cfg.Worker.PurgeInterval > 0, "worker.purge-interval", "must be positive")
+	}
+
+	v.check(cfg.Health.Timeout > 0, "health.timeout", "must be positive")
+	v.check(cfg.Health.PoolSaturation > 0 && cfg.Health.PoolSaturation <= 1, "health.pool-saturation", "must be in (0, 1]")
+
+	v.check(tracingExporters[cfg.Tracing.Exporter], "tracing.exporter", "must be one of none, stdout, file or otlp, got %q", cfg.Tracing.Exporter)
+	v.check(cfg.Tracing.Exporter != "file" || cfg.Tracing.FilePath != "", "tracing.file-path", "is required by the file exporter")
+	v.check(cfg.Tracing.Exporter != "otlp" || cfg.Tracing.Endpoint != "", "tracing.endpoint", "is required by the otlp exporter")
+	v.check(cfg.Tracing.SampleRatio >= 0 && cfg.Tracing.SampleRatio <= 1, "tracing.sample-ratio", "must be in [0, 1]")
+
+	v.check(logLevels[cfg.Log.Level], "log.level", "is not a log level, got %q", cfg.Log.Level)
+	v.check(logFormats[cfg.Log.Format], "log.format", "must be json or console, got %q", cfg.Log.Format)
+	v.check(cfg.Log.SampleEvery > 0, "log.sample-every", "must be positive")
+
+	v.check(cfg.ReloadInterval >= 0, "reload-interval", "must not be negative")
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, path, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+}
@@ -0,0 +1,56 @@
+// Package event delivers the domain events to the handlers subscribed in the same process, e.g. the caches
+// forgetting what an event changed. the delivery is synchronous so a handler has run once Publish returns.
+package event
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mfajri11/xyz-backend-monolith/util/log"
+)
+
+// Event is a change that happened, it is published once the change is stored.
+type Event interface {
+	EventName() string
+}
+
+type Handler func(ctx context.Context, e Event)
+
+// Bus is safe for concurrent use, a bus without subscriber drops the events.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		handlers: map[string][]Handler{},
+	}
+}
+
+// Subscribe runs handler for every event named name, in the order of subscription.
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// Publish runs the handlers of e, a handler panicking is logged and does not stop the others.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	handlers := b.handlers[e.EventName()]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		run(ctx, e, handler)
+	}
+}
+
+func run(ctx context.Context, e Event, handler Handler) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Ctx(ctx).Error().Interface("panic", r).Str("event", e.EventName()).Msg("event handler panicked")
+		}
+	}()
+	handler(ctx, e)
+}
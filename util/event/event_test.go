@@ -0,0 +1,34 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type loanCreated struct{ contractNumber string }
+
+func (loanCreated) EventName() string { return "loan.created" }
+
+type referenceChanged struct{}
+
+func (referenceChanged) EventName() string { return "reference.changed" }
+
+func TestBus_Publish(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	bus.Subscribe("loan.created", func(ctx context.Context, e Event) {
+		got = append(got, "first "+e.(loanCreated).contractNumber)
+	})
+	bus.Subscribe("loan.created", func(ctx context.Context, e Event) { panic("broken handler") })
+	bus.Subscribe("loan.created", func(ctx context.Context, e Event) {
+		got = append(got, "third "+e.(loanCreated).contractNumber)
+	})
+
+	bus.Publish(context.Background(), loanCreated{contractNumber: "JKT-01"})
+	bus.Publish(context.Background(), referenceChanged{})
+
+	assert.Equal(t, []string{"first JKT-01", "third JKT-01"}, got,
+		"the handlers of the event should run in order, past a panicking one")
+}
@@ -0,0 +1,201 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+var ErrDraining = errors.New("server is draining")
+
+// Check probes a single dependency. a failing critical check makes the instance not ready, a failing
+// non critical one only degrades it, e.g. the kyc api being down must not take every instance out of
+// the load balancer.
+type Check struct {
+	Name     string
+	Critical bool
+	Probe    func(ctx context.Context) (details map[string]interface{}, err error)
+}
+
+type Result struct {
+	Name      string                 `json:"name"`
+	Status    Status                 `json:"status"`
+	LatencyMS float64                `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+type Report struct {
+	Status  Status   `json:"status"`
+	Version string   `json:"version,omitempty"`
+	Checks  []Result `json:"checks,omitempty"`
+}
+
+type Checker struct {
+	version  string
+	timeout  time.Duration
+	checks   []Check
+	draining atomic.Bool
+}
+
+// New returns a checker running checks concurrently, each one within timeout.
+func New(version string, timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		version: version,
+		timeout: timeout,
+		checks:  checks,
+	}
+}
+
+// Summary returns the report with the build, and the status and latency of every check, without their
+// errors and details, which tell more about the dependencies than a caller should know.
+func (r Report) Summary() Report {
+	summary := Report{Status: r.Status, Version: r.Version, Checks: make([]Result, len(r.Checks))}
+	for i, result := range r.Checks {
+		summary.Checks[i] = Result{Name: result.Name, Status: result.Status, LatencyMS: result.LatencyMS}
+	}
+	return summary
+}
+
+// Drain makes the instance not ready so the load balancer stops sending traffic before the server shuts down.
+func (checker *Checker) Drain() {
+	checker.draining.Store(true)
+}
+
+// Ready runs every check and reports whether the instance can serve traffic.
+func (checker *Checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Version: checker.version, Checks: make([]Result, len(checker.checks))}
+	if checker.draining.Load() {
+		report.Status = StatusDown
+		report.Checks = append(report.Checks[:0], Result{Name: "server", Status: StatusDown, Error: ErrDraining.Error()})
+		return report
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checker.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = checker.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for i, result := range report.Checks {
+		if result.Status == StatusUp {
+			continue
+		}
+		if checker.checks[i].Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+func (checker *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, checker.timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := check.Probe(ctx)
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// DBCheck pings db and fails when the pool has at least saturation (0..1] of its max open connections in use.
+func DBCheck(name string, db *sql.DB, saturation float64) Check {
+	return Check{
+		Name:     name,
+		Critical: true,
+		Probe: func(ctx context.Context) (map[string]interface{}, error) {
+			stats := db.Stats()
+			details := map[string]interface{}{
+				"open_connections": stats.OpenConnections,
+				"in_use":           stats.InUse,
+				"idle":             stats.Idle,
+				"max_open":         stats.MaxOpenConnections,
+				"wait_count":       stats.WaitCount,
+			}
+
+			// a saturated pool would only make the ping wait for a connection
+			if stats.MaxOpenConnections > 0 && float64(stats.InUse) >= saturation*float64(stats.MaxOpenConnections) {
+				return details, fmt.Errorf("DBCheck: %s pool is saturated, %d of %d connections in use", name, stats.InUse, stats.MaxOpenConnections)
+			}
+			if err := db.PingContext(ctx); err != nil {
+				return details, fmt.Errorf("DBCheck: error ping %s: %w", name, err)
+			}
+
+			return details, nil
+		},
+	}
+}
+
+// BreakerCheck reports the state of a circuit breaker, it fails while the breaker is not closed.
+func BreakerCheck(name string, state func() string) Check {
+	return Check{
+		Name: name,
+		Probe: func(ctx context.Context) (map[string]interface{}, error) {
+			s := state()
+			details := map[string]interface{}{"circuit_breaker": s}
+			if s != "closed" {
+				return details, fmt.Errorf("BreakerCheck: %s circuit breaker is %s", name, s)
+			}
+			return details, nil
+		},
+	}
+}
+
+// ReplicaCheck reports a read replica as last probed, it fails while the replica takes no reads, e.g. it is
+// down or lags too much; the reads go to the primary meanwhile so the check is not critical.
+func ReplicaCheck(name string, state func() (lag time.Duration, err error)) Check {
+	return Check{
+		Name: name,
+		Probe: func(ctx context.Context) (map[string]interface{}, error) {
+			lag, err := state()
+			details := map[string]interface{}{"lag_seconds": lag.Seconds()}
+			if err != nil {
+				return details, fmt.Errorf("ReplicaCheck: %s takes no reads: %w", name, err)
+			}
+			return details, nil
+		},
+	}
+}
+
+// CacheCheck pings a cache shared by the instances, the reads go to the database while it is down so the
+// check is not critical.
+func CacheCheck(name string, ping func(ctx context.Context) error) Check {
+	return Check{
+		Name: name,
+		Probe: func(ctx context.Context) (map[string]interface{}, error) {
+			if err := ping(ctx); err != nil {
+				return nil, fmt.Errorf("CacheCheck: error ping %s: %w", name, err)
+			}
+			return nil, nil
+		},
+	}
+}
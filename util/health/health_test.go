@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func probe(err error) func(ctx context.Context) (map[string]interface{}, error) {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, err
+	}
+}
+
+func TestChecker_Ready(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		drain      bool
+		wantStatus Status
+	}{
+		{
+			name:       "Given every check passing, it should be up",
+			checks:     []Check{{Name: "mysql", Critical: true, Probe: probe(nil)}, {Name: "kyc", Probe: probe(nil)}},
+			wantStatus: StatusUp,
+		},
+		{
+			name:       "Given a failing non critical check, it should be degraded",
+			checks:     []Check{{Name: "mysql", Critical: true, Probe: probe(nil)}, {Name: "kyc", Probe: probe(errors.New("open"))}},
+			wantStatus: StatusDegraded,
+		},
+		{
+			name:       "Given a failing critical check, it should be down",
+			checks:     []Check{{Name: "mysql", Critical: true, Probe: probe(errors.New("timeout"))}, {Name: "kyc", Probe: probe(errors.New("open"))}},
+			wantStatus: StatusDown,
+		},
+		{
+			name:       "Given a draining server, it should be down",
+			checks:     []Check{{Name: "mysql", Critical: true, Probe: probe(nil)}},
+			drain:      true,
+			wantStatus: StatusDown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := New("v1.0.0", time.Second, tt.checks...)
+			if tt.drain {
+				checker.Drain()
+			}
+
+			report := checker.Ready(context.Background())
+			assert.Equal(t, tt.wantStatus, report.Status)
+			assert.Equal(t, "v1.0.0", report.Version)
+		})
+	}
+}
+
+func TestChecker_Ready_Timeout(t *testing.T) {
+	checker := New("", 10*time.Millisecond, Check{Name: "mysql", Critical: true, Probe: func(ctx context.Context) (map[string]interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}})
+
+	report := checker.Ready(context.Background())
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestDBCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxOpen    int
+		inUse      bool
+		saturation float64
+		pingErr    error
+		wantErr    bool
+	}{
+		{name: "Given a reachable database, it should pass", maxOpen: 10, saturation: 0.9},
+		{name: "Given an unreachable database, it should fail", maxOpen: 10, saturation: 0.9, pingErr: errors.New("connection refused"), wantErr: true},
+		{name: "Given a saturated pool, it should fail", maxOpen: 1, inUse: true, saturation: 0.5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+			assert.NoError(t, err)
+			defer db.Close()
+			db.SetMaxOpenConns(tt.maxOpen)
+
+			if tt.inUse {
+				conn, err := db.Conn(context.Background())
+				assert.NoError(t, err)
+				defer conn.Close()
+			} else {
+				mock.ExpectPing().WillReturnError(tt.pingErr)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			details, err := DBCheck("mysql", db, tt.saturation).Probe(ctx)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.maxOpen, details["max_open"])
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReport_Summary(t *testing.T) {
+	checker := New("v1.0.0", time.Second,
+		Check{Name: "mysql", Critical: true, Probe: func(ctx context.Context) (map[string]interface{}, error) {
+			return map[string]interface{}{"in_use": 3}, nil
+		}},
+		Check{Name: "kyc", Probe: probe(errors.New("dial tcp 10.0.0.7:443: connection refused"))},
+	)
+
+	report := checker.Ready(context.Background())
+	summary := report.Summary()
+	assert.Equal(t, Report{Status: StatusDegraded, Version: "v1.0.0", Checks: []Result{
+		{Name: "mysql", Status: StatusUp, LatencyMS: report.Checks[0].LatencyMS},
+		{Name: "kyc", Status: StatusDown, LatencyMS: report.Checks[1].LatencyMS},
+	}}, summary, "the errors and the details should be left out")
+}
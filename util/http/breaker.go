@@ -0,0 +1,105 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Breaker stops calling a failing upstream. after threshold consecutive failures (transport errors or 5xx)
+// it opens and rejects every request for cooldown, then lets a single probe through to decide whether
+// to close again.
+type Breaker struct {
+	doer      Doer
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewBreaker(doer Doer, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		doer:      doer,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     BreakerClosed,
+	}
+}
+
+func (b *Breaker) Do(req *http.Request) (*http.Response, error) {
+	if !b.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := b.doer.Do(req)
+	b.record(err == nil && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
+
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *Breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	var statusCode int
+	var calls int
+	doer := DoFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if statusCode == 0 {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: statusCode}, nil
+	})
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	breaker := NewBreaker(doer, 2, time.Minute)
+	breaker.now = func() time.Time { return now }
+	req, _ := http.NewRequest(http.MethodGet, "http://kyc.example.com", nil)
+
+	// failures below the threshold keep the breaker closed
+	statusCode = http.StatusBadGateway
+	_, _ = breaker.Do(req)
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	// a client error is not an upstream failure
+	statusCode = http.StatusBadRequest
+	_, _ = breaker.Do(req)
+	statusCode = 0
+	_, _ = breaker.Do(req)
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	_, _ = breaker.Do(req)
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	_, err := breaker.Do(req)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 4, calls)
+
+	// a failing probe opens the breaker again
+	now = now.Add(time.Minute)
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	_, err = breaker.Do(req)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	// a successful probe closes it
+	now = now.Add(time.Minute)
+	statusCode = http.StatusOK
+	_, err = breaker.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, BreakerClosed, breaker.State())
+	assert.Equal(t, 6, calls)
+}
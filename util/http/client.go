@@ -2,8 +2,12 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Doer interface {
@@ -12,6 +16,7 @@ type Doer interface {
 
 type HTTPClient struct {
 	cl      Doer
+	breaker *Breaker
 	BaseURL string
 	apiKey  string
 	appID   string
@@ -21,9 +26,11 @@ type HTTPClientMock struct {
 	doer Doer
 }
 
+// NewClient returns a client whose requests are traced, the trace context of the request is propagated
+// to the server in the traceparent header.
 func NewClient(baseURL, apiKey, appID string) *HTTPClient {
 	return &HTTPClient{
-		cl:      http.DefaultClient,
+		cl:      &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
 		BaseURL: baseURL,
 		apiKey:  apiKey,
 		appID:   appID,
@@ -39,6 +46,21 @@ func NewClientWithHTTPClient(cl *http.Client, baseURL, apiKey, appID string) *HT
 	}
 }
 
+// WithBreaker guards the client with a circuit breaker, see Breaker.
+func (c *HTTPClient) WithBreaker(threshold int, cooldown time.Duration) *HTTPClient {
+	c.breaker = NewBreaker(c.cl, threshold, cooldown)
+	c.cl = c.breaker
+	return c
+}
+
+// BreakerState returns the state of the circuit breaker, a client without breaker is always closed.
+func (c *HTTPClient) BreakerState() BreakerState {
+	if c.breaker == nil {
+		return BreakerClosed
+	}
+	return c.breaker.State()
+}
+
 func (c *HTTPClient) GetAPIKey() string {
 	return c.apiKey
 }
@@ -47,8 +69,8 @@ func (c *HTTPClient) GetAPIID() string {
 	return c.appID
 }
 
-func (c *HTTPClient) Get(url string, options ...Option) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+func (c *HTTPClient) Get(ctx context.Context, url string, options ...Option) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +80,7 @@ func (c *HTTPClient) Get(url string, options ...Option) (*http.Response, error)
 	return c.cl.Do(req)
 }
 
-func (c *HTTPClient) Post(url string, body interface{}, options ...Option) (*http.Response, error) {
+func (c *HTTPClient) Post(ctx context.Context, url string, body interface{}, options ...Option) (*http.Response, error) {
 	var (
 		err       error
 		bodyBytes []byte
@@ -69,7 +91,7 @@ func (c *HTTPClient) Post(url string, body interface{}, options ...Option) (*htt
 			return nil, err
 		}
 	}
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, err
 	}
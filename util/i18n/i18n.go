@@ -0,0 +1,160 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	ID Lang = "id"
+	EN Lang = "en"
+
+	// Fallback is used when the client accepts none of the supported languages or a message is missing
+	// from its bundle, most of our customers read bahasa.
+	Fallback = ID
+)
+
+// keys of the catalog are namespaced by their source: error.<error code>, validation.<validation code>
+// and notification.<event>.
+const (
+	ErrorPrefix        = "error."
+	ValidationPrefix   = "validation."
+	NotificationPrefix = "notification."
+)
+
+// Params are interpolated into the {name} placeholders of a message.
+type Params map[string]interface{}
+
+// IDR is a rupiah amount, it is formatted the way the language writes money, e.g. Rp1.500.000 or IDR 1,500,000.
+type IDR float64
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var (
+	bundles     = mustLoad(ID, EN)
+	placeholder = regexp.MustCompile(`\{(\w+)\}`)
+)
+
+// Message returns the message of key in lang with params interpolated. it falls back to the Fallback
+// bundle and returns an empty string when no bundle has the key.
+func Message(lang Lang, key string, params Params) string {
+	tmpl, ok := bundles[lang][key]
+	if !ok {
+		lang = Fallback
+		tmpl, ok = bundles[Fallback][key]
+	}
+	if !ok {
+		return ""
+	}
+
+	return placeholder.ReplaceAllStringFunc(tmpl, func(s string) string {
+		v, ok := params[s[1:len(s)-1]]
+		if !ok {
+			return s
+		}
+		return format(lang, v)
+	})
+}
+
+// Negotiate picks the supported language the client prefers the most from an Accept-Language header,
+// e.g. "en-US,en;q=0.9,id;q=0.8" picks en.
+func Negotiate(acceptLanguage string) Lang {
+	type candidate struct {
+		lang Lang
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, q := strings.TrimSpace(part), 1.0
+		if i := strings.Index(tag, ";"); i >= 0 {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(tag[i+1:]), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+			tag = tag[:i]
+		}
+
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		lang := Lang(base)
+		if base == "*" {
+			lang = Fallback
+		}
+		if _, ok := bundles[lang]; ok && q > 0 {
+			candidates = append(candidates, candidate{lang: lang, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return Fallback
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// FormatIDR writes amount as rupiah in lang, fractions are only written when there are any.
+func FormatIDR(lang Lang, amount float64) string {
+	thousands, decimal, prefix := ".", ",", "Rp"
+	if lang == EN {
+		thousands, decimal, prefix = ",", ".", "IDR "
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	cents := int64(math.Round(amount * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(thousands)
+		}
+		b.WriteRune(r)
+	}
+	if fraction := cents % 100; fraction != 0 {
+		b.WriteString(fmt.Sprintf("%s%02d", decimal, fraction))
+	}
+
+	return sign + prefix + b.String()
+}
+
+func format(lang Lang, v interface{}) string {
+	switch v := v.(type) {
+	case IDR:
+		return FormatIDR(lang, float64(v))
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func mustLoad(langs ...Lang) map[Lang]map[string]string {
+	bundles := make(map[Lang]map[string]string, len(langs))
+	for _, lang := range langs {
+		b, err := locales.ReadFile("locales/" + string(lang) + ".json")
+		if err != nil {
+			panic(fmt.Errorf("mustLoad: error read %s bundle: %w", lang, err))
+		}
+
+		var bundle map[string]string
+		if err := json.Unmarshal(b, &bundle); err != nil {
+			panic(fmt.Errorf("mustLoad: error parse %s bundle: %w", lang, err))
+		}
+		bundles[lang] = bundle
+	}
+
+	return bundles
+}
@@ -0,0 +1,108 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundles(t *testing.T) {
+	for lang, bundle := range bundles {
+		for key := range bundles[Fallback] {
+			assert.Contains(t, bundle, key, "%s bundle misses %s", lang, key)
+		}
+		for key := range bundle {
+			assert.Contains(t, bundles[Fallback], key, "%s bundle misses %s", Fallback, key)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           Lang
+	}{
+		{name: "Given no header, it should return the fallback language", want: Fallback},
+		{name: "Given a supported language, it should return it", acceptLanguage: "en", want: EN},
+		{name: "Given a regional tag, it should match its base language", acceptLanguage: "id-ID", want: ID},
+		{name: "Given weighted languages, it should return the most preferred", acceptLanguage: "id;q=0.5, en-GB;q=0.9, fr", want: EN},
+		{name: "Given unsupported languages only, it should return the fallback language", acceptLanguage: "fr-FR, de;q=0.9", want: Fallback},
+		{name: "Given a refused language, it should not return it", acceptLanguage: "en;q=0, id;q=0.1", want: ID},
+		{name: "Given a wildcard, it should return the fallback language", acceptLanguage: "*", want: Fallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Negotiate(tt.acceptLanguage))
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	params := Params{"amount": IDR(15_000_000), "contract_number": "JKT-01-20261019-0001230"}
+	tests := []struct {
+		name   string
+		lang   Lang
+		key    string
+		params Params
+		want   string
+	}{
+		{
+			name:   "Given bahasa, it should interpolate the amount in rupiah",
+			lang:   ID,
+			key:    NotificationPrefix + "LOAN_APPLICATION_APPROVED",
+			params: params,
+			want:   "Selamat! Pengajuan pinjaman Anda sebesar Rp15.000.000 disetujui dengan nomor kontrak JKT-01-20261019-0001230",
+		},
+		{
+			name:   "Given english, it should interpolate the amount in english notation",
+			lang:   EN,
+			key:    NotificationPrefix + "LOAN_APPLICATION_APPROVED",
+			params: params,
+			want:   "Congratulations! Your loan application of IDR 15,000,000 is approved under contract number JKT-01-20261019-0001230",
+		},
+		{
+			name: "Given a missing param, it should keep the placeholder",
+			lang: EN,
+			key:  ValidationPrefix + "TOO_LONG",
+			want: "Must be at most {param} characters",
+		},
+		{
+			name: "Given an unsupported language, it should use the fallback bundle",
+			lang: Lang("fr"),
+			key:  ErrorPrefix + "NOT_FOUND",
+			want: "Data tidak ditemukan",
+		},
+		{
+			name: "Given an unknown key, it should return an empty message",
+			lang: EN,
+			key:  ErrorPrefix + "UNKNOWN",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Message(tt.lang, tt.key, tt.params))
+		})
+	}
+}
+
+func TestFormatIDR(t *testing.T) {
+	tests := []struct {
+		name   string
+		lang   Lang
+		amount float64
+		want   string
+	}{
+		{name: "Given a small amount, it should not group digits", lang: ID, amount: 500, want: "Rp500"},
+		{name: "Given bahasa, it should group with dots", lang: ID, amount: 1_500_000, want: "Rp1.500.000"},
+		{name: "Given bahasa and a fraction, it should use a decimal comma", lang: ID, amount: 1_234_567.5, want: "Rp1.234.567,50"},
+		{name: "Given english, it should group with commas", lang: EN, amount: 250_000_000, want: "IDR 250,000,000"},
+		{name: "Given english and a fraction, it should use a decimal point", lang: EN, amount: 999.99, want: "IDR 999.99"},
+		{name: "Given a negative amount, it should keep the sign", lang: ID, amount: -25_000, want: "-Rp25.000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatIDR(tt.lang, tt.amount))
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		log   func(logger zerolog.Logger)
+		check func(t *testing.T, entry map[string]interface{})
+	}{
+		{
+			name: "Given personal data fields, it should mask them",
+			log: func(logger zerolog.Logger) {
+				logger.Info().Str("national_id", "3174014101900001").Str("legalName", "Budi Santoso").
+					Float64("salary", 8_500_000).Bytes("user_photo", []byte("\x89PNG")).Str("asset_name", "Honda Beat").Msg("kyc")
+			},
+			check: func(t *testing.T, entry map[string]interface{}) {
+				assert.Equal(t, "************0001", entry["national_id"])
+				assert.Equal(t, redacted, entry["legalName"])
+				assert.Equal(t, redacted, entry["salary"])
+				assert.Equal(t, redacted, entry["user_photo"])
+				assert.Equal(t, "Honda Beat", entry["asset_name"])
+			},
+		},
+		{
+			name: "Given a struct with personal data, it should mask the nested fields",
+			log: func(logger zerolog.Logger) {
+				logger.Info().Interface("req", struct {
+					NationalID      string
+					FullName        string
+					NationalIDPhoto []byte
+					Tenor           int
+				}{"3174014101900001", "Budi Santoso", []byte("\xff\xd8\xff"), 12}).Msg("request")
+			},
+			check: func(t *testing.T, entry map[string]interface{}) {
+				req := entry["req"].(map[string]interface{})
+				assert.Equal(t, "************0001", req["NationalID"])
+				assert.Equal(t, redacted, req["FullName"])
+				assert.Equal(t, redacted, req["NationalIDPhoto"])
+				assert.Equal(t, json.Number("12"), req["Tenor"])
+			},
+		},
+		{
+			name: "Given a national id in an error message, it should mask it",
+			log: func(logger zerolog.Logger) {
+				logger.Error().Err(errors.New("user with national id 3174014101900001 not found")).Msg("")
+			},
+			check: func(t *testing.T, entry map[string]interface{}) {
+				assert.Equal(t, "user with national id ************0001 not found", entry["error"])
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(zerolog.New(newRedactWriter(&buf)))
+
+			assert.NotContains(t, buf.String(), "3174014101900001")
+			dec := json.NewDecoder(&buf)
+			dec.UseNumber()
+			var entry map[string]interface{}
+			require.NoError(t, dec.Decode(&entry))
+			tt.check(t, entry)
+		})
+	}
+}
+
+func TestRedactWriter_Console(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(newRedactWriter(zerolog.ConsoleWriter{Out: &buf, NoColor: true}))
+	logger.Info().Str("nik", "3174014101900001").Msg("kyc")
+
+	assert.Contains(t, buf.String(), "nik=************0001")
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "Given a json file output, it should succeed", cfg: Config{Level: "info", Format: FormatJSON, Output: t.TempDir() + "/app.log"}},
+		{name: "Given a console output with sampling, it should succeed", cfg: Config{Level: "debug", Format: FormatConsole, Output: "stdout", SampleEvery: 10}},
+		{name: "Given an unknown level, it should return an error", cfg: Config{Level: "verbose"}, wantErr: true},
+		{name: "Given an unknown format, it should return an error", cfg: Config{Level: "info", Format: "xml"}, wantErr: true},
+		{name: "Given a file in a missing directory, it should return an error", cfg: Config{Level: "info", Output: "/nonexistent/app.log"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Init(tt.cfg)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+	assert.NoError(t, Close())
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	zlog = zerolog.New(newRedactWriter(&buf))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(requestid.New(), func(c *gin.Context) { c.Set("uid", int64(42)) }, Middleware())
+	router.GET("/loans/:contractNumber", func(c *gin.Context) {
+		Ctx(c).Info().Msg("handling")
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/loans/JKT-01-20261019-0001230", nil)
+	req.Header.Set("X-Request-ID", "rid-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	dec := json.NewDecoder(&buf)
+	for _, msg := range []string{"handling", "request served"} {
+		var entry map[string]interface{}
+		require.NoError(t, dec.Decode(&entry))
+		assert.Equal(t, msg, entry["message"])
+		assert.Equal(t, "rid-1", entry["requestID"])
+		assert.Equal(t, "/loans/:contractNumber", entry["route"])
+		assert.Equal(t, float64(42), entry["uid"])
+	}
+}
+
+func TestCtx_WithoutLogger(t *testing.T) {
+	assert.Equal(t, &zlog, Ctx(context.Background()))
+}
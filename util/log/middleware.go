@@ -0,0 +1,39 @@
+package log
+
+import (
+	"time"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+	"github.com/mfajri11/xyz-backend-monolith/util/tracing"
+	"github.com/rs/zerolog"
+)
+
+// Middleware puts a request scoped logger in the request context, it carries the request id, trace id and route
+// of the request and the user id once it is known. the request is logged once it is served. it must run after
+// the request id and tracing middlewares.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		logger := zlog.With().
+			Str("requestID", requestid.Get(c)).
+			Str("traceID", tracing.TraceID(c.Request.Context())).
+			Str("method", c.Request.Method).
+			Str("route", c.FullPath()).
+			Logger().
+			Hook(zerolog.HookFunc(func(e *zerolog.Event, level zerolog.Level, message string) {
+				if uid := c.GetInt64("uid"); uid != 0 {
+					e.Int64("uid", uid)
+				}
+			}))
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context()))
+
+		c.Next()
+
+		le := logger.Info()
+		if c.Writer.Status() >= 500 {
+			le = logger.Error()
+		}
+		le.Int("status", c.Writer.Status()).Dur("latency", time.Since(start)).Msg("request served")
+	}
+}
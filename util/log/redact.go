@@ -0,0 +1,120 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// personal data must never reach the logs (UU PDP). the keys are compared lower cased without _ and -,
+// so national_id, nationalId and NationalID all match.
+var (
+	sensitiveKeys = map[string]bool{
+		"nationalid":  true,
+		"nik":         true,
+		"legalname":   true,
+		"fullname":    true,
+		"name":        true,
+		"birthofdate": true,
+		"dateofbirth": true,
+	}
+	sensitiveKeyParts = []string{"salary", "photo"}
+
+	// a NIK can also hide in a free text value, e.g. an error message.
+	nikPattern = regexp.MustCompile(`\b\d{16}\b`)
+)
+
+// redactWriter masks personal data in every json log line before it reaches the underlying writer.
+type redactWriter struct {
+	w io.Writer
+}
+
+func newRedactWriter(w io.Writer) io.Writer {
+	return redactWriter{w: w}
+}
+
+func (rw redactWriter) Write(p []byte) (int, error) {
+	var entry map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&entry); err != nil {
+		// not a json line, the free text is still scanned for national ids
+		if _, err := rw.w.Write(nikPattern.ReplaceAllFunc(p, func(b []byte) []byte { return []byte(MaskNIK(string(b))) })); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	b, err := json.Marshal(redact("", entry))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := rw.w.Write(append(b, '\n')); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func redact(key string, v interface{}) interface{} {
+	if isSensitive(key) {
+		if s, ok := v.(string); ok && isNIKKey(key) {
+			return MaskNIK(s)
+		}
+		if v == nil {
+			return nil
+		}
+		return redacted
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			v[k] = redact(k, value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redact(key, value)
+		}
+		return v
+	case string:
+		return nikPattern.ReplaceAllStringFunc(v, MaskNIK)
+	default:
+		return v
+	}
+}
+
+func normalizeKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+}
+
+func isSensitive(key string) bool {
+	k := normalizeKey(key)
+	if sensitiveKeys[k] {
+		return true
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(k, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNIKKey(key string) bool {
+	k := normalizeKey(key)
+	return k == "nationalid" || k == "nik"
+}
+
+// MaskNIK keeps the last 4 digits of a national id so support can still tell two customers apart.
+func MaskNIK(nik string) string {
+	if len(nik) <= 4 {
+		return strings.Repeat("*", len(nik))
+	}
+	return strings.Repeat("*", len(nik)-4) + nik[len(nik)-4:]
+}
@@ -1,38 +1,110 @@
 package log
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog"
 )
 
-// type Logger interface {
-// 	Debug(message string, args ...interface{})
-// 	Info(message string, args ...interface{})
-// 	Warn(message string, args ...interface{})
-// 	Error(err error, message string, args ...interface{})
-// 	Fatal(err error, message string, args ...interface{})
-// }
+// output formats.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+type Config struct {
+	Level string
+	// Format is json or console.
+	Format string
+	// Output is stdout, stderr or the path of a file the logs are appended to.
+	Output string
+	// SampleEvery keeps one of every SampleEvery debug and info logs, warnings and errors are never sampled.
+	SampleEvery uint32
+}
 
 var (
-	zlog    zerolog.Logger
-	writter io.Writer
+	zlog   zerolog.Logger
+	output io.Closer
 )
 
-func initzlog() {
+func init() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-	f, err := os.Open("/etc/var/log/family-catering/access.log")
-	if err != nil {
-		writter = os.Stderr
-	} else {
-		writter = f
+	zlog = zerolog.New(newRedactWriter(os.Stderr)).With().Timestamp().Logger()
+}
+
+// Init replaces the global logger according to cfg. every log goes through the redaction layer,
+// whatever the output and format are.
+func Init(cfg Config) error {
+	level, err := zerolog.ParseLevel(strings.ToLower(cfg.Level))
+	if err != nil || cfg.Level == "" {
+		return fmt.Errorf("Init: invalid log level %q", cfg.Level)
+	}
+
+	var w io.Writer
+	switch cfg.Output {
+	case "", "stderr":
+		w = os.Stderr
+	case "stdout":
+		w = os.Stdout
+	default:
+		f, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+		if err != nil {
+			return fmt.Errorf("Init: error open log file: %w", err)
+		}
+		if output != nil {
+			output.Close()
+		}
+		output = f
+		w = f
 	}
-	zlog = zerolog.New(writter).With().Timestamp().Logger()
+
+	switch cfg.Format {
+	case "", FormatJSON:
+	case FormatConsole:
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: "15:04:05"}
+	default:
+		return fmt.Errorf("Init: invalid log format %q", cfg.Format)
+	}
+
+	zerolog.SetGlobalLevel(level)
+	logger := zerolog.New(newRedactWriter(w)).With().Timestamp().Logger()
+	if cfg.SampleEvery > 1 {
+		sampler := &zerolog.BasicSampler{N: cfg.SampleEvery}
+		logger = logger.Sample(zerolog.LevelSampler{DebugSampler: sampler, InfoSampler: sampler})
+	}
+	zlog = logger
+
+	return nil
 }
 
-func init() {
-	initzlog()
+// SetLevel changes the level of every logger, it is safe to call while logging.
+func SetLevel(level string) error {
+	l, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil || level == "" {
+		return fmt.Errorf("SetLevel: invalid log level %q", level)
+	}
+	zerolog.SetGlobalLevel(l)
+	return nil
+}
+
+// Close closes the log file, if any.
+func Close() error {
+	if output == nil {
+		return nil
+	}
+	return output.Close()
+}
+
+// Ctx returns the logger carried by ctx, i.e. the request scoped logger, or the global one.
+func Ctx(ctx context.Context) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+	return &zlog
 }
 
 func msg(le *zerolog.Event, message string, args ...interface{}) {
@@ -48,3 +48,17 @@ func NewSQLNUllableString(s string) sql.NullString {
 		Valid:  true,
 	}
 }
+
+func NewSQLNullableTime(t time.Time) sql.NullTime {
+	return sql.NullTime{
+		Time:  t,
+		Valid: true,
+	}
+}
+
+func NewSQLNullableInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{
+		Int64: v,
+		Valid: true,
+	}
+}
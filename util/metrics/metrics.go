@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "xyz"
+
+// outcomes of a kyc call or of a sql statement.
+const (
+	OutcomeSuccess     = "success"
+	OutcomeClientError = "client_error"
+	OutcomeServerError = "server_error"
+	OutcomeTransport   = "transport_error"
+	OutcomeError       = "error"
+	OutcomeTimeout     = "timeout"
+)
+
+// Registry holds every metric of the application, it is served by Handler.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the http requests by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	kycRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "kyc",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the calls to the kyc api by check and outcome.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"check", "outcome"})
+
+	kycVerdicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "kyc",
+		Name:      "verdicts_total",
+		Help:      "Verdicts of the kyc checks by check and result (pass or fail).",
+	}, []string{"check", "result"})
+
+	loansCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "loan",
+		Name:      "created_total",
+		Help:      "Loans created by loan type.",
+	}, []string{"loan_type"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of the sql statements by query name, database and outcome.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"query", "db", "outcome"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	paymentsPosted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "loan",
+		Name:      "payments_posted_total",
+		Help:      "Loan payments posted by channel.",
+	}, []string{"channel"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Requests refused over a rate limit by rule route and key (ip, user, nik or kyc).",
+	}, []string{"route", "by"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		kycRequestDuration,
+		kycVerdicts,
+		dbQueryDuration,
+		cacheLookups,
+		loansCreated,
+		paymentsPosted,
+		rateLimited,
+	)
+}
+
+// Handler serves the metrics in the prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Middleware observes the duration of every request. requests are labelled by their route template,
+// e.g. /loans/:contractNumber, so the cardinality does not grow with the path parameters.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDB exports the sql.DBStats of the pool under the db label.
+func RegisterDB(name string, db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveKYCRequest records the latency of a kyc call started at start, statusCode is 0 when the call
+// did not get a response.
+func ObserveKYCRequest(check string, start time.Time, statusCode int) {
+	outcome := OutcomeSuccess
+	switch {
+	case statusCode == 0:
+		outcome = OutcomeTransport
+	case statusCode >= http.StatusInternalServerError:
+		outcome = OutcomeServerError
+	case statusCode >= http.StatusBadRequest:
+		outcome = OutcomeClientError
+	}
+	kycRequestDuration.WithLabelValues(check, outcome).Observe(time.Since(start).Seconds())
+}
+
+// ObserveDBQuery records the latency of the statement query started at start on the database db,
+// timedOut tells a statement cut by its timeout from one failing on its own.
+func ObserveDBQuery(query, db string, start time.Time, err error, timedOut bool) {
+	outcome := OutcomeSuccess
+	switch {
+	case timedOut:
+		outcome = OutcomeTimeout
+	case err != nil:
+		outcome = OutcomeError
+	}
+	dbQueryDuration.WithLabelValues(query, db, outcome).Observe(time.Since(start).Seconds())
+}
+
+func IncKYCVerdict(check string, passed bool) {
+	result := "pass"
+	if !passed {
+		result = "fail"
+	}
+	kycVerdicts.WithLabelValues(check, result).Inc()
+}
+
+func IncCacheLookup(cache string, hit bool) {
+	result := "hit"
+	if !hit {
+		result = "miss"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+func IncLoansCreated(loanType string) {
+	loansCreated.WithLabelValues(loanType).Inc()
+}
+
+func IncPaymentsPosted(channel string) {
+	paymentsPosted.WithLabelValues(channel).Inc()
+}
+
+func IncRateLimited(route, by string) {
+	rateLimited.WithLabelValues(route, by).Inc()
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/loans/:contractNumber", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	router.GET("/metrics", gin.WrapH(Handler()))
+
+	for _, path := range []string{"/loans/JKT-01-20261019-0001230", "/loans/JKT-01-20261019-0001248", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, body, `xyz_http_request_duration_seconds_count{method="GET",route="/loans/:contractNumber",status="404"} 2`)
+	assert.Contains(t, body, `xyz_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.NotContains(t, body, "0001230")
+}
+
+func TestObserveKYCRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		wantOutcome string
+	}{
+		{name: "Given a 200, it should be a success", statusCode: http.StatusOK, wantOutcome: OutcomeSuccess},
+		{name: "Given a 422, it should be a client error", statusCode: http.StatusUnprocessableEntity, wantOutcome: OutcomeClientError},
+		{name: "Given a 503, it should be a server error", statusCode: http.StatusServiceUnavailable, wantOutcome: OutcomeServerError},
+		{name: "Given no response, it should be a transport error", wantOutcome: OutcomeTransport},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kycRequestDuration.Reset()
+			ObserveKYCRequest("salary", time.Now(), tt.statusCode)
+			assert.Equal(t, 1, testutil.CollectAndCount(kycRequestDuration))
+			assert.True(t, kycRequestDuration.DeleteLabelValues("salary", tt.wantOutcome))
+		})
+	}
+}
+
+func TestObserveDBQuery(t *testing.T) {
+	dbQueryDuration.Reset()
+	ObserveDBQuery("loan.create", "primary", time.Now(), nil, false)
+	ObserveDBQuery("loan.create", "primary", time.Now(), errors.New("deadlock"), false)
+	ObserveDBQuery("loan.list", "replica-1:3306", time.Now(), context.DeadlineExceeded, true)
+
+	assert.Equal(t, 3, testutil.CollectAndCount(dbQueryDuration))
+	assert.True(t, dbQueryDuration.DeleteLabelValues("loan.create", "primary", OutcomeSuccess))
+	assert.True(t, dbQueryDuration.DeleteLabelValues("loan.create", "primary", OutcomeError))
+	assert.True(t, dbQueryDuration.DeleteLabelValues("loan.list", "replica-1:3306", OutcomeTimeout))
+}
+
+func TestCounters(t *testing.T) {
+	IncKYCVerdict("photo", true)
+	IncKYCVerdict("photo", false)
+	IncKYCVerdict("photo", false)
+	IncLoansCreated("CAR")
+	IncPaymentsPosted("virtual_account")
+	IncCacheLookup("loan", true)
+	IncCacheLookup("loan", false)
+	IncRateLimited("POST /loan", "user")
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(kycVerdicts.WithLabelValues("photo", "pass")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(kycVerdicts.WithLabelValues("photo", "fail")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(loansCreated.WithLabelValues("CAR")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(paymentsPosted.WithLabelValues("virtual_account")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(cacheLookups.WithLabelValues("loan", "hit")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(cacheLookups.WithLabelValues("loan", "miss")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(rateLimited.WithLabelValues("POST /loan", "user")))
+}
+
+func TestRegisterDB(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	assert.NoError(t, RegisterDB("xyz", db))
+	assert.Error(t, RegisterDB("xyz", db), "registering the same pool twice must fail")
+
+	families, err := Registry.Gather()
+	assert.NoError(t, err)
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Contains(t, strings.Join(names, ","), "go_sql_open_connections")
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	uhttp "github.com/mfajri11/xyz-backend-monolith/util/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setup(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func TestStartEnd(t *testing.T) {
+	recorder := setup(t)
+
+	ctx, parent := Start(context.Background(), "LoanService.ListLoans")
+	_, child := Start(ctx, "LoanRepository.ListLoansByUserID")
+	End(child, errors.New("connection refused"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "LoanRepository.ListLoansByUserID", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Equal(t, spans[1].SpanContext().TraceID().String(), TraceID(ctx))
+}
+
+func TestTraceID_NotTraced(t *testing.T) {
+	assert.Empty(t, TraceID(context.Background()))
+}
+
+func TestHTTPClientPropagation(t *testing.T) {
+	recorder := setup(t)
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	ctx, span := Start(context.Background(), "UserService.ValidateData")
+	resp, err := uhttp.NewClient(srv.URL, "key", "xyz").Post(ctx, srv.URL+"/veryfi/national-id", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	span.End()
+
+	assert.Contains(t, traceparent, TraceID(ctx))
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, span.SpanContext().SpanID(), spans[0].Parent().SpanID(), "the http span must be a child of the service span")
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		wantErr  bool
+	}{
+		{name: "Given the none exporter, it should still generate trace ids", exporter: ExporterNone},
+		{name: "Given the file exporter, it should write the spans to the file", exporter: ExporterFile},
+		{name: "Given an unknown exporter, it should return an error", exporter: "jaeger", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Init(context.Background(), Config{
+				ServiceName: "xyz-test",
+				Exporter:    tt.exporter,
+				FilePath:    t.TempDir() + "/traces.json",
+				SampleRatio: 1,
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			ctx, span := Start(context.Background(), "test")
+			span.End()
+			assert.NotEmpty(t, TraceID(ctx))
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
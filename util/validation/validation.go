@@ -0,0 +1,204 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mfajri11/xyz-backend-monolith/util/i18n"
+)
+
+// machine readable codes of a failing field, clients must rely on these instead of the message.
+const (
+	CodeRequired     = "REQUIRED"
+	CodeTooShort     = "TOO_SHORT"
+	CodeTooLong      = "TOO_LONG"
+	CodeTooSmall     = "TOO_SMALL"
+	CodeTooLarge     = "TOO_LARGE"
+	CodeNotAllowed   = "NOT_ALLOWED"
+	CodeInvalidNIK   = "INVALID_NIK"
+	CodeInvalidIDR   = "INVALID_AMOUNT"
+	CodeInvalidDate  = "INVALID_DATE"
+	CodeInvalidImage = "INVALID_IMAGE"
+	CodeInvalid      = "INVALID"
+)
+
+const maxIDRAmount = 1_000_000_000_000
+
+var (
+	nikPattern        = regexp.MustCompile(`^\d{16}$`)
+	idrPattern        = regexp.MustCompile(`^[1-9]\d{0,12}$`)
+	allowedImageTypes = map[string]bool{"image/jpeg": true, "image/png": true}
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"-"`
+}
+
+type Errors []FieldError
+
+func (errs Errors) Error() string {
+	fields := make([]string, 0, len(errs))
+	for _, err := range errs {
+		fields = append(fields, err.Field+": "+err.Code)
+	}
+	return "validation failed: " + strings.Join(fields, ", ")
+}
+
+// Register adds the custom validation tags to v and reports fields by their json (or form) name.
+//
+//	nik       16 digit national id (NIK) with a plausible province, birth day and birth month
+//	idr       whole rupiah amount greater than zero, as a number or a numeric string
+//	dateonly  date string formatted as yyyy-mm-dd
+//	image=N   jpeg or png image no larger than N kilobytes
+func Register(v *validator.Validate) error {
+	v.RegisterTagNameFunc(fieldName)
+
+	validations := map[string]validator.Func{
+		"nik":      isNIK,
+		"idr":      isIDR,
+		"dateonly": isDateOnly,
+		"image":    isImage,
+	}
+	for tag, fn := range validations {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return fmt.Errorf("Register: error register %s validation: %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// Translate converts the error returned by a validator into field errors with messages in lang,
+// ok is false for any other error.
+func Translate(err error, lang i18n.Lang) (errs Errors, ok bool) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil, false
+	}
+
+	for _, fe := range validationErrs {
+		code := codeOf(fe)
+		errs = append(errs, FieldError{
+			Field:   fieldPath(fe),
+			Code:    code,
+			Message: i18n.Message(lang, i18n.ValidationPrefix+code, i18n.Params{"param": fe.Param()}),
+			Param:   fe.Param(),
+		})
+	}
+
+	return errs, true
+}
+
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// fieldPath drops the top level struct name from the namespace, e.g. CreateLoanReq.national_id becomes national_id.
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+func codeOf(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_if", "required_without", "required_with":
+		return CodeRequired
+	case "min", "gte", "gt":
+		if fe.Kind() == reflect.String || fe.Kind() == reflect.Slice {
+			return CodeTooShort
+		}
+		return CodeTooSmall
+	case "max", "lte", "lt", "ltfield", "ltefield":
+		if fe.Kind() == reflect.String || fe.Kind() == reflect.Slice {
+			return CodeTooLong
+		}
+		return CodeTooLarge
+	case "oneof":
+		return CodeNotAllowed
+	case "nik":
+		return CodeInvalidNIK
+	case "idr":
+		return CodeInvalidIDR
+	case "dateonly":
+		return CodeInvalidDate
+	case "image":
+		return CodeInvalidImage
+	default:
+		return CodeInvalid
+	}
+}
+
+func isNIK(fl validator.FieldLevel) bool {
+	nik := fl.Field().String()
+	if !nikPattern.MatchString(nik) {
+		return false
+	}
+
+	province, _ := strconv.Atoi(nik[0:2])
+	day, _ := strconv.Atoi(nik[6:8])
+	month, _ := strconv.Atoi(nik[8:10])
+	// women have 40 added to their birth day
+	if day > 40 {
+		day -= 40
+	}
+
+	return province >= 11 && province <= 94 && day >= 1 && day <= 31 && month >= 1 && month <= 12 && nik[12:] != "0000"
+}
+
+func isIDR(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.String:
+		return idrPattern.MatchString(field.String())
+	case reflect.Float32, reflect.Float64:
+		v := field.Float()
+		return v > 0 && v <= maxIDRAmount && v == float64(int64(v))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v := field.Int()
+		return v > 0 && v <= maxIDRAmount
+	default:
+		return false
+	}
+}
+
+func isDateOnly(fl validator.FieldLevel) bool {
+	_, err := time.Parse(time.DateOnly, fl.Field().String())
+	return err == nil
+}
+
+func isImage(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.Slice || field.Type().Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+
+	maxKB, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		return false
+	}
+
+	b := field.Bytes()
+	return len(b) > 0 && len(b) <= maxKB*1024 && allowedImageTypes[http.DetectContentType(b)]
+}
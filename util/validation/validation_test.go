@@ -0,0 +1,143 @@
+package validation
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mfajri11/xyz-backend-monolith/util/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+type testReq struct {
+	NationalID  string  `json:"national_id" validate:"required,nik"`
+	Amount      float64 `json:"amount" validate:"required,idr"`
+	DownPayment float64 `json:"down_payment" validate:"omitempty,idr,ltfield=Amount"`
+	Salary      string  `json:"salary" validate:"required,idr"`
+	BirthOfDate string  `json:"birth_of_date" validate:"required,dateonly"`
+	Photo       []byte  `json:"photo" validate:"required,image=1"`
+	Limit       int     `form:"limit" validate:"omitempty,max=100"`
+	Internal    string  `json:"-" validate:"max=1"`
+}
+
+var (
+	pngHeader = []byte("\x89PNG\r\n\x1a\n")
+	jpgHeader = []byte("\xff\xd8\xff")
+)
+
+func validReq() testReq {
+	return testReq{
+		NationalID:  "3174014101900001",
+		Amount:      10_000_000,
+		DownPayment: 1_000_000,
+		Salary:      "8500000",
+		BirthOfDate: "1990-01-01",
+		Photo:       pngHeader,
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	v := validator.New()
+	assert.NoError(t, Register(v))
+
+	tests := []struct {
+		name   string
+		modify func(req *testReq)
+		want   map[string]string
+	}{
+		{
+			name:   "Given a valid request, it should return no field error",
+			modify: func(req *testReq) {},
+			want:   map[string]string{},
+		},
+		{
+			name:   "Given a female NIK, it should return no field error",
+			modify: func(req *testReq) { req.NationalID = "3174015101900001"; req.Photo = jpgHeader },
+			want:   map[string]string{},
+		},
+		{
+			name: "Given an empty request, it should return every required field",
+			modify: func(req *testReq) {
+				*req = testReq{}
+			},
+			want: map[string]string{
+				"national_id":   CodeRequired,
+				"amount":        CodeRequired,
+				"salary":        CodeRequired,
+				"birth_of_date": CodeRequired,
+				"photo":         CodeRequired,
+			},
+		},
+		{
+			name: "Given malformed values, it should return a code per field",
+			modify: func(req *testReq) {
+				req.NationalID = "3174013201900001"
+				req.Amount = 1000.5
+				req.DownPayment = 20_000_000
+				req.Salary = "8.500.000"
+				req.BirthOfDate = "01-01-1990"
+				req.Photo = bytes.Repeat([]byte("a"), 10)
+				req.Limit = 101
+			},
+			want: map[string]string{
+				"national_id":   CodeInvalidNIK,
+				"amount":        CodeInvalidIDR,
+				"down_payment":  CodeTooLarge,
+				"salary":        CodeInvalidIDR,
+				"birth_of_date": CodeInvalidDate,
+				"photo":         CodeInvalidImage,
+				"limit":         CodeTooLarge,
+			},
+		},
+		{
+			name:   "Given an image over the size limit, it should return invalid image",
+			modify: func(req *testReq) { req.Photo = append(pngHeader, make([]byte, 1024)...) },
+			want:   map[string]string{"photo": CodeInvalidImage},
+		},
+		{
+			name:   "Given a NIK with an unknown province, it should return invalid nik",
+			modify: func(req *testReq) { req.NationalID = "0174014101900001" },
+			want:   map[string]string{"national_id": CodeInvalidNIK},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validReq()
+			tt.modify(&req)
+			err := v.Struct(req)
+
+			got := map[string]string{}
+			if err != nil {
+				errs, ok := Translate(err, i18n.EN)
+				assert.True(t, ok, err)
+				for _, fe := range errs {
+					got[fe.Field] = fe.Code
+					assert.NotEmpty(t, fe.Message)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTranslate_NotValidationError(t *testing.T) {
+	_, ok := Translate(assert.AnError, i18n.EN)
+	assert.False(t, ok)
+}
+
+func TestTranslate_Message(t *testing.T) {
+	v := validator.New()
+	assert.NoError(t, Register(v))
+
+	req := validReq()
+	req.Photo = append(pngHeader, make([]byte, 1024)...)
+	err := v.Struct(req)
+
+	errs, ok := Translate(err, i18n.ID)
+	assert.True(t, ok)
+	assert.Equal(t, Errors{{Field: "photo", Code: CodeInvalidImage, Message: "Harus berupa gambar jpeg atau png maksimal 1 KB", Param: "1"}}, errs)
+
+	errs, ok = Translate(err, i18n.EN)
+	assert.True(t, ok)
+	assert.Equal(t, "Must be a jpeg or png image no larger than 1 KB", errs[0].Message)
+}